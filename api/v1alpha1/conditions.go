@@ -0,0 +1,33 @@
+package v1alpha1
+
+// Condition types set on the status of idpbuilder resources. Ready is set on all of them, so
+// kubectl wait --for=condition=Ready works for every kind.
+const (
+	ConditionReady = "Ready"
+	// ConditionCorePackagesInstalled is set on a Localbuild when the core packages selected by the build are installed.
+	ConditionCorePackagesInstalled = "CorePackagesInstalled"
+	// ConditionRepositoryPushed is set on a GitRepository when its contents are pushed to the git server.
+	ConditionRepositoryPushed = "RepositoryPushed"
+	// ConditionSuperseded is set on a CustomPackage when a package with a higher priority defines the same application.
+	ConditionSuperseded = "Superseded"
+	// ConditionDependenciesBlocked is set on a CustomPackage while the GitRepositories it references are not ready.
+	ConditionDependenciesBlocked = "DependenciesBlocked"
+)
+
+// Reasons for the conditions above.
+const (
+	ReasonReconciled             = "Reconciled"
+	ReasonReconcileFailed        = "ReconcileFailed"
+	ReasonInstalled              = "Installed"
+	ReasonInstallFailed          = "InstallFailed"
+	ReasonCorePackagesNotReady   = "CorePackagesNotReady"
+	ReasonCredentialsNotReady    = "CredentialsNotReady"
+	ReasonBootstrapAppsFailed    = "BootstrapAppsFailed"
+	ReasonPushed                 = "Pushed"
+	ReasonPushFailed             = "PushFailed"
+	ReasonHigherPriorityPackage  = "HigherPriorityPackage"
+	ReasonHighestPriorityPackage = "HighestPriorityPackage"
+	ReasonRepositoriesNotReady   = "RepositoriesNotReady"
+	ReasonRepositoriesReady      = "RepositoriesReady"
+	ReasonSynced                 = "Synced"
+)
@@ -10,6 +10,11 @@ const (
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Application",type="string",JSONPath=".spec.argoCD.name"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Superseded",type="string",JSONPath=".status.conditions[?(@.type==\"Superseded\")].status"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type CustomPackage struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -34,9 +39,19 @@ type CustomPackageSpec struct {
 	GitServerAuthSecretRef SecretReference `json:"gitServerAuthSecretRef"`
 	// InternalGitServeURL specifies the base URL for the git server accessible within the cluster.
 	// for example, http://my-gitea-http.gitea.svc.cluster.local:3000
-	InternalGitServeURL string               `json:"internalGitServeURL"`
+	InternalGitServeURL string `json:"internalGitServeURL"`
+	// GitProvider is the provider of the git server at GitServerURL. Defaults to gitea.
+	// +kubebuilder:validation:Enum:=gitea;github;gitlab
+	// +kubebuilder:validation:Optional
+	GitProvider string `json:"gitProvider,omitempty"`
+	// GitOrganizationName is the organization or group repositories are created in. It is only used with
+	// GitProvider, repositories are created for the admin user of the in-cluster Gitea otherwise.
+	// +kubebuilder:validation:Optional
+	GitOrganizationName string               `json:"gitOrganizationName,omitempty"`
 	RemoteRepository    RemoteRepositorySpec `json:"remoteRepository"`
 	// Replicate specifies whether to replicate remote or local contents to the local gitea server.
+	// Local contents are always replicated. cnoe:// sources of remote contents that are not replicated refer to
+	// RemoteRepository at its ref, which must be readable without credentials.
 	// +kubebuilder:default:=false
 	Replicate bool `json:"replicate"`
 }
@@ -51,12 +66,16 @@ type RemoteRepositorySpec struct {
 	Ref string `json:"ref"`
 }
 
+// ArgoCDPackageSpec is the object that defines a package. Despite its name, it is a Flux object when the GitOps engine
+// is flux.
 type ArgoCDPackageSpec struct {
 	// ApplicationFile specifies the absolute path to the ArgoCD application file
 	ApplicationFile string `json:"applicationFile"`
 	Name            string `json:"name"`
 	Namespace       string `json:"namespace"`
-	// +kubebuilder:validation:Enum:=Application;ApplicationSet
+	// Type is the kind of the first object in ApplicationFile. Flux packages may define a GitRepository together with
+	// the Kustomizations and HelmReleases that use it in one file, all objects of the file are created.
+	// +kubebuilder:validation:Enum:=Application;ApplicationSet;GitRepository;Kustomization;HelmRelease
 	Type string `json:"type"`
 }
 
@@ -65,6 +84,12 @@ type CustomPackageStatus struct {
 	// This only applies for a package that references local directories
 	Synced            bool        `json:"synced,omitempty"`
 	GitRepositoryRefs []ObjectRef `json:"gitRepositoryRefs,omitempty"`
+	// Conditions are the latest observations of the package. See ConditionReady, ConditionSuperseded, and
+	// ConditionDependenciesBlocked.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 type ObjectRef struct {
@@ -7,6 +7,7 @@ import (
 const (
 	GitProviderGitea   = "gitea"
 	GitProviderGitHub  = "github"
+	GitProviderGitLab  = "gitlab"
 	GiteaAdminUserName = "giteaAdmin"
 	SourceTypeLocal    = "local"
 	SourceTypeRemote   = "remote"
@@ -24,7 +25,7 @@ type GitRepositorySpec struct {
 }
 
 type GitRepositorySource struct {
-	// +kubebuilder:validation:Enum:=argocd;gitea;nginx
+	// +kubebuilder:validation:Enum:=argocd;gitea;nginx;envoy-gateway
 	// +kubebuilder:validation:Optional
 	EmbeddedAppName string `json:"embeddedAppName,omitempty"`
 	// Path is the absolute path to directory that contains Kustomize structure or raw manifests.
@@ -39,7 +40,7 @@ type GitRepositorySource struct {
 }
 
 type Provider struct {
-	// +kubebuilder:validation:Enum:=gitea;github
+	// +kubebuilder:validation:Enum:=gitea;github;gitlab
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 	// GitURL is the base URL of Git server used for API calls.
@@ -47,7 +48,9 @@ type Provider struct {
 	// +kubebuilder:validation:Pattern=`^https?:\/\/.+$`
 	GitURL string `json:"gitURL"`
 	// InternalGitURL is the base URL of Git server accessible within the cluster only.
-	InternalGitURL   string `json:"internalGitURL"`
+	InternalGitURL string `json:"internalGitURL"`
+	// OrganizationName is the owner of repositories: the organization in GitHub, the user or organization in Gitea,
+	// or the group in GitLab.
 	OrganizationName string `json:"organizationName"`
 }
 
@@ -76,10 +79,20 @@ type GitRepositoryStatus struct {
 	// +kubebuilder:validation:Optional
 	Path   string `json:"path"`
 	Synced bool   `json:"synced"`
+	// Conditions are the latest observations of the repository. See ConditionReady and ConditionRepositoryPushed.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.commit.hash"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.externalGitRepositoryUrl"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type GitRepository struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
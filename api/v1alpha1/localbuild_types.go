@@ -28,6 +28,8 @@ const (
 	ArgoCDPackageName       = "argocd"
 	GiteaPackageName        = "gitea"
 	IngressNginxPackageName = "nginx"
+	EnvoyGatewayPackageName = "envoy-gateway"
+	FluxPackageName         = "flux"
 )
 
 // ArgoPackageConfigSpec Allows for configuration of the ArgoCD Installation.
@@ -51,6 +53,44 @@ type PackageConfigsSpec struct {
 	CustomPackageUrls        []string                                  `json:"customPackageUrls,omitempty"`
 	// +kubebuilder:validation:Optional
 	CorePackageCustomization map[string]PackageCustomization `json:"packageCustomization,omitempty"`
+	// GitServer is an external git server custom packages are pushed to instead of the in-cluster Gitea.
+	// +kubebuilder:validation:Optional
+	GitServer GitServerSpec `json:"gitServer,omitempty"`
+}
+
+// GitServerTokenKey is the key of the access token in the secret referenced by GitServerSpec.SecretRef.
+const GitServerTokenKey = "token"
+
+// GitServerSpec is a git server outside the cluster. Argo CD pulls from it directly.
+type GitServerSpec struct {
+	// Provider is the provider of the git server. An empty provider means the in-cluster Gitea.
+	// +kubebuilder:validation:Enum:=gitea;github;gitlab
+	// +kubebuilder:validation:Optional
+	Provider string `json:"provider,omitempty"`
+	// URL is the base URL of the git server, e.g. https://gitlab.com. Defaults to https://github.com for GitHub.
+	URL string `json:"url,omitempty"`
+	// OrganizationName is the organization or group repositories are created in.
+	OrganizationName string `json:"organizationName,omitempty"`
+	// SecretRef is the secret with the access token of the git server, in the token key.
+	SecretRef SecretReference `json:"secretRef,omitempty"`
+}
+
+// PackageAuthSpec configures how remote repositories of packages are cloned. It only references credentials, which are
+// read when a repository is cloned and are never copied into resources.
+type PackageAuthSpec struct {
+	// SSHKeyPath is the private key file used for ssh urls. The ssh agent is used when empty.
+	SSHKeyPath string `json:"sshKeyPath,omitempty"`
+	// KnownHostsPath is the known_hosts file host keys of ssh urls are checked against. Defaults to the files used by
+	// ssh, see SSH_KNOWN_HOSTS.
+	KnownHostsPath string `json:"knownHostsPath,omitempty"`
+	// SecretRef is a secret with the username and password, or the sshPrivateKey, of remote repositories.
+	SecretRef SecretReference `json:"secretRef,omitempty"`
+	// CredentialHelper asks the git credential helpers of the host for the credentials of https urls.
+	CredentialHelper bool `json:"credentialHelper,omitempty"`
+	// URL is the prefix of the https urls the access token in IDPBUILDER_GIT_TOKEN and the username and password of
+	// SecretRef are sent to, e.g. https://github.com/my-org. A url key in the secret takes precedence, like in Argo CD
+	// repository credential templates. Credentials are not sent to other urls.
+	URL string `json:"url,omitempty"`
 }
 
 // BuildCustomizationSpec fields cannot change once a cluster is created
@@ -62,6 +102,32 @@ type BuildCustomizationSpec struct {
 	UsePathRouting bool   `json:"usePathRouting,omitempty"`
 	SelfSignedCert string `json:"selfSignedCert,omitempty"`
 	StaticPassword bool   `json:"staticPassword,omitempty"`
+	// Proxy configures proxy environment variables for cluster nodes and core packages.
+	Proxy ProxySpec `json:"proxy,omitempty"`
+	// ExtraCACerts is a PEM encoded bundle of certificate authorities trusted by cluster nodes, core packages and idpbuilder.
+	ExtraCACerts string `json:"extraCACerts,omitempty"`
+	// GiteaDataDir is a directory on the host that stores Gitea data so it survives recreating the cluster.
+	GiteaDataDir string `json:"giteaDataDir,omitempty"`
+	// Ingress selects how requests are routed to packages: nginx (ingress-nginx with Ingress objects) or gateway-api
+	// (Envoy Gateway with HTTPRoute objects). Defaults to nginx.
+	Ingress string `json:"ingress,omitempty"`
+	// SkipGitea disables installing the in-cluster Gitea. Packages must then be pushed to an external git server, see
+	// PackageConfigsSpec.GitServer.
+	SkipGitea bool `json:"skipGitea,omitempty"`
+	// GiteaPrivateRepositories creates repositories of the in-cluster Gitea as private repositories. Argo CD then
+	// reads them with a read-only token of the Gitea admin user.
+	GiteaPrivateRepositories bool `json:"giteaPrivateRepositories,omitempty"`
+	// PackageAuth configures authentication to remote repositories of packages.
+	PackageAuth PackageAuthSpec `json:"packageAuth,omitempty"`
+	// GitOpsEngine selects the engine that syncs packages from the git server: argocd (Argo CD with Application
+	// objects) or flux (Flux with GitRepository and Kustomization objects). Defaults to argocd.
+	GitOpsEngine string `json:"gitOpsEngine,omitempty"`
+}
+
+type ProxySpec struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
 }
 
 type LocalbuildSpec struct {
@@ -75,6 +141,16 @@ type PackageCustomization struct {
 	Name string `json:"name,omitempty'"`
 	// FilePath is the absolute file path to a YAML file that contains Kubernetes manifests.
 	FilePath string `json:"filePath,omitempty"`
+	// ReadinessTimeout is how long to wait for the resources of the package to become ready. Defaults to 5 minutes.
+	// +optional
+	ReadinessTimeout *metav1.Duration `json:"readinessTimeout,omitempty"`
+	// Version is the version of the package to install. Empty means the version embedded in idpbuilder.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// ManifestsPath is the absolute path to a directory in the manifest cache that contains the manifests of Version.
+	// See idpbuilder manifests pull.
+	// +optional
+	ManifestsPath string `json:"manifestsPath,omitempty"`
 }
 
 type LocalbuildStatus struct {
@@ -84,6 +160,18 @@ type LocalbuildStatus struct {
 	ArgoCD             ArgoCDStatus `json:"ArgoCD,omitempty"`
 	Nginx              NginxStatus  `json:"nginx,omitempty"`
 	Gitea              GiteaStatus  `json:"gitea,omitempty"`
+	// EnvoyGateway is the status of Envoy Gateway, which is installed instead of ingress-nginx when the ingress is
+	// gateway-api.
+	// +optional
+	EnvoyGateway EnvoyGatewayStatus `json:"envoyGateway,omitempty"`
+	// Flux is the status of Flux, which is installed instead of Argo CD when the GitOps engine is flux.
+	// +optional
+	Flux FluxStatus `json:"flux,omitempty"`
+	// Conditions are the latest observations of the Localbuild. See ConditionReady and ConditionCorePackagesInstalled.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 type GiteaStatus struct {
@@ -92,20 +180,42 @@ type GiteaStatus struct {
 	InternalURL              string `json:"internalURL,omitempty"`
 	AdminUserSecretName      string `json:"adminUserSecretNameecret,omitempty"`
 	AdminUserSecretNamespace string `json:"adminUserSecretNamespace,omitempty"`
+	// Version is the version of the installed Gitea manifests.
+	Version string `json:"version,omitempty"`
 }
 
 type ArgoCDStatus struct {
 	Available   bool `json:"available,omitempty"`
 	AppsCreated bool `json:"appsCreated,omitempty"`
+	// Version is the version of the installed Argo CD manifests.
+	Version string `json:"version,omitempty"`
 }
 
 type NginxStatus struct {
 	Available bool `json:"available,omitempty"`
+	// Version is the version of the installed ingress-nginx manifests.
+	Version string `json:"version,omitempty"`
+}
+
+type EnvoyGatewayStatus struct {
+	Available bool `json:"available,omitempty"`
+	// Version is the version of the installed Envoy Gateway manifests.
+	Version string `json:"version,omitempty"`
+}
+
+type FluxStatus struct {
+	Available bool `json:"available,omitempty"`
+	// Version is the version of the installed Flux manifests.
+	Version string `json:"version,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=localbuilds,scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Gitea",type="string",JSONPath=".status.gitea.externalURL"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Localbuild struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
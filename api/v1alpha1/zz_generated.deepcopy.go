@@ -21,6 +21,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -72,6 +73,8 @@ func (in *ArgoPackageConfigSpec) DeepCopy() *ArgoPackageConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildCustomizationSpec) DeepCopyInto(out *BuildCustomizationSpec) {
 	*out = *in
+	out.Proxy = in.Proxy
+	out.PackageAuth = in.PackageAuth
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCustomizationSpec.
@@ -184,6 +187,13 @@ func (in *CustomPackageStatus) DeepCopyInto(out *CustomPackageStatus) {
 		*out = make([]ObjectRef, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomPackageStatus.
@@ -217,7 +227,7 @@ func (in *GitRepository) DeepCopyInto(out *GitRepository) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepository.
@@ -309,6 +319,13 @@ func (in *GitRepositorySpec) DeepCopy() *GitRepositorySpec {
 func (in *GitRepositoryStatus) DeepCopyInto(out *GitRepositoryStatus) {
 	*out = *in
 	out.LatestCommit = in.LatestCommit
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositoryStatus.
@@ -321,6 +338,36 @@ func (in *GitRepositoryStatus) DeepCopy() *GitRepositoryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvoyGatewayStatus) DeepCopyInto(out *EnvoyGatewayStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvoyGatewayStatus.
+func (in *EnvoyGatewayStatus) DeepCopy() *EnvoyGatewayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvoyGatewayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluxStatus) DeepCopyInto(out *FluxStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluxStatus.
+func (in *FluxStatus) DeepCopy() *FluxStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FluxStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GiteaStatus) DeepCopyInto(out *GiteaStatus) {
 	*out = *in
@@ -336,13 +383,29 @@ func (in *GiteaStatus) DeepCopy() *GiteaStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitServerSpec) DeepCopyInto(out *GitServerSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitServerSpec.
+func (in *GitServerSpec) DeepCopy() *GitServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Localbuild) DeepCopyInto(out *Localbuild) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Localbuild.
@@ -418,6 +481,15 @@ func (in *LocalbuildStatus) DeepCopyInto(out *LocalbuildStatus) {
 	out.ArgoCD = in.ArgoCD
 	out.Nginx = in.Nginx
 	out.Gitea = in.Gitea
+	out.EnvoyGateway = in.EnvoyGateway
+	out.Flux = in.Flux
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalbuildStatus.
@@ -460,6 +532,22 @@ func (in *ObjectRef) DeepCopy() *ObjectRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageAuthSpec) DeepCopyInto(out *PackageAuthSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageAuthSpec.
+func (in *PackageAuthSpec) DeepCopy() *PackageAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PackageConfigsSpec) DeepCopyInto(out *PackageConfigsSpec) {
 	*out = *in
@@ -484,9 +572,10 @@ func (in *PackageConfigsSpec) DeepCopyInto(out *PackageConfigsSpec) {
 		in, out := &in.CorePackageCustomization, &out.CorePackageCustomization
 		*out = make(map[string]PackageCustomization, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	out.GitServer = in.GitServer
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageConfigsSpec.
@@ -502,6 +591,11 @@ func (in *PackageConfigsSpec) DeepCopy() *PackageConfigsSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PackageCustomization) DeepCopyInto(out *PackageCustomization) {
 	*out = *in
+	if in.ReadinessTimeout != nil {
+		in, out := &in.ReadinessTimeout, &out.ReadinessTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCustomization.
@@ -529,6 +623,21 @@ func (in *Provider) DeepCopy() *Provider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxySpec) DeepCopyInto(out *ProxySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxySpec.
+func (in *ProxySpec) DeepCopy() *ProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemoteRepositorySpec) DeepCopyInto(out *RemoteRepositorySpec) {
 	*out = *in
@@ -0,0 +1,8 @@
+// Package hack holds the inputs used to generate the core package manifests embedded in idpbuilder. They are embedded
+// so other versions of the core packages can be generated the same way, see pkg/manifests.
+package hack
+
+import "embed"
+
+//go:embed argo-cd/*.yaml argo-cd/*.tmpl gitea/*.yaml ingress-nginx/*.yaml ingress-nginx/*.tmpl
+var FS embed.FS
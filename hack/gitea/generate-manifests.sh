@@ -21,6 +21,4 @@ sed -i.bak '3d' ${INSTALL_YAML}
 # and: https://github.com/splunk/splunk-connect-for-kubernetes/pull/790
 sed -i.bak 's/namespace: default/namespace: gitea/g' ${INSTALL_YAML}
 
-cat ${GITEA_DIR}/ingress.yaml.tmpl >>${INSTALL_YAML}
-
 rm -rf "${INSTALL_YAML}.bak"
@@ -9,7 +9,10 @@ import (
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/controllers"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/state"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -28,8 +31,10 @@ var (
 
 type Build struct {
 	name                 string
+	clusterName          string
 	cfg                  v1alpha1.BuildCustomizationSpec
 	kindConfigPath       string
+	kindConfigPatches    []string
 	kubeConfigPath       string
 	kubeVersion          string
 	extraPortsMapping    string
@@ -38,7 +43,10 @@ type Build struct {
 	customPackageDirs    []string
 	customPackageUrls    []string
 	packageCustomization map[string]v1alpha1.PackageCustomization
+	gitServer            v1alpha1.GitServerSpec
+	gitServerToken       string
 	exitOnSync           bool
+	importState          *state.Snapshot
 	scheme               *runtime.Scheme
 	CancelFunc           context.CancelFunc
 }
@@ -47,6 +55,7 @@ type NewBuildOptions struct {
 	Name                 string
 	TemplateData         v1alpha1.BuildCustomizationSpec
 	KindConfigPath       string
+	KindConfigPatches    []string
 	KubeConfigPath       string
 	KubeVersion          string
 	ExtraPortsMapping    string
@@ -56,14 +65,27 @@ type NewBuildOptions struct {
 	CustomPackageUrls    []string
 	PackageCustomization map[string]v1alpha1.PackageCustomization
 	ExitOnSync           bool
+	ImportState          *state.Snapshot
 	Scheme               *runtime.Scheme
 	CancelFunc           context.CancelFunc
+	// ClusterName is the name of the kind cluster. It defaults to Name. Several builds can share a cluster.
+	ClusterName string
+	// GitServer is the external git server custom packages are pushed to. Empty uses the in-cluster Gitea.
+	GitServer v1alpha1.GitServerSpec
+	// GitServerToken is the access token of GitServer. It is stored in a secret referenced by GitServer.SecretRef.
+	GitServerToken string
 }
 
 func NewBuild(opts NewBuildOptions) *Build {
+	clusterName := opts.ClusterName
+	if clusterName == "" {
+		clusterName = opts.Name
+	}
 	return &Build{
 		name:                 opts.Name,
+		clusterName:          clusterName,
 		kindConfigPath:       opts.KindConfigPath,
+		kindConfigPatches:    opts.KindConfigPatches,
 		kubeConfigPath:       opts.KubeConfigPath,
 		kubeVersion:          opts.KubeVersion,
 		extraPortsMapping:    opts.ExtraPortsMapping,
@@ -72,7 +94,10 @@ func NewBuild(opts NewBuildOptions) *Build {
 		customPackageDirs:    opts.CustomPackageDirs,
 		customPackageUrls:    opts.CustomPackageUrls,
 		packageCustomization: opts.PackageCustomization,
+		gitServer:            opts.GitServer,
+		gitServerToken:       opts.GitServerToken,
 		exitOnSync:           opts.ExitOnSync,
+		importState:          opts.ImportState,
 		scheme:               opts.Scheme,
 		cfg:                  opts.TemplateData,
 		CancelFunc:           opts.CancelFunc,
@@ -81,7 +106,7 @@ func NewBuild(opts NewBuildOptions) *Build {
 
 func (b *Build) ReconcileKindCluster(ctx context.Context, recreateCluster bool) error {
 	// Initialize Kind Cluster
-	cluster, err := kind.NewCluster(b.name, b.kubeVersion, b.kubeConfigPath, b.kindConfigPath, b.extraPortsMapping, b.registryConfig, b.cfg, setupLog)
+	cluster, err := kind.NewCluster(b.clusterName, b.kubeVersion, b.kubeConfigPath, b.kindConfigPath, b.kindConfigPatches, b.extraPortsMapping, b.registryConfig, b.cfg, setupLog)
 	if err != nil {
 		setupLog.Error(err, "Error Creating kind cluster")
 		return err
@@ -94,7 +119,7 @@ func (b *Build) ReconcileKindCluster(ctx context.Context, recreateCluster bool)
 	}
 
 	// Create Kube Config for Kind cluster
-	if err := cluster.ExportKubeConfig(b.name, false); err != nil {
+	if err := cluster.ExportKubeConfig(b.clusterName, false); err != nil {
 		setupLog.Error(err, "Error exporting kubeconfig from kind cluster")
 		return err
 	}
@@ -129,7 +154,7 @@ func (b *Build) ReconcileCRDs(ctx context.Context, kubeClient client.Client) err
 }
 
 func (b *Build) RunControllers(ctx context.Context, mgr manager.Manager, exitCh chan error, tmpDir string) error {
-	return controllers.RunControllers(ctx, mgr, exitCh, b.CancelFunc, b.exitOnSync, b.cfg, tmpDir)
+	return controllers.RunControllers(ctx, mgr, exitCh, b.CancelFunc, b.exitOnSync, b.name, b.cfg, tmpDir, b.importState)
 }
 
 func (b *Build) isCompatible(ctx context.Context, kubeClient client.Client) (bool, error) {
@@ -156,12 +181,32 @@ func (b *Build) isCompatible(ctx context.Context, kubeClient client.Client) (boo
 	existing, given := localBuild.Spec.BuildCustomization, b.cfg
 	existing.SelfSignedCert = ""
 	given.SelfSignedCert = ""
+	existing.ExtraCACerts = ""
+	given.ExtraCACerts = ""
 
 	return false, fmt.Errorf("provided command flags and existing configurations are incompatible. please recreate the cluster. "+
 		"existing: %+v, given: %+v",
 		existing, given)
 }
 
+// checkSharedCluster returns an error if other Localbuilds in the cluster use different settings. Builds in the same
+// cluster share the core packages, so they must agree on how they are exposed. Application names of custom packages
+// are only known once the packages are read, so collisions between them are detected by the Localbuild controller.
+func (b *Build) checkSharedCluster(ctx context.Context, kubeClient client.Client) error {
+	localBuilds := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &localBuilds); err != nil {
+		return fmt.Errorf("listing localbuilds: %w", err)
+	}
+	for i := range localBuilds.Items {
+		other := localBuilds.Items[i]
+		if other.Name != b.name && !isBuildCustomizationSpecEqual(b.cfg, other.Spec.BuildCustomization) {
+			return fmt.Errorf("localbuild %s in cluster %s uses different settings. localbuilds sharing a cluster "+
+				"must use the same host, ingress host, port, protocol, path routing, password, ingress, GitOps engine and Gitea settings", other.Name, b.clusterName)
+		}
+	}
+	return nil
+}
+
 func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 	setupLog.Info("Creating kind cluster")
 	if err := b.ReconcileKindCluster(ctx, recreateCluster); err != nil {
@@ -228,6 +273,24 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 	if !ok {
 		return err
 	}
+	if err = b.checkSharedCluster(ctx, kubeClient); err != nil {
+		return err
+	}
+
+	if b.importState != nil {
+		setupLog.Info("Restoring secrets from state file")
+		if err := state.RestoreSecrets(ctx, kubeClient, b.importState); err != nil {
+			return fmt.Errorf("restoring secrets: %w", err)
+		}
+	}
+
+	if b.gitServer.Provider != "" {
+		setupLog.Info("Storing git server token", "provider", b.gitServer.Provider, "url", b.gitServer.URL)
+		b.gitServer.SecretRef, err = setupGitServerToken(ctx, kubeClient, b.name, b.gitServerToken)
+		if err != nil {
+			return err
+		}
+	}
 
 	managerExit := make(chan error)
 
@@ -251,22 +314,7 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 			localBuild.ObjectMeta.Annotations = map[string]string{}
 		}
 		localBuild.ObjectMeta.Annotations[v1alpha1.CliStartTimeAnnotation] = cliStartTime
-		localBuild.Spec = v1alpha1.LocalbuildSpec{
-			BuildCustomization: b.cfg,
-			PackageConfigs: v1alpha1.PackageConfigsSpec{
-				Argo: v1alpha1.ArgoPackageConfigSpec{
-					Enabled: true,
-				},
-				EmbeddedArgoApplications: v1alpha1.EmbeddedArgoApplicationsPackageConfigSpec{
-					Enabled: true,
-				},
-				CustomPackageDirs:        b.customPackageDirs,
-				CustomPackageFiles:       b.customPackageFiles,
-				CustomPackageUrls:        b.customPackageUrls,
-				CorePackageCustomization: b.packageCustomization,
-			},
-		}
-
+		localBuild.Spec = b.localbuild(cliStartTime).Spec
 		return nil
 	})
 	if err != nil {
@@ -284,6 +332,34 @@ func (b *Build) Run(ctx context.Context, recreateCluster bool) error {
 	return nil
 }
 
+// localbuild returns the Localbuild resource for this build.
+func (b *Build) localbuild(cliStartTime string) v1alpha1.Localbuild {
+	return v1alpha1.Localbuild{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: b.name,
+			Annotations: map[string]string{
+				v1alpha1.CliStartTimeAnnotation: cliStartTime,
+			},
+		},
+		Spec: v1alpha1.LocalbuildSpec{
+			BuildCustomization: b.cfg,
+			PackageConfigs: v1alpha1.PackageConfigsSpec{
+				Argo: v1alpha1.ArgoPackageConfigSpec{
+					Enabled: true,
+				},
+				EmbeddedArgoApplications: v1alpha1.EmbeddedArgoApplicationsPackageConfigSpec{
+					Enabled: true,
+				},
+				CustomPackageDirs:        b.customPackageDirs,
+				CustomPackageFiles:       b.customPackageFiles,
+				CustomPackageUrls:        b.customPackageUrls,
+				CorePackageCustomization: b.packageCustomization,
+				GitServer:                b.gitServer,
+			},
+		},
+	}
+}
+
 func isBuildCustomizationSpecEqual(s1, s2 v1alpha1.BuildCustomizationSpec) bool {
 	// probably ok to use cmp.Equal but keeping it simple for now
 	return s1.Protocol == s2.Protocol &&
@@ -292,5 +368,17 @@ func isBuildCustomizationSpecEqual(s1, s2 v1alpha1.BuildCustomizationSpec) bool
 		s1.Port == s2.Port &&
 		s1.UsePathRouting == s2.UsePathRouting &&
 		s1.SelfSignedCert == s2.SelfSignedCert &&
-		s1.StaticPassword == s2.StaticPassword
+		s1.StaticPassword == s2.StaticPassword &&
+		valueOrDefault(s1.Ingress, ingress.NginxProviderName) == valueOrDefault(s2.Ingress, ingress.NginxProviderName) &&
+		valueOrDefault(s1.GitOpsEngine, gitops.ArgoCDEngineName) == valueOrDefault(s2.GitOpsEngine, gitops.ArgoCDEngineName) &&
+		s1.SkipGitea == s2.SkipGitea &&
+		s1.GiteaPrivateRepositories == s2.GiteaPrivateRepositories
+}
+
+// valueOrDefault returns def if v is empty, so settings left empty compare equal to their default.
+func valueOrDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
 }
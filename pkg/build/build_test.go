@@ -5,12 +5,17 @@ import (
 	"testing"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestIsCompatible(t *testing.T) {
@@ -65,3 +70,45 @@ func TestIsCompatible(t *testing.T) {
 	fClient.AssertExpectations(t)
 	require.True(t, ok)
 }
+
+func TestCheckSharedCluster(t *testing.T) {
+	ctx := context.Background()
+	cfg := v1alpha1.BuildCustomizationSpec{Protocol: "https", Host: "cnoe.localtest.me", IngressHost: "cnoe.localtest.me", Port: "8443"}
+	other := cfg
+	other.Port = "9443"
+
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(
+		&v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}, Spec: v1alpha1.LocalbuildSpec{BuildCustomization: cfg}},
+	).Build()
+
+	b := Build{name: "team-b", clusterName: "shared", cfg: cfg}
+	assert.NoError(t, b.checkSharedCluster(ctx, kubeClient))
+
+	b = Build{name: "team-b", clusterName: "shared", cfg: other}
+	assert.ErrorContains(t, b.checkSharedCluster(ctx, kubeClient), "team-a")
+
+	// its own settings are checked by isCompatible
+	b = Build{name: "team-a", clusterName: "shared", cfg: other}
+	assert.NoError(t, b.checkSharedCluster(ctx, kubeClient))
+}
+
+func TestIsBuildCustomizationSpecEqual(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{Protocol: "https", Host: "cnoe.localtest.me", Port: "8443"}
+
+	// empty settings are their defaults.
+	withDefaults := cfg
+	withDefaults.Ingress = ingress.NginxProviderName
+	withDefaults.GitOpsEngine = gitops.ArgoCDEngineName
+	assert.True(t, isBuildCustomizationSpecEqual(cfg, withDefaults))
+
+	for name, change := range map[string]func(*v1alpha1.BuildCustomizationSpec){
+		"ingress":      func(c *v1alpha1.BuildCustomizationSpec) { c.Ingress = ingress.GatewayAPIProviderName },
+		"gitops":       func(c *v1alpha1.BuildCustomizationSpec) { c.GitOpsEngine = gitops.FluxEngineName },
+		"skip gitea":   func(c *v1alpha1.BuildCustomizationSpec) { c.SkipGitea = true },
+		"private repo": func(c *v1alpha1.BuildCustomizationSpec) { c.GiteaPrivateRepositories = true },
+	} {
+		changed := cfg
+		change(&changed)
+		assert.False(t, isBuildCustomizationSpecEqual(cfg, changed), name)
+	}
+}
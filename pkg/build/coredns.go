@@ -6,71 +6,59 @@ import (
 	"fmt"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
 	coreDNSTemplatePath = "templates/coredns"
+	// the config map users edit to add their own rules.
+	coreDNSCustomConfigName = "coredns-conf-custom"
 )
 
 //go:embed templates
 var templates embed.FS
 
+// setupCoreDNS applies the CoreDNS configuration with server-side apply, so manual edits and changes in newer
+// releases are reconciled. The custom config is only created, because users edit it to add their own rules.
 func setupCoreDNS(ctx context.Context, kubeClient client.Client, scheme *runtime.Scheme, templateData v1alpha1.BuildCustomizationSpec) error {
-	checkCM := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "coredns-conf-default",
-			Namespace: "kube-system",
-		},
-	}
-	err := kubeClient.Get(ctx, client.ObjectKeyFromObject(checkCM), checkCM)
-	if err == nil {
-		return nil
-	}
-
-	objs, err := k8s.BuildCustomizedObjects("", coreDNSTemplatePath, templates, scheme, templateData)
+	objs, err := coreDNSObjects(scheme, templateData)
 	if err != nil {
-		return fmt.Errorf("rendering embedded coredns files: %w", err)
+		return err
 	}
 
 	for i := range objs {
-		obj := objs[i]
-		switch t := obj.(type) {
-		case *appsv1.Deployment:
-			dep := &appsv1.Deployment{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      t.Name,
-					Namespace: t.Namespace,
-				},
-			}
-			_, err = controllerutil.CreateOrUpdate(ctx, kubeClient, dep, func() error {
-				dep.Spec = t.Spec
-				return nil
-			})
-			if err != nil {
-				return fmt.Errorf("creating/updating deployment: %w", err)
-			}
-		case *corev1.ConfigMap:
-			cm := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      t.Name,
-					Namespace: t.Namespace,
-				},
-			}
-			_, err = controllerutil.CreateOrUpdate(ctx, kubeClient, cm, func() error {
-				cm.Data = t.Data
-				return nil
-			})
-			if err != nil {
-				return fmt.Errorf("creating/updating configmap: %w", err)
+		if objs[i].GetName() == coreDNSCustomConfigName {
+			if err = k8s.EnsureObject(ctx, kubeClient, objs[i], ""); err != nil {
+				return fmt.Errorf("creating coredns custom config: %w", err)
 			}
+			continue
+		}
+		if _, err = k8s.ApplyObject(ctx, kubeClient, objs[i]); err != nil {
+			return fmt.Errorf("setting up coredns: %w", err)
 		}
 	}
 	return nil
 }
+
+// coreDNSTemplateData is the data the CoreDNS templates are rendered with.
+type coreDNSTemplateData struct {
+	v1alpha1.BuildCustomizationSpec
+	// IngressService is the DNS name of the service of the ingress, which host names of the cluster resolve to.
+	IngressService string
+}
+
+func coreDNSObjects(scheme *runtime.Scheme, templateData v1alpha1.BuildCustomizationSpec) ([]client.Object, error) {
+	provider, err := ingress.ForBuild(templateData)
+	if err != nil {
+		return nil, err
+	}
+	data := coreDNSTemplateData{BuildCustomizationSpec: templateData, IngressService: ingress.ServiceHost(provider)}
+	objs, err := k8s.BuildCustomizedObjects("", coreDNSTemplatePath, templates, scheme, data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering embedded coredns files: %w", err)
+	}
+	return objs, nil
+}
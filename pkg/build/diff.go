@@ -0,0 +1,109 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DiffCore writes the differences between the core package objects in the cluster and the objects idpbuilder applies
+// for localBuild to out. Objects that the next install prunes are shown as deleted. It reports whether there are
+// differences. Nothing is changed in the cluster.
+func DiffCore(ctx context.Context, kubeClient client.Client, scheme *runtime.Scheme, localBuild *v1alpha1.Localbuild, out io.Writer) (bool, error) {
+	cfg := localBuild.Spec.BuildCustomization
+	drift := false
+	write := func(diff string) error {
+		if diff == "" {
+			return nil
+		}
+		drift = true
+		_, err := io.WriteString(out, diff)
+		return err
+	}
+
+	coreDNS, err := coreDNSObjects(scheme, cfg)
+	if err != nil {
+		return false, err
+	}
+	for i := range coreDNS {
+		if coreDNS[i].GetName() == coreDNSCustomConfigName {
+			continue
+		}
+		diff, dErr := k8s.DiffObject(ctx, kubeClient, coreDNS[i])
+		if dErr != nil {
+			return false, dErr
+		}
+		if err = write(diff.Diff); err != nil {
+			return false, err
+		}
+	}
+
+	names, err := localbuild.CorePackageNames(cfg)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		inst, err := localbuild.NewCorePackageInstallation(name, localBuild.Spec.PackageConfigs.CorePackageCustomization[name])
+		if err != nil {
+			return false, err
+		}
+		manifests, err := inst.Manifests(scheme, cfg)
+		if err != nil {
+			return false, fmt.Errorf("rendering %s manifests: %w", name, err)
+		}
+		objs, err := k8s.ConvertRawResourcesToObjects(scheme, manifests)
+		if err != nil {
+			return false, fmt.Errorf("reading %s manifests: %w", name, err)
+		}
+		if _, _, err = diffCorePackage(ctx, kubeClient, inst.Namespace(), objs, write); err != nil {
+			return false, fmt.Errorf("diffing %s: %w", name, err)
+		}
+	}
+	return drift, nil
+}
+
+// diffCorePackage passes the diff of each object in objs, and of each object pruned when objs are installed, to write.
+// It returns the number of objects that change and the number of objects that are pruned.
+func diffCorePackage(ctx context.Context, kubeClient client.Client, namespace string, objs []client.Object, write func(string) error) (int, int, error) {
+	nsClient := client.NewNamespacedClient(kubeClient, namespace)
+	changed, pruned := 0, 0
+	current := make([]corev1.ObjectReference, 0, len(objs))
+	for i := range objs {
+		diff, err := k8s.DiffObject(ctx, nsClient, objs[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		if diff.Diff != "" {
+			changed++
+		}
+		if err = write(diff.Diff); err != nil {
+			return 0, 0, err
+		}
+		current = append(current, diff.Object)
+	}
+
+	previous, err := k8s.GetInventory(ctx, kubeClient, namespace)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ref := range k8s.PruneCandidates(previous, current) {
+		diff, err := k8s.DiffPruned(ctx, kubeClient, ref)
+		if err != nil {
+			return 0, 0, err
+		}
+		if diff != "" {
+			pruned++
+		}
+		if err = write(diff); err != nil {
+			return 0, 0, err
+		}
+	}
+	return changed, pruned, nil
+}
@@ -0,0 +1,43 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const gitServerTokenSecretName = "git-server-token"
+
+// setupGitServerToken stores the access token of the external git server in the project namespace of the build and
+// returns the reference to it. The token is never written to the Localbuild resource.
+func setupGitServerToken(ctx context.Context, kubeClient client.Client, buildName, token string) (v1alpha1.SecretReference, error) {
+	namespace := globals.GetProjectNamespace(buildName)
+	if err := k8s.EnsureNamespace(ctx, kubeClient, namespace); err != nil {
+		return v1alpha1.SecretReference{}, fmt.Errorf("creating namespace %s: %w", namespace, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      gitServerTokenSecretName,
+			Namespace: namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kubeClient, secret, func() error {
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{
+			v1alpha1.GitServerTokenKey: []byte(token),
+		}
+		return nil
+	})
+	if err != nil {
+		return v1alpha1.SecretReference{}, fmt.Errorf("creating git server token secret: %w", err)
+	}
+	return v1alpha1.SecretReference{Name: secret.Name, Namespace: secret.Namespace}, nil
+}
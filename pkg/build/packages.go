@@ -0,0 +1,104 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// UpdateFunc changes the Localbuild of an existing cluster before the controllers run.
+type UpdateFunc func(ctx context.Context, kubeClient client.Client, localBuild *v1alpha1.Localbuild) error
+
+// UpdatePackages changes the Localbuild of an existing cluster with update, then runs the controllers until the
+// packages are synced. Settings that cannot change once a cluster is created are taken from the existing Localbuild.
+func (b *Build) UpdatePackages(ctx context.Context, update UpdateFunc) error {
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: b.kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + b.clusterName},
+	).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+
+	kubeClient, err := b.GetKubeClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	localBuild := v1alpha1.Localbuild{}
+	if err = kubeClient.Get(ctx, client.ObjectKey{Name: b.name}, &localBuild); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("cluster %s was not created by idpbuilder: %w", b.name, err)
+		}
+		return fmt.Errorf("getting localbuild %s: %w", b.name, err)
+	}
+
+	b.cfg = localBuild.Spec.BuildCustomization
+	if err = util.SetExtraCACerts([]byte(b.cfg.ExtraCACerts)); err != nil {
+		return err
+	}
+	if err = util.SetProxyEnv(b.cfg.Proxy); err != nil {
+		return err
+	}
+
+	if err = update(ctx, kubeClient, &localBuild); err != nil {
+		return err
+	}
+
+	// the Localbuild is updated before the controllers start, so they never act on the previous package list.
+	setupLog.Info("Updating localbuild resource")
+	if localBuild.Annotations == nil {
+		localBuild.Annotations = map[string]string{}
+	}
+	localBuild.Annotations[v1alpha1.CliStartTimeAnnotation] = time.Now().Format(time.RFC3339Nano)
+	if err = kubeClient.Update(ctx, &localBuild); err != nil {
+		return fmt.Errorf("updating localbuild resource: %w", err)
+	}
+
+	setupLog.V(1).Info("Creating controller manager")
+	mgr, err := ctrl.NewManager(kubeConfig, ctrl.Options{
+		Scheme: b.scheme,
+		Metrics: server.Options{
+			BindAddress: "0",
+		},
+	})
+	if err != nil {
+		setupLog.Error(err, "Error creating controller manager")
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-%s-", globals.ProjectName, b.name))
+	if err != nil {
+		setupLog.Error(err, "creating temp dir")
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	managerExit := make(chan error)
+
+	setupLog.V(1).Info("Running controllers")
+	if err = b.RunControllers(ctx, mgr, managerExit, dir); err != nil {
+		setupLog.Error(err, "Error running controllers")
+		return err
+	}
+
+	select {
+	case mgrErr := <-managerExit:
+		if mgrErr != nil {
+			return mgrErr
+		}
+	case <-ctx.Done():
+		return nil
+	}
+	return nil
+}
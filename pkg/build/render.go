@@ -0,0 +1,288 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/custompackage"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/gitrepository"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// used instead of the start time so rendered files can be compared between runs.
+	dryRunCLIStartTime = "dry-run"
+	// the controllers only need a few passes to create all objects and point them at each other.
+	renderPasses = 2
+)
+
+// Render writes everything Run would apply to dir without creating a cluster. Package objects are generated by
+// running the controllers against an in-memory client. Git repositories are not created, their URLs are predicted.
+func (b *Build) Render(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating output dir: %w", err)
+	}
+
+	kindConfig, err := kind.RenderConfig(b.clusterName, b.kubeVersion, b.kindConfigPath, b.kindConfigPatches, b.extraPortsMapping, b.registryConfig, b.cfg)
+	if err != nil {
+		return fmt.Errorf("rendering kind config: %w", err)
+	}
+	if err = writeFile(dir, "kind-config.yaml", kindConfig); err != nil {
+		return err
+	}
+
+	coreDNS, err := coreDNSObjects(b.scheme, b.cfg)
+	if err != nil {
+		return err
+	}
+	if err = b.writeObjects(dir, "coredns.yaml", coreDNS); err != nil {
+		return err
+	}
+
+	crds, err := controllers.CRDs(b.scheme, b.cfg)
+	if err != nil {
+		return fmt.Errorf("rendering crds: %w", err)
+	}
+	if err = b.writeObjects(dir, "crds.yaml", crds); err != nil {
+		return err
+	}
+
+	names, err := localbuild.CorePackageNames(b.cfg)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		manifests, err := localbuild.GetEmbeddedRawInstallResources(name, b.cfg, b.packageCustomization[name], b.scheme)
+		if err != nil {
+			return fmt.Errorf("rendering %s manifests: %w", name, err)
+		}
+		var out []byte
+		for _, m := range manifests {
+			out = append(out, []byte("---\n")...)
+			out = append(out, m...)
+		}
+		if err = writeFile(dir, filepath.Join("core", name+".yaml"), out); err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "idpbuilder-render-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	return b.renderPackages(ctx, dir, tmpDir)
+}
+
+// renderPackages writes the Localbuild and the Applications or Flux objects, CustomPackages, and GitRepositories
+// created for it.
+func (b *Build) renderPackages(ctx context.Context, dir, tmpDir string) error {
+	localBuild := b.localbuild(dryRunCLIStartTime)
+	if !b.cfg.SkipGitea {
+		giteaUrl := util.GiteaBaseUrl(b.cfg)
+		localBuild.Status.Gitea = v1alpha1.GiteaStatus{
+			ExternalURL:              giteaUrl,
+			InternalURL:              giteaUrl,
+			AdminUserSecretName:      util.GiteaAdminSecret,
+			AdminUserSecretNamespace: util.GiteaNamespace,
+			Available:                true,
+		}
+	}
+
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(b.scheme).
+		WithStatusSubresource(&v1alpha1.Localbuild{}, &v1alpha1.GitRepository{}, &v1alpha1.CustomPackage{}).
+		WithObjects(&localBuild).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// the fake client does not support server-side apply, which is only used for bookkeeping annotations.
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() == types.ApplyPatchType {
+					return nil
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	if b.gitServer.Provider != "" {
+		// the token secret is only created in the fake client, so the token is not written to dir.
+		ref, err := setupGitServerToken(ctx, kubeClient, b.name, b.gitServerToken)
+		if err != nil {
+			return err
+		}
+		localBuild.Spec.PackageConfigs.GitServer.SecretRef = ref
+		if err = kubeClient.Update(ctx, &localBuild); err != nil {
+			return err
+		}
+	}
+
+	repoMap := util.NewRepoLock()
+	lr := &localbuild.LocalbuildReconciler{
+		Client:  kubeClient,
+		Scheme:  b.scheme,
+		Config:  b.cfg,
+		TempDir: tmpDir,
+		RepoMap: repoMap,
+	}
+	cr := &custompackage.Reconciler{
+		Client:   kubeClient,
+		Recorder: &record.FakeRecorder{},
+		Scheme:   b.scheme,
+		Config:   b.cfg,
+		TempDir:  tmpDir,
+		RepoMap:  repoMap,
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&localBuild)}
+	for i := 0; i < renderPasses; i++ {
+		if _, err := lr.ReconcileArgoAppsWithGitea(ctx, req, &localBuild); err != nil {
+			return fmt.Errorf("rendering packages: %w", err)
+		}
+
+		pkgs := v1alpha1.CustomPackageList{}
+		if err := kubeClient.List(ctx, &pkgs); err != nil {
+			return err
+		}
+		for j := range pkgs.Items {
+			if _, err := cr.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&pkgs.Items[j])}); err != nil {
+				return fmt.Errorf("rendering custom package %s: %w", pkgs.Items[j].Name, err)
+			}
+		}
+
+		if err := predictRepositoryURLs(ctx, kubeClient); err != nil {
+			return err
+		}
+	}
+
+	if err := b.writeObjects(dir, "localbuild.yaml", []client.Object{&localBuild}); err != nil {
+		return err
+	}
+
+	lists := map[string]client.ObjectList{
+		"custompackages.yaml":  &v1alpha1.CustomPackageList{},
+		"gitrepositories.yaml": &v1alpha1.GitRepositoryList{},
+	}
+	if b.cfg.GitOpsEngine == gitops.FluxEngineName {
+		lists["flux-gitrepositories.yaml"] = gitops.FluxList(gitops.FluxGitRepositoryGVK)
+		lists["kustomizations.yaml"] = gitops.FluxList(gitops.FluxKustomizationGVK)
+		lists["helmreleases.yaml"] = gitops.FluxList(gitops.FluxHelmReleaseGVK)
+	} else {
+		lists["applications.yaml"] = &argov1alpha1.ApplicationList{}
+		lists["applicationsets.yaml"] = &argov1alpha1.ApplicationSetList{}
+	}
+	for name, list := range lists {
+		if err := kubeClient.List(ctx, list); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		objs := make([]client.Object, 0, len(items))
+		for i := range items {
+			if obj, ok := items[i].(client.Object); ok {
+				objs = append(objs, obj)
+			}
+		}
+		if err = b.writeObjects(dir, filepath.Join("packages", name), objs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// predictRepositoryURLs sets the status URLs the GitRepository controller would set once the repositories are created.
+func predictRepositoryURLs(ctx context.Context, kubeClient client.Client) error {
+	repos := v1alpha1.GitRepositoryList{}
+	if err := kubeClient.List(ctx, &repos); err != nil {
+		return err
+	}
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if repo.Status.InternalGitRepositoryUrl != "" {
+			continue
+		}
+		repo.Status.ExternalGitRepositoryUrl, repo.Status.InternalGitRepositoryUrl = gitrepository.RepositoryURLs(*repo)
+		if err := kubeClient.Status().Update(ctx, repo); err != nil {
+			return fmt.Errorf("updating git repository %s: %w", repo.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeObjects writes objs sorted by kind, namespace, and name without fields set by the API server.
+// Status is only kept for GitRepositories because it contains the predicted repository URLs.
+func (b *Build) writeObjects(dir, name string, objs []client.Object) error {
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, b.scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		obj.SetResourceVersion("")
+		obj.SetUID("")
+		obj.SetGeneration(0)
+		obj.SetManagedFields(nil)
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		ki, kj := objs[i].GetObjectKind().GroupVersionKind().Kind, objs[j].GetObjectKind().GroupVersionKind().Kind
+		if ki != kj {
+			return ki < kj
+		}
+		if objs[i].GetNamespace() != objs[j].GetNamespace() {
+			return objs[i].GetNamespace() < objs[j].GetNamespace()
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+
+	var out []byte
+	for _, obj := range objs {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		if _, ok := obj.(*v1alpha1.GitRepository); !ok {
+			delete(u, "status")
+		}
+		unstructured.RemoveNestedField(u, "metadata", "creationTimestamp")
+		data, err := yaml.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("marshaling %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		out = append(out, []byte("---\n")...)
+		out = append(out, data...)
+	}
+	return writeFile(dir, name, out)
+}
+
+func writeFile(dir, name string, data []byte) error {
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("creating dir for %s: %w", name, err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
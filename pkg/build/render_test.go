@@ -0,0 +1,129 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRender(t *testing.T) {
+	pkgDir, err := filepath.Abs("../controllers/custompackage/test/resources/customPackages/testDir")
+	require.NoError(t, err)
+
+	b := NewBuild(NewBuildOptions{
+		Name: "test",
+		TemplateData: v1alpha1.BuildCustomizationSpec{
+			Protocol:    "https",
+			Host:        "cnoe.localtest.me",
+			IngressHost: "cnoe.localtest.me",
+			Port:        "8443",
+		},
+		KubeVersion:       "v1.33.1",
+		CustomPackageDirs: []string{pkgDir},
+		Scheme:            k8s.GetScheme(),
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, b.Render(context.Background(), dir))
+
+	for _, f := range []string{
+		"kind-config.yaml", "coredns.yaml", "crds.yaml", "localbuild.yaml",
+		"core/argocd.yaml", "core/gitea.yaml", "core/nginx.yaml",
+		"packages/applications.yaml", "packages/custompackages.yaml", "packages/gitrepositories.yaml",
+	} {
+		info, err := os.Stat(filepath.Join(dir, f))
+		require.NoError(t, err, f)
+		assert.NotZero(t, info.Size(), f)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "packages/applications.yaml"))
+	require.NoError(t, err)
+	objs, err := k8s.ConvertYamlToObjects(k8s.GetScheme(), data)
+	require.NoError(t, err)
+
+	urls := map[string]string{}
+	for _, o := range objs {
+		app, ok := o.(*argov1alpha1.Application)
+		require.True(t, ok)
+		urls[app.Name] = app.Spec.GetSource().RepoURL
+	}
+	assert.Equal(t, map[string]string{
+		"argocd":  "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-argocd.git",
+		"gitea":   "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-gitea.git",
+		"nginx":   "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-nginx.git",
+		"my-app":  "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-my-app-app1.git",
+		"my-app2": "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-my-app2-app2.git",
+	}, urls)
+
+	// rendering is repeatable so the output can be compared between versions
+	lb1, err := os.ReadFile(filepath.Join(dir, "localbuild.yaml"))
+	require.NoError(t, err)
+	dir2 := t.TempDir()
+	require.NoError(t, b.Render(context.Background(), dir2))
+	lb2, err := os.ReadFile(filepath.Join(dir2, "localbuild.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, string(lb1), string(lb2))
+}
+
+func TestRenderFlux(t *testing.T) {
+	pkgDir, err := filepath.Abs("../controllers/custompackage/test/resources/customPackages/flux")
+	require.NoError(t, err)
+	// Flux is not embedded, its manifests come from the manifest cache.
+	fluxDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(fluxDir, "install.yaml"),
+		[]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: flux-system\n"), 0644))
+
+	b := NewBuild(NewBuildOptions{
+		Name: "test",
+		TemplateData: v1alpha1.BuildCustomizationSpec{
+			Protocol:     "https",
+			Host:         "cnoe.localtest.me",
+			IngressHost:  "cnoe.localtest.me",
+			Port:         "8443",
+			GitOpsEngine: gitops.FluxEngineName,
+		},
+		KubeVersion:       "v1.33.1",
+		CustomPackageDirs: []string{pkgDir},
+		PackageCustomization: map[string]v1alpha1.PackageCustomization{
+			v1alpha1.FluxPackageName: {Name: v1alpha1.FluxPackageName, Version: "v2.6.4", ManifestsPath: fluxDir},
+		},
+		Scheme: k8s.GetScheme(),
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, b.Render(context.Background(), dir))
+
+	for _, f := range []string{"core/flux.yaml", "packages/flux-gitrepositories.yaml", "packages/kustomizations.yaml", "packages/helmreleases.yaml"} {
+		info, err := os.Stat(filepath.Join(dir, f))
+		require.NoError(t, err, f)
+		assert.NotZero(t, info.Size(), f)
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "core/argocd.yaml"))
+	assert.NoFileExists(t, filepath.Join(dir, "packages/applications.yaml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "packages/flux-gitrepositories.yaml"))
+	require.NoError(t, err)
+	objs, err := k8s.ConvertYamlToObjects(k8s.GetScheme(), data)
+	require.NoError(t, err)
+	urls := map[string]string{}
+	for _, o := range objs {
+		u, ok := o.(*unstructured.Unstructured)
+		require.True(t, ok)
+		urls[u.GetName()], _, _ = unstructured.NestedString(u.Object, "spec", "url")
+	}
+	assert.Equal(t, map[string]string{
+		"flux":    "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-flux.git",
+		"gitea":   "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-gitea.git",
+		"nginx":   "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-nginx.git",
+		"podinfo": "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-test-podinfo-podinfo.git",
+	}, urls)
+}
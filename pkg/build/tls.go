@@ -16,6 +16,8 @@ import (
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -183,13 +185,24 @@ func createSelfSignedCertificate(sans []string) ([]byte, []byte, error) {
 }
 
 func setupSelfSignedCertificate(ctx context.Context, logger logr.Logger, kubeclient client.Client, config v1alpha1.BuildCustomizationSpec) ([]byte, error) {
-	if err := k8s.EnsureNamespace(ctx, kubeclient, globals.NginxNamespace); err != nil {
+	// the certificate is stored in the namespace of the ingress, which serves it.
+	provider, err := ingress.ForBuild(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := k8s.EnsureNamespace(ctx, kubeclient, provider.Namespace()); err != nil {
 		return nil, err
 	}
 
-	if err := k8s.EnsureNamespace(ctx, kubeclient, globals.ArgoCDNamespace); err != nil {
+	engine, err := gitops.ForBuild(config)
+	if err != nil {
 		return nil, err
 	}
+	if engine.Name() == gitops.ArgoCDEngineName {
+		if err := k8s.EnsureNamespace(ctx, kubeclient, globals.ArgoCDNamespace); err != nil {
+			return nil, err
+		}
+	}
 
 	sans := []string{
 		globals.DefaultHostName,
@@ -206,7 +219,7 @@ func setupSelfSignedCertificate(ctx context.Context, logger logr.Logger, kubecli
 	}
 
 	logger.V(1).Info("Creating/getting certificate", "host", config.Host, "sans", sans)
-	cert, privateKey, err := getOrCreateIngressCertificateAndKey(ctx, kubeclient, globals.SelfSignedCertSecretName, globals.NginxNamespace, sans)
+	cert, privateKey, err := getOrCreateIngressCertificateAndKey(ctx, kubeclient, globals.SelfSignedCertSecretName, provider.Namespace(), sans)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +230,9 @@ func setupSelfSignedCertificate(ctx context.Context, logger logr.Logger, kubecli
 		return nil, err
 	}
 
+	if engine.Name() != gitops.ArgoCDEngineName {
+		return cert, nil
+	}
 	logger.V(1).Info("Creating secret for ArgoCD server", "host", config.Host)
 	err = createCertificateAndKeySecret(ctx, kubeclient, argocdTLSSecretName, globals.ArgoCDNamespace, cert, privateKey)
 	if err != nil {
@@ -0,0 +1,172 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// CorePackageUpgrade is the planned upgrade of a core package.
+type CorePackageUpgrade struct {
+	Name           string
+	CurrentVersion string
+	TargetVersion  string
+	// Changed is the number of objects that are created or updated.
+	Changed int
+	// Pruned is the number of objects that are deleted.
+	Pruned int
+
+	manifests [][]byte
+}
+
+// HasChanges reports whether upgrading the package changes the cluster.
+func (u CorePackageUpgrade) HasChanges() bool {
+	return u.Changed > 0 || u.Pruned > 0
+}
+
+// PlanUpgrade compares the core packages in the cluster with the manifests embedded in this binary, rendered with the
+// settings of localBuild. If rollback is set, the manifests recorded before the last upgrade are compared instead.
+// Nothing is changed in the cluster.
+func PlanUpgrade(ctx context.Context, kubeClient client.Client, scheme *runtime.Scheme, localBuild *v1alpha1.Localbuild, rollback bool) ([]CorePackageUpgrade, error) {
+	names, err := localbuild.CorePackageNames(localBuild.Spec.BuildCustomization)
+	if err != nil {
+		return nil, err
+	}
+	plan := make([]CorePackageUpgrade, 0, len(names))
+	for _, name := range names {
+		inst, err := localbuild.NewCorePackageInstallation(name, localBuild.Spec.PackageConfigs.CorePackageCustomization[name])
+		if err != nil {
+			return nil, err
+		}
+
+		applied, err := localbuild.GetManifestRecord(ctx, kubeClient, inst.Namespace(), localbuild.AppliedManifestsName)
+		if err != nil {
+			return nil, err
+		}
+		u := CorePackageUpgrade{Name: name, CurrentVersion: localbuild.CorePackageVersion(localBuild.Status, name)}
+		if u.CurrentVersion == "" && applied != nil {
+			u.CurrentVersion = applied.Version
+		}
+
+		if rollback {
+			previous, err := localbuild.GetManifestRecord(ctx, kubeClient, inst.Namespace(), localbuild.PreviousManifestsName)
+			if err != nil {
+				return nil, err
+			}
+			if previous == nil {
+				return nil, fmt.Errorf("no manifests of %s were recorded before the last upgrade", inst.Name())
+			}
+			u.manifests = previous.Manifests
+		} else {
+			u.manifests, err = inst.Manifests(scheme, localBuild.Spec.BuildCustomization)
+			if err != nil {
+				return nil, fmt.Errorf("rendering %s manifests: %w", name, err)
+			}
+		}
+
+		objs, err := k8s.ConvertRawResourcesToObjects(scheme, u.manifests)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s manifests: %w", name, err)
+		}
+		u.TargetVersion = inst.Version(objs)
+		u.Changed, u.Pruned, err = diffCorePackage(ctx, kubeClient, inst.Namespace(), objs, func(string) error { return nil })
+		if err != nil {
+			return nil, fmt.Errorf("diffing %s: %w", name, err)
+		}
+		plan = append(plan, u)
+	}
+	return plan, nil
+}
+
+// UpgradeCorePackages installs the manifests of the packages in plan that have changes, one at a time in the order of
+// the plan, and waits for each to become ready before installing the next. The manifests installed before are
+// recorded, so the upgrade can be rolled back with a plan from PlanUpgrade. The installed versions are recorded in
+// the status of localBuild.
+//
+// Automated sync of the Argo CD applications or Flux Kustomizations of the core packages is disabled first, because
+// they sync the manifests pushed to Gitea by the release that created them. The localbuild controller enables it
+// again when it pushes the manifests of its own release.
+func UpgradeCorePackages(ctx context.Context, kubeClient client.Client, watchClient client.WithWatch, scheme *runtime.Scheme, localBuild *v1alpha1.Localbuild, plan []CorePackageUpgrade) error {
+	names := make([]string, 0, len(plan))
+	for _, u := range plan {
+		names = append(names, u.Name)
+	}
+	engine, err := gitops.ForBuild(localBuild.Spec.BuildCustomization)
+	if err != nil {
+		return err
+	}
+	if err := disableCoreApplicationSync(ctx, kubeClient, engine, names); err != nil {
+		return err
+	}
+
+	for _, u := range plan {
+		if !u.HasChanges() {
+			continue
+		}
+		inst, err := localbuild.NewCorePackageInstallation(u.Name, localBuild.Spec.PackageConfigs.CorePackageCustomization[u.Name])
+		if err != nil {
+			return err
+		}
+
+		applied, err := localbuild.GetManifestRecord(ctx, kubeClient, inst.Namespace(), localbuild.AppliedManifestsName)
+		if err != nil {
+			return err
+		}
+		if applied != nil {
+			if err = localbuild.SaveManifestRecord(ctx, kubeClient, inst.Namespace(), localbuild.PreviousManifestsName, *applied); err != nil {
+				return err
+			}
+		} else {
+			setupLog.Info("The installed manifests were not recorded, the upgrade cannot be rolled back", "package", inst.Name())
+		}
+
+		setupLog.Info("Upgrading core package", "package", inst.Name(), "from", u.CurrentVersion, "to", u.TargetVersion)
+		version, err := inst.InstallManifests(ctx, kubeClient, watchClient, scheme, u.manifests)
+		if err != nil {
+			return fmt.Errorf("upgrading %s: %w", inst.Name(), err)
+		}
+		localbuild.SetCorePackageVersion(&localBuild.Status, u.Name, version)
+		if err = kubeClient.Status().Update(ctx, localBuild); err != nil {
+			return fmt.Errorf("updating localbuild status: %w", err)
+		}
+	}
+	return nil
+}
+
+// disableCoreApplicationSync turns off automated sync of the objects engine syncs the core packages names with.
+func disableCoreApplicationSync(ctx context.Context, kubeClient client.Client, engine gitops.Engine, names []string) error {
+	for _, name := range names {
+		for _, obj := range engine.CorePackageObjects(name) {
+			gvk, err := apiutil.GVKForObject(obj, kubeClient.Scheme())
+			if err != nil {
+				return err
+			}
+			kind := strings.ToLower(gvk.Kind)
+			err = kubeClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					continue
+				}
+				return fmt.Errorf("getting %s %s: %w", kind, name, err)
+			}
+
+			patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+			if !engine.DisableSync(obj) {
+				continue
+			}
+			if err = kubeClient.Patch(ctx, obj, patch); err != nil {
+				return fmt.Errorf("disabling automated sync of %s %s: %w", kind, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDisableCoreApplicationSync(t *testing.T) {
+	ctx := context.Background()
+	app := func(name string) *argov1alpha1.Application {
+		return &argov1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: globals.ArgoCDNamespace},
+			Spec: argov1alpha1.ApplicationSpec{
+				SyncPolicy: &argov1alpha1.SyncPolicy{
+					Automated:   &argov1alpha1.SyncPolicyAutomated{SelfHeal: true},
+					SyncOptions: argov1alpha1.SyncOptions{"CreateNamespace=true"},
+				},
+			},
+		}
+	}
+	// the gitea application does not exist yet.
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(app("argocd"), app("nginx"), app("my-app")).Build()
+
+	argocd, err := gitops.ForName(gitops.ArgoCDEngineName)
+	require.NoError(t, err)
+	require.NoError(t, disableCoreApplicationSync(ctx, kubeClient, argocd, []string{"nginx", "gitea", "argocd"}))
+
+	for name, automated := range map[string]bool{"argocd": false, "nginx": false, "my-app": true} {
+		got := argov1alpha1.Application{}
+		require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Namespace: globals.ArgoCDNamespace, Name: name}, &got))
+		assert.Equal(t, automated, got.Spec.SyncPolicy.Automated != nil, name)
+		assert.Equal(t, argov1alpha1.SyncOptions{"CreateNamespace=true"}, got.Spec.SyncPolicy.SyncOptions, name)
+	}
+}
+
+func TestDisableCoreKustomizationSync(t *testing.T) {
+	ctx := context.Background()
+	flux, err := gitops.ForName(gitops.FluxEngineName)
+	require.NoError(t, err)
+	kustomization := func(name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gitops.FluxKustomizationGVK)
+		u.SetName(name)
+		u.SetNamespace(globals.FluxNamespace)
+		u.Object["spec"] = map[string]any{"suspend": false, "path": "./"}
+		return u
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(kustomization("nginx"), kustomization("my-app")).Build()
+
+	require.NoError(t, disableCoreApplicationSync(ctx, kubeClient, flux, []string{"nginx", "gitea"}))
+
+	for name, suspended := range map[string]bool{"nginx": true, "my-app": false} {
+		got := &unstructured.Unstructured{}
+		got.SetGroupVersionKind(gitops.FluxKustomizationGVK)
+		require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Namespace: globals.FluxNamespace, Name: name}, got))
+		s, _, _ := unstructured.NestedBool(got.Object, "spec", "suspend")
+		assert.Equal(t, suspended, s, name)
+		p, _, _ := unstructured.NestedString(got.Object, "spec", "path")
+		assert.Equal(t, "./", p, name)
+	}
+}
@@ -0,0 +1,113 @@
+package create
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+)
+
+// gitTokenEnvs are the environment variables the access token of an external git server is read from by default.
+var gitTokenEnvs = map[string]string{
+	v1alpha1.GitProviderGitea:  "GITEA_TOKEN",
+	v1alpha1.GitProviderGitHub: "GITHUB_TOKEN",
+	v1alpha1.GitProviderGitLab: "GITLAB_TOKEN",
+}
+
+// externalGitServer reports whether packages are pushed to a git server outside the cluster.
+func externalGitServer() bool {
+	return gitProvider != v1alpha1.GitProviderGitea || gitURL != ""
+}
+
+// validateGitServer checks the flags of the git server packages are pushed to.
+func validateGitServer() error {
+	if _, ok := gitTokenEnvs[gitProvider]; !ok {
+		return fmt.Errorf("unsupported git provider %s, valid values are: %s, %s, %s", gitProvider,
+			v1alpha1.GitProviderGitea, v1alpha1.GitProviderGitHub, v1alpha1.GitProviderGitLab)
+	}
+
+	if !externalGitServer() {
+		if gitOrg != "" || gitTokenSecret != "" {
+			return fmt.Errorf("--git-org and --git-token-secret can only be used with an external git server")
+		}
+		if skipGitea {
+			return fmt.Errorf("--skip-gitea requires an external git server")
+		}
+		return nil
+	}
+
+	if giteaPrivateRepos {
+		return fmt.Errorf("--gitea-private-repos can only be used with the in-cluster Gitea")
+	}
+	if gitOrg == "" {
+		return fmt.Errorf("--git-org is required with an external git server")
+	}
+	if gitURL == "" && gitProvider != v1alpha1.GitProviderGitHub {
+		return fmt.Errorf("--git-url is required with --git-provider %s", gitProvider)
+	}
+	if gitURL != "" {
+		u, err := url.Parse(gitURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("git url must be an http or https url: %s", gitURL)
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	token, err := gitServerToken()
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("the access token of the git server must be given with --git-token-secret or %s", gitTokenEnvs[gitProvider])
+	}
+	return nil
+}
+
+// validateGitOpsEngine checks the GitOps engine can sync from the git server. Flux is not given credentials of the git
+// server, so it only syncs the public repositories of the in-cluster Gitea.
+func validateGitOpsEngine() error {
+	if _, err := gitops.ForName(gitOpsEngine); err != nil {
+		return err
+	}
+	if gitOpsEngine != gitops.FluxEngineName {
+		return nil
+	}
+	if externalGitServer() {
+		return fmt.Errorf("--gitops-engine %s can only be used with the in-cluster Gitea", gitOpsEngine)
+	}
+	if giteaPrivateRepos {
+		return fmt.Errorf("--gitea-private-repos can not be used with --gitops-engine %s", gitOpsEngine)
+	}
+	return nil
+}
+
+// gitServerSpec returns the external git server selected by the flags, or an empty spec for the in-cluster Gitea.
+func gitServerSpec() v1alpha1.GitServerSpec {
+	if !externalGitServer() {
+		return v1alpha1.GitServerSpec{}
+	}
+	return v1alpha1.GitServerSpec{
+		Provider:         gitProvider,
+		URL:              strings.TrimSuffix(gitURL, "/"),
+		OrganizationName: gitOrg,
+	}
+}
+
+// gitServerToken returns the access token of the external git server.
+func gitServerToken() (string, error) {
+	if !externalGitServer() {
+		return "", nil
+	}
+	if gitTokenSecret == "" {
+		return os.Getenv(gitTokenEnvs[gitProvider]), nil
+	}
+	b, err := os.ReadFile(gitTokenSecret)
+	if err != nil {
+		return "", fmt.Errorf("reading git token: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
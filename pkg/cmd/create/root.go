@@ -6,14 +6,24 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/build"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/doctor"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/manifests"
+	"github.com/cnoe-io/idpbuilder/pkg/state"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/homedir"
 )
 
@@ -22,41 +32,110 @@ const (
 	buildNameUsage         = "Name for build (Prefix for kind cluster name, pod names, etc)."
 	devPasswordUsage       = "Set the password \"developer\" for the admin user of the applications: argocd & gitea."
 	kubeVersionUsage       = "Version of the kind kubernetes cluster to create."
-	extraPortsMappingUsage = "List of extra ports to expose on the docker container and kubernetes cluster as nodePort " +
-		"(e.g. \"22:32222,9090:39090,etc\")."
-	registryConfigUsage = "List of paths to mount as the registry config, uses the first one that exists"
-	kindConfigPathUsage = "Path or URL to the kind config file to be used instead of the default."
-	hostUsage           = "Host name to access resources in this cluster."
-	ingressHostUsage    = "Host name used by ingresses. Useful when you have another proxy in front of ingress-nginx that idpbuilder provisions."
-	protocolUsage       = "Protocol to use to access web UIs. http or https."
-	portUsage           = "Port number to use to access web UIs."
-	pathRoutingUsage    = "When set to true, web UIs are exposed under single domain name. " +
+	extraPortsMappingUsage = "List of extra ports to expose on the docker container and kubernetes cluster as nodePort. " +
+		"Each entry has the form [listenAddress:]hostPort:containerPort[/tcp|udp|sctp] and ports may be ranges " +
+		"(e.g. \"22:32222,127.0.0.1:9090:39090/udp,30000-30010:30000-30010\")."
+	clusterNameUsage = "Name of the kind cluster. Defaults to --name. Builds with different names can share a cluster, " +
+		"each with its own idpbuilder-<name> namespace."
+	registryConfigUsage  = "List of paths to mount as the registry config, uses the first one that exists"
+	kindConfigPathUsage  = "Path or URL to the kind config file to be used instead of the default."
+	kindConfigPatchUsage = "Path or URL to a partial kind cluster config merged into the rendered kind config. " +
+		"Can be specified multiple times. Patches are applied in order."
+	hostUsage        = "Host name to access resources in this cluster."
+	ingressHostUsage = "Host name used by ingresses. Useful when you have another proxy in front of ingress-nginx that idpbuilder provisions."
+	protocolUsage    = "Protocol to use to access web UIs. http or https."
+	portUsage        = "Port number to use to access web UIs."
+	ingressUsage     = "Ingress that routes requests to web UIs. nginx for ingress-nginx, or gateway-api for Envoy Gateway. " +
+		"The Envoy Gateway manifests must be pulled first with idpbuilder manifests pull."
+	gitOpsEngineUsage = "GitOps engine that syncs packages from the git server: argocd for Argo CD, or flux for Flux. " +
+		"The Flux manifests must be pulled first with idpbuilder manifests pull. Flux only syncs from the in-cluster Gitea."
+	pathRoutingUsage = "When set to true, web UIs are exposed under single domain name. " +
 		"e.g. \"https://cnoe.localtest.me/argocd\" instead of \"https://argocd.cnoe.localtest.me\""
-	extraPackagesUsage             = "Paths to locations containing custom packages"
+	extraPackagesUsage = "Paths to locations containing custom packages. " +
+		"Add ?replicate=false to a remote url to let Argo CD pull the repository directly instead of a copy in the git server. " +
+		"Packages with cnoe:// urls in Helm values, or that need package credentials to clone, must be replicated."
 	packageCustomizationFilesUsage = "Name of the package and the path to file to customize the core packages with. " +
-		"valid package names are: argocd, nginx, and gitea. e.g. argocd:/tmp/argocd.yaml"
-	noExitUsage = "When set, idpbuilder will not exit after all packages are synced. Useful for continuously syncing local directories."
+		"valid package names are: argocd, flux, nginx, envoy-gateway, and gitea. e.g. argocd:/tmp/argocd.yaml"
+	corePackageTimeoutUsage = "How long to wait for the resources of core packages to become ready. " +
+		"Either a duration for all core packages, or the name of a package and a duration. e.g. 10m,gitea:15m"
+	corePackageVersionUsage = "Version of %s to install instead of the version embedded in idpbuilder. " +
+		"The manifests must be pulled first with idpbuilder manifests pull."
+	manifestCacheDirUsage = "Directory of the manifest cache populated by idpbuilder manifests pull. " +
+		"Defaults to idpbuilder/manifests in the user cache directory."
+	proxyUsage        = "URL of the HTTP(S) proxy used by cluster nodes, Argo CD, Gitea, and idpbuilder. e.g. http://proxy.example.com:3128"
+	noProxyUsage      = "Host names, domains, and CIDRs that should not go through the proxy. In-cluster addresses and the host are always added."
+	extraCACertsUsage = "Paths to PEM encoded CA certificates trusted by cluster nodes, Argo CD, Gitea, and idpbuilder."
+	giteaDataDirUsage = "Directory on the host used to store Gitea data and the admin password. " +
+		"Repositories, issues, and tokens are kept when the cluster is recreated."
+	gitProviderUsage = "Git server packages are pushed to and Argo CD pulls from: gitea, github, or gitlab. " +
+		"gitea without --git-url is the in-cluster Gitea."
+	gitURLUsage         = "Base URL of an external git server, e.g. https://gitlab.com. Defaults to https://github.com for github."
+	gitOrgUsage         = "Organization or group of the external git server that repositories are created in."
+	gitTokenSecretUsage = "Path to a file that contains the access token of the external git server. " +
+		"Defaults to the GITEA_TOKEN, GITHUB_TOKEN, or GITLAB_TOKEN environment variable of the provider."
+	skipGiteaUsage         = "Do not install the in-cluster Gitea. Requires an external git server."
+	giteaPrivateReposUsage = "Create repositories of the in-cluster Gitea as private repositories. " +
+		"Argo CD pulls them with a read-only token."
+	packageAuthSecretUsage = "Secret in the form namespace/name with the username and password, or the sshPrivateKey, " +
+		"used to clone package urls."
+	noExitUsage     = "When set, idpbuilder will not exit after all packages are synced. Useful for continuously syncing local directories."
+	skipDoctorUsage = "Skip the preflight checks run by the doctor command."
+	dryRunUsage     = "Render the kind config and all manifests idpbuilder would apply to the directory given by --output " +
+		"without creating a cluster."
+	outputUsage = "Directory to write rendered files to. Used with --dry-run."
+	fromUsage   = "State file written by the export command. Its Gitea repositories and secrets are restored before " +
+		"packages are reconciled, and flags that are not set default to the values of the exported cluster."
 )
 
 var (
 	// Flags
 	recreateCluster           bool
 	buildName                 string
+	clusterName               string
 	devPassword               bool
 	kubeVersion               string
 	extraPortsMapping         string
 	kindConfigPath            string
+	kindConfigPatches         []string
 	extraPackages             []string
 	registryConfig            []string
 	packageCustomizationFiles []string
+	corePackageTimeouts       []string
+	argocdVersion             string
+	giteaVersion              string
+	nginxVersion              string
+	envoyGatewayVersion       string
+	fluxVersion               string
+	manifestCacheDir          string
 	noExit                    bool
 	protocol                  string
 	host                      string
 	ingressHost               string
 	port                      string
 	pathRouting               bool
+	ingressName               string
+	gitOpsEngine              string
+	skipDoctor                bool
+	proxy                     string
+	noProxy                   []string
+	extraCACerts              []string
+	giteaDataDir              string
+	gitProvider               string
+	gitURL                    string
+	gitOrg                    string
+	gitTokenSecret            string
+	skipGitea                 bool
+	giteaPrivateRepos         bool
+	packageAuth               v1alpha1.PackageAuthSpec
+	packageAuthSecret         string
+	dryRun                    bool
+	outputDir                 string
+	fromFile                  string
 )
 
+var corePackageNames = []string{v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName, v1alpha1.IngressNginxPackageName,
+	v1alpha1.EnvoyGatewayPackageName, v1alpha1.FluxPackageName}
+
 var CreateCmd = &cobra.Command{
 	Use:          "create",
 	Short:        "(Re)Create an IDP cluster",
@@ -72,10 +151,12 @@ func init() {
 	CreateCmd.PersistentFlags().StringVar(&buildName, "build-name", "localdev", buildNameUsage)
 	CreateCmd.PersistentFlags().MarkDeprecated("build-name", "use --name instead.")
 	CreateCmd.PersistentFlags().StringVar(&buildName, "name", "localdev", buildNameUsage)
+	CreateCmd.PersistentFlags().StringVar(&clusterName, "cluster-name", "", clusterNameUsage)
 	CreateCmd.PersistentFlags().BoolVar(&devPassword, "dev-password", false, devPasswordUsage)
 	CreateCmd.PersistentFlags().StringVar(&kubeVersion, "kube-version", "v1.33.1", kubeVersionUsage)
 	CreateCmd.PersistentFlags().StringVar(&extraPortsMapping, "extra-ports", "", extraPortsMappingUsage)
 	CreateCmd.PersistentFlags().StringVar(&kindConfigPath, "kind-config", "", kindConfigPathUsage)
+	CreateCmd.PersistentFlags().StringArrayVar(&kindConfigPatches, "kind-config-patch", []string{}, kindConfigPatchUsage)
 	CreateCmd.PersistentFlags().StringSliceVar(&registryConfig, "registry-config", []string{}, registryConfigUsage)
 	CreateCmd.PersistentFlags().Lookup("registry-config").NoOptDefVal = "$XDG_RUNTIME_DIR/containers/auth.json,$HOME/.docker/config.json"
 
@@ -85,10 +166,35 @@ func init() {
 	CreateCmd.PersistentFlags().StringVar(&protocol, "protocol", "https", protocolUsage)
 	CreateCmd.PersistentFlags().StringVar(&port, "port", "8443", portUsage)
 	CreateCmd.PersistentFlags().BoolVar(&pathRouting, "use-path-routing", false, pathRoutingUsage)
+	CreateCmd.PersistentFlags().StringVar(&ingressName, "ingress", ingress.NginxProviderName, ingressUsage)
+	CreateCmd.PersistentFlags().StringVar(&gitOpsEngine, "gitops-engine", gitops.ArgoCDEngineName, gitOpsEngineUsage)
+	CreateCmd.PersistentFlags().StringVar(&proxy, "proxy", "", proxyUsage)
+	CreateCmd.PersistentFlags().StringSliceVar(&noProxy, "no-proxy", []string{}, noProxyUsage)
+	CreateCmd.PersistentFlags().StringSliceVar(&extraCACerts, "extra-ca-certs", []string{}, extraCACertsUsage)
+	CreateCmd.PersistentFlags().StringVar(&giteaDataDir, "gitea-data-dir", "", giteaDataDirUsage)
+	CreateCmd.PersistentFlags().StringVar(&gitProvider, "git-provider", v1alpha1.GitProviderGitea, gitProviderUsage)
+	CreateCmd.PersistentFlags().StringVar(&gitURL, "git-url", "", gitURLUsage)
+	CreateCmd.PersistentFlags().StringVar(&gitOrg, "git-org", "", gitOrgUsage)
+	CreateCmd.PersistentFlags().StringVar(&gitTokenSecret, "git-token-secret", "", gitTokenSecretUsage)
+	CreateCmd.PersistentFlags().BoolVar(&skipGitea, "skip-gitea", false, skipGiteaUsage)
+	CreateCmd.PersistentFlags().BoolVar(&giteaPrivateRepos, "gitea-private-repos", false, giteaPrivateReposUsage)
 	CreateCmd.Flags().StringSliceVarP(&extraPackages, "package", "p", []string{}, extraPackagesUsage)
+	helpers.AddPackageAuthFlags(CreateCmd, &packageAuth)
+	CreateCmd.Flags().StringVar(&packageAuthSecret, "package-auth-secret", "", packageAuthSecretUsage)
 	CreateCmd.Flags().StringSliceVarP(&packageCustomizationFiles, "package-custom-file", "c", []string{}, packageCustomizationFilesUsage)
+	CreateCmd.Flags().StringSliceVar(&corePackageTimeouts, "core-package-timeout", []string{}, corePackageTimeoutUsage)
+	CreateCmd.Flags().StringVar(&argocdVersion, "argocd-version", "", fmt.Sprintf(corePackageVersionUsage, "Argo CD"))
+	CreateCmd.Flags().StringVar(&giteaVersion, "gitea-version", "", fmt.Sprintf(corePackageVersionUsage, "the Gitea helm chart"))
+	CreateCmd.Flags().StringVar(&nginxVersion, "nginx-version", "", fmt.Sprintf(corePackageVersionUsage, "ingress-nginx"))
+	CreateCmd.Flags().StringVar(&envoyGatewayVersion, "envoy-gateway-version", "", fmt.Sprintf(corePackageVersionUsage, "Envoy Gateway"))
+	CreateCmd.Flags().StringVar(&fluxVersion, "flux-version", "", fmt.Sprintf(corePackageVersionUsage, "Flux"))
+	CreateCmd.Flags().StringVar(&manifestCacheDir, "manifest-cache-dir", "", manifestCacheDirUsage)
 	// idpbuilder related flags
 	CreateCmd.Flags().BoolVarP(&noExit, "no-exit", "n", true, noExitUsage)
+	CreateCmd.Flags().BoolVar(&skipDoctor, "skip-doctor", false, skipDoctorUsage)
+	CreateCmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunUsage)
+	CreateCmd.Flags().StringVarP(&outputDir, "output", "o", "", outputUsage)
+	CreateCmd.Flags().StringVar(&fromFile, "from", "", fromUsage)
 }
 
 func preCreateE(cmd *cobra.Command, args []string) error {
@@ -96,7 +202,19 @@ func preCreateE(cmd *cobra.Command, args []string) error {
 }
 
 func create(cmd *cobra.Command, args []string) error {
+	if fromFile == "" {
+		return createCluster(cmd, nil)
+	}
+	snapshot, err := readSnapshot(fromFile)
+	if err != nil {
+		return err
+	}
+	applySnapshotDefaults(cmd, snapshot)
+	return createCluster(cmd, snapshot)
+}
 
+// createCluster creates the cluster from the flags. If importState is set, it is restored into the cluster.
+func createCluster(cmd *cobra.Command, importState *state.Snapshot) error {
 	ctx, ctxCancel := context.WithCancel(cmd.Context())
 	defer ctxCancel()
 
@@ -113,6 +231,38 @@ func create(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	caCerts, err := util.ReadCACerts(extraCACerts)
+	if err != nil {
+		return err
+	}
+	if err = util.SetExtraCACerts(caCerts); err != nil {
+		return err
+	}
+
+	proxySpec := util.GetProxySpec(proxy, noProxy, v1alpha1.BuildCustomizationSpec{Host: host, IngressHost: ingressHost})
+	if err = util.SetProxyEnv(proxySpec); err != nil {
+		return err
+	}
+
+	if giteaDataDir != "" {
+		// kind requires absolute host paths
+		giteaDataDir, err = filepath.Abs(giteaDataDir)
+		if err != nil {
+			return fmt.Errorf("getting absolute path of gitea data dir: %w", err)
+		}
+		if !dryRun {
+			if err = util.PrepareGiteaDataDir(giteaDataDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !skipDoctor && !dryRun {
+		if err := runPreflightChecks(ctx, kubeConfigPath); err != nil {
+			return err
+		}
+	}
+
 	var localFiles []string
 	var localDirs []string
 	var remotePaths []string
@@ -135,6 +285,29 @@ func create(cmd *cobra.Command, args []string) error {
 		}
 		o[c.Name] = c
 	}
+	if err = setCorePackageTimeouts(o, corePackageTimeouts); err != nil {
+		return err
+	}
+	if ingressName == ingress.GatewayAPIProviderName && envoyGatewayVersion == "" {
+		envoyGatewayVersion = manifests.DefaultEnvoyGatewayVersion
+	}
+	if gitOpsEngine == gitops.FluxEngineName && fluxVersion == "" {
+		fluxVersion = manifests.DefaultFluxVersion
+	}
+	if err = setCorePackageVersions(o, manifestCacheDir, map[string]string{
+		v1alpha1.ArgoCDPackageName:       argocdVersion,
+		v1alpha1.GiteaPackageName:        giteaVersion,
+		v1alpha1.IngressNginxPackageName: nginxVersion,
+		v1alpha1.EnvoyGatewayPackageName: envoyGatewayVersion,
+		v1alpha1.FluxPackageName:         fluxVersion,
+	}); err != nil {
+		return err
+	}
+
+	gitToken, err := gitServerToken()
+	if err != nil {
+		return err
+	}
 
 	exitOnSync := true
 	if cmd.Flags().Changed("no-exit") {
@@ -151,9 +324,11 @@ func create(cmd *cobra.Command, args []string) error {
 
 	opts := build.NewBuildOptions{
 		Name:              buildName,
+		ClusterName:       clusterName,
 		KubeVersion:       kubeVersion,
 		KubeConfigPath:    kubeConfigPath,
 		KindConfigPath:    kindConfigPath,
+		KindConfigPatches: kindConfigPatches,
 		ExtraPortsMapping: extraPortsMapping,
 		RegistryConfig:    maybeRegistryConfig,
 
@@ -163,7 +338,16 @@ func create(cmd *cobra.Command, args []string) error {
 			IngressHost:    ingressHost,
 			Port:           port,
 			UsePathRouting: pathRouting,
+			Ingress:        ingressName,
 			StaticPassword: devPassword,
+			Proxy:          proxySpec,
+			ExtraCACerts:   string(caCerts),
+			GiteaDataDir:   giteaDataDir,
+			SkipGitea:      skipGitea,
+
+			GiteaPrivateRepositories: giteaPrivateRepos,
+			PackageAuth:              packageAuth,
+			GitOpsEngine:             gitOpsEngine,
 		},
 
 		CustomPackageFiles:   localFiles,
@@ -171,6 +355,9 @@ func create(cmd *cobra.Command, args []string) error {
 		CustomPackageUrls:    remotePaths,
 		ExitOnSync:           exitOnSync,
 		PackageCustomization: o,
+		ImportState:          importState,
+		GitServer:            gitServerSpec(),
+		GitServerToken:       gitToken,
 
 		Scheme:     k8s.GetScheme(),
 		CancelFunc: ctxCancel,
@@ -178,6 +365,14 @@ func create(cmd *cobra.Command, args []string) error {
 
 	b := build.NewBuild(opts)
 
+	if dryRun {
+		if err := b.Render(ctx, outputDir); err != nil {
+			return err
+		}
+		fmt.Printf("Rendered files written to %s\n", outputDir)
+		return nil
+	}
+
 	if err := b.Run(ctx, recreateCluster); err != nil {
 		return err
 	}
@@ -195,11 +390,59 @@ func validate() error {
 		return fmt.Errorf("must specify build-name")
 	}
 
+	if dryRun && outputDir == "" {
+		return fmt.Errorf("--output is required with --dry-run")
+	}
+	if !dryRun && outputDir != "" {
+		return fmt.Errorf("--output can only be used with --dry-run")
+	}
+
+	if _, err := ingress.ForName(ingressName); err != nil {
+		return err
+	}
+
+	if err := validateGitServer(); err != nil {
+		return err
+	}
+
+	if err := validateGitOpsEngine(); err != nil {
+		return err
+	}
+
+	if err := helpers.ValidatePackageAuth(&packageAuth); err != nil {
+		return err
+	}
+	if packageAuthSecret != "" {
+		ns, name, ok := strings.Cut(packageAuthSecret, "/")
+		if !ok || ns == "" || name == "" {
+			return fmt.Errorf("--package-auth-secret must be in the form namespace/name: %s", packageAuthSecret)
+		}
+		packageAuth.SecretRef = v1alpha1.SecretReference{Namespace: ns, Name: name}
+	}
+
 	_, err := url.Parse(fmt.Sprintf("%s://%s:%s", protocol, host, port))
 	if err != nil {
 		return fmt.Errorf("invalid url: %w", err)
 	}
 
+	if proxy != "" {
+		u, pErr := url.Parse(proxy)
+		if pErr != nil {
+			return fmt.Errorf("invalid proxy url: %w", pErr)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("proxy url must use http or https scheme: %s", proxy)
+		}
+	}
+
+	portMappings, err := kind.ParsePortMappings(extraPortsMapping)
+	if err != nil {
+		return fmt.Errorf("invalid extra-ports: %w", err)
+	}
+	if err = kind.ValidatePortMappings(portMappings, port); err != nil {
+		return fmt.Errorf("invalid extra-ports: %w", err)
+	}
+
 	for i := range packageCustomizationFiles {
 		_, pErr := getPackageCustomFile(packageCustomizationFiles[i])
 		if pErr != nil {
@@ -207,10 +450,50 @@ func validate() error {
 		}
 	}
 
+	if err = setCorePackageTimeouts(map[string]v1alpha1.PackageCustomization{}, corePackageTimeouts); err != nil {
+		return err
+	}
+
 	_, _, _, err = helpers.ParsePackageStrings(extraPackages)
 	return err
 }
 
+// kindClusterName returns the name of the kind cluster the build is created in.
+func kindClusterName() string {
+	if clusterName != "" {
+		return clusterName
+	}
+	return buildName
+}
+
+func runPreflightChecks(ctx context.Context, kubeConfigPath string) error {
+	checks := doctor.Run(ctx, doctor.Options{
+		ClusterName:       kindClusterName(),
+		Host:              host,
+		Port:              port,
+		ExtraPortsMapping: extraPortsMapping,
+		UsePathRouting:    pathRouting,
+		KubeConfigPath:    kubeConfigPath,
+	})
+
+	var failed []string
+	for _, c := range checks {
+		switch c.Status {
+		case doctor.StatusFail:
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Message))
+		case doctor.StatusWarn:
+			helpers.CmdLogger.Info("preflight check warning", "check", c.Name, "message", c.Message)
+		default:
+			helpers.CmdLogger.V(1).Info("preflight check passed", "check", c.Name, "message", c.Message)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("preflight checks failed, run idpbuilder doctor for details or use --skip-doctor: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
 func getPackageCustomFile(input string) (v1alpha1.PackageCustomization, error) {
 	// the format should be `<package-name>:<path-to-file>`
 	s := strings.Split(input, ":")
@@ -228,10 +511,8 @@ func getPackageCustomFile(input string) (v1alpha1.PackageCustomization, error) {
 		return v1alpha1.PackageCustomization{}, err
 	}
 
-	corePkgs := map[string]struct{}{v1alpha1.ArgoCDPackageName: {}, v1alpha1.GiteaPackageName: {}, v1alpha1.IngressNginxPackageName: {}}
 	name := s[0]
-	_, ok := corePkgs[name]
-	if !ok {
+	if !slices.Contains(corePackageNames, name) {
 		return v1alpha1.PackageCustomization{}, fmt.Errorf("customization for %s not supported", name)
 	}
 	return v1alpha1.PackageCustomization{
@@ -240,27 +521,103 @@ func getPackageCustomFile(input string) (v1alpha1.PackageCustomization, error) {
 	}, nil
 }
 
+// setCorePackageTimeouts sets the readiness timeouts of core packages from inputs formatted as <duration> or
+// <package-name>:<duration>. Timeouts for a single package take precedence over a timeout for all packages.
+func setCorePackageTimeouts(customizations map[string]v1alpha1.PackageCustomization, inputs []string) error {
+	all := time.Duration(0)
+	perPackage := map[string]time.Duration{}
+	for _, input := range inputs {
+		name, value, found := strings.Cut(input, ":")
+		if !found {
+			name, value = "", input
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("ensure %s is formatted as <duration> or <package-name>:<duration> with a positive duration", input)
+		}
+		if !found {
+			all = d
+			continue
+		}
+		if !slices.Contains(corePackageNames, name) {
+			return fmt.Errorf("timeout for %s not supported, valid package names are: %s", name, strings.Join(corePackageNames, ", "))
+		}
+		perPackage[name] = d
+	}
+
+	for _, name := range corePackageNames {
+		d, ok := perPackage[name]
+		if !ok {
+			d = all
+		}
+		if d == 0 {
+			continue
+		}
+		c := customizations[name]
+		c.Name = name
+		c.ReadinessTimeout = &metav1.Duration{Duration: d}
+		customizations[name] = c
+	}
+	return nil
+}
+
+// setCorePackageVersions selects the versions of core packages to install from the manifest cache in cacheDir. Packages
+// without a version use the embedded manifests.
+func setCorePackageVersions(customizations map[string]v1alpha1.PackageCustomization, cacheDir string, versions map[string]string) error {
+	for _, name := range corePackageNames {
+		version := versions[name]
+		if version == "" {
+			continue
+		}
+		if cacheDir == "" {
+			dir, err := manifests.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+			cacheDir = dir
+		}
+		dir, err := manifests.Resolve(cacheDir, name, version)
+		if err != nil {
+			return err
+		}
+
+		c := customizations[name]
+		c.Name = name
+		c.Version = version
+		c.ManifestsPath = dir
+		customizations[name] = c
+	}
+	return nil
+}
+
+// printSuccessMsg prints how to access the UI of the GitOps engine. Flux has no UI, so the Gitea it syncs from is
+// printed instead.
 func printSuccessMsg() {
-	subDomain := "argocd."
+	name, app, username := "ArgoCD", "argocd", util.ArgocdAdminName
+	if gitOpsEngine == gitops.FluxEngineName {
+		name, app, username = "Gitea", "gitea", util.GiteaAdminName
+	}
+
+	subDomain := app + "."
 	subPath := ""
 
 	if pathRouting == true {
 		subDomain = ""
-		subPath = "argocd"
+		subPath = app
 	}
 
-	var argoURL string
+	var accessURL string
 
 	proxy := behindProxy()
 	if proxy {
-		argoURL = fmt.Sprintf("https://%s/argocd", host)
+		accessURL = fmt.Sprintf("https://%s/%s", host, app)
 	} else {
-		argoURL = fmt.Sprintf("%s://%s%s:%s/%s", protocol, subDomain, host, port, subPath)
+		accessURL = fmt.Sprintf("%s://%s%s:%s/%s", protocol, subDomain, host, port, subPath)
 	}
 
 	fmt.Print("\n\n########################### Finished Creating IDP Successfully! ############################\n\n\n")
-	fmt.Printf("Can Access ArgoCD at %s\nUsername: admin\n", argoURL)
-	fmt.Print(`Password can be retrieved by running: idpbuilder get secrets -p argocd`, "\n")
+	fmt.Printf("Can Access %s at %s\nUsername: %s\n", name, accessURL, username)
+	fmt.Printf("Password can be retrieved by running: idpbuilder get secrets -p %s\n", app)
 }
 
 func behindProxy() bool {
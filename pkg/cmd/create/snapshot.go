@@ -0,0 +1,60 @@
+package create
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cnoe-io/idpbuilder/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// readSnapshot reads a state file written by the export command.
+func readSnapshot(path string) (*state.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening state file: %w", err)
+	}
+	defer f.Close()
+	return state.Read(f)
+}
+
+// applySnapshotDefaults sets the flags not given on the command line to the values of the exported cluster.
+// Local package paths are only used if they exist on this machine.
+func applySnapshotDefaults(cmd *cobra.Command, snapshot *state.Snapshot) {
+	flags := cmd.Flags()
+	spec := snapshot.Localbuild.Spec
+	cfg := spec.BuildCustomization
+
+	if !flags.Changed("name") && !flags.Changed("build-name") && snapshot.Localbuild.Name != "" {
+		buildName = snapshot.Localbuild.Name
+	}
+	if !flags.Changed("host") && cfg.Host != "" {
+		host = cfg.Host
+	}
+	if !flags.Changed("ingress-host-name") && cfg.IngressHost != cfg.Host {
+		ingressHost = cfg.IngressHost
+	}
+	if !flags.Changed("protocol") && cfg.Protocol != "" {
+		protocol = cfg.Protocol
+	}
+	if !flags.Changed("port") && cfg.Port != "" {
+		port = cfg.Port
+	}
+	if !flags.Changed("use-path-routing") {
+		pathRouting = cfg.UsePathRouting
+	}
+	if !flags.Changed("dev-password") {
+		devPassword = cfg.StaticPassword
+	}
+
+	if !flags.Changed("package") {
+		extraPackages = append([]string{}, spec.PackageConfigs.CustomPackageUrls...)
+		for _, p := range append(append([]string{}, spec.PackageConfigs.CustomPackageDirs...), spec.PackageConfigs.CustomPackageFiles...) {
+			if _, err := os.Stat(p); err != nil {
+				fmt.Fprintf(os.Stderr, "skipping package %s of the exported cluster: %v\n", p, err)
+				continue
+			}
+			extraPackages = append(extraPackages, p)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/version"
+	"github.com/cnoe-io/idpbuilder/pkg/debug"
+	"github.com/cnoe-io/idpbuilder/pkg/doctor"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// Flags
+	clusterName    string
+	localBuildName string
+	kubeConfigPath string
+	outputPath     string
+	includeSecrets bool
+)
+
+var BundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Create a tarball with logs and resources of an IDP cluster",
+	Long: "Create a tarball with kind node logs, pod logs and events of the core namespaces, idpbuilder and Argo CD " +
+		"resources, the kind config, version information and doctor results. Secret values are redacted by default.",
+	RunE:         bundleE,
+	PreRunE:      preBundleE,
+	SilenceUsage: true,
+}
+
+func init() {
+	BundleCmd.Flags().StringVar(&clusterName, "cluster-name", "localdev", "Name of the kind cluster.")
+	BundleCmd.Flags().StringVar(&localBuildName, "name", "", "Name of the localbuild whose settings doctor checks use. "+
+		"Required if the cluster has more than one.")
+	BundleCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	BundleCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Path of the tarball. Defaults to idpbuilder-debug-<cluster-name>-<timestamp>.tar.gz in the current directory.")
+	BundleCmd.Flags().BoolVar(&includeSecrets, "include-secrets", false, "Do not redact secret values. Only use this if you trust everyone who receives the bundle.")
+}
+
+func preBundleE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func bundleE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	logger := helpers.CmdLogger
+
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("idpbuilder-debug-%s-%s.tar.gz", clusterName, time.Now().Format("20060102-150405"))
+	}
+
+	b := debug.Bundle{
+		ClusterName:    clusterName,
+		IncludeSecrets: includeSecrets,
+		Files:          map[string][]byte{},
+		Scheme:         k8s.GetScheme(),
+	}
+
+	if v, err := version.JSONInfo(); err == nil {
+		b.Files["version.json"] = []byte(v + "\n")
+	}
+
+	if p, err := kind.RenderedConfigPath(clusterName); err == nil {
+		b.KindConfigPath = p
+	}
+
+	c, err := kind.NewCluster(clusterName, "", kubeConfigPath, "", nil, "", nil, v1alpha1.BuildCustomizationSpec{}, logger)
+	if err != nil {
+		logger.Info("skipping node logs", "err", err)
+	} else {
+		b.Nodes = c
+	}
+
+	kubeClient, clientset, err := clusterClients()
+	if err != nil {
+		logger.Info("skipping cluster resources", "err", err)
+	} else {
+		b.KubeClient = kubeClient
+		b.Clientset = clientset
+	}
+
+	checks, err := json.MarshalIndent(doctor.Run(ctx, doctorOptions(ctx, kubeClient)), "", "  ")
+	if err == nil {
+		b.Files["doctor.json"] = append(checks, '\n')
+	}
+
+	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", outputPath, err)
+	}
+	defer f.Close()
+
+	if err = b.Write(ctx, f); err != nil {
+		return fmt.Errorf("writing debug bundle: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("writing debug bundle: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Debug bundle written to %s\n", outputPath)
+	if !includeSecrets {
+		fmt.Fprintln(cmd.OutOrStdout(), "Secret values were redacted. Please review the bundle before sharing it.")
+	}
+	return nil
+}
+
+// clusterClients returns clients for the kind context of the cluster regardless of the current context.
+func clusterClients() (client.Client, kubernetes.Interface, error) {
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + clusterName},
+	).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("building kubeconfig: %w", err)
+	}
+	restConfig.Timeout = 30 * time.Second
+
+	kubeClient, err := client.New(restConfig, client.Options{Scheme: k8s.GetScheme()})
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating kubernetes client: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating kubernetes clientset: %w", err)
+	}
+	return kubeClient, clientset, nil
+}
+
+// doctorOptions uses the settings of the Localbuild selected by --name if the cluster can be reached.
+func doctorOptions(ctx context.Context, kubeClient client.Client) doctor.Options {
+	opts := doctor.Options{
+		ClusterName:    clusterName,
+		Host:           globals.DefaultHostName,
+		Port:           "8443",
+		KubeConfigPath: kubeConfigPath,
+	}
+	if kubeClient == nil {
+		return opts
+	}
+
+	localBuild, err := util.GetLocalbuild(ctx, kubeClient, localBuildName)
+	if err != nil {
+		helpers.CmdLogger.Info("using default settings for doctor checks", "err", err)
+		return opts
+	}
+	spec := localBuild.Spec.BuildCustomization
+	if spec.Host != "" {
+		opts.Host = spec.Host
+	}
+	if spec.Port != "" {
+		opts.Port = spec.Port
+	}
+	opts.UsePathRouting = spec.UsePathRouting
+	return opts
+}
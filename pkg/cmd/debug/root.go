@@ -0,0 +1,22 @@
+package debug
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var DebugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Collect information to debug an IDP cluster",
+	Long:  ``,
+	RunE:  debugE,
+}
+
+func init() {
+	DebugCmd.AddCommand(BundleCmd)
+}
+
+func debugE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
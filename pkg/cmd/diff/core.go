@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/build"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var CoreCmd = &cobra.Command{
+	Use:   "core",
+	Short: "Show drift of the core packages",
+	Long: "Compare the CoreDNS, ingress-nginx, Argo CD and Gitea objects in the cluster with the embedded manifests, " +
+		"including package customizations, as the next create would apply them with server-side apply. " +
+		"Objects removed from the manifests since they were installed are shown as deleted, because they are pruned.",
+	RunE:         coreE,
+	PreRunE:      preCoreE,
+	SilenceUsage: true,
+}
+
+func preCoreE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func coreE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + clusterName},
+	).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+	scheme := k8s.GetScheme()
+	kubeClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	localBuild := v1alpha1.Localbuild{}
+	if err = kubeClient.Get(ctx, client.ObjectKey{Name: helpers.BuildName(localBuildName, clusterName)}, &localBuild); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("cluster %s was not created by idpbuilder: %w", clusterName, err)
+		}
+		return fmt.Errorf("getting localbuild %s: %w", helpers.BuildName(localBuildName, clusterName), err)
+	}
+
+	drift, err := build.DiffCore(ctx, kubeClient, scheme, &localBuild, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	if !drift {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No differences found")
+		return nil
+	}
+	if exitCode {
+		return fmt.Errorf("core packages differ from the embedded manifests")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	// Flags
+	clusterName    string
+	localBuildName string
+	kubeConfigPath string
+	exitCode       bool
+)
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show differences between a running cluster and what idpbuilder applies",
+	Long:  ``,
+	RunE:  diffE,
+}
+
+func init() {
+	DiffCmd.AddCommand(CoreCmd)
+	DiffCmd.PersistentFlags().StringVar(&clusterName, "cluster-name", "localdev", "Name of the kind cluster.")
+	DiffCmd.PersistentFlags().StringVar(&localBuildName, "name", "", "Name of the localbuild. Defaults to the cluster name.")
+	DiffCmd.PersistentFlags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	DiffCmd.PersistentFlags().BoolVar(&exitCode, "exit-code", false, "Exit with an error when there are differences.")
+}
+
+func diffE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
@@ -0,0 +1,74 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/doctor"
+	"github.com/cnoe-io/idpbuilder/pkg/printer"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	// Flags
+	name              string
+	host              string
+	port              string
+	extraPortsMapping string
+	pathRouting       bool
+	kubeConfigPath    string
+	outputFormat      string
+)
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that this machine can run an IDP cluster",
+	Long: "Run preflight checks for the container runtime, host ports, resources, inotify limits, DNS resolution, " +
+		"and kubeconfig. Exits with a non-zero code if any check fails.",
+	RunE:         runDoctor,
+	PreRunE:      preDoctorE,
+	SilenceUsage: true,
+}
+
+func init() {
+	DoctorCmd.Flags().StringVar(&name, "name", "localdev", "Name of the cluster. Port checks are skipped if the cluster already exists.")
+	DoctorCmd.Flags().StringVar(&host, "host", globals.DefaultHostName, "Host name to access resources in the cluster.")
+	DoctorCmd.Flags().StringVar(&port, "port", "8443", "Port number to use to access web UIs.")
+	DoctorCmd.Flags().StringVar(&extraPortsMapping, "extra-ports", "", "List of extra ports to expose on the docker container.")
+	DoctorCmd.Flags().BoolVar(&pathRouting, "use-path-routing", false, "Check DNS for path based routing.")
+	DoctorCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	DoctorCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table (default if not specified), json or yaml.")
+}
+
+func preDoctorE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	checks := doctor.Run(cmd.Context(), doctor.Options{
+		ClusterName:       name,
+		Host:              strings.ToLower(host),
+		Port:              port,
+		ExtraPortsMapping: extraPortsMapping,
+		UsePathRouting:    pathRouting,
+		KubeConfigPath:    kubeConfigPath,
+	})
+
+	p := printer.CheckPrinter{
+		Checks:    checks,
+		OutWriter: os.Stdout,
+	}
+	if err := p.PrintOutput(outputFormat); err != nil {
+		return err
+	}
+
+	if doctor.HasFailure(checks) {
+		return fmt.Errorf("one or more checks failed")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/state"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// Flags
+	clusterName    string
+	localBuildName string
+	kubeConfigPath string
+	outputPath     string
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export Gitea repositories and idpbuilder state of an IDP cluster",
+	Long: "Write the Gitea repositories created by idpbuilder, the secrets shown by get secrets, and the Localbuild settings " +
+		"to a tar file that can be restored with create --from. The file contains credentials in plain text.",
+	RunE:         exportE,
+	PreRunE:      preExportE,
+	SilenceUsage: true,
+}
+
+func init() {
+	ExportCmd.Flags().StringVar(&clusterName, "cluster-name", "localdev", "Name of the kind cluster.")
+	ExportCmd.Flags().StringVar(&localBuildName, "name", "", "Name of the localbuild. Required if the cluster has more than one.")
+	ExportCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	ExportCmd.Flags().StringVarP(&outputPath, "output", "o", "state.tar", "Path of the state file.")
+}
+
+func preExportE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func exportE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + clusterName},
+	).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+	kubeClient, err := client.New(restConfig, client.Options{Scheme: k8s.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	localBuild, err := util.GetLocalbuild(ctx, kubeClient, localBuildName)
+	if err != nil {
+		return err
+	}
+	snapshot, err := state.Export(ctx, kubeClient, localBuild.Name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", outputPath, err)
+	}
+	defer f.Close()
+
+	if err = state.Write(f, snapshot); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d repositories and %d secrets to %s\n", len(snapshot.Repositories), len(snapshot.Secrets), outputPath)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"github.com/cnoe-io/idpbuilder/pkg/kind"
 	"github.com/cnoe-io/idpbuilder/pkg/printer"
@@ -21,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kind/pkg/cluster"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -43,7 +45,7 @@ func preClustersE(cmd *cobra.Command, args []string) error {
 }
 
 func list(cmd *cobra.Command, args []string) error {
-	clusters, err := populateClusterList()
+	clusters, err := populateClusterList(cmd.Context())
 	if err != nil {
 		return err
 	} else {
@@ -55,7 +57,7 @@ func list(cmd *cobra.Command, args []string) error {
 	}
 }
 
-func populateClusterList() ([]idpTypes.Cluster, error) {
+func populateClusterList(ctx context.Context) ([]idpTypes.Cluster, error) {
 	logger := helpers.CmdLogger
 
 	detectOpt, err := util.DetectKindNodeProvider()
@@ -98,6 +100,19 @@ func populateClusterList() ([]idpTypes.Cluster, error) {
 	for _, cluster := range clusters {
 		aCluster := idpTypes.Cluster{Name: cluster}
 
+		state, err := clusterState(ctx, cluster)
+		if err != nil {
+			// one broken cluster must not hide the others
+			logger.Error(err, "failed to get the state of the cluster", "cluster", cluster)
+			state = kind.ClusterStateUnknown
+		}
+		aCluster.State = string(state)
+		// a stopped cluster has no API server to query
+		if state != kind.ClusterStateRunning {
+			clusterList = append(clusterList, aCluster)
+			continue
+		}
+
 		// Search about the idp cluster within the kubeconfig file and show information
 		c, found := findClusterByName(config, "kind-"+cluster)
 		if !found {
@@ -180,6 +195,18 @@ func populateClusterList() ([]idpTypes.Cluster, error) {
 	return clusterList, nil
 }
 
+func clusterState(ctx context.Context, name string) (kind.ClusterState, error) {
+	c, err := kind.NewCluster(name, "", "", "", nil, "", nil, v1alpha1.BuildCustomizationSpec{}, helpers.CmdLogger)
+	if err != nil {
+		return "", err
+	}
+	state, err := c.State(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting state of cluster %s: %w", name, err)
+	}
+	return state, nil
+}
+
 func printAllocatedResources(ctx context.Context, k8sClient client.Client, nodeName string) (idpTypes.Allocated, error) {
 	// List all pods on the specified node
 	var podList corev1.PodList
@@ -212,30 +239,33 @@ func printAllocatedResources(ctx context.Context, k8sClient client.Client, nodeN
 }
 
 func findExternalHTTPSPort(cli client.Client, clusterName string) (int32, error) {
-	service := corev1.Service{}
-	namespacedName := types.NamespacedName{
-		Name:      "ingress-nginx-controller",
-		Namespace: "ingress-nginx",
-	}
-	err := cli.Get(context.TODO(), namespacedName, &service)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get the ingress service on the cluster. %w", err)
-	}
-
 	localBuild := v1alpha1.Localbuild{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: clusterName,
 		},
 	}
-	err = cli.Get(context.TODO(), client.ObjectKeyFromObject(&localBuild), &localBuild)
+	err := cli.Get(context.TODO(), client.ObjectKeyFromObject(&localBuild), &localBuild)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get the localbuild on the cluster. %w", err)
 	}
 
+	provider, err := ingress.ForBuild(localBuild.Spec.BuildCustomization)
+	if err != nil {
+		return 0, err
+	}
+	service := corev1.Service{}
+	err = cli.Get(context.TODO(), provider.Service(), &service)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the ingress service on the cluster. %w", err)
+	}
+
+	// the port names of the ingress-nginx service are prefixed with the protocol. the ports of the Envoy Gateway
+	// service are named by Envoy Gateway, so the port is matched too.
 	var targetPort corev1.ServicePort
 	protocol := localBuild.Spec.BuildCustomization.Protocol + "-"
 	for _, port := range service.Spec.Ports {
-		if port.Name != "" && strings.HasPrefix(port.Name, protocol) {
+		if strconv.Itoa(int(port.Port)) == localBuild.Spec.BuildCustomization.Port ||
+			port.Name != "" && strings.HasPrefix(port.Name, protocol) {
 			targetPort = port
 			break
 		}
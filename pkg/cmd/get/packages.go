@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/printer"
 	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
@@ -45,17 +46,13 @@ func printPackages(ctx context.Context, outWriter io.Writer, kubeClient client.C
 	customPackages := v1alpha1.CustomPackageList{}
 	var err error
 
-	idpbuilderNamespace, err := getIDPNamespace(ctx, kubeClient)
+	localBuild, err := util.GetLocalbuild(ctx, kubeClient, localBuildName)
 	if err != nil {
-		return fmt.Errorf("getting namespace: %w", err)
+		return err
 	}
+	idpbuilderNamespace := globals.GetProjectNamespace(localBuild.Name)
 
-	config, err := util.GetConfig(ctx)
-	if err != nil {
-		return fmt.Errorf("getting idp config: %w", err)
-	}
-
-	argocdBaseUrl := util.ArgocdBaseUrl(config)
+	argocdBaseUrl := util.ArgocdBaseUrl(localBuild.Spec.BuildCustomization)
 
 	if len(packages) == 0 {
 		// Get all custom packages
@@ -81,7 +78,11 @@ func printPackages(ctx context.Context, outWriter io.Writer, kubeClient client.C
 		newPackage.ArgocdRepository = argocdBaseUrl + "/applications/" + cp.Spec.ArgoCD.Namespace + "/" + cp.Spec.ArgoCD.Name
 		// There is a GitRepositoryRefs when the project has been cloned to the internal git repository
 		if cp.Status.GitRepositoryRefs != nil {
-			newPackage.GitRepository = cp.Spec.InternalGitServeURL + "/" + v1alpha1.GiteaAdminUserName + "/" + idpbuilderNamespace + "-" + cp.Status.GitRepositoryRefs[0].Name
+			org := v1alpha1.GiteaAdminUserName
+			if cp.Spec.GitOrganizationName != "" {
+				org = cp.Spec.GitOrganizationName
+			}
+			newPackage.GitRepository = cp.Spec.InternalGitServeURL + "/" + org + "/" + idpbuilderNamespace + "-" + cp.Status.GitRepositoryRefs[0].Name
 		} else {
 			// Default branch reference
 			ref := "main"
@@ -108,21 +109,6 @@ func getPackageByName(ctx context.Context, kubeClient client.Client, ns, name st
 	return p, kubeClient.Get(ctx, client.ObjectKey{Name: name, Namespace: ns}, &p)
 }
 
-func getIDPNamespace(ctx context.Context, kubeClient client.Client) (string, error) {
-	build, err := getLocalBuild(ctx, kubeClient)
-	if err != nil {
-		return "", err
-	}
-	// TODO: We assume that only one LocalBuild has been created for one cluster !
-	idpNamespace := v1alpha1.FieldManager + "-" + build.Items[0].Name
-	return idpNamespace, nil
-}
-
-func getLocalBuild(ctx context.Context, kubeClient client.Client) (v1alpha1.LocalbuildList, error) {
-	localBuildList := v1alpha1.LocalbuildList{}
-	return localBuildList, kubeClient.List(ctx, &localBuildList)
-}
-
 func getPackages(ctx context.Context, kubeClient client.Client, ns string) (v1alpha1.CustomPackageList, error) {
 	packageList := v1alpha1.CustomPackageList{}
 	return packageList, kubeClient.List(ctx, &packageList, client.InNamespace(ns))
@@ -14,8 +14,9 @@ var GetCmd = &cobra.Command{
 }
 
 var (
-	packages     []string
-	outputFormat string
+	packages       []string
+	outputFormat   string
+	localBuildName string
 )
 
 func init() {
@@ -25,6 +26,9 @@ func init() {
 	GetCmd.PersistentFlags().StringSliceVarP(&packages, "packages", "p", []string{}, "names of packages.")
 	GetCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table (default if not specified), json or yaml.")
 	GetCmd.PersistentFlags().StringVarP(&util.KubeConfigPath, "kubeconfig", "", "", "kube config file Path.")
+	for _, c := range []*cobra.Command{PackagesCmd, SecretsCmd} {
+		c.Flags().StringVar(&localBuildName, "name", "", "Name of the localbuild. Required if the cluster has more than one.")
+	}
 }
 
 func exportE(cmd *cobra.Command, args []string) error {
@@ -3,7 +3,9 @@ package get
 import (
 	"context"
 	"fmt"
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/printer"
 	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
@@ -15,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"slices"
 )
 
 const (
@@ -37,6 +40,9 @@ var (
 		"argocd": []string{argoCDInitialAdminSecretName},
 		"gitea":  []string{giteaAdminSecretName},
 	}
+	// secretNamespaces limits package secrets to these namespaces when several localbuilds share a cluster.
+	// Secrets in all namespaces are shown if it is nil.
+	secretNamespaces []string
 )
 
 func getSecretsE(cmd *cobra.Command, args []string) error {
@@ -53,6 +59,11 @@ func getSecretsE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("getting kube client: %w", err)
 	}
 
+	secretNamespaces, err = localbuildNamespaces(ctx, kubeClient, localBuildName)
+	if err != nil {
+		return err
+	}
+
 	if len(packages) == 0 {
 		return printAllPackageSecrets(ctx, os.Stdout, kubeClient, outputFormat)
 	}
@@ -87,7 +98,9 @@ func printAllPackageSecrets(ctx context.Context, outWriter io.Writer, kubeClient
 	}
 
 	for i := range cnoeLabelSecrets.Items {
-		secrets = append(secrets, populateSecret(cnoeLabelSecrets.Items[i], false))
+		if inSecretNamespaces(cnoeLabelSecrets.Items[i].Namespace) {
+			secrets = append(secrets, populateSecret(cnoeLabelSecrets.Items[i], false))
+		}
 	}
 
 	if len(secrets) == 0 {
@@ -136,7 +149,9 @@ func printPackageSecrets(ctx context.Context, outWriter io.Writer, kubeClient cl
 		}
 
 		for i := range cnoeLabelSecrets.Items {
-			secrets = append(secrets, populateSecret(cnoeLabelSecrets.Items[i], false))
+			if inSecretNamespaces(cnoeLabelSecrets.Items[i].Namespace) {
+				secrets = append(secrets, populateSecret(cnoeLabelSecrets.Items[i], false))
+			}
 		}
 
 		if len(secrets) == 0 {
@@ -199,3 +214,61 @@ func getCorePackageSecret(ctx context.Context, kubeClient client.Client, ns, nam
 	}
 	return s, nil
 }
+
+func inSecretNamespaces(ns string) bool {
+	return secretNamespaces == nil || slices.Contains(secretNamespaces, ns)
+}
+
+// localbuildNamespaces returns the namespaces the packages of the selected localbuild deploy to. It returns nil if the
+// cluster has at most one localbuild, because all package secrets belong to it.
+func localbuildNamespaces(ctx context.Context, kubeClient client.Client, name string) ([]string, error) {
+	list := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("listing localbuilds: %w", err)
+	}
+	if len(list.Items) <= 1 {
+		if name != "" && (len(list.Items) == 0 || list.Items[0].Name != name) {
+			return nil, fmt.Errorf("localbuild %s not found", name)
+		}
+		return nil, nil
+	}
+
+	localBuild, err := util.GetLocalbuild(ctx, kubeClient, name)
+	if err != nil {
+		return nil, err
+	}
+	projectNamespace := globals.GetProjectNamespace(localBuild.Name)
+	pkgs := v1alpha1.CustomPackageList{}
+	if err = kubeClient.List(ctx, &pkgs, client.InNamespace(projectNamespace)); err != nil {
+		return nil, fmt.Errorf("listing custom packages: %w", err)
+	}
+
+	namespaces := []string{projectNamespace}
+	for i := range pkgs.Items {
+		argo := pkgs.Items[i].Spec.ArgoCD
+		key := client.ObjectKey{Name: argo.Name, Namespace: argo.Namespace}
+		if key.Namespace == "" {
+			key.Namespace = globals.ArgoCDNamespace
+		}
+
+		var ns string
+		if argo.Type == "ApplicationSet" {
+			appSet := argov1alpha1.ApplicationSet{}
+			if err = kubeClient.Get(ctx, key, &appSet); err == nil {
+				ns = appSet.Spec.Template.Spec.Destination.Namespace
+			}
+		} else {
+			app := argov1alpha1.Application{}
+			if err = kubeClient.Get(ctx, key, &app); err == nil {
+				ns = app.Spec.Destination.Namespace
+			}
+		}
+		if client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("getting %s %s: %w", argo.Type, argo.Name, err)
+		}
+		if ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces, nil
+}
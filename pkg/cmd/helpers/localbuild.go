@@ -0,0 +1,10 @@
+package helpers
+
+// BuildName returns the name of the localbuild selected by the --name and --cluster-name flags. The localbuild is
+// named after the cluster unless a name is given.
+func BuildName(localBuildName, clusterName string) string {
+	if localBuildName != "" {
+		return localBuildName
+	}
+	return clusterName
+}
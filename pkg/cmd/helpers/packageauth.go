@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	packageSSHKeyUsage = "Private key file used to clone ssh package urls. The ssh agent is used by default. " +
+		"The passphrase is read from " + util.SSHKeyPassphraseEnv + "."
+	packageKnownHostsUsage       = "known_hosts file host keys of ssh package urls are checked against. Defaults to the files used by ssh."
+	packageCredentialHelperUsage = "Ask the git credential helpers for the credentials of https package urls. " +
+		"The access token in " + util.GitTokenEnv + " is used first when set."
+	packageAuthURLUsage = "Prefix of the https package urls the access token in " + util.GitTokenEnv + " is sent to. " +
+		"e.g. https://github.com/my-org. Required when the token is set."
+)
+
+// AddPackageAuthFlags adds the flags that configure authentication to remote repositories of packages.
+func AddPackageAuthFlags(cmd *cobra.Command, auth *v1alpha1.PackageAuthSpec) {
+	cmd.Flags().StringVar(&auth.SSHKeyPath, "package-ssh-key", "", packageSSHKeyUsage)
+	cmd.Flags().StringVar(&auth.KnownHostsPath, "package-known-hosts", "", packageKnownHostsUsage)
+	cmd.Flags().BoolVar(&auth.CredentialHelper, "package-credential-helper", false, packageCredentialHelperUsage)
+	cmd.Flags().StringVar(&auth.URL, "package-auth-url", "", packageAuthURLUsage)
+}
+
+// ValidatePackageAuth checks the files given to AddPackageAuthFlags exist and makes their paths absolute. The access
+// token in the environment must be scoped with a url, because it is not sent anywhere else.
+func ValidatePackageAuth(auth *v1alpha1.PackageAuthSpec) error {
+	if os.Getenv(util.GitTokenEnv) != "" && auth.URL == "" {
+		return fmt.Errorf("%s is set, set --package-auth-url to the urls it is sent to", util.GitTokenEnv)
+	}
+	for _, p := range []*string{&auth.SSHKeyPath, &auth.KnownHostsPath} {
+		if *p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			return fmt.Errorf("failed to validate path %s : %w", *p, err)
+		}
+		if _, err = os.Stat(abs); err != nil {
+			return fmt.Errorf("failed to validate path %s : %w", abs, err)
+		}
+		*p = abs
+	}
+	return nil
+}
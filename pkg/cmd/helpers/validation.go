@@ -39,8 +39,12 @@ func ParsePackageStrings(pkgStrings []string) ([]string, []string, []string, err
 	remote, files, dirs := make([]string, 0, 2), make([]string, 0, 2), make([]string, 0, 2)
 	for i := range pkgStrings {
 		loc := pkgStrings[i]
-		_, err := util.NewKustomizeRemote(loc)
+		r, err := util.NewKustomizeRemote(loc)
 		if err == nil {
+			// the url is stored in resources and shown in logs.
+			if r.Password != "" {
+				return nil, nil, nil, fmt.Errorf("package url of %s must not contain a password, use %s or a git credential helper instead", r.Host, util.GitTokenEnv)
+			}
 			remote = append(remote, loc)
 			continue
 		}
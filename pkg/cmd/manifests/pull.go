@@ -0,0 +1,77 @@
+package manifests
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/manifests"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags
+	argocdVersion       string
+	giteaVersion        string
+	nginxVersion        string
+	envoyGatewayVersion string
+	fluxVersion         string
+)
+
+var PullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull manifests of core package versions into the cache",
+	Long: "Generate the manifests of the given core package versions the same way the embedded manifests are generated " +
+		"and store them in the manifest cache. Requires kustomize for Argo CD and ingress-nginx, and helm for Gitea. " +
+		"Envoy Gateway and Flux manifests are downloaded from their releases.",
+	RunE:         pullE,
+	PreRunE:      prePullE,
+	SilenceUsage: true,
+}
+
+func init() {
+	PullCmd.Flags().StringVar(&argocdVersion, "argocd-version", "", "Version of Argo CD to pull. e.g. v3.0.0")
+	PullCmd.Flags().StringVar(&giteaVersion, "gitea-version", "", "Version of the Gitea helm chart to pull. e.g. 12.1.2")
+	PullCmd.Flags().StringVar(&nginxVersion, "nginx-version", "", "Version of the ingress-nginx controller to pull. e.g. v1.13.0")
+	PullCmd.Flags().StringVar(&envoyGatewayVersion, "envoy-gateway-version", "",
+		fmt.Sprintf("Version of Envoy Gateway to pull. Installed with --ingress gateway-api. e.g. %s", manifests.DefaultEnvoyGatewayVersion))
+	PullCmd.Flags().StringVar(&fluxVersion, "flux-version", "",
+		fmt.Sprintf("Version of Flux to pull. Installed with --gitops-engine flux. e.g. %s", manifests.DefaultFluxVersion))
+}
+
+func prePullE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func pullE(cmd *cobra.Command, args []string) error {
+	dir, err := getCacheDir()
+	if err != nil {
+		return err
+	}
+
+	versions := map[string]string{
+		v1alpha1.ArgoCDPackageName:       argocdVersion,
+		v1alpha1.GiteaPackageName:        giteaVersion,
+		v1alpha1.IngressNginxPackageName: nginxVersion,
+		v1alpha1.EnvoyGatewayPackageName: envoyGatewayVersion,
+		v1alpha1.FluxPackageName:         fluxVersion,
+	}
+	pulled := 0
+	for _, name := range manifests.PackageNames {
+		version := versions[name]
+		if version == "" {
+			continue
+		}
+		helpers.CmdLogger.Info("Pulling manifests", "package", name, "version", version)
+		out, pErr := manifests.Pull(cmd.Context(), dir, name, version)
+		if pErr != nil {
+			return pErr
+		}
+		fmt.Printf("Pulled %s %s to %s\n", name, version, out)
+		pulled++
+	}
+	if pulled == 0 {
+		return fmt.Errorf("specify at least one of --argocd-version, --gitea-version, --nginx-version, --envoy-gateway-version and --flux-version")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package manifests
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/pkg/manifests"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags
+	cacheDir string
+)
+
+var ManifestsCmd = &cobra.Command{
+	Use:   "manifests",
+	Short: "Manage the cache of core package manifests",
+	Long: "Manage the cache of Argo CD, Gitea and ingress-nginx manifests used by " +
+		"create --argocd-version, --gitea-version and --nginx-version.",
+	RunE: manifestsE,
+}
+
+func init() {
+	ManifestsCmd.AddCommand(PullCmd)
+	ManifestsCmd.PersistentFlags().StringVar(&cacheDir, "manifest-cache-dir", "",
+		"Directory of the manifest cache. Defaults to idpbuilder/manifests in the user cache directory.")
+}
+
+func manifestsE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
+
+// getCacheDir returns the manifest cache selected by the flags.
+func getCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return manifests.DefaultCacheDir()
+}
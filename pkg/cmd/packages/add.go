@@ -0,0 +1,74 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/build"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	pkgs "github.com/cnoe-io/idpbuilder/pkg/packages"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var AddCmd = &cobra.Command{
+	Use:   "add <path|url>...",
+	Short: "Add packages to a running cluster",
+	Long: "Adds package locations to the cluster created by idpbuilder, with the same format as create -p, " +
+		"and waits until they are synced. Added packages have the highest priority, as if they were passed last to create -p.",
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         addE,
+	PreRunE:      preAddE,
+	SilenceUsage: true,
+}
+
+func init() {
+	addClusterFlags(AddCmd)
+}
+
+func preAddE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func addE(cmd *cobra.Command, args []string) error {
+	remotes, files, dirs, err := helpers.ParsePackageStrings(args)
+	if err != nil {
+		return err
+	}
+
+	return updatePackages(cmd.Context(), func(ctx context.Context, kubeClient client.Client, localBuild *v1alpha1.Localbuild) error {
+		added := pkgs.AddSources(&localBuild.Spec.PackageConfigs, remotes, files, dirs)
+		if len(added) == 0 {
+			helpers.CmdLogger.Info("packages are already configured, syncing them")
+		}
+		for _, a := range added {
+			helpers.CmdLogger.Info("adding package", "location", a)
+		}
+		return nil
+	})
+}
+
+// updatePackages runs update on the Localbuild of the cluster and waits for the controllers to sync the packages.
+func updatePackages(parent context.Context, update build.UpdateFunc) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	b := build.NewBuild(build.NewBuildOptions{
+		Name:           helpers.BuildName(localBuildName, clusterName),
+		ClusterName:    clusterName,
+		KubeConfigPath: kubeConfigPath,
+		ExitOnSync:     true,
+		Scheme:         k8s.GetScheme(),
+		CancelFunc:     cancel,
+	})
+	if err := b.UpdatePackages(ctx, update); err != nil {
+		return err
+	}
+	if parent.Err() != nil {
+		return context.Cause(parent)
+	}
+	fmt.Println("Packages synced")
+	return nil
+}
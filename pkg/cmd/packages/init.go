@@ -0,0 +1,100 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	pkgs "github.com/cnoe-io/idpbuilder/pkg/packages"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// Flags
+	packageType string
+	ingress     bool
+	outputDir   string
+)
+
+var InitCmd = &cobra.Command{
+	Use:   "init <name>",
+	Short: "Create a new package",
+	Long: "Creates a package directory with an Argo CD Application and a cnoe:// source containing manifests, " +
+		"a kustomization, or a Helm chart. The route kind, host and path match the settings of the running cluster, " +
+		"or the create defaults if the cluster cannot be reached. Pass the directory to create -p to deploy it.",
+	Args:         cobra.ExactArgs(1),
+	RunE:         initE,
+	PreRunE:      preInitE,
+	SilenceUsage: true,
+}
+
+func init() {
+	InitCmd.Flags().StringVar(&packageType, "type", string(pkgs.PackageTypeKustomize), fmt.Sprintf("Type of the package. One of %v.", pkgs.PackageTypes))
+	InitCmd.Flags().BoolVar(&ingress, "ingress", false, "Expose the package with an Ingress, or an HTTPRoute on gateway-api clusters.")
+	InitCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Directory to create the package in.")
+	addClusterFlags(InitCmd)
+}
+
+func preInitE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func initE(cmd *cobra.Command, args []string) error {
+	opts := pkgs.InitOptions{
+		Name:               args[0],
+		Type:               pkgs.PackageType(packageType),
+		Ingress:            ingress,
+		BuildCustomization: buildCustomization(cmd.Context()),
+	}
+
+	dir, err := pkgs.Init(outputDir, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Created package %s in %s\n", opts.Name, dir)
+	if opts.Ingress {
+		fmt.Fprintf(cmd.OutOrStdout(), "Once synced, it is served at %s\n", opts.URL())
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Deploy it with: idpbuilder create -p %s\n", dir)
+	return nil
+}
+
+// buildCustomization returns the settings of the Localbuild if the cluster can be reached, and the create defaults otherwise.
+func buildCustomization(ctx context.Context) v1alpha1.BuildCustomizationSpec {
+	logger := helpers.CmdLogger
+	spec := v1alpha1.BuildCustomizationSpec{
+		Protocol:    "https",
+		Host:        globals.DefaultHostName,
+		IngressHost: globals.DefaultHostName,
+		Port:        "8443",
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + clusterName},
+	).ClientConfig()
+	if err != nil {
+		logger.V(1).Info("using default settings, cannot load kubeconfig", "err", err)
+		return spec
+	}
+	restConfig.Timeout = 10 * time.Second
+	kubeClient, err := client.New(restConfig, client.Options{Scheme: k8s.GetScheme()})
+	if err != nil {
+		logger.V(1).Info("using default settings, cannot create kubernetes client", "err", err)
+		return spec
+	}
+
+	localBuild := v1alpha1.Localbuild{}
+	if err = kubeClient.Get(ctx, client.ObjectKey{Name: helpers.BuildName(localBuildName, clusterName)}, &localBuild); err != nil {
+		logger.Info("using default settings, cannot get cluster settings", "cluster", clusterName, "localbuild", helpers.BuildName(localBuildName, clusterName), "err", err)
+		return spec
+	}
+	return localBuild.Spec.BuildCustomization
+}
@@ -0,0 +1,79 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	pkgs "github.com/cnoe-io/idpbuilder/pkg/packages"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var RemoveCmd = &cobra.Command{
+	Use:   "remove <name>...",
+	Short: "Remove packages from a running cluster",
+	Long: "Removes packages by the name of their Argo CD Application or ApplicationSet. The location the package was " +
+		"added from is removed from the cluster configuration, and the CustomPackage, its GitRepositories and its " +
+		"Argo CD Application, including the resources it deployed, are deleted. If the location contains other " +
+		"packages, they must be removed together.",
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         removeE,
+	PreRunE:      preRemoveE,
+	SilenceUsage: true,
+}
+
+func init() {
+	addClusterFlags(RemoveCmd)
+}
+
+func preRemoveE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func removeE(cmd *cobra.Command, args []string) error {
+	return updatePackages(cmd.Context(), func(ctx context.Context, kubeClient client.Client, localBuild *v1alpha1.Localbuild) error {
+		var toRemove []*v1alpha1.CustomPackage
+		for _, name := range args {
+			pkg, err := pkgs.Find(ctx, kubeClient, localBuild.Name, name)
+			if err != nil {
+				return err
+			}
+			others, err := pkgs.FromSameSource(ctx, kubeClient, pkg)
+			if err != nil {
+				return err
+			}
+			var missing []string
+			for _, o := range others {
+				if !slices.Contains(args, o) {
+					missing = append(missing, o)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("%s also contains %s, remove them together with %s",
+					pkg.Annotations[v1alpha1.PackageSourcePathAnnotation], strings.Join(missing, ", "), name)
+			}
+			if !slices.ContainsFunc(toRemove, func(p *v1alpha1.CustomPackage) bool { return p.Name == pkg.Name }) {
+				toRemove = append(toRemove, pkg)
+			}
+		}
+
+		// packages from the same location share the source, which is removed once.
+		removedSources := map[string]bool{}
+		for _, pkg := range toRemove {
+			source := pkg.Annotations[v1alpha1.PackageSourcePathAnnotation]
+			if !removedSources[source] && !pkgs.RemoveSource(&localBuild.Spec.PackageConfigs, source) {
+				helpers.CmdLogger.Info("package location is not configured", "package", pkg.Spec.ArgoCD.Name, "location", source)
+			}
+			removedSources[source] = true
+			helpers.CmdLogger.Info("removing package", "package", pkg.Spec.ArgoCD.Name, "location", source)
+			if err := pkgs.Remove(ctx, kubeClient, pkg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
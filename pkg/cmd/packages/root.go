@@ -0,0 +1,40 @@
+package packages
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	// Flags
+	clusterName    string
+	localBuildName string
+	kubeConfigPath string
+)
+
+var PackageCmd = &cobra.Command{
+	Use:   "package",
+	Short: "Work with idpbuilder packages",
+	Long:  ``,
+	RunE:  packageE,
+}
+
+func init() {
+	PackageCmd.AddCommand(AddCmd)
+	PackageCmd.AddCommand(InitCmd)
+	PackageCmd.AddCommand(RemoveCmd)
+	PackageCmd.AddCommand(ValidateCmd)
+}
+
+func packageE(cmd *cobra.Command, args []string) error {
+	return fmt.Errorf("specify subcommand")
+}
+
+func addClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "localdev", "Name of the kind cluster.")
+	cmd.Flags().StringVar(&localBuildName, "name", "", "Name of the localbuild. Defaults to the cluster name.")
+	cmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+}
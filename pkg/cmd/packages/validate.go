@@ -0,0 +1,59 @@
+package packages
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	pkgs "github.com/cnoe-io/idpbuilder/pkg/packages"
+	"github.com/spf13/cobra"
+)
+
+var ValidateCmd = &cobra.Command{
+	Use:   "validate <path|url>...",
+	Short: "Validate packages before using them with create -p",
+	Long: "Checks packages the same way idpbuilder processes them: Argo CD Applications and ApplicationSets are decoded, " +
+		"cnoe:// references must point to existing directories, and application and repository names must be unique. " +
+		"Exits with a non-zero status if errors are found.",
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         validateE,
+	PreRunE:      preValidateE,
+	SilenceUsage: true,
+}
+
+var validateAuth v1alpha1.PackageAuthSpec
+
+func init() {
+	helpers.AddPackageAuthFlags(ValidateCmd, &validateAuth)
+}
+
+func preValidateE(cmd *cobra.Command, args []string) error {
+	if err := helpers.ValidatePackageAuth(&validateAuth); err != nil {
+		return err
+	}
+	return helpers.SetLogger()
+}
+
+func validateE(cmd *cobra.Command, args []string) error {
+	errCount, warnCount := 0, 0
+	for _, location := range args {
+		findings, err := pkgs.ValidateWithAuth(cmd.Context(), location, validateAuth)
+		if err != nil {
+			return err
+		}
+		for _, f := range findings {
+			fmt.Fprintln(cmd.OutOrStdout(), f.String())
+			if f.Severity == pkgs.SeverityError {
+				errCount++
+			} else {
+				warnCount++
+			}
+		}
+	}
+
+	if errCount > 0 {
+		return fmt.Errorf("validation failed: %d errors, %d warnings", errCount, warnCount)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "validation passed: %d warnings\n", warnCount)
+	return nil
+}
@@ -0,0 +1,60 @@
+package restart
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	// Flags
+	name           string
+	kubeConfigPath string
+	timeout        time.Duration
+)
+
+var RestartCmd = &cobra.Command{
+	Use:          "restart",
+	Short:        "Restart an IDP cluster",
+	Long:         "Stop the node containers of an IDP cluster, start them again and wait for the cluster to become ready.",
+	RunE:         restartE,
+	PreRunE:      preRestartE,
+	SilenceUsage: true,
+}
+
+func init() {
+	RestartCmd.Flags().StringVar(&name, "name", "localdev", "Name of the kind cluster to be restarted.")
+	RestartCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	RestartCmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the cluster to become ready.")
+}
+
+func preRestartE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func restartE(cmd *cobra.Command, args []string) error {
+	c, err := kind.NewCluster(name, "", kubeConfigPath, "", nil, "", nil, v1alpha1.BuildCustomizationSpec{}, helpers.CmdLogger)
+	if err != nil {
+		return err
+	}
+
+	if err = c.Stop(cmd.Context()); err != nil {
+		return fmt.Errorf("failed to stop cluster %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	if err = c.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start cluster %s: %w", name, err)
+	}
+	helpers.CmdLogger.Info("cluster restarted", "clusterName", name)
+	return nil
+}
@@ -6,9 +6,19 @@ import (
 	"os"
 
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/create"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/debug"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/delete"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/diff"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/doctor"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/export"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/get"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/manifests"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/packages"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/restart"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/start"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/stop"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/upgrade"
 	"github.com/cnoe-io/idpbuilder/pkg/cmd/version"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +35,16 @@ func init() {
 	rootCmd.AddCommand(create.CreateCmd)
 	rootCmd.AddCommand(get.GetCmd)
 	rootCmd.AddCommand(delete.DeleteCmd)
+	rootCmd.AddCommand(diff.DiffCmd)
+	rootCmd.AddCommand(doctor.DoctorCmd)
+	rootCmd.AddCommand(debug.DebugCmd)
+	rootCmd.AddCommand(export.ExportCmd)
+	rootCmd.AddCommand(manifests.ManifestsCmd)
+	rootCmd.AddCommand(packages.PackageCmd)
+	rootCmd.AddCommand(restart.RestartCmd)
+	rootCmd.AddCommand(start.StartCmd)
+	rootCmd.AddCommand(stop.StopCmd)
+	rootCmd.AddCommand(upgrade.UpgradeCmd)
 	rootCmd.AddCommand(version.VersionCmd)
 }
 
@@ -0,0 +1,56 @@
+package start
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	// Flags
+	name           string
+	kubeConfigPath string
+	timeout        time.Duration
+)
+
+var StartCmd = &cobra.Command{
+	Use:          "start",
+	Short:        "Start a stopped IDP cluster",
+	Long:         "Start the node containers of a stopped IDP cluster and wait for the nodes, CoreDNS, the installed ingress and Gitea, unless it is skipped, to become ready.",
+	RunE:         startE,
+	PreRunE:      preStartE,
+	SilenceUsage: true,
+}
+
+func init() {
+	StartCmd.Flags().StringVar(&name, "name", "localdev", "Name of the kind cluster to be started.")
+	StartCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	StartCmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the cluster to become ready.")
+}
+
+func preStartE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func startE(cmd *cobra.Command, args []string) error {
+	c, err := kind.NewCluster(name, "", kubeConfigPath, "", nil, "", nil, v1alpha1.BuildCustomizationSpec{}, helpers.CmdLogger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	if err = c.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start cluster %s: %w", name, err)
+	}
+	helpers.CmdLogger.Info("cluster started", "clusterName", name)
+	return nil
+}
@@ -0,0 +1,44 @@
+package stop
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags
+	name string
+)
+
+var StopCmd = &cobra.Command{
+	Use:          "stop",
+	Short:        "Stop an IDP cluster without deleting it",
+	Long:         "Stop the node containers of an IDP cluster to free resources. Use the start command to resume it.",
+	RunE:         stopE,
+	PreRunE:      preStopE,
+	SilenceUsage: true,
+}
+
+func init() {
+	StopCmd.Flags().StringVar(&name, "name", "localdev", "Name of the kind cluster to be stopped.")
+}
+
+func preStopE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func stopE(cmd *cobra.Command, args []string) error {
+	c, err := kind.NewCluster(name, "", "", "", nil, "", nil, v1alpha1.BuildCustomizationSpec{}, helpers.CmdLogger)
+	if err != nil {
+		return err
+	}
+
+	if err = c.Stop(cmd.Context()); err != nil {
+		return fmt.Errorf("failed to stop cluster %s: %w", name, err)
+	}
+	return nil
+}
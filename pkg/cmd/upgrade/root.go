@@ -0,0 +1,154 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/build"
+	"github.com/cnoe-io/idpbuilder/pkg/cmd/helpers"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/printer"
+	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// Flags
+	clusterName    string
+	localBuildName string
+	kubeConfigPath string
+	dryRun         bool
+	rollback       bool
+)
+
+var UpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the core packages of a running cluster",
+	Long: "Upgrade the core packages installed by the build, e.g. the ingress, Gitea and the GitOps engine, to the " +
+		"versions embedded in this binary. The command shows a plan, then installs the packages one at a time " +
+		"and waits for each to become ready. " +
+		"Use --rollback to install the manifests recorded before the last upgrade.",
+	RunE:         upgradeE,
+	PreRunE:      preUpgradeE,
+	SilenceUsage: true,
+}
+
+func init() {
+	UpgradeCmd.Flags().StringVar(&clusterName, "cluster-name", "localdev", "Name of the kind cluster.")
+	UpgradeCmd.Flags().StringVar(&localBuildName, "name", "", "Name of the localbuild. Defaults to the cluster name.")
+	UpgradeCmd.Flags().StringVar(&kubeConfigPath, "kubeconfig", filepath.Join(homedir.HomeDir(), ".kube", "config"), "kube config file Path.")
+	UpgradeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the plan.")
+	UpgradeCmd.Flags().BoolVar(&rollback, "rollback", false, "Install the core package manifests recorded before the last upgrade.")
+}
+
+func preUpgradeE(cmd *cobra.Command, args []string) error {
+	return helpers.SetLogger()
+}
+
+func upgradeE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: "kind-" + clusterName},
+	).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+	scheme := k8s.GetScheme()
+	kubeClient, err := client.NewWithWatch(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	localBuild := v1alpha1.Localbuild{}
+	if err = kubeClient.Get(ctx, client.ObjectKey{Name: helpers.BuildName(localBuildName, clusterName)}, &localBuild); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("cluster %s was not created by idpbuilder: %w", clusterName, err)
+		}
+		return fmt.Errorf("getting localbuild %s: %w", helpers.BuildName(localBuildName, clusterName), err)
+	}
+
+	plan, err := build.PlanUpgrade(ctx, kubeClient, scheme, &localBuild, rollback)
+	if err != nil {
+		return err
+	}
+	if err = printPlan(cmd, plan); err != nil {
+		return err
+	}
+
+	changes := false
+	for _, u := range plan {
+		changes = changes || u.HasChanges()
+	}
+	if !changes {
+		fmt.Fprintln(cmd.OutOrStdout(), "Core packages are up to date")
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err = build.UpgradeCorePackages(ctx, kubeClient, kubeClient, scheme, &localBuild, plan); err != nil {
+		return err
+	}
+	if rollback {
+		fmt.Fprintln(cmd.OutOrStdout(), "Core packages rolled back. Automated sync of the core package applications in "+
+			"Argo CD stays disabled until the next create, because Gitea still serves the manifests of the last upgrade.")
+		return nil
+	}
+
+	// the controllers push the new manifests to Gitea and enable automated sync of the core package applications again.
+	if err = syncPackages(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Core packages upgraded")
+	return nil
+}
+
+func printPlan(cmd *cobra.Command, plan []build.CorePackageUpgrade) error {
+	packages := make([]types.CorePackageUpgrade, 0, len(plan))
+	for _, u := range plan {
+		packages = append(packages, types.CorePackageUpgrade{
+			Name:           u.Name,
+			CurrentVersion: u.CurrentVersion,
+			TargetVersion:  u.TargetVersion,
+			Changed:        u.Changed,
+			Pruned:         u.Pruned,
+		})
+	}
+	p := printer.UpgradePlanPrinter{
+		Packages:  packages,
+		OutWriter: cmd.OutOrStdout(),
+	}
+	return p.PrintOutput("table")
+}
+
+// syncPackages runs the controllers until the packages of the cluster are synced.
+func syncPackages(parent context.Context) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	b := build.NewBuild(build.NewBuildOptions{
+		Name:           helpers.BuildName(localBuildName, clusterName),
+		ClusterName:    clusterName,
+		KubeConfigPath: kubeConfigPath,
+		ExitOnSync:     true,
+		Scheme:         k8s.GetScheme(),
+		CancelFunc:     cancel,
+	})
+	noop := func(context.Context, client.Client, *v1alpha1.Localbuild) error { return nil }
+	if err := b.UpdatePackages(ctx, noop); err != nil {
+		return err
+	}
+	if parent.Err() != nil {
+		return context.Cause(parent)
+	}
+	return nil
+}
@@ -55,7 +55,7 @@ func version(cmd *cobra.Command, args []string) error {
 			buildDate,
 		}))
 	case "json":
-		jsonInfo, err := jsonInfo()
+		jsonInfo, err := JSONInfo()
 		if err != nil {
 			return err
 		}
@@ -79,7 +79,8 @@ func version(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func jsonInfo() (string, error) {
+// JSONInfo returns the version information as printed by version -o json.
+func JSONInfo() (string, error) {
 	info := idpbuilderInfo{
 		IdpbuilderVersion: idpbuilderVersion,
 		GoVersion:         goVersion,
@@ -19,7 +19,8 @@ import (
 //go:embed resources/*.yaml
 var crdFS embed.FS
 
-func getK8sResources(scheme *runtime.Scheme, templateData any) ([]client.Object, error) {
+// CRDs returns the idpbuilder CRDs rendered with the given template data.
+func CRDs(scheme *runtime.Scheme, templateData any) ([]client.Object, error) {
 	rawResources, err := fs.ConvertFSToBytes(crdFS, "resources", templateData)
 	if err != nil {
 		return nil, err
@@ -88,7 +89,7 @@ func EnsureCRD(ctx context.Context, scheme *runtime.Scheme, kubeClient client.Cl
 }
 
 func EnsureCRDs(ctx context.Context, scheme *runtime.Scheme, kubeClient client.Client, templateData any) error {
-	installObjs, err := getK8sResources(scheme, templateData)
+	installObjs, err := CRDs(scheme, templateData)
 	if err != nil {
 		return err
 	}
@@ -12,16 +12,20 @@ import (
 	argocdapplication "github.com/cnoe-io/argocd-api/api/argo/application"
 	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 const (
@@ -35,6 +39,8 @@ type Reconciler struct {
 	Config   v1alpha1.BuildCustomizationSpec
 	TempDir  string
 	RepoMap  *util.RepoMap
+	// Namespace limits reconciliation to CustomPackages in this namespace. All namespaces are reconciled if it is empty.
+	Namespace string
 }
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -54,10 +60,40 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	} else {
 		r.Recorder.Event(&pkg, "Normal", "reconcile success", "Successfully reconciled")
 	}
+	if !meta.IsStatusConditionTrue(pkg.Status.Conditions, v1alpha1.ConditionSuperseded) {
+		setConditions(&pkg, err)
+	}
 
 	return result, err
 }
 
+// setConditions sets the DependenciesBlocked and Ready conditions of a package that is not superseded from the result
+// of a reconcile.
+func setConditions(pkg *v1alpha1.CustomPackage, reconcileErr error) {
+	if reconcileErr != nil {
+		setCondition(pkg, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonReconcileFailed, reconcileErr.Error())
+		return
+	}
+	if !pkg.Status.Synced {
+		msg := "waiting for referenced git repositories to be pushed to the git server"
+		setCondition(pkg, v1alpha1.ConditionDependenciesBlocked, metav1.ConditionTrue, v1alpha1.ReasonRepositoriesNotReady, msg)
+		setCondition(pkg, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonRepositoriesNotReady, msg)
+		return
+	}
+	setCondition(pkg, v1alpha1.ConditionDependenciesBlocked, metav1.ConditionFalse, v1alpha1.ReasonRepositoriesReady, "referenced git repositories are ready")
+	setCondition(pkg, v1alpha1.ConditionReady, metav1.ConditionTrue, v1alpha1.ReasonSynced, fmt.Sprintf("%s %s is synced", pkg.Spec.ArgoCD.Type, pkg.Spec.ArgoCD.Name))
+}
+
+func setCondition(pkg *v1alpha1.CustomPackage, condType string, status metav1.ConditionStatus, reason, msg string) {
+	meta.SetStatusCondition(&pkg.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: pkg.Generation,
+		Reason:             reason,
+		Message:            msg,
+	})
+}
+
 func (r *Reconciler) postProcessReconcile(ctx context.Context, req ctrl.Request, pkg *v1alpha1.CustomPackage) {
 	logger := log.FromContext(ctx)
 
@@ -232,8 +268,13 @@ func (r *Reconciler) reconcileCustomPackage(ctx context.Context, resource *v1alp
 			"appName", resource.Spec.ArgoCD.Name,
 			"sourcePath", resource.ObjectMeta.Annotations[v1alpha1.PackageSourcePathAnnotation])
 		resource.Status.Synced = false
+		msg := fmt.Sprintf("a package with a higher priority defines %s %s", resource.Spec.ArgoCD.Type, resource.Spec.ArgoCD.Name)
+		setCondition(resource, v1alpha1.ConditionSuperseded, metav1.ConditionTrue, v1alpha1.ReasonHigherPriorityPackage, msg)
+		setCondition(resource, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonHigherPriorityPackage, msg)
 		return ctrl.Result{}, nil
 	}
+	setCondition(resource, v1alpha1.ConditionSuperseded, metav1.ConditionFalse, v1alpha1.ReasonHighestPriorityPackage,
+		fmt.Sprintf("no package with a higher priority defines %s %s", resource.Spec.ArgoCD.Type, resource.Spec.ArgoCD.Name))
 
 	logger.V(1).Info("proceeding with reconciliation as highest priority package",
 		"name", resource.Name,
@@ -325,8 +366,11 @@ func (r *Reconciler) reconcileCustomPackage(ctx context.Context, resource *v1alp
 		}
 		return res, nil
 
+	case gitops.FluxGitRepositoryKind, gitops.FluxKustomizationKind, gitops.FluxHelmReleaseKind:
+		return r.reconcileFluxObjects(ctx, resource, objs)
+
 	default:
-		return ctrl.Result{}, fmt.Errorf("file is not a supported argocd kind %s", resource.Spec.ArgoCD.ApplicationFile)
+		return ctrl.Result{}, fmt.Errorf("file is not a supported argocd or flux kind %s", resource.Spec.ArgoCD.ApplicationFile)
 	}
 }
 
@@ -341,6 +385,9 @@ func (r *Reconciler) reconcileArgoCDApp(ctx context.Context, resource *v1alpha1.
 			if sErr != nil {
 				return res, sErr
 			}
+			if setRemoteSource(resource, s) {
+				continue
+			}
 
 			res, repo, sErr := r.reconcileArgoCDSource(ctx, resource, s.RepoURL, app.Name)
 			if sErr != nil {
@@ -366,6 +413,7 @@ func (r *Reconciler) reconcileArgoCDApp(ctx context.Context, resource *v1alpha1.
 		if sErr != nil {
 			return res, sErr
 		}
+		setRemoteSource(resource, s)
 
 		res, repo, sErr := r.reconcileArgoCDSource(ctx, resource, s.RepoURL, app.Name)
 		if sErr != nil {
@@ -396,7 +444,7 @@ func (r *Reconciler) reconcileArgoCDAppSet(ctx context.Context, resource *v1alph
 	notSyncedRepos := 0
 	for i := range appSet.Spec.Generators {
 		g := appSet.Spec.Generators[i]
-		if g.Git != nil {
+		if g.Git != nil && !setRemoteGitGenerator(resource, g.Git) {
 			res, repo, gErr := r.reconcileArgoCDSource(ctx, resource, g.Git.RepoURL, appSet.GetName())
 			if gErr != nil {
 				return res, fmt.Errorf("reconciling git generator URL %s, %s: %w", g.Git.RepoURL, resource.Spec.ArgoCD.ApplicationFile, gErr)
@@ -411,7 +459,7 @@ func (r *Reconciler) reconcileArgoCDAppSet(ctx context.Context, resource *v1alph
 		if g.Matrix != nil {
 			for j := range g.Matrix.Generators {
 				nestedGenerator := g.Matrix.Generators[j]
-				if nestedGenerator.Git != nil {
+				if nestedGenerator.Git != nil && !setRemoteGitGenerator(resource, nestedGenerator.Git) {
 					res, repo, gErr := r.reconcileArgoCDSource(ctx, resource, nestedGenerator.Git.RepoURL, appSet.GetName())
 					if gErr != nil {
 						return res, fmt.Errorf("reconciling git generator URL %s, %s: %w", nestedGenerator.Git.RepoURL, resource.Spec.ArgoCD.ApplicationFile, gErr)
@@ -449,7 +497,7 @@ func (r *Reconciler) reconcileArgoCDAppSet(ctx context.Context, resource *v1alph
 
 // create a gitrepository custom resource, then let the git repository controller take care of the rest
 func (r *Reconciler) reconcileArgoCDSource(ctx context.Context, resource *v1alpha1.CustomPackage, repoUrl, appName string) (ctrl.Result, *v1alpha1.GitRepository, error) {
-	if isCNOEScheme(repoUrl) {
+	if IsCNOEScheme(repoUrl) {
 		if resource.Spec.RemoteRepository.Url == "" {
 			return r.reconcileArgoCDSourceFromLocal(ctx, resource, appName, repoUrl)
 		}
@@ -466,7 +514,7 @@ func (r *Reconciler) reconcileArgoCDSourceFromRemote(ctx context.Context, resour
 
 	repo := &v1alpha1.GitRepository{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      remoteRepoName(appName, dirPath, resource.Spec.RemoteRepository),
+			Name:      RemoteRepoName(appName, dirPath, resource.Spec.RemoteRepository),
 			Namespace: resource.Namespace,
 		},
 	}
@@ -514,12 +562,7 @@ func (r *Reconciler) reconcileArgoCDSourceFromRemote(ctx context.Context, resour
 				RemoteRepository: resource.Spec.RemoteRepository,
 				Path:             dirPath,
 			},
-			Provider: v1alpha1.Provider{
-				Name:             v1alpha1.GitProviderGitea,
-				GitURL:           resource.Spec.GitServerURL,
-				InternalGitURL:   resource.Spec.InternalGitServeURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
-			},
+			Provider:  gitServerProvider(resource),
 			SecretRef: resource.Spec.GitServerAuthSecretRef,
 		}
 
@@ -536,7 +579,7 @@ func (r *Reconciler) reconcileArgoCDSourceFromRemote(ctx context.Context, resour
 func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resource *v1alpha1.CustomPackage, appName, repoURL string) (ctrl.Result, *v1alpha1.GitRepository, error) {
 	logger := log.FromContext(ctx)
 
-	absPath, err := getCNOEAbsPath(resource.Spec.ArgoCD.ApplicationFile, repoURL)
+	absPath, err := GetCNOEAbsPath(resource.Spec.ArgoCD.ApplicationFile, repoURL)
 	if err != nil {
 		logger.Error(err, "processing argocd app source", "dir", resource.Spec.ArgoCD.ApplicationFile, "repoURL", repoURL)
 		return ctrl.Result{}, nil, err
@@ -544,7 +587,7 @@ func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resourc
 
 	repo := &v1alpha1.GitRepository{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      localRepoName(appName, absPath),
+			Name:      LocalRepoName(appName, absPath),
 			Namespace: resource.Namespace,
 		},
 	}
@@ -590,12 +633,7 @@ func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resourc
 				Type: v1alpha1.SourceTypeLocal,
 				Path: absPath,
 			},
-			Provider: v1alpha1.Provider{
-				Name:             v1alpha1.GitProviderGitea,
-				GitURL:           resource.Spec.GitServerURL,
-				InternalGitURL:   resource.Spec.InternalGitServeURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
-			},
+			Provider:  gitServerProvider(resource),
 			SecretRef: resource.Spec.GitServerAuthSecretRef,
 		}
 
@@ -610,9 +648,75 @@ func (r *Reconciler) reconcileArgoCDSourceFromLocal(ctx context.Context, resourc
 	return ctrl.Result{}, repo, nil
 }
 
+// referencesRemote reports whether cnoe:// sources of a remote package point to the remote repository instead of a
+// GitRepository replicated into the git server.
+func referencesRemote(resource *v1alpha1.CustomPackage) bool {
+	return !resource.Spec.Replicate && resource.Spec.RemoteRepository.Url != ""
+}
+
+// remotePath returns the directory of the remote repository a cnoe:// url refers to.
+func remotePath(resource *v1alpha1.CustomPackage, repoURL string) string {
+	return filepath.Join(resource.Spec.RemoteRepository.Path, strings.TrimPrefix(repoURL, v1alpha1.CNOEURIScheme))
+}
+
+// setRemoteSource points a cnoe:// source to the remote repository at the pinned ref. It returns false if the
+// source needs a GitRepository.
+func setRemoteSource(resource *v1alpha1.CustomPackage, s *argov1alpha1.ApplicationSource) bool {
+	if !referencesRemote(resource) || !IsCNOEScheme(s.RepoURL) {
+		return false
+	}
+	dir := remotePath(resource, s.RepoURL)
+	s.RepoURL = resource.Spec.RemoteRepository.Url
+	// templated paths of application sets come from git generators, which already include the directory.
+	if !strings.Contains(s.Path, "{{") {
+		s.Path = filepath.Join(dir, s.Path)
+	}
+	if resource.Spec.RemoteRepository.Ref != "" {
+		s.TargetRevision = resource.Spec.RemoteRepository.Ref
+	}
+	return true
+}
+
+// setRemoteGitGenerator is setRemoteSource for git generators of application sets.
+func setRemoteGitGenerator(resource *v1alpha1.CustomPackage, g *argov1alpha1.GitGenerator) bool {
+	if !referencesRemote(resource) || !IsCNOEScheme(g.RepoURL) {
+		return false
+	}
+	dir := remotePath(resource, g.RepoURL)
+	g.RepoURL = resource.Spec.RemoteRepository.Url
+	for i := range g.Directories {
+		g.Directories[i].Path = filepath.Join(dir, g.Directories[i].Path)
+	}
+	for i := range g.Files {
+		g.Files[i].Path = filepath.Join(dir, g.Files[i].Path)
+	}
+	if resource.Spec.RemoteRepository.Ref != "" {
+		g.Revision = resource.Spec.RemoteRepository.Ref
+	}
+	return true
+}
+
+// gitServerProvider returns the git server the repositories of resource are created in. It is the in-cluster Gitea
+// unless a provider is set.
+func gitServerProvider(resource *v1alpha1.CustomPackage) v1alpha1.Provider {
+	p := v1alpha1.Provider{
+		Name:             v1alpha1.GitProviderGitea,
+		GitURL:           resource.Spec.GitServerURL,
+		InternalGitURL:   resource.Spec.InternalGitServeURL,
+		OrganizationName: v1alpha1.GiteaAdminUserName,
+	}
+	if resource.Spec.GitProvider != "" {
+		p.Name = resource.Spec.GitProvider
+		p.OrganizationName = resource.Spec.GitOrganizationName
+	}
+	return p
+}
+
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.CustomPackage{}).
+		For(&v1alpha1.CustomPackage{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return r.Namespace == "" || o.GetNamespace() == r.Namespace
+		}))).
 		Complete(r)
 }
 
@@ -624,9 +728,18 @@ func (r *Reconciler) getArgoCDAppFile(ctx context.Context, resource *v1alpha1.Cu
 	}
 
 	cloneDir := util.RepoDir(resource.Spec.RemoteRepository.Url, r.TempDir)
+	auth, err := util.RemoteRepoAuth(ctx, r.Client, r.Config.PackageAuth, resource.Spec.RemoteRepository.Url)
+	if err != nil {
+		return nil, fmt.Errorf("getting credentials of repo, %s: %w", resource.Spec.RemoteRepository.Url, err)
+	}
+	// Argo CD does not get the package credentials, so it could not pull the remote repository.
+	if auth != nil && referencesRemote(resource) {
+		return nil, fmt.Errorf("%s requires credentials, which are not passed to Argo CD, "+
+			"remove replicate=false from the package url", resource.Spec.RemoteRepository.Url)
+	}
 	st := r.RepoMap.LoadOrStore(resource.Spec.RemoteRepository.Url, cloneDir)
 	st.MU.Lock()
-	wt, _, err := util.CloneRemoteRepoToDir(ctx, resource.Spec.RemoteRepository, 1, false, cloneDir, "")
+	wt, _, err := util.CloneRemoteRepoToDirWithAuth(ctx, resource.Spec.RemoteRepository, 1, false, cloneDir, "", auth)
 	defer st.MU.Unlock()
 	if err != nil {
 		return nil, fmt.Errorf("cloning repo, %s: %w", resource.Spec.RemoteRepository.Url, err)
@@ -667,6 +780,11 @@ func (r *Reconciler) reconcileHelmValueObjectSource(ctx context.Context,
 
 	switch val := (*valueObject).(type) {
 	case string:
+		// values only hold a url, so the directory and ref of the remote repository cannot be referenced.
+		if referencesRemote(resource) && IsCNOEScheme(val) {
+			return ctrl.Result{}, fmt.Errorf("%s in helm values requires replicating the remote repository, "+
+				"remove replicate=false from the package url", val)
+		}
 		res, repo, err := r.reconcileArgoCDSource(ctx, resource, val, appName)
 		if err != nil {
 			return res, fmt.Errorf("processing %s in helmValueObject: %w", val, err)
@@ -697,19 +815,23 @@ func (r *Reconciler) reconcileHelmValueObjectSource(ctx context.Context,
 	return ctrl.Result{}, nil
 }
 
-func localRepoName(appName, dir string) string {
+// LocalRepoName returns the name of the GitRepository created for a cnoe:// source in a local package.
+func LocalRepoName(appName, dir string) string {
 	return fmt.Sprintf("%s-%s", appName, filepath.Base(dir))
 }
 
-func remoteRepoName(appName, pathToPkg string, repo v1alpha1.RemoteRepositorySpec) string {
+// RemoteRepoName returns the name of the GitRepository created for a cnoe:// source in a remote package.
+func RemoteRepoName(appName, pathToPkg string, repo v1alpha1.RemoteRepositorySpec) string {
 	return fmt.Sprintf("%s-%s", appName, filepath.Base(pathToPkg))
 }
 
-func isCNOEScheme(repoURL string) bool {
+// IsCNOEScheme returns true if repoURL refers to a local directory with the cnoe:// scheme.
+func IsCNOEScheme(repoURL string) bool {
 	return strings.HasPrefix(repoURL, v1alpha1.CNOEURIScheme)
 }
 
-func getCNOEAbsPath(fPath, repoURL string) (string, error) {
+// GetCNOEAbsPath returns the directory a cnoe:// URL in the file at fPath refers to. The directory must exist.
+func GetCNOEAbsPath(fPath, repoURL string) (string, error) {
 	parentDir := filepath.Dir(fPath)
 	relativePath := strings.TrimPrefix(repoURL, v1alpha1.CNOEURIScheme)
 	absPath, err := filepath.Abs(filepath.Join(parentDir, relativePath))
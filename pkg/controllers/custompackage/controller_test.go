@@ -13,9 +13,11 @@ import (
 
 	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -161,7 +163,7 @@ func TestReconcileCustomPkg(t *testing.T) {
 	c := mgr.GetClient()
 	repo := v1alpha1.GitRepository{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      localRepoName("my-app", "test/resources/customPackages/testDir/app1"),
+			Name:      LocalRepoName("my-app", "test/resources/customPackages/testDir/app1"),
 			Namespace: "test",
 		},
 	}
@@ -324,7 +326,7 @@ func TestReconcileCustomPkgAppSet(t *testing.T) {
 			},
 			expectedGitRepo: v1alpha1.GitRepository{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      localRepoName("generator-single-source", "test/resources/customPackages/applicationSet/test1"),
+					Name:      LocalRepoName("generator-single-source", "test/resources/customPackages/applicationSet/test1"),
 					Namespace: "test",
 				},
 				Spec: v1alpha1.GitRepositorySpec{
@@ -382,7 +384,7 @@ func TestReconcileCustomPkgAppSet(t *testing.T) {
 			},
 			expectedGitRepo: v1alpha1.GitRepository{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      localRepoName("generator-multi-sources", "test/resources/customPackages/applicationSet/test1"),
+					Name:      LocalRepoName("generator-multi-sources", "test/resources/customPackages/applicationSet/test1"),
 					Namespace: "test",
 				},
 				Spec: v1alpha1.GitRepositorySpec{
@@ -442,7 +444,7 @@ func TestReconcileCustomPkgAppSet(t *testing.T) {
 			},
 			expectedGitRepo: v1alpha1.GitRepository{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      localRepoName("no-generator-single-source", "test/resources/customPackages/applicationSet/test1"),
+					Name:      LocalRepoName("no-generator-single-source", "test/resources/customPackages/applicationSet/test1"),
 					Namespace: "test",
 				},
 				Spec: v1alpha1.GitRepositorySpec{
@@ -493,7 +495,7 @@ func TestReconcileCustomPkgAppSet(t *testing.T) {
 			},
 			expectedGitRepo: v1alpha1.GitRepository{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      localRepoName("generator-matrix", "test/resources/customPackages/applicationSet/test1"),
+					Name:      LocalRepoName("generator-matrix", "test/resources/customPackages/applicationSet/test1"),
 					Namespace: "test",
 				},
 				Spec: v1alpha1.GitRepositorySpec{
@@ -843,6 +845,16 @@ func TestPackagePriority(t *testing.T) {
 		assert.True(t, shouldReconcile, "pkg2 (priority 1) should reconcile as it has highest priority")
 	})
 
+	t.Run("lower priority package is marked superseded", func(t *testing.T) {
+		_, err := r.reconcileCustomPackage(context.Background(), &pkg1)
+		assert.NoError(t, err)
+		assert.True(t, meta.IsStatusConditionTrue(pkg1.Status.Conditions, v1alpha1.ConditionSuperseded))
+		ready := meta.FindStatusCondition(pkg1.Status.Conditions, v1alpha1.ConditionReady)
+		require.NotNil(t, ready)
+		assert.Equal(t, metav1.ConditionFalse, ready.Status)
+		assert.Equal(t, v1alpha1.ReasonHigherPriorityPackage, ready.Reason)
+	})
+
 	t.Run("getPackagePriority should extract priority correctly", func(t *testing.T) {
 		priority1, err := getPackagePriority(&pkg1)
 		assert.NoError(t, err)
@@ -854,6 +866,34 @@ func TestPackagePriority(t *testing.T) {
 	})
 }
 
+func TestSetConditions(t *testing.T) {
+	pkg := v1alpha1.CustomPackage{
+		Spec: v1alpha1.CustomPackageSpec{
+			ArgoCD: v1alpha1.ArgoCDPackageSpec{Name: "my-app", Type: "Application"},
+		},
+	}
+
+	setConditions(&pkg, nil)
+	blocked := meta.FindStatusCondition(pkg.Status.Conditions, v1alpha1.ConditionDependenciesBlocked)
+	require.NotNil(t, blocked)
+	assert.Equal(t, metav1.ConditionTrue, blocked.Status)
+	assert.Equal(t, v1alpha1.ReasonRepositoriesNotReady, blocked.Reason)
+	assert.False(t, meta.IsStatusConditionTrue(pkg.Status.Conditions, v1alpha1.ConditionReady))
+
+	pkg.Status.Synced = true
+	setConditions(&pkg, nil)
+	assert.False(t, meta.IsStatusConditionTrue(pkg.Status.Conditions, v1alpha1.ConditionDependenciesBlocked))
+	ready := meta.FindStatusCondition(pkg.Status.Conditions, v1alpha1.ConditionReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, metav1.ConditionTrue, ready.Status)
+	assert.Equal(t, "Application my-app is synced", ready.Message)
+
+	setConditions(&pkg, fmt.Errorf("reading file"))
+	ready = meta.FindStatusCondition(pkg.Status.Conditions, v1alpha1.ConditionReady)
+	assert.Equal(t, metav1.ConditionFalse, ready.Status)
+	assert.Equal(t, v1alpha1.ReasonReconcileFailed, ready.Reason)
+}
+
 func TestGetPackagePriority(t *testing.T) {
 	t.Run("valid priority annotation", func(t *testing.T) {
 		pkg := &v1alpha1.CustomPackage{
@@ -926,3 +966,90 @@ func TestGetPackagePriority(t *testing.T) {
 		assert.Equal(t, 1000, priority)
 	})
 }
+
+func TestReconcileArgoCDAppRemoteWithoutReplication(t *testing.T) {
+	ctx := context.Background()
+	// no client is set, so no GitRepository can be created.
+	r := Reconciler{}
+	resource := &v1alpha1.CustomPackage{
+		Spec: v1alpha1.CustomPackageSpec{
+			Replicate: false,
+			RemoteRepository: v1alpha1.RemoteRepositorySpec{
+				Url:  "https://github.com/cnoe-io/stacks",
+				Ref:  "v0.1.0",
+				Path: "ref-implementation",
+			},
+		},
+	}
+
+	app := &argov1alpha1.Application{
+		Spec: argov1alpha1.ApplicationSpec{
+			Sources: argov1alpha1.ApplicationSources{
+				{RepoURL: "cnoe://backstage", Path: "manifests", TargetRevision: "HEAD"},
+				{RepoURL: "https://charts.example.com", Chart: "app", TargetRevision: "1.0.0"},
+			},
+		},
+	}
+	_, err := r.reconcileArgoCDApp(ctx, resource, app)
+	require.NoError(t, err)
+	assert.Equal(t, argov1alpha1.ApplicationSource{
+		RepoURL:        "https://github.com/cnoe-io/stacks",
+		Path:           "ref-implementation/backstage/manifests",
+		TargetRevision: "v0.1.0",
+	}, app.Spec.Sources[0])
+	assert.Equal(t, "https://charts.example.com", app.Spec.Sources[1].RepoURL)
+	assert.Equal(t, "1.0.0", app.Spec.Sources[1].TargetRevision)
+	assert.True(t, resource.Status.Synced)
+	assert.Empty(t, resource.Status.GitRepositoryRefs)
+
+	appSet := &argov1alpha1.ApplicationSet{
+		Spec: argov1alpha1.ApplicationSetSpec{
+			Generators: []argov1alpha1.ApplicationSetGenerator{{
+				Git: &argov1alpha1.GitGenerator{
+					RepoURL:     "cnoe://apps",
+					Revision:    "HEAD",
+					Directories: []argov1alpha1.GitDirectoryGeneratorItem{{Path: "*"}},
+				},
+			}},
+			Template: argov1alpha1.ApplicationSetTemplate{
+				Spec: argov1alpha1.ApplicationSpec{
+					Source: &argov1alpha1.ApplicationSource{RepoURL: "cnoe://apps", Path: "{{path}}"},
+				},
+			},
+		},
+	}
+	_, err = r.reconcileArgoCDAppSet(ctx, resource, appSet)
+	require.NoError(t, err)
+	g := appSet.Spec.Generators[0].Git
+	assert.Equal(t, "https://github.com/cnoe-io/stacks", g.RepoURL)
+	assert.Equal(t, "v0.1.0", g.Revision)
+	assert.Equal(t, "ref-implementation/apps/*", g.Directories[0].Path)
+	assert.Equal(t, argov1alpha1.ApplicationSource{
+		RepoURL:        "https://github.com/cnoe-io/stacks",
+		Path:           "{{path}}",
+		TargetRevision: "v0.1.0",
+	}, *appSet.Spec.Template.Spec.Source)
+	assert.True(t, resource.Status.Synced)
+
+	// helm values only hold a url, which cannot refer to a directory of the remote repository.
+	app = &argov1alpha1.Application{
+		Spec: argov1alpha1.ApplicationSpec{
+			Source: &argov1alpha1.ApplicationSource{
+				RepoURL: "https://charts.example.com",
+				Chart:   "app",
+				Helm: &argov1alpha1.ApplicationSourceHelm{
+					ValuesObject: &k8sruntime.RawExtension{Raw: []byte(`{"repoURL": "cnoe://backstage"}`)},
+				},
+			},
+		},
+	}
+	_, err = r.reconcileArgoCDApp(ctx, resource, app)
+	assert.ErrorContains(t, err, "remove replicate=false")
+
+	// Argo CD does not get the credentials used to clone the remote repository.
+	t.Setenv(util.GitTokenEnv, "token")
+	r.Config.PackageAuth.URL = "https://github.com/cnoe-io"
+	resource.Spec.ArgoCD.ApplicationFile = "app.yaml"
+	_, err = r.getArgoCDAppFile(ctx, resource)
+	assert.ErrorContains(t, err, "requires credentials")
+}
@@ -0,0 +1,176 @@
+package custompackage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// commitSHA matches full git commit hashes, which are pinned with spec.ref.commit of Flux GitRepositories.
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// reconcileFluxObjects creates the Flux objects of a package. Unlike Argo CD applications, a Flux package is made of
+// several objects, so all objects in the file are created. GitRepositories with a cnoe:// url are pointed to the
+// repository created in the git server, or to the remote repository when it is not replicated.
+func (r *Reconciler) reconcileFluxObjects(ctx context.Context, resource *v1alpha1.CustomPackage, objs []client.Object) (ctrl.Result, error) {
+	notSyncedRepos := 0
+	repoRefs := make([]v1alpha1.ObjectRef, 0, 1)
+	// directories of the remote repository that GitRepositories pointed to it refer to, by name.
+	remoteDirs := map[string]string{}
+
+	fluxObjs := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, o := range objs {
+		u, ok := o.(*unstructured.Unstructured)
+		if !ok || !gitops.IsPackageKind(u.GroupVersionKind()) {
+			return ctrl.Result{}, fmt.Errorf("object %s is not a supported flux kind %s", o.GetName(), resource.Spec.ArgoCD.ApplicationFile)
+		}
+		if u.GetNamespace() == "" {
+			u.SetNamespace(globals.FluxNamespace)
+		}
+		fluxObjs = append(fluxObjs, u)
+	}
+
+	for _, u := range fluxObjs {
+		if u.GetKind() != gitops.FluxGitRepositoryKind {
+			continue
+		}
+		url, _, _ := unstructured.NestedString(u.Object, "spec", "url")
+		if !IsCNOEScheme(url) {
+			continue
+		}
+		if referencesRemote(resource) {
+			remoteDirs[u.GetName()] = remotePath(resource, url)
+			if err := setFluxRemoteRef(resource, u); err != nil {
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+
+		res, repo, err := r.reconcileArgoCDSource(ctx, resource, url, resource.Spec.ArgoCD.Name)
+		if err != nil {
+			return res, fmt.Errorf("reconciling git repository %s, %s: %w", u.GetName(), resource.Spec.ArgoCD.ApplicationFile, err)
+		}
+		if repo.Status.InternalGitRepositoryUrl == "" {
+			notSyncedRepos += 1
+		}
+		if err = unstructured.SetNestedField(u.Object, repo.Status.InternalGitRepositoryUrl, "spec", "url"); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err = unstructured.SetNestedStringMap(u.Object, map[string]string{"branch": gitops.DefaultBranch}, "spec", "ref"); err != nil {
+			return ctrl.Result{}, err
+		}
+		repoRefs = append(repoRefs, v1alpha1.ObjectRef{
+			Namespace: repo.Namespace,
+			Name:      repo.Name,
+			UID:       string(repo.ObjectMeta.UID),
+		})
+	}
+
+	for _, u := range fluxObjs {
+		switch u.GetKind() {
+		case gitops.FluxKustomizationKind:
+			if err := setFluxRemotePath(u, remoteDirs, "spec", "path"); err != nil {
+				return ctrl.Result{}, err
+			}
+		case gitops.FluxHelmReleaseKind:
+			if err := setFluxRemotePath(u, remoteDirs, "spec", "chart", "spec", "chart"); err != nil {
+				return ctrl.Result{}, err
+			}
+			if values, ok, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "values"); ok {
+				res, err := r.reconcileHelmValueObjectSource(ctx, &values, resource, resource.Spec.ArgoCD.Name)
+				if err != nil {
+					return res, err
+				}
+				if err = unstructured.SetNestedField(u.Object, values, "spec", "values"); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+	}
+
+	for _, u := range fluxObjs {
+		util.SetPackageLabels(u)
+		// the objects of a package are labeled with the same name, so they are removed together.
+		labels := u.GetLabels()
+		labels[v1alpha1.PackageNameLabelKey] = resource.Spec.ArgoCD.Name
+		u.SetLabels(labels)
+		if err := r.createOrUpdateFluxObject(ctx, u); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	resource.Status.GitRepositoryRefs = repoRefs
+	resource.Status.Synced = notSyncedRepos == 0
+
+	// Only requeue if not synced yet to avoid continuous reconciliation
+	if !resource.Status.Synced {
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) createOrUpdateFluxObject(ctx context.Context, u *unstructured.Unstructured) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(u.GroupVersionKind())
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(u), found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if err = r.Client.Create(ctx, u); err != nil {
+				return fmt.Errorf("creating flux %s %s: %w", u.GetKind(), u.GetName(), err)
+			}
+			return nil
+		}
+		return fmt.Errorf("getting flux %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+
+	u.SetResourceVersion(found.GetResourceVersion())
+	if err = r.Client.Update(ctx, u); err != nil {
+		return fmt.Errorf("updating flux %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return nil
+}
+
+// setFluxRemoteRef points the GitRepository u to the remote repository at the pinned ref. Refs starting with refs/,
+// e.g. refs/tags/v1.0.0, and commit hashes are pinned as is, other refs are branches.
+func setFluxRemoteRef(resource *v1alpha1.CustomPackage, u *unstructured.Unstructured) error {
+	if err := unstructured.SetNestedField(u.Object, resource.Spec.RemoteRepository.Url, "spec", "url"); err != nil {
+		return err
+	}
+	ref := resource.Spec.RemoteRepository.Ref
+	if ref == "" {
+		return nil
+	}
+	key := "branch"
+	switch {
+	case strings.HasPrefix(ref, "refs/"):
+		key = "name"
+	case commitSHA.MatchString(ref):
+		key = "commit"
+	}
+	return unstructured.SetNestedStringMap(u.Object, map[string]string{key: ref}, "spec", "ref")
+}
+
+// setFluxRemotePath prefixes the path at fields of u with the directory of the remote repository, when the
+// GitRepository u refers to is pointed to the remote repository.
+func setFluxRemotePath(u *unstructured.Unstructured, remoteDirs map[string]string, fields ...string) error {
+	refFields := append(fields[:len(fields)-1:len(fields)-1], "sourceRef")
+	kind, _, _ := unstructured.NestedString(u.Object, append(refFields, "kind")...)
+	name, _, _ := unstructured.NestedString(u.Object, append(refFields, "name")...)
+	dir, ok := remoteDirs[name]
+	if kind != gitops.FluxGitRepositoryKind || !ok {
+		return nil
+	}
+	p, _, _ := unstructured.NestedString(u.Object, fields...)
+	return unstructured.SetNestedField(u.Object, filepath.Join(dir, p), fields...)
+}
@@ -0,0 +1,163 @@
+package custompackage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileFluxObjects(t *testing.T) {
+	ctx := context.Background()
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	appFile := filepath.Join(cwd, "test/resources/customPackages/flux/app.yaml")
+	b, err := os.ReadFile(appFile)
+	require.NoError(t, err)
+
+	get := func(t *testing.T, c client.Client, gvk schema.GroupVersionKind, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: globals.FluxNamespace, Name: name}, u))
+		return u
+	}
+
+	t.Run("local", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithStatusSubresource(&v1alpha1.GitRepository{}).Build()
+		r := &Reconciler{Client: c, Scheme: k8s.GetScheme()}
+		resource := &v1alpha1.CustomPackage{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-podinfo", Namespace: "test", UID: "abc"},
+			Spec: v1alpha1.CustomPackageSpec{
+				Replicate:           true,
+				GitServerURL:        "https://cnoe.io",
+				InternalGitServeURL: "http://internal.cnoe.io",
+				ArgoCD: v1alpha1.ArgoCDPackageSpec{
+					ApplicationFile: appFile,
+					Name:            "podinfo",
+					Type:            gitops.FluxGitRepositoryKind,
+				},
+			},
+		}
+
+		objs, err := k8s.ConvertYamlToObjects(k8s.GetScheme(), b)
+		require.NoError(t, err)
+		_, err = r.reconcileFluxObjects(ctx, resource, objs)
+		require.NoError(t, err)
+
+		repos := v1alpha1.GitRepositoryList{}
+		require.NoError(t, c.List(ctx, &repos))
+		require.Len(t, repos.Items, 1)
+		assert.Equal(t, "podinfo-podinfo", repos.Items[0].Name)
+		assert.Equal(t, filepath.Join(cwd, "test/resources/customPackages/flux/podinfo"), repos.Items[0].Spec.Source.Path)
+		// the repository URL is set once the GitRepository controller created the repository.
+		assert.False(t, resource.Status.Synced)
+		assert.Len(t, resource.Status.GitRepositoryRefs, 1)
+
+		repo := &repos.Items[0]
+		repo.Status.InternalGitRepositoryUrl = "http://internal.cnoe.io/giteaAdmin/podinfo-podinfo.git"
+		require.NoError(t, c.Status().Update(ctx, repo))
+
+		objs, err = k8s.ConvertYamlToObjects(k8s.GetScheme(), b)
+		require.NoError(t, err)
+		_, err = r.reconcileFluxObjects(ctx, resource, objs)
+		require.NoError(t, err)
+		assert.True(t, resource.Status.Synced)
+
+		gitRepo := get(t, c, gitops.FluxGitRepositoryGVK, "podinfo")
+		url, _, _ := unstructured.NestedString(gitRepo.Object, "spec", "url")
+		assert.Equal(t, repo.Status.InternalGitRepositoryUrl, url)
+		ref, _, _ := unstructured.NestedStringMap(gitRepo.Object, "spec", "ref")
+		assert.Equal(t, map[string]string{"branch": "main"}, ref)
+		assert.Equal(t, "podinfo", gitRepo.GetLabels()[v1alpha1.PackageNameLabelKey])
+
+		kustomization := get(t, c, gitops.FluxKustomizationGVK, "podinfo")
+		path, _, _ := unstructured.NestedString(kustomization.Object, "spec", "path")
+		assert.Equal(t, "./", path)
+
+		helmRelease := get(t, c, gitops.FluxHelmReleaseGVK, "podinfo-chart")
+		values, _, _ := unstructured.NestedString(helmRelease.Object, "spec", "values", "repoURL")
+		assert.Equal(t, repo.Status.InternalGitRepositoryUrl, values)
+		assert.Equal(t, "podinfo", helmRelease.GetLabels()[v1alpha1.PackageNameLabelKey])
+	})
+
+	t.Run("remote without replication", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build()
+		r := &Reconciler{Client: c, Scheme: k8s.GetScheme()}
+		resource := &v1alpha1.CustomPackage{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-podinfo", Namespace: "test"},
+			Spec: v1alpha1.CustomPackageSpec{
+				RemoteRepository: v1alpha1.RemoteRepositorySpec{
+					Url:  "https://github.com/cnoe-io/stacks",
+					Ref:  "refs/tags/v0.1.0",
+					Path: "flux",
+				},
+				ArgoCD: v1alpha1.ArgoCDPackageSpec{Name: "podinfo", Type: gitops.FluxGitRepositoryKind},
+			},
+		}
+
+		// cnoe:// urls in helm values cannot refer to a directory of the remote repository.
+		objs, err := k8s.ConvertYamlToObjects(k8s.GetScheme(), b)
+		require.NoError(t, err)
+		_, err = r.reconcileFluxObjects(ctx, resource, objs)
+		assert.ErrorContains(t, err, "cnoe://podinfo in helm values requires replicating the remote repository")
+
+		objs, err = k8s.ConvertYamlToObjects(k8s.GetScheme(), b)
+		require.NoError(t, err)
+		for _, o := range objs {
+			if o.GetObjectKind().GroupVersionKind().Kind == gitops.FluxHelmReleaseKind {
+				unstructured.RemoveNestedField(o.(*unstructured.Unstructured).Object, "spec", "values")
+			}
+		}
+		_, err = r.reconcileFluxObjects(ctx, resource, objs)
+		require.NoError(t, err)
+		assert.True(t, resource.Status.Synced)
+		assert.Empty(t, resource.Status.GitRepositoryRefs)
+
+		repos := v1alpha1.GitRepositoryList{}
+		require.NoError(t, c.List(ctx, &repos))
+		assert.Empty(t, repos.Items)
+
+		gitRepo := get(t, c, gitops.FluxGitRepositoryGVK, "podinfo")
+		url, _, _ := unstructured.NestedString(gitRepo.Object, "spec", "url")
+		assert.Equal(t, "https://github.com/cnoe-io/stacks", url)
+		ref, _, _ := unstructured.NestedStringMap(gitRepo.Object, "spec", "ref")
+		assert.Equal(t, map[string]string{"name": "refs/tags/v0.1.0"}, ref)
+
+		kustomization := get(t, c, gitops.FluxKustomizationGVK, "podinfo")
+		path, _, _ := unstructured.NestedString(kustomization.Object, "spec", "path")
+		assert.Equal(t, "flux/podinfo", path)
+
+		helmRelease := get(t, c, gitops.FluxHelmReleaseGVK, "podinfo-chart")
+		chart, _, _ := unstructured.NestedString(helmRelease.Object, "spec", "chart", "spec", "chart")
+		assert.Equal(t, "flux/podinfo/chart", chart)
+	})
+}
+
+func TestSetFluxRemoteRef(t *testing.T) {
+	for ref, want := range map[string]map[string]string{
+		"":                 nil,
+		"main":             {"branch": "main"},
+		"refs/tags/v1.0.0": {"name": "refs/tags/v1.0.0"},
+		"0123456789abcdef0123456789abcdef01234567": {"commit": "0123456789abcdef0123456789abcdef01234567"},
+	} {
+		u := &unstructured.Unstructured{Object: map[string]any{"spec": map[string]any{"url": "cnoe://podinfo"}}}
+		resource := &v1alpha1.CustomPackage{Spec: v1alpha1.CustomPackageSpec{
+			RemoteRepository: v1alpha1.RemoteRepositorySpec{Url: "https://github.com/cnoe-io/stacks", Ref: ref},
+		}}
+		require.NoError(t, setFluxRemoteRef(resource, u))
+		got, _, _ := unstructured.NestedStringMap(u.Object, "spec", "ref")
+		assert.Equal(t, want, got, ref)
+	}
+}
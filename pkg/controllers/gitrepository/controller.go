@@ -2,10 +2,12 @@ package gitrepository
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"code.gitea.io/sdk/gitea"
@@ -16,12 +18,16 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 const (
@@ -47,6 +53,8 @@ type RepositoryReconciler struct {
 	GitProviderFunc gitProviderFunc
 	TempDir         string
 	RepoMap         *util.RepoMap
+	// Namespace limits reconciliation to GitRepositories in this namespace. All namespaces are reconciled if it is empty.
+	Namespace string
 }
 
 type gitProviderFunc func(context.Context, *v1alpha1.GitRepository, client.Client, *runtime.Scheme, v1alpha1.BuildCustomizationSpec) (gitProvider, error)
@@ -69,10 +77,21 @@ func getFallbackRepositoryURL(repo *v1alpha1.GitRepository, info repoInfo) strin
 	return fmt.Sprintf("%s/%s.git", repo.Spec.Provider.GitURL, info.fullName)
 }
 
+// isInClusterGitea reports whether repo is pushed to the Gitea installed by idpbuilder. It serves the self-signed
+// certificate of the build, so TLS is only verified for other git servers, which receive their access tokens.
+func isInClusterGitea(repo *v1alpha1.GitRepository, tmplConfig v1alpha1.BuildCustomizationSpec) bool {
+	return repo.Spec.Provider.Name == v1alpha1.GitProviderGitea &&
+		strings.TrimSuffix(repo.Spec.Provider.GitURL, "/") == util.GiteaBaseUrl(tmplConfig)
+}
+
 func GetGitProvider(ctx context.Context, repo *v1alpha1.GitRepository, kubeClient client.Client, scheme *runtime.Scheme, tmplConfig v1alpha1.BuildCustomizationSpec) (gitProvider, error) {
 	switch repo.Spec.Provider.Name {
 	case v1alpha1.GitProviderGitea:
-		giteaClient, err := NewGiteaClient(repo.Spec.Provider.GitURL, gitea.SetHTTPClient(util.GetHttpClient()))
+		httpClient := util.GetRemoteHttpClient()
+		if isInClusterGitea(repo, tmplConfig) {
+			httpClient = util.GetHttpClient()
+		}
+		giteaClient, err := NewGiteaClient(repo.Spec.Provider.GitURL, gitea.SetHTTPClient(httpClient))
 		if err != nil {
 			return nil, err
 		}
@@ -83,11 +102,22 @@ func GetGitProvider(ctx context.Context, repo *v1alpha1.GitRepository, kubeClien
 			config:      tmplConfig,
 		}, nil
 	case v1alpha1.GitProviderGitHub:
+		gitHubClient, err := newGitHubClient(repo.Spec.Provider.GitURL, util.GetRemoteHttpClient())
+		if err != nil {
+			return nil, err
+		}
 		return &gitHubProvider{
 			Client:       kubeClient,
 			Scheme:       scheme,
 			config:       tmplConfig,
-			gitHubClient: newGitHubClient(nil),
+			gitHubClient: gitHubClient,
+		}, nil
+	case v1alpha1.GitProviderGitLab:
+		return &gitLabProvider{
+			Client:       kubeClient,
+			Scheme:       scheme,
+			config:       tmplConfig,
+			gitLabClient: newGitLabClient(repo.Spec.Provider.GitURL, util.GetRemoteHttpClient()),
 		}, nil
 	}
 	return nil, fmt.Errorf("invalid git provider %s ", repo.Spec.Provider.Name)
@@ -111,10 +141,31 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	} else {
 		r.Recorder.Event(&gitRepo, "Normal", "reconcile success", "Successfully reconciled")
 	}
+	setConditions(&gitRepo, err)
 
 	return result, err
 }
 
+// setConditions sets the RepositoryPushed and Ready conditions from the result of a reconcile.
+func setConditions(repo *v1alpha1.GitRepository, reconcileErr error) {
+	cond := metav1.Condition{
+		Type:               v1alpha1.ConditionRepositoryPushed,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: repo.Generation,
+		Reason:             v1alpha1.ReasonPushed,
+		Message:            fmt.Sprintf("contents pushed to %s", repo.Status.ExternalGitRepositoryUrl),
+	}
+	if reconcileErr != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = v1alpha1.ReasonPushFailed
+		cond.Message = reconcileErr.Error()
+	}
+	meta.SetStatusCondition(&repo.Status.Conditions, cond)
+
+	cond.Type = v1alpha1.ConditionReady
+	meta.SetStatusCondition(&repo.Status.Conditions, cond)
+}
+
 func (r *RepositoryReconciler) postProcessReconcile(ctx context.Context, req ctrl.Request, repo *v1alpha1.GitRepository) {
 	logger := log.FromContext(ctx)
 	err := r.Status().Update(ctx, repo)
@@ -143,7 +194,8 @@ func (r *RepositoryReconciler) reconcileGitRepo(ctx context.Context, repo *v1alp
 		return ctrl.Result{}, fmt.Errorf("getting git provider credentials: %w", err)
 	}
 
-	if r.Config.StaticPassword {
+	// the static password is the password of the in-cluster Gitea admin.
+	if r.Config.StaticPassword && repo.Spec.Provider.Name == v1alpha1.GitProviderGitea {
 		creds.password = util.StaticPassword
 	}
 
@@ -184,8 +236,12 @@ func (r *RepositoryReconciler) reconcileGitRepo(ctx context.Context, repo *v1alp
 
 func (r *RepositoryReconciler) SetupWithManager(mgr ctrl.Manager, notifyChan chan event.GenericEvent) error {
 	// TODO: should use notifyChan to trigger reconcile when FS changes
+	// extra CA certificates are only known after flags are parsed.
+	configureGitClient()
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.GitRepository{}).
+		For(&v1alpha1.GitRepository{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return r.Namespace == "" || o.GetNamespace() == r.Namespace
+		}))).
 		Complete(r)
 }
 
@@ -222,20 +278,21 @@ func addAllAndCommit(path string, gitRepo *git.Repository) (plumbing.Hash, bool,
 	return h, true, nil
 }
 
-func pushToRemote(ctx context.Context, remoteRepo *git.Repository, creds gitProviderCredentials) error {
+func pushToRemote(ctx context.Context, remoteRepo *git.Repository, creds gitProviderCredentials, insecureSkipTLS bool) error {
 	auth, err := getBasicAuth(creds)
 	if err != nil {
 		return fmt.Errorf("getting basic auth: %w", err)
 	}
 	return remoteRepo.PushContext(ctx, &git.PushOptions{
 		Auth:            &auth,
-		InsecureSkipTLS: true,
+		InsecureSkipTLS: insecureSkipTLS,
 	})
 }
 
 // add files from local fs to target repository (gitea for now)
 func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, tgtRepo repoInfo, creds gitProviderCredentials, scheme *runtime.Scheme, tmplConfig v1alpha1.BuildCustomizationSpec, tmpDir string, repoMap *util.RepoMap) error {
 	logger := log.FromContext(ctx)
+	insecureSkipTLS := isInClusterGitea(repo, tmplConfig)
 	tgtCloneDir := util.RepoDir(tgtRepo.cloneUrl, tmpDir)
 
 	st := repoMap.LoadOrStore(tgtRepo.cloneUrl, tgtCloneDir)
@@ -249,7 +306,11 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 		Ref:             "",
 	}
 	logger.V(1).Info("cloning repo", "repoUrl", tgtRepoSpec.Url, "fallbackUrl", getFallbackRepositoryURL(repo, tgtRepo), "cloneDir", tgtCloneDir)
-	_, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
+	auth, err := getBasicAuth(creds)
+	if err != nil {
+		return fmt.Errorf("getting basic auth: %w", err)
+	}
+	_, tgtRepository, err := util.CloneRemoteRepoToDirWithAuth(ctx, tgtRepoSpec, 1, insecureSkipTLS, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo), &auth)
 	if err != nil {
 		return fmt.Errorf("cloning repo %s: %w", tgtRepoSpec.Url, err)
 	}
@@ -271,7 +332,7 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 		}
 
 		logger.V(1).Info("pushing to remote url", "remoteUrl", remoteUrl)
-		err = pushToRemote(ctx, tgtRepository, creds)
+		err = pushToRemote(ctx, tgtRepository, creds, insecureSkipTLS)
 		if err != nil {
 			return fmt.Errorf("pushing to git: %w", err)
 		}
@@ -285,8 +346,9 @@ func reconcileLocalRepoContent(ctx context.Context, repo *v1alpha1.GitRepository
 }
 
 // add files from another repository at specified path to target repository (gitea for now)
-func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepository, tgtRepo repoInfo, creds gitProviderCredentials, tmpDir string, repoMap *util.RepoMap) error {
+func reconcileRemoteRepoContent(ctx context.Context, kubeClient client.Client, repo *v1alpha1.GitRepository, tgtRepo repoInfo, creds gitProviderCredentials, tmplConfig v1alpha1.BuildCustomizationSpec, tmpDir string, repoMap *util.RepoMap) error {
 	logger := log.FromContext(ctx)
+	insecureSkipTLS := isInClusterGitea(repo, tmplConfig)
 	srcRepo := repo.Spec.Source.RemoteRepository
 	cloneDir := util.RepoDir(srcRepo.Url, tmpDir)
 
@@ -295,7 +357,11 @@ func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepositor
 	defer st.MU.Unlock()
 
 	logger.V(1).Info("cloning repo", "repoUrl", srcRepo.Url, "fallbackUrl", "", "cloneDir", cloneDir)
-	remoteWT, _, err := util.CloneRemoteRepoToDir(ctx, srcRepo, 1, false, cloneDir, "")
+	srcAuth, err := util.RemoteRepoAuth(ctx, kubeClient, tmplConfig.PackageAuth, srcRepo.Url)
+	if err != nil {
+		return fmt.Errorf("getting credentials of repo, %s: %w", srcRepo.Url, err)
+	}
+	remoteWT, _, err := util.CloneRemoteRepoToDirWithAuth(ctx, srcRepo, 1, false, cloneDir, "", srcAuth)
 	if err != nil {
 		return fmt.Errorf("cloning repo, %s: %w", srcRepo.Url, err)
 	}
@@ -314,7 +380,11 @@ func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepositor
 	defer lst.MU.Unlock()
 
 	logger.V(1).Info("cloning repo", "repoUrl", tgtRepoSpec.Url, "fallbackUrl", getFallbackRepositoryURL(repo, tgtRepo), "cloneDir", tgtCloneDir)
-	tgtRepoWT, tgtRepository, err := util.CloneRemoteRepoToDir(ctx, tgtRepoSpec, 1, true, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo))
+	auth, err := getBasicAuth(creds)
+	if err != nil {
+		return fmt.Errorf("getting basic auth: %w", err)
+	}
+	tgtRepoWT, tgtRepository, err := util.CloneRemoteRepoToDirWithAuth(ctx, tgtRepoSpec, 1, insecureSkipTLS, tgtCloneDir, getFallbackRepositoryURL(repo, tgtRepo), &auth)
 	if err != nil {
 		return fmt.Errorf("cloning repo %s: %w", srcRepo.Url, err)
 	}
@@ -336,7 +406,7 @@ func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepositor
 		}
 
 		logger.V(1).Info("pushing to remote url", "remoteUrl", remoteUrl)
-		err = pushToRemote(ctx, tgtRepository, creds)
+		err = pushToRemote(ctx, tgtRepository, creds, insecureSkipTLS)
 		if err != nil {
 			return fmt.Errorf("pushing to git: %w", err)
 		}
@@ -351,6 +421,7 @@ func reconcileRemoteRepoContent(ctx context.Context, repo *v1alpha1.GitRepositor
 
 func configureGitClient() {
 	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{RootCAs: util.GetCertPool()}
 
 	tr.DialContext = (&net.Dialer{
 		Timeout:   gitTCPTimeout,
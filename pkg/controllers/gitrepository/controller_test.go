@@ -11,6 +11,8 @@ import (
 
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -463,3 +465,37 @@ func TestGitRepositoryPostReconcile(t *testing.T) {
 		t.Fatalf("annotation values does not match")
 	}
 }
+
+func TestSetConditions(t *testing.T) {
+	repo := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Generation: 2},
+		Status:     v1alpha1.GitRepositoryStatus{ExternalGitRepositoryUrl: "https://cnoe.io/test.git"},
+	}
+
+	setConditions(&repo, fmt.Errorf("updating repository contents: push failed"))
+	for _, condType := range []string{v1alpha1.ConditionRepositoryPushed, v1alpha1.ConditionReady} {
+		c := meta.FindStatusCondition(repo.Status.Conditions, condType)
+		require.NotNil(t, c, condType)
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, v1alpha1.ReasonPushFailed, c.Reason)
+		assert.Equal(t, "updating repository contents: push failed", c.Message)
+		assert.Equal(t, int64(2), c.ObservedGeneration)
+	}
+
+	setConditions(&repo, nil)
+	assert.True(t, meta.IsStatusConditionTrue(repo.Status.Conditions, v1alpha1.ConditionRepositoryPushed))
+	assert.True(t, meta.IsStatusConditionTrue(repo.Status.Conditions, v1alpha1.ConditionReady))
+	assert.Equal(t, "contents pushed to https://cnoe.io/test.git", meta.FindStatusCondition(repo.Status.Conditions, v1alpha1.ConditionReady).Message)
+}
+
+func TestIsInClusterGitea(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{Protocol: "https", Host: "cnoe.localtest.me", Port: "8443"}
+	repo := func(name, url string) *v1alpha1.GitRepository {
+		return &v1alpha1.GitRepository{Spec: v1alpha1.GitRepositorySpec{Provider: v1alpha1.Provider{Name: name, GitURL: url}}}
+	}
+
+	assert.True(t, isInClusterGitea(repo(v1alpha1.GitProviderGitea, "https://gitea.cnoe.localtest.me:8443"), cfg))
+	// external git servers receive access tokens, so their certificates are verified.
+	assert.False(t, isInClusterGitea(repo(v1alpha1.GitProviderGitea, "https://gitea.example.com"), cfg))
+	assert.False(t, isInClusterGitea(repo(v1alpha1.GitProviderGitHub, "https://github.com"), cfg))
+}
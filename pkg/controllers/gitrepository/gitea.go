@@ -21,6 +21,7 @@ import (
 const (
 	giteaAdminUsernameKey = "username"
 	giteaAdminPasswordKey = "password"
+	giteaTokenKey         = v1alpha1.GitServerTokenKey
 )
 
 type GiteaClientFunc func(url string, options ...gitea.ClientOption) (GiteaClient, error)
@@ -33,14 +34,21 @@ type giteaProvider struct {
 }
 
 func (g *giteaProvider) createRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
-	resp, _, err := g.giteaClient.CreateRepo(gitea.CreateRepoOption{
-		Name:        getRepositoryName(*repo),
-		Description: fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace),
-		// we should reconsider this when targeting non-local clusters.
-		Private:       false,
+	opt := gitea.CreateRepoOption{
+		Name:          getRepositoryName(*repo),
+		Description:   fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace),
+		Private:       g.config.GiteaPrivateRepositories,
 		DefaultBranch: DefaultBranchName,
 		AutoInit:      true,
-	})
+	}
+	var resp *gitea.Repository
+	var err error
+	// repositories of the in-cluster Gitea belong to the admin user. an external Gitea creates them in an organization.
+	if org := giteaOrganizationName(*repo); org == v1alpha1.GiteaAdminUserName {
+		resp, _, err = g.giteaClient.CreateRepo(opt)
+	} else {
+		resp, _, err = g.giteaClient.CreateOrgRepo(org, opt)
+	}
 
 	if err != nil {
 		return repoInfo{}, fmt.Errorf("failed to create git repository: %w", err)
@@ -64,6 +72,12 @@ func (g *giteaProvider) getProviderCredentials(ctx context.Context, repo *v1alph
 
 	username, ok := secret.Data[giteaAdminUsernameKey]
 	if !ok {
+		// an external Gitea is accessed with a token instead of the admin user of the in-cluster Gitea.
+		if token, ok := secret.Data[giteaTokenKey]; ok {
+			return gitProviderCredentials{
+				accessToken: string(token),
+			}, nil
+		}
 		return gitProviderCredentials{}, fmt.Errorf("%s key not found in secret %s in %s ns", giteaAdminUsernameKey, repo.Spec.SecretRef.Name, repo.Spec.SecretRef.Namespace)
 	}
 	password, ok := secret.Data[giteaAdminPasswordKey]
@@ -78,7 +92,11 @@ func (g *giteaProvider) getProviderCredentials(ctx context.Context, repo *v1alph
 }
 
 func (g *giteaProvider) setProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository, creds gitProviderCredentials) error {
-	g.giteaClient.SetBasicAuth(creds.username, creds.password)
+	auth, err := getBasicAuth(creds)
+	if err != nil {
+		return err
+	}
+	g.giteaClient.SetBasicAuth(auth.Username, auth.Password)
 	g.giteaClient.SetContext(ctx)
 	return nil
 }
@@ -96,7 +114,7 @@ func (g *giteaProvider) getRepository(ctx context.Context, repo *v1alpha1.GitRep
 		name:                     resp.Name,
 		fullName:                 resp.FullName,
 		cloneUrl:                 resp.CloneURL,
-		internalGitRepositoryUrl: getInternalGiteaRepositoryURL(repo.Namespace, repo.Name, repo.Spec.Provider.InternalGitURL),
+		internalGitRepositoryUrl: getInternalGiteaRepositoryURL(*repo),
 	}, nil
 }
 
@@ -112,7 +130,7 @@ func (g *giteaProvider) updateRepoContent(
 	case v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded:
 		return reconcileLocalRepoContent(ctx, repo, repoInfo, creds, g.Scheme, g.config, tmpDir, repoMap)
 	case v1alpha1.SourceTypeRemote:
-		return reconcileRemoteRepoContent(ctx, repo, repoInfo, creds, tmpDir, repoMap)
+		return reconcileRemoteRepoContent(ctx, g.Client, repo, repoInfo, creds, g.config, tmpDir, repoMap)
 	default:
 		return nil
 	}
@@ -122,7 +140,12 @@ func writeRepoContents(repo *v1alpha1.GitRepository, dstPath string, config v1al
 	if repo.Spec.Source.EmbeddedAppName != "" {
 		resources, err := localbuild.GetEmbeddedRawInstallResources(
 			repo.Spec.Source.EmbeddedAppName, config,
-			v1alpha1.PackageCustomization{Name: repo.Spec.Customization.Name, FilePath: repo.Spec.Customization.FilePath}, scheme)
+			v1alpha1.PackageCustomization{
+				Name:          repo.Spec.Customization.Name,
+				FilePath:      repo.Spec.Customization.FilePath,
+				Version:       repo.Spec.Customization.Version,
+				ManifestsPath: repo.Spec.Customization.ManifestsPath,
+			}, scheme)
 		if err != nil {
 			return fmt.Errorf("getting embedded resource; %w", err)
 		}
@@ -151,6 +174,9 @@ func getBasicAuth(creds gitProviderCredentials) (githttp.BasicAuth, error) {
 	}
 	if creds.password == "" {
 		b.Password = creds.accessToken
+		if b.Username == "" {
+			b.Username = util.GitTokenUsername
+		}
 	}
 	return b, nil
 }
@@ -159,6 +185,34 @@ func NewGiteaClient(url string, options ...gitea.ClientOption) (GiteaClient, err
 	return gitea.NewClient(url, options...)
 }
 
-func getInternalGiteaRepositoryURL(namespace, name, baseUrl string) string {
-	return fmt.Sprintf("%s/%s/%s-%s.git", baseUrl, v1alpha1.GiteaAdminUserName, namespace, name)
+// RepositoryURLs returns the clone URL and the in-cluster URL set in the status of repo once it is created.
+func RepositoryURLs(repo v1alpha1.GitRepository) (string, string) {
+	switch repo.Spec.Provider.Name {
+	case v1alpha1.GitProviderGitHub:
+		u := gitHubRepositoryURL(repo)
+		return u, u
+	case v1alpha1.GitProviderGitLab:
+		u := gitLabRepositoryURL(repo)
+		return u, u
+	default:
+		return GiteaRepositoryURLs(repo)
+	}
+}
+
+// GiteaRepositoryURLs returns the clone URL and the in-cluster URL set in the status of a repository created in Gitea.
+func GiteaRepositoryURLs(repo v1alpha1.GitRepository) (string, string) {
+	cloneUrl := fmt.Sprintf("%s/%s/%s.git", repo.Spec.Provider.GitURL, giteaOrganizationName(repo), getRepositoryName(repo))
+	return cloneUrl, getInternalGiteaRepositoryURL(repo)
+}
+
+func getInternalGiteaRepositoryURL(repo v1alpha1.GitRepository) string {
+	return fmt.Sprintf("%s/%s/%s.git", repo.Spec.Provider.InternalGitURL, giteaOrganizationName(repo), getRepositoryName(repo))
+}
+
+// giteaOrganizationName returns the owner of repositories in Gitea. It defaults to the admin user of the in-cluster Gitea.
+func giteaOrganizationName(repo v1alpha1.GitRepository) string {
+	if org := getOrganizationName(repo); org != "" {
+		return org
+	}
+	return v1alpha1.GiteaAdminUserName
 }
@@ -0,0 +1,163 @@
+package gitrepository
+
+import (
+	"context"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type orgGitea struct {
+	mockGitea
+	org      string
+	opt      gitea.CreateRepoOption
+	username string
+	password string
+}
+
+func (g *orgGitea) CreateOrgRepo(org string, opt gitea.CreateRepoOption) (*gitea.Repository, *gitea.Response, error) {
+	g.org = org
+	g.opt = opt
+	return &gitea.Repository{Name: opt.Name, FullName: org + "/" + opt.Name}, &gitea.Response{}, nil
+}
+
+func (g *orgGitea) SetBasicAuth(username, password string) {
+	g.username = username
+	g.password = password
+}
+
+func TestGiteaExternalOrganization(t *testing.T) {
+	ctx := context.Background()
+	giteaClient := &orgGitea{}
+	g := giteaProvider{
+		Client:      &fakeClient{},
+		giteaClient: giteaClient,
+		config:      v1alpha1.BuildCustomizationSpec{GiteaPrivateRepositories: true},
+	}
+	resource := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.GitRepositorySpec{
+			Provider: v1alpha1.Provider{
+				Name:             v1alpha1.GitProviderGitea,
+				GitURL:           "https://gitea.example.com",
+				InternalGitURL:   "https://gitea.example.com",
+				OrganizationName: "team",
+			},
+		},
+	}
+
+	require.NoError(t, g.setProviderCredentials(ctx, &resource, gitProviderCredentials{accessToken: "token"}))
+	assert.Equal(t, util.GitTokenUsername, giteaClient.username)
+	assert.Equal(t, "token", giteaClient.password)
+
+	info, err := g.createRepository(ctx, &resource)
+	require.NoError(t, err)
+	assert.Equal(t, "team", giteaClient.org)
+	assert.True(t, giteaClient.opt.Private)
+	assert.Equal(t, "team/test-test", info.fullName)
+
+	external, internal := RepositoryURLs(resource)
+	assert.Equal(t, "https://gitea.example.com/team/test-test.git", external)
+	assert.Equal(t, "https://gitea.example.com/team/test-test.git", internal)
+}
+
+func TestGiteaGetProviderCredentialsToken(t *testing.T) {
+	fakeK8sClient := new(fakeKubeClient)
+	ctx := context.Background()
+	g := giteaProvider{
+		Client: fakeK8sClient,
+	}
+
+	resource := v1alpha1.GitRepository{
+		Spec: v1alpha1.GitRepositorySpec{
+			SecretRef: v1alpha1.SecretReference{
+				Name:      "test",
+				Namespace: "testNS",
+			},
+		},
+	}
+	fakeK8sClient.On("Get", ctx, types.NamespacedName{
+		Namespace: "testNS",
+		Name:      "test",
+	}, &v1.Secret{}, []client.GetOption(nil)).Run(func(args mock.Arguments) {
+		sec := args.Get(2).(*v1.Secret)
+		sec.Data = map[string][]byte{giteaTokenKey: []byte("token")}
+	}).Return(nil)
+
+	creds, err := g.getProviderCredentials(ctx, &resource)
+	require.NoError(t, err)
+	assert.Equal(t, gitProviderCredentials{accessToken: "token"}, creds)
+	fakeK8sClient.AssertExpectations(t)
+}
+
+func TestGiteaGetProviderCredentialsAdmin(t *testing.T) {
+	fakeK8sClient := new(fakeKubeClient)
+	ctx := context.Background()
+	g := giteaProvider{
+		Client: fakeK8sClient,
+	}
+
+	resource := v1alpha1.GitRepository{
+		Spec: v1alpha1.GitRepositorySpec{
+			SecretRef: v1alpha1.SecretReference{
+				Name:      "gitea-credential",
+				Namespace: "gitea",
+			},
+		},
+	}
+	// the admin secret of the in-cluster Gitea also contains a token, which is not used for repositories.
+	fakeK8sClient.On("Get", ctx, types.NamespacedName{
+		Namespace: "gitea",
+		Name:      "gitea-credential",
+	}, &v1.Secret{}, []client.GetOption(nil)).Run(func(args mock.Arguments) {
+		sec := args.Get(2).(*v1.Secret)
+		sec.Data = map[string][]byte{
+			giteaAdminUsernameKey: []byte("giteaAdmin"),
+			giteaAdminPasswordKey: []byte("password"),
+			giteaTokenKey:         []byte("token"),
+		}
+	}).Return(nil)
+
+	creds, err := g.getProviderCredentials(ctx, &resource)
+	require.NoError(t, err)
+	assert.Equal(t, gitProviderCredentials{username: "giteaAdmin", password: "password"}, creds)
+}
+
+func TestRepositoryURLs(t *testing.T) {
+	repo := v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd",
+			Namespace: "idpbuilder-localdev",
+		},
+		Spec: v1alpha1.GitRepositorySpec{
+			Provider: v1alpha1.Provider{
+				Name:             v1alpha1.GitProviderGitHub,
+				OrganizationName: "myorg",
+			},
+		},
+	}
+	external, internal := RepositoryURLs(repo)
+	assert.Equal(t, "https://github.com/myorg/idpbuilder-localdev-argocd.git", external)
+	assert.Equal(t, external, internal)
+
+	repo.Spec.Provider = v1alpha1.Provider{
+		Name:           v1alpha1.GitProviderGitea,
+		GitURL:         "https://gitea.cnoe.localtest.me:8443",
+		InternalGitURL: "http://my-gitea-http.gitea.svc.cluster.local:3000",
+	}
+	external, internal = RepositoryURLs(repo)
+	assert.Equal(t, "https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-localdev-argocd.git", external)
+	assert.Equal(t, "http://my-gitea-http.gitea.svc.cluster.local:3000/giteaAdmin/idpbuilder-localdev-argocd.git", internal)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
@@ -15,7 +16,9 @@ import (
 )
 
 const (
-	gitHubTokenKey = "token"
+	gitHubTokenKey = v1alpha1.GitServerTokenKey
+	// gitHubURL is the URL of github.com. Other URLs are treated as GitHub Enterprise Server.
+	gitHubURL = "https://github.com"
 )
 
 type ghClient struct {
@@ -44,20 +47,18 @@ type gitHubProvider struct {
 
 func (g *gitHubProvider) createRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
 	req := github.Repository{
-		Name:    github.String(getRepositoryName(*repo)),
-		Private: github.Bool(true),
+		Name:          github.String(getRepositoryName(*repo)),
+		Description:   github.String(fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace)),
+		Private:       github.Bool(true),
+		AutoInit:      github.Bool(true),
+		DefaultBranch: github.String(DefaultBranchName),
 	}
 	r, _, err := g.gitHubClient.createRepo(ctx, getOrganizationName(*repo), &req)
 	if err != nil {
 		return repoInfo{}, fmt.Errorf("creating repo: %w", err)
 	}
 
-	return repoInfo{
-		name:                     *r.Name,
-		cloneUrl:                 *r.CloneURL,
-		internalGitRepositoryUrl: "",
-		fullName:                 *r.FullName,
-	}, nil
+	return gitHubRepoInfo(r), nil
 }
 
 func (g *gitHubProvider) getRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
@@ -70,12 +71,7 @@ func (g *gitHubProvider) getRepository(ctx context.Context, repo *v1alpha1.GitRe
 		}
 	}
 
-	return repoInfo{
-		name:                     *r.Name,
-		cloneUrl:                 *r.CloneURL,
-		internalGitRepositoryUrl: "",
-		fullName:                 *r.FullName,
-	}, nil
+	return gitHubRepoInfo(r), nil
 }
 
 func (g *gitHubProvider) getProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository) (gitProviderCredentials, error) {
@@ -90,7 +86,7 @@ func (g *gitHubProvider) getProviderCredentials(ctx context.Context, repo *v1alp
 
 	token, ok := secret.Data[gitHubTokenKey]
 	if !ok {
-		return gitProviderCredentials{}, fmt.Errorf("%s key not found in secret %s in %s ns", giteaAdminUsernameKey, repo.Spec.SecretRef.Name, repo.Spec.SecretRef.Namespace)
+		return gitProviderCredentials{}, fmt.Errorf("%s key not found in secret %s in %s ns", gitHubTokenKey, repo.Spec.SecretRef.Name, repo.Spec.SecretRef.Namespace)
 	}
 
 	return gitProviderCredentials{
@@ -110,11 +106,45 @@ func (g *gitHubProvider) updateRepoContent(
 	tmpDir string,
 	repoMap *util.RepoMap,
 ) error {
-	return reconcileLocalRepoContent(ctx, repo, repoInfo, creds, g.Scheme, g.config, tmpDir, repoMap)
+	switch repo.Spec.Source.Type {
+	case v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded:
+		return reconcileLocalRepoContent(ctx, repo, repoInfo, creds, g.Scheme, g.config, tmpDir, repoMap)
+	case v1alpha1.SourceTypeRemote:
+		return reconcileRemoteRepoContent(ctx, g.Client, repo, repoInfo, creds, g.config, tmpDir, repoMap)
+	default:
+		return nil
+	}
 }
 
-func newGitHubClient(httpClient *http.Client) gitHubClient {
-	return &ghClient{
-		c: github.NewClient(httpClient),
+// gitHubRepoInfo returns the info of r. Argo CD pulls from GitHub directly, so the in-cluster URL is the clone URL.
+func gitHubRepoInfo(r *github.Repository) repoInfo {
+	return repoInfo{
+		name:                     r.GetName(),
+		cloneUrl:                 r.GetCloneURL(),
+		internalGitRepositoryUrl: r.GetCloneURL(),
+		fullName:                 r.GetFullName(),
+	}
+}
+
+// gitHubRepositoryURL returns the clone URL of a repository created in GitHub.
+func gitHubRepositoryURL(repo v1alpha1.GitRepository) string {
+	base := strings.TrimSuffix(repo.Spec.Provider.GitURL, "/")
+	if base == "" {
+		base = gitHubURL
+	}
+	return fmt.Sprintf("%s/%s/%s.git", base, getOrganizationName(repo), getRepositoryName(repo))
+}
+
+// newGitHubClient returns a client of github.com, or of GitHub Enterprise Server at baseURL.
+func newGitHubClient(baseURL string, httpClient *http.Client) (gitHubClient, error) {
+	c := github.NewClient(httpClient)
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if baseURL != "" && baseURL != gitHubURL {
+		var err error
+		c, err = c.WithEnterpriseURLs(baseURL, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("configuring github enterprise url %s: %w", baseURL, err)
+		}
 	}
+	return &ghClient{c: c}, nil
 }
@@ -80,8 +80,11 @@ func TestGitHubCreateRepository(t *testing.T) {
 	}
 
 	expectedInput := &github.Repository{
-		Name:    github.String(getRepositoryName(resource)),
-		Private: github.Bool(true),
+		Name:          github.String(getRepositoryName(resource)),
+		Description:   github.String("created by Git Repository controller for test in test namespace"),
+		Private:       github.Bool(true),
+		AutoInit:      github.Bool(true),
+		DefaultBranch: github.String(DefaultBranchName),
 	}
 
 	fakeGH.On("createRepo", ctx, "owner", expectedInput).Return(
@@ -0,0 +1,242 @@
+package gitrepository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	gitLabTokenKey = v1alpha1.GitServerTokenKey
+	gitLabAPIPath  = "/api/v4"
+)
+
+type gitLabProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+}
+
+type gitLabGroup struct {
+	ID       int    `json:"id"`
+	FullPath string `json:"full_path"`
+}
+
+type gitLabCreateProjectOption struct {
+	Name                 string `json:"name"`
+	Path                 string `json:"path"`
+	NamespaceID          int    `json:"namespace_id"`
+	Description          string `json:"description,omitempty"`
+	Visibility           string `json:"visibility"`
+	DefaultBranch        string `json:"default_branch"`
+	InitializeWithReadme bool   `json:"initialize_with_readme"`
+}
+
+// gitLabError is returned for responses of the GitLab API with an error status.
+type gitLabError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *gitLabError) Error() string {
+	return fmt.Sprintf("gitlab api returned %d: %s", e.StatusCode, e.Message)
+}
+
+type gitLabClient interface {
+	getProject(ctx context.Context, path string) (*gitLabProject, error)
+	getGroup(ctx context.Context, path string) (*gitLabGroup, error)
+	createProject(ctx context.Context, opt gitLabCreateProjectOption) (*gitLabProject, error)
+	setToken(token string) error
+}
+
+// glClient calls the REST API of GitLab. See https://docs.gitlab.com/api/rest/
+type glClient struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+func (g *glClient) getProject(ctx context.Context, path string) (*gitLabProject, error) {
+	p := gitLabProject{}
+	if err := g.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(path), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (g *glClient) getGroup(ctx context.Context, path string) (*gitLabGroup, error) {
+	grp := gitLabGroup{}
+	if err := g.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(path), nil, &grp); err != nil {
+		return nil, err
+	}
+	return &grp, nil
+}
+
+func (g *glClient) createProject(ctx context.Context, opt gitLabCreateProjectOption) (*gitLabProject, error) {
+	p := gitLabProject{}
+	if err := g.do(ctx, http.MethodPost, "/projects", opt, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (g *glClient) setToken(token string) error {
+	g.token = token
+	return nil
+}
+
+func (g *glClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+gitLabAPIPath+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &gitLabError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// gitLabProvider creates repositories as projects in the GitLab group given by the organization name. Argo CD pulls
+// from GitLab directly, so the in-cluster URL of a repository is its clone URL.
+type gitLabProvider struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	gitLabClient gitLabClient
+	config       v1alpha1.BuildCustomizationSpec
+}
+
+func (g *gitLabProvider) createRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
+	group, err := g.gitLabClient.getGroup(ctx, getOrganizationName(*repo))
+	if err != nil {
+		return repoInfo{}, fmt.Errorf("getting group %s: %w", getOrganizationName(*repo), err)
+	}
+
+	name := getRepositoryName(*repo)
+	p, err := g.gitLabClient.createProject(ctx, gitLabCreateProjectOption{
+		Name:                 name,
+		Path:                 name,
+		NamespaceID:          group.ID,
+		Description:          fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace),
+		Visibility:           "private",
+		DefaultBranch:        DefaultBranchName,
+		InitializeWithReadme: true,
+	})
+	if err != nil {
+		return repoInfo{}, fmt.Errorf("creating project: %w", err)
+	}
+	return gitLabRepoInfo(p), nil
+}
+
+func (g *gitLabProvider) getRepository(ctx context.Context, repo *v1alpha1.GitRepository) (repoInfo, error) {
+	p, err := g.gitLabClient.getProject(ctx, getOrganizationName(*repo)+"/"+getRepositoryName(*repo))
+	if err != nil {
+		glErr := &gitLabError{}
+		if errors.As(err, &glErr) && glErr.StatusCode == http.StatusNotFound {
+			return repoInfo{}, notFoundError{}
+		}
+		return repoInfo{}, fmt.Errorf("getting project: %w", err)
+	}
+	return gitLabRepoInfo(p), nil
+}
+
+func (g *gitLabProvider) getProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository) (gitProviderCredentials, error) {
+	var secret v1.Secret
+	err := g.Client.Get(ctx, types.NamespacedName{
+		Namespace: repo.Spec.SecretRef.Namespace,
+		Name:      repo.Spec.SecretRef.Name,
+	}, &secret)
+	if err != nil {
+		return gitProviderCredentials{}, err
+	}
+
+	token, ok := secret.Data[gitLabTokenKey]
+	if !ok {
+		return gitProviderCredentials{}, fmt.Errorf("%s key not found in secret %s in %s ns", gitLabTokenKey, repo.Spec.SecretRef.Name, repo.Spec.SecretRef.Namespace)
+	}
+
+	return gitProviderCredentials{
+		username:    util.GitTokenUsername,
+		accessToken: string(token),
+	}, nil
+}
+
+func (g *gitLabProvider) setProviderCredentials(ctx context.Context, repo *v1alpha1.GitRepository, creds gitProviderCredentials) error {
+	return g.gitLabClient.setToken(creds.accessToken)
+}
+
+func (g *gitLabProvider) updateRepoContent(
+	ctx context.Context,
+	repo *v1alpha1.GitRepository,
+	repoInfo repoInfo,
+	creds gitProviderCredentials,
+	tmpDir string,
+	repoMap *util.RepoMap,
+) error {
+	switch repo.Spec.Source.Type {
+	case v1alpha1.SourceTypeLocal, v1alpha1.SourceTypeEmbedded:
+		return reconcileLocalRepoContent(ctx, repo, repoInfo, creds, g.Scheme, g.config, tmpDir, repoMap)
+	case v1alpha1.SourceTypeRemote:
+		return reconcileRemoteRepoContent(ctx, g.Client, repo, repoInfo, creds, g.config, tmpDir, repoMap)
+	default:
+		return nil
+	}
+}
+
+func gitLabRepoInfo(p *gitLabProject) repoInfo {
+	return repoInfo{
+		name:                     p.Name,
+		cloneUrl:                 p.HTTPURLToRepo,
+		internalGitRepositoryUrl: p.HTTPURLToRepo,
+		fullName:                 p.PathWithNamespace,
+	}
+}
+
+// gitLabRepositoryURL returns the clone URL of a repository created in GitLab.
+func gitLabRepositoryURL(repo v1alpha1.GitRepository) string {
+	return fmt.Sprintf("%s/%s/%s.git", strings.TrimSuffix(repo.Spec.Provider.GitURL, "/"), getOrganizationName(repo), getRepositoryName(repo))
+}
+
+func newGitLabClient(baseURL string, httpClient *http.Client) gitLabClient {
+	return &glClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
@@ -0,0 +1,175 @@
+package gitrepository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeGitLab stands in for the GitLab API. It serves the group platform/team and the projects in projects.
+type fakeGitLab struct {
+	t        *testing.T
+	token    string
+	projects map[string]gitLabProject
+	created  []gitLabCreateProjectOption
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("PRIVATE-TOKEN") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"401 Unauthorized"}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/groups/platform%2Fteam":
+		json.NewEncoder(w).Encode(gitLabGroup{ID: 42, FullPath: "platform/team"})
+	case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects":
+		opt := gitLabCreateProjectOption{}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&opt))
+		f.created = append(f.created, opt)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(gitLabProject{
+			ID:                7,
+			Name:              opt.Name,
+			PathWithNamespace: "platform/team/" + opt.Path,
+			HTTPURLToRepo:     "https://gitlab.example.com/platform/team/" + opt.Path + ".git",
+		})
+	case r.Method == http.MethodGet:
+		p, ok := f.projects[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"404 Project Not Found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeGitLab(t *testing.T) (*fakeGitLab, gitLabClient) {
+	f := &fakeGitLab{t: t, token: "token", projects: map[string]gitLabProject{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	c := newGitLabClient(server.URL+"/", server.Client())
+	require.NoError(t, c.setToken(f.token))
+	return f, c
+}
+
+func gitLabTestResource() v1alpha1.GitRepository {
+	return v1alpha1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.GitRepositorySpec{
+			Source: v1alpha1.GitRepositorySource{
+				Path: "ac",
+				Type: "local",
+			},
+			Provider: v1alpha1.Provider{
+				Name:             v1alpha1.GitProviderGitLab,
+				GitURL:           "https://gitlab.example.com",
+				OrganizationName: "platform/team",
+			},
+		},
+	}
+}
+
+func TestGitLabCreateRepository(t *testing.T) {
+	fake, c := newFakeGitLab(t)
+	ctx := context.Background()
+	gl := gitLabProvider{
+		Client:       &fakeClient{},
+		gitLabClient: c,
+	}
+	resource := gitLabTestResource()
+
+	resp, err := gl.createRepository(ctx, &resource)
+	require.NoError(t, err)
+	assert.Equal(t, repoInfo{
+		name:                     "test-test",
+		cloneUrl:                 "https://gitlab.example.com/platform/team/test-test.git",
+		internalGitRepositoryUrl: "https://gitlab.example.com/platform/team/test-test.git",
+		fullName:                 "platform/team/test-test",
+	}, resp)
+
+	require.Len(t, fake.created, 1)
+	assert.Equal(t, 42, fake.created[0].NamespaceID)
+	assert.Equal(t, "test-test", fake.created[0].Path)
+	assert.Equal(t, "private", fake.created[0].Visibility)
+	assert.Equal(t, DefaultBranchName, fake.created[0].DefaultBranch)
+	assert.Equal(t, gitLabRepositoryURL(resource), resp.cloneUrl)
+}
+
+func TestGitLabGetRepository(t *testing.T) {
+	fake, c := newFakeGitLab(t)
+	ctx := context.Background()
+	gl := gitLabProvider{
+		Client:       &fakeClient{},
+		gitLabClient: c,
+	}
+	resource := gitLabTestResource()
+
+	resp, err := gl.getRepository(ctx, &resource)
+	assert.Equal(t, notFoundError{}, err)
+	assert.Equal(t, repoInfo{}, resp)
+
+	fake.projects["/api/v4/projects/platform%2Fteam%2Ftest-test"] = gitLabProject{
+		ID:                7,
+		Name:              "test-test",
+		PathWithNamespace: "platform/team/test-test",
+		HTTPURLToRepo:     "https://gitlab.example.com/platform/team/test-test.git",
+	}
+	resp, err = gl.getRepository(ctx, &resource)
+	require.NoError(t, err)
+	assert.Equal(t, "platform/team/test-test", resp.fullName)
+	assert.Equal(t, "https://gitlab.example.com/platform/team/test-test.git", resp.internalGitRepositoryUrl)
+
+	fake.token = "other"
+	_, err = gl.getRepository(ctx, &resource)
+	assert.ErrorContains(t, err, "gitlab api returned 401")
+}
+
+func TestGitLabGetProviderCredentials(t *testing.T) {
+	fakeK8sClient := new(fakeKubeClient)
+	ctx := context.Background()
+	gl := gitLabProvider{
+		Client: fakeK8sClient,
+	}
+
+	resource := gitLabTestResource()
+	resource.Spec.SecretRef = v1alpha1.SecretReference{
+		Name:      "test",
+		Namespace: "testNS",
+	}
+	inputSecret := &v1.Secret{}
+	fakeK8sClient.On("Get", ctx, types.NamespacedName{
+		Namespace: "testNS",
+		Name:      "test",
+	}, inputSecret, []client.GetOption(nil)).Run(func(args mock.Arguments) {
+		sec := args.Get(2).(*v1.Secret)
+		sec.Data = make(map[string][]byte, 1)
+		sec.Data[gitLabTokenKey] = []byte("token")
+	}).Return(nil)
+
+	creds, err := gl.getProviderCredentials(ctx, &resource)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", creds.accessToken)
+	assert.Equal(t, util.GitTokenUsername, creds.username)
+	fakeK8sClient.AssertExpectations(t)
+}
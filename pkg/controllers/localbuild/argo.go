@@ -3,56 +3,187 @@ package localbuild
 import (
 	"context"
 	"embed"
+	"fmt"
+	"slices"
+
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
-	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 //go:embed resources/argo/*
 var installArgoFS embed.FS
 
-func RawArgocdInstallResources(templateData any, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
-	return k8s.BuildCustomizedManifests(config.FilePath, "resources/argo", installArgoFS, scheme, templateData)
+const (
+	argocdRepoServerDeploymentName = "argocd-repo-server"
+	argocdServerName               = "argocd-server"
+	argocdGitServerRepoCredsName   = "idpbuilder-git-server-repo-creds"
+	argocdSecretTypeLabel          = "argocd.argoproj.io/secret-type"
+	gitHubURL                      = "https://github.com"
+)
+
+func RawArgocdInstallResources(templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+	argocd := newArgocdInstallation()
+	argocd.customization = config
+	return argocd.Manifests(scheme, templateData)
 }
 
-func (r *LocalbuildReconciler) ReconcileArgo(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
-	argocd := EmbeddedInstallation{
-		name:         "Argo CD",
-		resourcePath: "resources/argo",
-		resourceFS:   installArgoFS,
-		namespace:    globals.ArgoCDNamespace,
-		monitoredResources: map[string]schema.GroupVersionKind{
-			"argocd-server": {
-				Group:   "apps",
-				Version: "v1",
-				Kind:    "Deployment",
-			},
-			"argocd-repo-server": {
-				Group:   "apps",
-				Version: "v1",
-				Kind:    "Deployment",
-			},
-			"argocd-application-controller": {
-				Group:   "apps",
-				Version: "v1",
-				Kind:    "StatefulSet",
-			},
-		},
+func customizeArgocdManifests(manifests [][]byte, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	provider, err := ingress.ForBuild(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the server serves TLS itself unless path routing is used. without TLS passthrough the ingress terminates TLS, so
+	// the server must accept plain HTTP.
+	if !cfg.UsePathRouting && !provider.TLSPassthrough() {
+		manifests, err = setArgocdServerInsecure(manifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return customizeProxy(manifests, argocdRepoServerDeploymentName, globals.ArgoCDNamespace, cfg)
+}
+
+// argocdRoutes returns the routes of the Argo CD UI and API.
+func argocdRoutes(cfg v1alpha1.BuildCustomizationSpec, provider ingress.Provider) []ingress.Route {
+	if cfg.UsePathRouting {
+		return []ingress.Route{{
+			Name:      "argocd-server-ingress-http",
+			Namespace: globals.ArgoCDNamespace,
+			Path:      "/argocd",
+			Rewrite:   "/",
+			Service:   argocdServerName,
+			Port:      80,
+		}}
+	}
+
+	route := ingress.Route{
+		Name:      "argocd-server-ingress",
+		Namespace: globals.ArgoCDNamespace,
+		Subdomain: "argocd",
+		Path:      "/",
+		Service:   argocdServerName,
+		Port:      80,
+	}
+	if provider.TLSPassthrough() {
+		route.Port = 443
+		route.TLSPassthrough = true
+	}
+	return []ingress.Route{route}
+}
+
+// setArgocdServerInsecure adds the --insecure flag to the Argo CD server, so it serves plain HTTP.
+func setArgocdServerInsecure(manifests [][]byte) ([][]byte, error) {
+	out := make([][]byte, 0, len(manifests))
+	for i := range manifests {
+		nodes, err := kio.FromBytes(manifests[i])
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifests: %w", err)
+		}
+
+		for _, n := range nodes {
+			if n.GetKind() != "Deployment" || n.GetName() != argocdServerName {
+				continue
+			}
+			args, err := n.Pipe(kyaml.Lookup("spec", "template", "spec", "containers", "[name="+argocdServerName+"]", "args"))
+			if err != nil {
+				return nil, fmt.Errorf("finding arguments of %s: %w", argocdServerName, err)
+			}
+			if args == nil {
+				return nil, fmt.Errorf("arguments of %s not found", argocdServerName)
+			}
+			if slices.ContainsFunc(args.Content(), func(a *kyaml.Node) bool { return a.Value == "--insecure" }) {
+				continue
+			}
+			if err = args.PipeE(kyaml.Append(kyaml.NewStringRNode("--insecure").YNode())); err != nil {
+				return nil, fmt.Errorf("setting arguments of %s: %w", argocdServerName, err)
+			}
+		}
+
+		s, err := kio.StringAll(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("converting manifests to string: %w", err)
+		}
+		out = append(out, []byte(s))
+	}
+	return out, nil
+}
+
+func newArgocdInstallation() EmbeddedInstallation {
+	return EmbeddedInstallation{
+		name:               "Argo CD",
+		packageName:        v1alpha1.ArgoCDPackageName,
+		resourcePath:       "resources/argo",
+		resourceFS:         installArgoFS,
+		namespace:          globals.ArgoCDNamespace,
 		skipReadinessCheck: true,
+		customizeManifests: customizeArgocdManifests,
+		routes:             argocdRoutes,
+		versionDeployment:  "argocd-server",
+		versionContainer:   "argocd-server",
 	}
+}
+
+func (r *LocalbuildReconciler) ReconcileArgo(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	argocd := newArgocdInstallation()
 
 	v, ok := resource.Spec.PackageConfigs.CorePackageCustomization[v1alpha1.ArgoCDPackageName]
 	if ok {
 		argocd.customization = v
 	}
 
-	if result, err := argocd.Install(ctx, resource, r.Client, r.Scheme, r.Config); err != nil {
+	if result, err := argocd.Install(ctx, resource, r.Client, r.WatchClient, r.Scheme, r.Config); err != nil {
 		return result, err
 	}
 
 	resource.Status.ArgoCD.Available = true
 	return ctrl.Result{}, nil
 }
+
+// reconcileGitServerRepoCreds lets Argo CD pull every repository of the organization of an external git server with
+// the token of the build. Private repositories of the in-cluster Gitea use the credentials of reconcileGiteaRepoCreds.
+func (r *LocalbuildReconciler) reconcileGitServerRepoCreds(ctx context.Context, resource *v1alpha1.Localbuild) error {
+	if resource.Spec.PackageConfigs.GitServer.Provider == "" {
+		return nil
+	}
+	provider, secretRef := gitServer(resource)
+
+	tokenSecret := corev1.Secret{}
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}, &tokenSecret)
+	if err != nil {
+		return fmt.Errorf("getting git server token secret: %w", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      argocdGitServerRepoCredsName,
+			Namespace: globals.ArgoCDNamespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[argocdSecretTypeLabel] = "repo-creds"
+		secret.StringData = map[string]string{
+			"type":     "git",
+			"url":      provider.GitURL + "/" + provider.OrganizationName,
+			"username": util.GitTokenUsername,
+			"password": string(tokenSecret.Data[v1alpha1.GitServerTokenKey]),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("creating argocd repo credentials: %w", err)
+	}
+	return nil
+}
@@ -54,22 +54,20 @@ func TestGetRawInstallResources(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetRawInstallResources() error: %v", err)
 	}
-	if len(resources) != 2 {
-		t.Fatalf("GetRawInstallResources() resources len != 2, got %d", len(resources))
+	if len(resources) != 1 {
+		t.Fatalf("GetRawInstallResources() resources len != 1, got %d", len(resources))
 	}
 
 	resourcePrefix := "# UCP ARGO INSTALL RESOURCES\n"
-	checkPrefix := resources[1][0:len(resourcePrefix)]
+	checkPrefix := resources[0][0:len(resourcePrefix)]
 	if resourcePrefix != string(checkPrefix) {
 		t.Fatalf("GetRawInstallResources() expected 1 resource with prefix %q, got %q", resourcePrefix, checkPrefix)
 	}
 }
 
 func TestGetK8sInstallResources(t *testing.T) {
-	e := EmbeddedInstallation{
-		resourceFS:   installArgoFS,
-		resourcePath: "resources/argo",
-	}
+	// the ingress of argocd-server is generated from its routes.
+	e := newArgocdInstallation()
 	objs, err := e.installResources(k8s.GetScheme(), v1alpha1.BuildCustomizationSpec{
 		Protocol:       "",
 		Host:           "",
@@ -19,10 +19,13 @@ import (
 	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
-	"github.com/cnoe-io/idpbuilder/pkg/resources/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/state"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -31,15 +34,16 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 const (
-	defaultArgoCDProjectName string = "default"
-	defaultRequeueTime              = time.Second * 15
-	errRequeueTime                  = time.Second * 5
+	defaultRequeueTime = time.Second * 15
+	errRequeueTime     = time.Second * 5
 
 	argoCDApplicationAnnotationKeyRefresh         = "argocd.argoproj.io/refresh"
 	argoCDApplicationAnnotationValueRefreshNormal = "normal"
@@ -53,6 +57,10 @@ type ArgocdSession struct {
 
 type LocalbuildReconciler struct {
 	client.Client
+	// WatchClient watches core package resources while waiting for them to become ready.
+	WatchClient client.WithWatch
+	// Name is the name of the Localbuild to reconcile. All Localbuilds are reconciled if it is empty.
+	Name           string
 	Scheme         *runtime.Scheme
 	CancelFunc     context.CancelFunc
 	ExitOnSync     bool
@@ -60,6 +68,10 @@ type LocalbuildReconciler struct {
 	Config         v1alpha1.BuildCustomizationSpec
 	TempDir        string
 	RepoMap        *util.RepoMap
+	// ImportState is restored into Gitea once the core packages are installed. It is nil unless the cluster was
+	// created by the import command.
+	ImportState *state.Snapshot
+	imported    bool
 }
 
 type subReconciler func(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error)
@@ -84,6 +96,11 @@ func (r *LocalbuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	engine, err := gitops.ForBuild(localBuild.Spec.BuildCustomization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	instCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	errChan := make(chan error, 3)
@@ -95,78 +112,123 @@ func (r *LocalbuildReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	case instErr := <-errChan:
 		if instErr != nil {
-			// likely due to ingress-nginx admission hook not ready. debug log and try again.
+			// likely due to the admission hook of the ingress not ready. debug log and try again.
 			logger.V(1).Info("failed installing core package. likely not fatal. will try again", "error", instErr)
+			setCondition(&localBuild, v1alpha1.ConditionCorePackagesInstalled, metav1.ConditionFalse, v1alpha1.ReasonInstallFailed, instErr.Error())
+			setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonCorePackagesNotReady, "core packages are not installed")
 			return ctrl.Result{RequeueAfter: errRequeueTime}, nil
 		}
 	}
+	installedMsg := fmt.Sprintf("the ingress, %s, and gitea are installed", engine.Name())
+	if localBuild.Spec.BuildCustomization.SkipGitea {
+		installedMsg = fmt.Sprintf("the ingress and %s are installed", engine.Name())
+	}
+	setCondition(&localBuild, v1alpha1.ConditionCorePackagesInstalled, metav1.ConditionTrue, v1alpha1.ReasonInstalled, installedMsg)
 
 	if r.Config.StaticPassword {
 		logger.V(1).Info("static password is enabled")
 
-		// Check if the Argocd Initial admin secret exists
-		argocdInitialAdminPassword, err := r.extractArgocdInitialAdminSecret(ctx)
-		if err != nil {
-			// Argocd initial admin secret is not yet available ...
-			return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
-		}
-
-		logger.V(1).Info("Initial argocd admin secret found ...")
-
-		// Secret containing the initial argocd password exists
-		// Lets try to update the password
-		if argocdInitialAdminPassword != "" && argocdInitialAdminPassword != util.StaticPassword {
-			err = r.updateArgocdPassword(ctx, argocdInitialAdminPassword)
+		// the Argo CD admin password is only set when Argo CD is the GitOps engine.
+		if engine.Name() == gitops.ArgoCDEngineName {
+			// Check if the Argocd Initial admin secret exists
+			argocdInitialAdminPassword, err := r.extractArgocdInitialAdminSecret(ctx)
 			if err != nil {
-				return ctrl.Result{}, err
-			} else {
-				logger.V(1).Info(fmt.Sprintf("Argocd admin password change succeeded !"))
+				// Argocd initial admin secret is not yet available ...
+				setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonCredentialsNotReady, "waiting for the argocd admin secret")
+				return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
+			}
+
+			logger.V(1).Info("Initial argocd admin secret found ...")
+
+			// Secret containing the initial argocd password exists
+			// Lets try to update the password
+			if argocdInitialAdminPassword != "" && argocdInitialAdminPassword != util.StaticPassword {
+				err = r.updateArgocdPassword(ctx, argocdInitialAdminPassword)
+				if err != nil {
+					setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonReconcileFailed, err.Error())
+					return ctrl.Result{}, err
+				} else {
+					logger.V(1).Info(fmt.Sprintf("Argocd admin password change succeeded !"))
+				}
 			}
 		}
 
-		// Check if the Gitea credentials secret exists
-		giteaAdminPassword, err := r.extractGiteaAdminSecret(ctx)
-		if err != nil {
-			// Gitea admin secret is not yet available ...
-			return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
-		}
-		logger.V(1).Info("Gitea admin secret found ...")
-		// Secret containing the gitea password exists
-		// Lets try to update the password
-		if giteaAdminPassword != "" && giteaAdminPassword != util.StaticPassword {
-			err = r.updateGiteaPassword(ctx, giteaAdminPassword)
+		if !localBuild.Spec.BuildCustomization.SkipGitea {
+			// Check if the Gitea credentials secret exists
+			giteaAdminPassword, err := r.extractGiteaAdminSecret(ctx)
 			if err != nil {
-				return ctrl.Result{}, err
-			} else {
-				logger.V(1).Info(fmt.Sprintf("Gitea admin password change succeeded !"))
+				// Gitea admin secret is not yet available ...
+				setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonCredentialsNotReady, "waiting for the gitea admin secret")
+				return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
+			}
+			logger.V(1).Info("Gitea admin secret found ...")
+			// Secret containing the gitea password exists
+			// Lets try to update the password
+			if giteaAdminPassword != "" && giteaAdminPassword != util.StaticPassword {
+				err = r.updateGiteaPassword(ctx, giteaAdminPassword)
+				if err != nil {
+					setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonReconcileFailed, err.Error())
+					return ctrl.Result{}, err
+				} else {
+					logger.V(1).Info(fmt.Sprintf("Gitea admin password change succeeded !"))
+				}
 			}
 		}
 	}
 
-	logger.V(1).Info("done installing core packages. passing control to argocd")
+	if r.ImportState != nil && !r.imported {
+		if err = r.restoreRepositories(ctx); err != nil {
+			logger.Error(err, "failed restoring repositories")
+			setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonReconcileFailed, err.Error())
+			return ctrl.Result{RequeueAfter: errRequeueTime}, nil
+		}
+		r.imported = true
+	}
+
+	logger.V(1).Info("done installing core packages. passing control to " + engine.Name())
 	_, err = r.ReconcileArgoAppsWithGitea(ctx, req, &localBuild)
 	if err != nil {
+		setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonBootstrapAppsFailed, err.Error())
 		return ctrl.Result{}, err
 	}
+	setCondition(&localBuild, v1alpha1.ConditionReady, metav1.ConditionTrue, v1alpha1.ReasonReconciled,
+		"core packages are installed and packages are created")
 
 	return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
 }
 
+func setCondition(resource *v1alpha1.Localbuild, condType string, status metav1.ConditionStatus, reason, msg string) {
+	meta.SetStatusCondition(&resource.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: resource.GetGeneration(),
+		Reason:             reason,
+		Message:            msg,
+	})
+}
+
 func (r *LocalbuildReconciler) installCorePackages(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild, errChan chan error) {
 	logger := log.FromContext(ctx)
 	defer close(errChan)
 	var wg sync.WaitGroup
 
-	installers := map[string]subReconciler{
+	reconcilers := map[string]subReconciler{
 		v1alpha1.IngressNginxPackageName: r.ReconcileNginx,
+		v1alpha1.EnvoyGatewayPackageName: r.ReconcileEnvoyGateway,
 		v1alpha1.ArgoCDPackageName:       r.ReconcileArgo,
 		v1alpha1.GiteaPackageName:        r.ReconcileGitea,
+		v1alpha1.FluxPackageName:         r.ReconcileFlux,
+	}
+	names, err := CorePackageNames(resource.Spec.BuildCustomization)
+	if err != nil {
+		errChan <- err
+		return
 	}
 	logger.V(1).Info("installing core packages")
-	for k, v := range installers {
+	for _, n := range names {
 		wg.Add(1)
-		name := k
-		inst := v
+		name := n
+		inst := reconcilers[n]
 		go func() {
 			defer wg.Done()
 			_, iErr := inst(ctx, req, resource)
@@ -179,28 +241,31 @@ func (r *LocalbuildReconciler) installCorePackages(ctx context.Context, req ctrl
 	wg.Wait()
 }
 
-// Responsible to updating ObservedGeneration in status
+// Responsible to updating ObservedGeneration and conditions in status. The status is updated before shutting down, so
+// the conditions of the last reconcile are visible after the CLI exits.
 func (r *LocalbuildReconciler) postProcessReconcile(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) {
 	logger := log.FromContext(ctx)
 
+	resource.Status.ObservedGeneration = resource.GetGeneration()
+	if err := r.Status().Update(ctx, resource); err != nil {
+		logger.Error(err, "Failed to update resource status after reconcile")
+	}
+
 	logger.Info("Checking if we should shutdown")
 	if r.shouldShutdown {
 		logger.Info("Shutting Down")
-		err := r.requestArgoCDAppRefresh(ctx)
-		if err != nil {
-			logger.V(1).Info("failed requesting argocd application refresh", "error", err)
-		}
-		err = r.requestArgoCDAppSetRefresh(ctx)
-		if err != nil {
-			logger.V(1).Info("failed requesting argocd application set refresh", "error", err)
+		// Flux reconciles its objects at their interval, only Argo CD is asked to refresh.
+		if resource.Spec.BuildCustomization.GitOpsEngine != gitops.FluxEngineName {
+			err := r.requestArgoCDAppRefresh(ctx)
+			if err != nil {
+				logger.V(1).Info("failed requesting argocd application refresh", "error", err)
+			}
+			err = r.requestArgoCDAppSetRefresh(ctx)
+			if err != nil {
+				logger.V(1).Info("failed requesting argocd application set refresh", "error", err)
+			}
 		}
 		r.CancelFunc()
-		return
-	}
-
-	resource.Status.ObservedGeneration = resource.GetGeneration()
-	if err := r.Status().Update(ctx, resource); err != nil {
-		logger.Error(err, "Failed to update resource status after reconcile")
 	}
 }
 
@@ -230,24 +295,42 @@ func (r *LocalbuildReconciler) ReconcileProjectNamespace(ctx context.Context, re
 // SetupWithManager sets up the controller with the Manager.
 func (r *LocalbuildReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Localbuild{}).
+		For(&v1alpha1.Localbuild{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return r.Name == "" || o.GetName() == r.Name
+		}))).
 		Complete(r)
 }
 
+// ReconcileArgoAppsWithGitea pushes core and custom packages to the git server and lets the GitOps engine take over.
+// The name predates the other engines.
 func (r *LocalbuildReconciler) ReconcileArgoAppsWithGitea(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("installing bootstrap apps to ArgoCD")
+	engine, err := gitops.ForBuild(resource.Spec.BuildCustomization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.Info("installing bootstrap apps", "engine", engine.Name())
 
-	// push bootstrap app manifests to Gitea. let ArgoCD take over
+	// push bootstrap app manifests to Gitea. let the engine take over
 	// will need a way to filter them based on user input
-	bootStrapApps := []string{v1alpha1.ArgoCDPackageName, v1alpha1.IngressNginxPackageName, v1alpha1.GiteaPackageName}
+	bootStrapApps, err := CorePackageNames(resource.Spec.BuildCustomization)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 	for _, n := range bootStrapApps {
-		result, err := r.reconcileEmbeddedApp(ctx, n, resource)
+		result, err := r.reconcileEmbeddedApp(ctx, engine, n, resource)
 		if err != nil {
 			return result, fmt.Errorf("reconciling bootstrap apps %w", err)
 		}
 	}
 
+	// Argo CD repository credentials. Flux reads public repositories only, see validateGitOpsEngine of the create command.
+	if engine.Name() == gitops.ArgoCDEngineName {
+		if err = r.reconcileGitServerRepoCreds(ctx, resource); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Process packages in REVERSE order (highest priority first) to avoid creating
 	// lower priority packages first then having to delete them
 	for i := len(resource.Spec.PackageConfigs.CustomPackageDirs) - 1; i >= 0; i-- {
@@ -283,58 +366,34 @@ func (r *LocalbuildReconciler) ReconcileArgoAppsWithGitea(ctx context.Context, r
 	return ctrl.Result{}, nil
 }
 
-func (r *LocalbuildReconciler) reconcileEmbeddedApp(ctx context.Context, appName string, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+func (r *LocalbuildReconciler) reconcileEmbeddedApp(ctx context.Context, engine gitops.Engine, appName string, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	logger.V(1).Info("Ensuring embedded ArgoCD Application", "name", appName)
+	logger.V(1).Info("Ensuring embedded app", "name", appName, "engine", engine.Name())
 	repo, err := r.reconcileGitRepo(ctx, resource, "embedded", appName, appName, "")
 
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("creating %s repo CR: %w", appName, err)
 	}
 
-	app := &argov1alpha1.Application{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      appName,
-			Namespace: globals.ArgoCDNamespace,
-		},
-	}
-
-	util.SetPackageLabels(app)
-
-	if err := controllerutil.SetControllerReference(resource, app, r.Scheme); err != nil {
+	namespace, err := CorePackageNamespace(appName)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	err = r.Client.Get(ctx, client.ObjectKeyFromObject(app), app)
-	if err != nil && k8serrors.IsNotFound(err) {
-		localbuild.SetApplicationSpec(
-			app,
-			repo.Status.InternalGitRepositoryUrl,
-			".",
-			defaultArgoCDProjectName,
-			appName,
-			nil,
-		)
-		err = r.Client.Create(ctx, app)
+	for _, obj := range engine.CorePackageObjects(appName) {
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+			util.SetPackageLabels(obj)
+			if err := controllerutil.SetControllerReference(resource, obj, r.Scheme); err != nil {
+				return err
+			}
+			return engine.SetCorePackageSpec(obj, repo.Status.InternalGitRepositoryUrl, namespace)
+		})
 		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("creating %s app CR: %w", appName, err)
+			return ctrl.Result{}, fmt.Errorf("creating or updating %s app CR: %w", appName, err)
 		}
 	}
 
-	localbuild.SetApplicationSpec(
-		app,
-		repo.Status.InternalGitRepositoryUrl,
-		".",
-		defaultArgoCDProjectName,
-		appName,
-		nil,
-	)
-	err = r.Client.Update(ctx, app)
-	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("updating argoapp: %w", err)
-	}
-
 	return ctrl.Result{}, nil
 }
 
@@ -361,21 +420,29 @@ func (r *LocalbuildReconciler) shouldShutDown(ctx context.Context, resource *v1a
 		LabelSelector: selector.Add(*req),
 		Namespace:     "",
 	}
-	apps := argov1alpha1.ApplicationList{}
-	err = r.Client.List(ctx, &apps, &opts)
+	engine, err := gitops.ForBuild(resource.Spec.BuildCustomization)
+	if err != nil {
+		return false, err
+	}
+	apps := engine.CorePackageList()
+	err = r.Client.List(ctx, apps, &opts)
+	if err != nil {
+		return false, fmt.Errorf("listing core packages: %w", err)
+	}
+	items, err := meta.ExtractList(apps)
 	if err != nil {
 		return false, fmt.Errorf("listing core packages: %w", err)
 	}
 
-	for _, app := range apps.Items {
-		if app.Status.Health.Status != "Healthy" {
+	for _, app := range items {
+		if !engine.Healthy(app) {
 			return false, nil
 		}
 	}
 
 	// check if repositories are ready
 	repos := &v1alpha1.GitRepositoryList{}
-	err = r.Client.List(ctx, repos, client.InNamespace(resource.Namespace))
+	err = r.Client.List(ctx, repos, client.InNamespace(globals.GetProjectNamespace(resource.Name)))
 	if err != nil {
 		return false, fmt.Errorf("listing repositories %w", err)
 	}
@@ -407,7 +474,7 @@ func (r *LocalbuildReconciler) shouldShutDown(ctx context.Context, resource *v1a
 
 	// check if custom packages are ready
 	pkgs := &v1alpha1.CustomPackageList{}
-	err = r.Client.List(ctx, pkgs, client.InNamespace(resource.Namespace))
+	err = r.Client.List(ctx, pkgs, client.InNamespace(globals.GetProjectNamespace(resource.Name)))
 	if err != nil {
 		return false, fmt.Errorf("listing custom packages %w", err)
 	}
@@ -450,11 +517,19 @@ func (r *LocalbuildReconciler) reconcileCustomPkg(
 		return fErr
 	}
 
-	if isSupportedArgoCDTypes(gvk) {
+	engine, err := gitops.ForBuild(resource.Spec.BuildCustomization)
+	if err != nil {
+		return err
+	}
+	if gvk != nil && engine.IsPackageKind(*gvk) {
 		kind := o.GetKind()
 		appName := o.GetName()
 		appNS := o.GetNamespace()
 
+		if err := r.checkPackageNameCollision(ctx, resource, engine, appName, appNS); err != nil {
+			return err
+		}
+
 		// Check if a higher-priority CustomPackage already exists for this app
 		projectNS := globals.GetProjectNamespace(resource.Name)
 		existingPkgs := &v1alpha1.CustomPackageList{}
@@ -520,14 +595,12 @@ func (r *LocalbuildReconciler) reconcileCustomPkg(
 			customPkg.ObjectMeta.Annotations[v1alpha1.PackagePriorityAnnotation] = fmt.Sprintf("%d", priority)
 			customPkg.ObjectMeta.Annotations[v1alpha1.PackageSourcePathAnnotation] = sourcePath
 
+			provider, secretRef := gitServer(resource)
 			customPkg.Spec = v1alpha1.CustomPackageSpec{
-				Replicate:           true,
-				GitServerURL:        resource.Status.Gitea.ExternalURL,
-				InternalGitServeURL: resource.Status.Gitea.InternalURL,
-				GitServerAuthSecretRef: v1alpha1.SecretReference{
-					Name:      resource.Status.Gitea.AdminUserSecretName,
-					Namespace: resource.Status.Gitea.AdminUserSecretNamespace,
-				},
+				Replicate:              remote == nil || remote.Replicate,
+				GitServerURL:           provider.GitURL,
+				InternalGitServeURL:    provider.InternalGitURL,
+				GitServerAuthSecretRef: secretRef,
 				ArgoCD: v1alpha1.ArgoCDPackageSpec{
 					ApplicationFile: filePath,
 					Name:            appName,
@@ -535,6 +608,10 @@ func (r *LocalbuildReconciler) reconcileCustomPkg(
 					Type:            kind,
 				},
 			}
+			if resource.Spec.PackageConfigs.GitServer.Provider != "" {
+				customPkg.Spec.GitProvider = provider.Name
+				customPkg.Spec.GitOrganizationName = provider.OrganizationName
+			}
 
 			if remote != nil {
 				customPkg.Spec.RemoteRepository = v1alpha1.RemoteRepositorySpec{
@@ -552,6 +629,36 @@ func (r *LocalbuildReconciler) reconcileCustomPkg(
 	return nil
 }
 
+// checkPackageNameCollision returns an error if a package of another Localbuild in the cluster deploys an application
+// with the same name and namespace. The applications of all Localbuilds share the namespace of the GitOps engine, so
+// they would overwrite each other.
+func (r *LocalbuildReconciler) checkPackageNameCollision(ctx context.Context, resource *v1alpha1.Localbuild, engine gitops.Engine, appName, appNS string) error {
+	if appNS == "" {
+		appNS = engine.Namespace()
+	}
+	pkgs := &v1alpha1.CustomPackageList{}
+	if err := r.Client.List(ctx, pkgs); err != nil {
+		return fmt.Errorf("listing custom packages: %w", err)
+	}
+	projectNS := globals.GetProjectNamespace(resource.Name)
+	for i := range pkgs.Items {
+		pkg := &pkgs.Items[i]
+		ns := pkg.Spec.ArgoCD.Namespace
+		if ns == "" {
+			ns = engine.Namespace()
+		}
+		if pkg.Namespace == projectNS || pkg.Spec.ArgoCD.Name != appName || ns != appNS {
+			continue
+		}
+		owner := pkg.Namespace
+		if ref := metav1.GetControllerOf(pkg); ref != nil {
+			owner = ref.Name
+		}
+		return fmt.Errorf("package %s in namespace %s is already deployed by localbuild %s, rename the application", appName, appNS, owner)
+	}
+	return nil
+}
+
 func (r *LocalbuildReconciler) reconcileCustomPkgUrl(ctx context.Context, resource *v1alpha1.Localbuild, pkgUrl string, priority int) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -570,7 +677,11 @@ func (r *LocalbuildReconciler) reconcileCustomPkgUrl(ctx context.Context, resour
 	st := r.RepoMap.LoadOrStore(rs.Url, cloneDir)
 	st.MU.Lock()
 	defer st.MU.Unlock()
-	wt, _, err := util.CloneRemoteRepoToDir(ctx, rs, 1, false, cloneDir, "")
+	auth, err := util.RemoteRepoAuth(ctx, r.Client, r.Config.PackageAuth, rs.Url)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting credentials of repo, %s: %w", pkgUrl, err)
+	}
+	wt, _, err := util.CloneRemoteRepoToDirWithAuth(ctx, rs, 1, false, cloneDir, "", auth)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("cloning repo, %s: %w", pkgUrl, err)
 	}
@@ -683,20 +794,13 @@ func (r *LocalbuildReconciler) reconcileGitRepo(ctx context.Context, resource *v
 		}
 		util.SetCLIStartTimeAnnotationValue(repo.ObjectMeta.Annotations, cliStartTime)
 
+		provider, secretRef := gitServer(resource)
 		repo.Spec = v1alpha1.GitRepositorySpec{
 			Source: v1alpha1.GitRepositorySource{
 				Type: repoType,
 			},
-			Provider: v1alpha1.Provider{
-				Name:             v1alpha1.GitProviderGitea,
-				GitURL:           resource.Status.Gitea.ExternalURL,
-				InternalGitURL:   resource.Status.Gitea.InternalURL,
-				OrganizationName: v1alpha1.GiteaAdminUserName,
-			},
-			SecretRef: v1alpha1.SecretReference{
-				Name:      resource.Status.Gitea.AdminUserSecretName,
-				Namespace: resource.Status.Gitea.AdminUserSecretNamespace,
-			},
+			Provider:  provider,
+			SecretRef: secretRef,
 		}
 
 		if repoType == v1alpha1.SourceTypeEmbedded {
@@ -707,8 +811,10 @@ func (r *LocalbuildReconciler) reconcileGitRepo(ctx context.Context, resource *v
 		f, ok := resource.Spec.PackageConfigs.CorePackageCustomization[embeddedName]
 		if ok {
 			repo.Spec.Customization = v1alpha1.PackageCustomization{
-				Name:     embeddedName,
-				FilePath: f.FilePath,
+				Name:          embeddedName,
+				FilePath:      f.FilePath,
+				Version:       f.Version,
+				ManifestsPath: f.ManifestsPath,
 			}
 		}
 		return nil
@@ -717,6 +823,32 @@ func (r *LocalbuildReconciler) reconcileGitRepo(ctx context.Context, resource *v
 	return repo, err
 }
 
+// gitServer returns the git server packages are pushed to and the secret with its credentials. It is the external git
+// server of resource if one is set, and the in-cluster Gitea otherwise.
+func gitServer(resource *v1alpha1.Localbuild) (v1alpha1.Provider, v1alpha1.SecretReference) {
+	if s := resource.Spec.PackageConfigs.GitServer; s.Provider != "" {
+		url := strings.TrimSuffix(s.URL, "/")
+		if url == "" && s.Provider == v1alpha1.GitProviderGitHub {
+			url = gitHubURL
+		}
+		return v1alpha1.Provider{
+			Name:             s.Provider,
+			GitURL:           url,
+			InternalGitURL:   url,
+			OrganizationName: s.OrganizationName,
+		}, s.SecretRef
+	}
+	return v1alpha1.Provider{
+		Name:             v1alpha1.GitProviderGitea,
+		GitURL:           resource.Status.Gitea.ExternalURL,
+		InternalGitURL:   resource.Status.Gitea.InternalURL,
+		OrganizationName: v1alpha1.GiteaAdminUserName,
+	}, v1alpha1.SecretReference{
+		Name:      resource.Status.Gitea.AdminUserSecretName,
+		Namespace: resource.Status.Gitea.AdminUserSecretNamespace,
+	}
+}
+
 func (r *LocalbuildReconciler) requestArgoCDAppRefresh(ctx context.Context) error {
 	apps := &argov1alpha1.ApplicationList{}
 	err := r.Client.List(ctx, apps, client.InNamespace(globals.ArgoCDNamespace))
@@ -788,6 +920,17 @@ func (r *LocalbuildReconciler) extractGiteaAdminSecret(ctx context.Context) (str
 	return string(sec.Data["password"]), nil
 }
 
+// restoreRepositories pushes the imported repositories to Gitea before the GitRepository objects are created.
+func (r *LocalbuildReconciler) restoreRepositories(ctx context.Context) error {
+	password, err := r.extractGiteaAdminSecret(ctx)
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("restoring gitea repositories", "count", len(r.ImportState.Repositories))
+	return state.RestoreRepositories(ctx, r.ImportState, util.GiteaBaseUrl(r.Config), util.GiteaAdminName, password,
+		r.Config.GiteaPrivateRepositories)
+}
+
 func (r *LocalbuildReconciler) updateGiteaPassword(ctx context.Context, adminPassword string) error {
 	giteaBaseUrl := util.GiteaBaseUrl(r.Config)
 
@@ -950,14 +1093,16 @@ func getCustomPackageName(fileName, appName string) string {
 	return fmt.Sprintf("%s-%s", strings.ToLower(s[0]), appName)
 }
 
-func isSupportedArgoCDTypes(gvk *schema.GroupVersionKind) bool {
+// IsSupportedArgoCDTypes returns true for the Argo CD kinds that can be used in custom packages.
+func IsSupportedArgoCDTypes(gvk *schema.GroupVersionKind) bool {
 	if gvk == nil {
 		return false
 	}
-	return gvk.Group == argocdapp.Group && (gvk.Kind == argocdapp.ApplicationKind || gvk.Kind == argocdapp.ApplicationSetKind)
+	engine, _ := gitops.ForName(gitops.ArgoCDEngineName)
+	return engine.IsPackageKind(*gvk)
 }
 
-func GetEmbeddedRawInstallResources(name string, templateData any, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+func GetEmbeddedRawInstallResources(name string, templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
 	switch name {
 	case v1alpha1.ArgoCDPackageName:
 		return RawArgocdInstallResources(templateData, config, scheme)
@@ -965,7 +1110,47 @@ func GetEmbeddedRawInstallResources(name string, templateData any, config v1alph
 		return RawGiteaInstallResources(templateData, config, scheme)
 	case v1alpha1.IngressNginxPackageName:
 		return RawNginxInstallResources(templateData, config, scheme)
+	case v1alpha1.EnvoyGatewayPackageName:
+		return RawEnvoyGatewayInstallResources(templateData, config, scheme)
+	case v1alpha1.FluxPackageName:
+		return RawFluxInstallResources(templateData, config, scheme)
 	default:
 		return nil, fmt.Errorf("unsupported embedded app name %s", name)
 	}
 }
+
+// CorePackageNamespace returns the namespace the embedded app name is installed in.
+func CorePackageNamespace(name string) (string, error) {
+	switch name {
+	case v1alpha1.ArgoCDPackageName:
+		return globals.ArgoCDNamespace, nil
+	case v1alpha1.GiteaPackageName:
+		return util.GiteaNamespace, nil
+	case v1alpha1.IngressNginxPackageName:
+		return globals.NginxNamespace, nil
+	case v1alpha1.EnvoyGatewayPackageName:
+		return globals.EnvoyGatewayNamespace, nil
+	case v1alpha1.FluxPackageName:
+		return globals.FluxNamespace, nil
+	default:
+		return "", fmt.Errorf("unsupported embedded app name %s", name)
+	}
+}
+
+// CorePackageNames returns the core packages installed with cfg in the order they are upgraded: the package of the
+// selected ingress serves Gitea and Argo CD, and the selected GitOps engine syncs from Gitea. Gitea is left out when it
+// is skipped.
+func CorePackageNames(cfg v1alpha1.BuildCustomizationSpec) ([]string, error) {
+	provider, err := ingress.ForBuild(cfg)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := gitops.ForBuild(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SkipGitea {
+		return []string{provider.PackageName(), engine.PackageName()}, nil
+	}
+	return []string{provider.PackageName(), v1alpha1.GiteaPackageName, engine.PackageName()}, nil
+}
@@ -0,0 +1,258 @@
+package localbuild
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func syncedPackage(name, namespace, cliStartTime string, synced bool) *v1alpha1.CustomPackage {
+	annotations := map[string]string{}
+	util.SetCLIStartTimeAnnotationValue(annotations, cliStartTime)
+	util.SetLastObservedSyncTimeAnnotationValue(annotations, cliStartTime)
+	return &v1alpha1.CustomPackage{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+		Status:     v1alpha1.CustomPackageStatus{Synced: synced},
+	}
+}
+
+func TestShouldShutDownScopedToLocalbuild(t *testing.T) {
+	ctx := context.Background()
+	teamA := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team-a",
+		Annotations: map[string]string{v1alpha1.CliStartTimeAnnotation: "a-start"},
+	}}
+
+	cases := map[string]struct {
+		pkg      *v1alpha1.CustomPackage
+		expected bool
+	}{
+		"synced": {
+			pkg:      syncedPackage("app", "idpbuilder-team-a", "a-start", true),
+			expected: true,
+		},
+		"not synced": {
+			pkg:      syncedPackage("app", "idpbuilder-team-a", "a-start", false),
+			expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			// a package of another localbuild that was created by a different invocation and is not synced.
+			other := syncedPackage("app", "idpbuilder-team-b", "b-start", false)
+			kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).
+				WithObjects(teamA, c.pkg, other).Build()
+
+			r := LocalbuildReconciler{Client: kubeClient, Name: "team-a", ExitOnSync: true}
+			shutdown, err := r.shouldShutDown(ctx, teamA)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, shutdown)
+		})
+	}
+}
+
+func TestGitServer(t *testing.T) {
+	giteaBuild := &v1alpha1.Localbuild{Status: v1alpha1.LocalbuildStatus{Gitea: v1alpha1.GiteaStatus{
+		ExternalURL:              "https://gitea.cnoe.localtest.me:8443",
+		InternalURL:              "http://my-gitea-http.gitea.svc.cluster.local:3000",
+		AdminUserSecretName:      util.GiteaAdminSecret,
+		AdminUserSecretNamespace: util.GiteaNamespace,
+	}}}
+	provider, secretRef := gitServer(giteaBuild)
+	assert.Equal(t, v1alpha1.Provider{
+		Name:             v1alpha1.GitProviderGitea,
+		GitURL:           "https://gitea.cnoe.localtest.me:8443",
+		InternalGitURL:   "http://my-gitea-http.gitea.svc.cluster.local:3000",
+		OrganizationName: v1alpha1.GiteaAdminUserName,
+	}, provider)
+	assert.Equal(t, v1alpha1.SecretReference{Name: util.GiteaAdminSecret, Namespace: util.GiteaNamespace}, secretRef)
+
+	gitHubBuild := giteaBuild.DeepCopy()
+	gitHubBuild.Spec.PackageConfigs.GitServer = v1alpha1.GitServerSpec{
+		Provider:         v1alpha1.GitProviderGitHub,
+		OrganizationName: "myorg",
+		SecretRef:        v1alpha1.SecretReference{Name: "git-server-token", Namespace: "idpbuilder-localdev"},
+	}
+	provider, secretRef = gitServer(gitHubBuild)
+	assert.Equal(t, v1alpha1.Provider{
+		Name:             v1alpha1.GitProviderGitHub,
+		GitURL:           "https://github.com",
+		InternalGitURL:   "https://github.com",
+		OrganizationName: "myorg",
+	}, provider)
+	assert.Equal(t, "git-server-token", secretRef.Name)
+}
+
+func TestReconcileGitServerRepoCreds(t *testing.T) {
+	ctx := context.Background()
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-server-token", Namespace: "idpbuilder-localdev"},
+		Data:       map[string][]byte{v1alpha1.GitServerTokenKey: []byte("secret-token")},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(token).Build()
+	r := LocalbuildReconciler{Client: kubeClient}
+
+	resource := &v1alpha1.Localbuild{}
+	require.NoError(t, r.reconcileGitServerRepoCreds(ctx, resource))
+	secrets := corev1.SecretList{}
+	require.NoError(t, kubeClient.List(ctx, &secrets))
+	assert.Len(t, secrets.Items, 1, "no repo credentials for the in-cluster gitea")
+
+	resource.Spec.PackageConfigs.GitServer = v1alpha1.GitServerSpec{
+		Provider:         v1alpha1.GitProviderGitLab,
+		URL:              "https://gitlab.example.com/",
+		OrganizationName: "platform",
+		SecretRef:        v1alpha1.SecretReference{Name: token.Name, Namespace: token.Namespace},
+	}
+	require.NoError(t, r.reconcileGitServerRepoCreds(ctx, resource))
+
+	creds := corev1.Secret{}
+	require.NoError(t, kubeClient.Get(ctx, types.NamespacedName{Namespace: "argocd", Name: argocdGitServerRepoCredsName}, &creds))
+	assert.Equal(t, "repo-creds", creds.Labels[argocdSecretTypeLabel])
+	assert.Equal(t, "https://gitlab.example.com/platform", creds.StringData["url"])
+	assert.Equal(t, "secret-token", creds.StringData["password"])
+}
+
+func TestReconcileGiteaRepoCreds(t *testing.T) {
+	ctx := context.Background()
+	adminSecret := corev1.Secret{
+		Data: map[string][]byte{"username": []byte("giteaAdmin"), "password": []byte("password")},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build()
+	r := LocalbuildReconciler{Client: kubeClient}
+
+	// no gitea api is reachable, so nothing may be requested unless private repositories are enabled.
+	resource := &v1alpha1.Localbuild{}
+	require.NoError(t, r.reconcileGiteaRepoCreds(ctx, resource, adminSecret, "http://127.0.0.1:0"))
+
+	r.Config.GiteaPrivateRepositories = true
+	resource.Spec.PackageConfigs.GitServer.Provider = v1alpha1.GitProviderGitHub
+	require.NoError(t, r.reconcileGiteaRepoCreds(ctx, resource, adminSecret, "http://127.0.0.1:0"))
+	secrets := corev1.SecretList{}
+	require.NoError(t, kubeClient.List(ctx, &secrets))
+	assert.Empty(t, secrets.Items)
+
+	// the token of an existing secret is kept.
+	creds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: giteaRepoCredsName, Namespace: "argocd"},
+		Data:       map[string][]byte{"password": []byte("read-only")},
+	}
+	require.NoError(t, kubeClient.Create(ctx, creds))
+	resource.Spec.PackageConfigs.GitServer = v1alpha1.GitServerSpec{}
+	require.NoError(t, r.reconcileGiteaRepoCreds(ctx, resource, adminSecret, "http://127.0.0.1:0"))
+}
+
+func TestCorePackageNamesSkipGitea(t *testing.T) {
+	names, err := CorePackageNames(v1alpha1.BuildCustomizationSpec{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{v1alpha1.IngressNginxPackageName, v1alpha1.GiteaPackageName, v1alpha1.ArgoCDPackageName}, names)
+
+	names, err = CorePackageNames(v1alpha1.BuildCustomizationSpec{SkipGitea: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{v1alpha1.IngressNginxPackageName, v1alpha1.ArgoCDPackageName}, names)
+}
+
+func TestCorePackageNamesFlux(t *testing.T) {
+	names, err := CorePackageNames(v1alpha1.BuildCustomizationSpec{GitOpsEngine: gitops.FluxEngineName})
+	require.NoError(t, err)
+	assert.Equal(t, []string{v1alpha1.IngressNginxPackageName, v1alpha1.GiteaPackageName, v1alpha1.FluxPackageName}, names)
+
+	_, err = CorePackageNames(v1alpha1.BuildCustomizationSpec{GitOpsEngine: "jenkins"})
+	assert.Error(t, err)
+}
+
+func TestReconcileCustomPkgReplicate(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build()
+	r := LocalbuildReconciler{Client: kubeClient, Scheme: k8s.GetScheme()}
+	resource := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "localdev", UID: "abc"}}
+	app := []byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app
+  namespace: argocd
+spec:
+  source:
+    repoURL: cnoe://manifests
+`)
+
+	cases := map[string]struct {
+		pkgUrl    string
+		replicate bool
+	}{
+		"local":          {replicate: true},
+		"remote":         {pkgUrl: "https://github.com/cnoe-io/stacks//ref-implementation", replicate: true},
+		"remote direct":  {pkgUrl: "https://github.com/cnoe-io/stacks//ref-implementation?replicate=false", replicate: false},
+		"invalid values": {pkgUrl: "https://github.com/cnoe-io/stacks//ref-implementation?replicate=no", replicate: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var remote *util.KustomizeRemote
+			if c.pkgUrl != "" {
+				var err error
+				remote, err = util.NewKustomizeRemote(c.pkgUrl)
+				require.NoError(t, err)
+			}
+			require.NoError(t, r.reconcileCustomPkg(ctx, resource, app, "app.yaml", remote, 0, c.pkgUrl))
+
+			pkg := v1alpha1.CustomPackage{}
+			require.NoError(t, kubeClient.Get(ctx, types.NamespacedName{
+				Namespace: "idpbuilder-localdev",
+				Name:      getCustomPackageName("app.yaml", "my-app"),
+			}, &pkg))
+			assert.Equal(t, c.replicate, pkg.Spec.Replicate)
+		})
+	}
+}
+
+func TestReconcileCustomPkgNameCollision(t *testing.T) {
+	ctx := context.Background()
+	controller := true
+	other := &v1alpha1.CustomPackage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getCustomPackageName("app.yaml", "my-app"),
+			Namespace: "idpbuilder-team-a",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(), Kind: "Localbuild", Name: "team-a", UID: "def", Controller: &controller,
+			}},
+		},
+		Spec: v1alpha1.CustomPackageSpec{ArgoCD: v1alpha1.ArgoCDPackageSpec{Name: "my-app"}},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(other).Build()
+	r := LocalbuildReconciler{Client: kubeClient, Scheme: k8s.GetScheme()}
+	resource := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-b", UID: "abc"}}
+
+	app := []byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app
+  namespace: argocd
+spec:
+  source:
+    repoURL: cnoe://manifests
+`)
+	err := r.reconcileCustomPkg(ctx, resource, app, "app.yaml", nil, 0, "")
+	assert.ErrorContains(t, err, "already deployed by localbuild team-a")
+
+	// packages of the same localbuild are handled by priority
+	own := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-a", UID: "def"}}
+	assert.NoError(t, r.reconcileCustomPkg(ctx, own, app, "app.yaml", nil, 0, ""))
+
+	// the same name in another namespace does not collide
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(other), other))
+	other.Spec.ArgoCD.Namespace = "team-a"
+	require.NoError(t, kubeClient.Update(ctx, other))
+	assert.NoError(t, r.reconcileCustomPkg(ctx, resource, app, "app.yaml", nil, 0, ""))
+}
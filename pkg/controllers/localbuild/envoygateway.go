@@ -0,0 +1,50 @@
+package localbuild
+
+import (
+	"context"
+	"embed"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// only the Gateway config of idpbuilder is embedded. the upstream manifests are pulled into the manifest cache.
+//
+//go:embed resources/envoy-gateway/*
+var installEnvoyGatewayFS embed.FS
+
+func RawEnvoyGatewayInstallResources(templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+	envoyGateway := newEnvoyGatewayInstallation()
+	envoyGateway.customization = config
+	return envoyGateway.Manifests(scheme, templateData)
+}
+
+func newEnvoyGatewayInstallation() EmbeddedInstallation {
+	return EmbeddedInstallation{
+		name:              "Envoy Gateway",
+		packageName:       v1alpha1.EnvoyGatewayPackageName,
+		resourceFS:        installEnvoyGatewayFS,
+		configPath:        "resources/envoy-gateway",
+		namespace:         globals.EnvoyGatewayNamespace,
+		versionDeployment: "envoy-gateway",
+		versionContainer:  "envoy-gateway",
+	}
+}
+
+func (r *LocalbuildReconciler) ReconcileEnvoyGateway(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	envoyGateway := newEnvoyGatewayInstallation()
+
+	v, ok := resource.Spec.PackageConfigs.CorePackageCustomization[v1alpha1.EnvoyGatewayPackageName]
+	if ok {
+		envoyGateway.customization = v
+	}
+
+	if result, err := envoyGateway.Install(ctx, resource, r.Client, r.WatchClient, r.Scheme, r.Config); err != nil {
+		return result, err
+	}
+
+	resource.Status.EnvoyGateway.Available = true
+	return ctrl.Result{}, nil
+}
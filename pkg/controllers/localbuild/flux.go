@@ -0,0 +1,51 @@
+package localbuild
+
+import (
+	"context"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// fluxSourceControllerName is the Flux controller that clones repositories.
+const fluxSourceControllerName = "source-controller"
+
+func RawFluxInstallResources(templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+	flux := newFluxInstallation()
+	flux.customization = config
+	return flux.Manifests(scheme, templateData)
+}
+
+func customizeFluxManifests(manifests [][]byte, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	return customizeProxy(manifests, fluxSourceControllerName, globals.FluxNamespace, cfg)
+}
+
+// newFluxInstallation returns the installation of the Flux controllers that sync packages. Nothing is embedded, the
+// manifests are pulled into the manifest cache. The version is always the version selected from the cache, because
+// each controller has its own image version.
+func newFluxInstallation() EmbeddedInstallation {
+	return EmbeddedInstallation{
+		name:               "Flux",
+		packageName:        v1alpha1.FluxPackageName,
+		namespace:          globals.FluxNamespace,
+		customizeManifests: customizeFluxManifests,
+	}
+}
+
+func (r *LocalbuildReconciler) ReconcileFlux(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	flux := newFluxInstallation()
+
+	v, ok := resource.Spec.PackageConfigs.CorePackageCustomization[v1alpha1.FluxPackageName]
+	if ok {
+		flux.customization = v
+	}
+
+	if result, err := flux.Install(ctx, resource, r.Client, r.WatchClient, r.Scheme, r.Config); err != nil {
+		return result, err
+	}
+
+	resource.Status.Flux.Available = true
+	return ctrl.Result{}, nil
+}
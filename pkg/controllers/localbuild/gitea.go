@@ -5,27 +5,177 @@ import (
 	"embed"
 	"encoding/base64"
 	"fmt"
-	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"net/http"
+	"path"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/yaml"
 )
 
 //go:embed resources/gitea/k8s/*
 var installGiteaFS embed.FS
 
-func RawGiteaInstallResources(templateData any, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
-	return k8s.BuildCustomizedManifests(config.FilePath, "resources/gitea/k8s", installGiteaFS, scheme, templateData)
+const (
+	giteaDeploymentName = "my-gitea"
+	giteaServiceName    = "my-gitea-http"
+	giteaHTTPPort       = 3000
+	giteaDataPVCName    = "gitea-shared-storage"
+	giteaDataPVName     = "idpbuilder-gitea-data"
+	// OCI images pushed to the Gitea registry can be large.
+	giteaMaxBodySize = "1024m"
+	// giteaRepoCredsName is the Argo CD secret with the read-only token used to pull private repositories of Gitea.
+	giteaRepoCredsName = "idpbuilder-gitea-repo-creds"
+)
+
+func RawGiteaInstallResources(templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+	gitea := newGiteaInstallation()
+	gitea.customization = config
+	return gitea.Manifests(scheme, templateData)
+}
+
+// giteaRoutes returns the routes of the Gitea UI, API and OCI registry.
+func giteaRoutes(cfg v1alpha1.BuildCustomizationSpec, _ ingress.Provider) []ingress.Route {
+	route := func(name, subdomain, path, rewrite string) ingress.Route {
+		return ingress.Route{
+			Name:        name,
+			Namespace:   util.GiteaNamespace,
+			Subdomain:   subdomain,
+			Path:        path,
+			Rewrite:     rewrite,
+			Service:     giteaServiceName,
+			Port:        giteaHTTPPort,
+			MaxBodySize: giteaMaxBodySize,
+		}
+	}
+	if cfg.UsePathRouting {
+		// OCI clients always use /v2 at the root of the host.
+		return []ingress.Route{
+			route("my-gitea-path-oci-root", "", "/v2", ""),
+			route("my-gitea-path-oci-repo", "", "/v2/gitea", "/v2"),
+			route("my-gitea-path", "", "/gitea", "/"),
+		}
+	}
+	return []ingress.Route{route("my-gitea-custom", "gitea", "/", "")}
+}
+
+func customizeGiteaManifests(manifests [][]byte, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	manifests, err := customizeProxy(manifests, giteaDeploymentName, util.GiteaNamespace, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return customizeGiteaStorage(manifests, cfg)
+}
+
+// customizeGiteaStorage binds the gitea volume claim to a local volume backed by the host data directory
+// so the data survives recreating the cluster.
+func customizeGiteaStorage(manifests [][]byte, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	if cfg.GiteaDataDir == "" {
+		return manifests, nil
+	}
+
+	var storage resource.Quantity
+	out := make([][]byte, 0, len(manifests)+1)
+	for i := range manifests {
+		nodes, err := kio.FromBytes(manifests[i])
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifests: %w", err)
+		}
+
+		for _, n := range nodes {
+			if n.GetKind() != "PersistentVolumeClaim" || n.GetName() != giteaDataPVCName {
+				continue
+			}
+			request, err := n.GetString("spec.resources.requests.storage")
+			if err != nil {
+				return nil, fmt.Errorf("getting storage request of %s: %w", giteaDataPVCName, err)
+			}
+			if storage, err = resource.ParseQuantity(request); err != nil {
+				return nil, fmt.Errorf("parsing storage request of %s: %w", giteaDataPVCName, err)
+			}
+			// an empty storage class disables dynamic provisioning
+			err = n.PipeE(kyaml.LookupCreate(kyaml.MappingNode, "spec"), kyaml.SetField("storageClassName", kyaml.NewStringRNode("")))
+			if err != nil {
+				return nil, err
+			}
+			err = n.PipeE(kyaml.LookupCreate(kyaml.MappingNode, "spec"), kyaml.SetField("volumeName", kyaml.NewStringRNode(giteaDataPVName)))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		s, err := kio.StringAll(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("converting manifests to string: %w", err)
+		}
+		out = append(out, []byte(s))
+	}
+
+	if storage.IsZero() {
+		return nil, fmt.Errorf("persistent volume claim %s not found", giteaDataPVCName)
+	}
+
+	b, err := yaml.Marshal(giteaDataVolume(storage))
+	if err != nil {
+		return nil, fmt.Errorf("marshaling gitea data volume: %w", err)
+	}
+	return append(out, b), nil
+}
+
+func giteaDataVolume(storage resource.Quantity) corev1.PersistentVolume {
+	return corev1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "PersistentVolume",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: giteaDataPVName,
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: storage,
+			},
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			StorageClassName:              "",
+			ClaimRef: &corev1.ObjectReference{
+				Namespace: util.GiteaNamespace,
+				Name:      giteaDataPVCName,
+			},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				Local: &corev1.LocalVolumeSource{
+					Path: path.Join(util.GiteaDataNodeDir, util.GiteaDataSubDir),
+				},
+			},
+			// the host directory is mounted on the node that receives requests from the host, see pkg/kind.
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      ingress.NodeLabelKey,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{ingress.NodeLabelValue},
+						}},
+					}},
+				},
+			},
+		},
+	}
 }
 
 func (r *LocalbuildReconciler) newGiteaAdminSecret(password string) corev1.Secret {
@@ -37,21 +187,23 @@ func (r *LocalbuildReconciler) newGiteaAdminSecret(password string) corev1.Secre
 	return obj
 }
 
+func newGiteaInstallation() EmbeddedInstallation {
+	return EmbeddedInstallation{
+		name:               "Gitea",
+		packageName:        v1alpha1.GiteaPackageName,
+		resourcePath:       "resources/gitea/k8s",
+		resourceFS:         installGiteaFS,
+		namespace:          util.GiteaNamespace,
+		customizeManifests: customizeGiteaManifests,
+		routes:             giteaRoutes,
+		versionDeployment:  giteaDeploymentName,
+		versionContainer:   "gitea",
+	}
+}
+
 func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
 	logger := log.FromContext(ctx, "installer", "gitea")
-	gitea := EmbeddedInstallation{
-		name:         "Gitea",
-		resourcePath: "resources/gitea/k8s",
-		resourceFS:   installGiteaFS,
-		namespace:    util.GiteaNamespace,
-		monitoredResources: map[string]schema.GroupVersionKind{
-			"my-gitea": {
-				Group:   "apps",
-				Version: "v1",
-				Kind:    "Deployment",
-			},
-		},
-	}
+	gitea := newGiteaInstallation()
 
 	sec := util.GiteaAdminSecretObject()
 	err := r.Client.Get(ctx, types.NamespacedName{
@@ -61,7 +213,7 @@ func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Requ
 
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			genPassword, err := util.GeneratePassword()
+			genPassword, err := util.GiteaAdminPassword(r.Config.GiteaDataDir)
 			if err != nil {
 				return ctrl.Result{}, fmt.Errorf("generating gitea password: %w", err)
 			}
@@ -82,7 +234,7 @@ func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Requ
 		gitea.customization = v
 	}
 
-	if result, err := gitea.Install(ctx, resource, r.Client, r.Scheme, r.Config); err != nil {
+	if result, err := gitea.Install(ctx, resource, r.Client, r.WatchClient, r.Scheme, r.Config); err != nil {
 		return result, err
 	}
 
@@ -108,6 +260,11 @@ func (r *LocalbuildReconciler) ReconcileGitea(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, fmt.Errorf("creating gitea token: %w", err)
 	}
 
+	err = r.reconcileGiteaRepoCreds(ctx, resource, sec, baseUrl)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("creating gitea repo credentials: %w", err)
+	}
+
 	resource.Status.Gitea.ExternalURL = baseUrl
 	resource.Status.Gitea.InternalURL = util.GiteaBaseUrl(r.Config)
 	resource.Status.Gitea.AdminUserSecretName = util.GiteaAdminSecret
@@ -150,3 +307,54 @@ func (r *LocalbuildReconciler) setGiteaToken(ctx context.Context, secret corev1.
 
 	return r.Client.Patch(ctx, &u, client.Apply, client.ForceOwnership, client.FieldOwner(v1alpha1.FieldManager))
 }
+
+// reconcileGiteaRepoCreds lets Argo CD pull private repositories of Gitea with a token that can only read
+// repositories. The token is created once because Gitea does not return the value of existing tokens.
+func (r *LocalbuildReconciler) reconcileGiteaRepoCreds(ctx context.Context, resource *v1alpha1.Localbuild, adminSecret corev1.Secret, baseUrl string) error {
+	if !r.Config.GiteaPrivateRepositories || resource.Spec.PackageConfigs.GitServer.Provider != "" {
+		return nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      giteaRepoCredsName,
+			Namespace: globals.ArgoCDNamespace,
+		},
+	}
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("getting gitea repo credentials: %w", err)
+	}
+	if len(secret.Data["password"]) != 0 {
+		return nil
+	}
+
+	user, ok := adminSecret.Data["username"]
+	if !ok {
+		return fmt.Errorf("username field not found in gitea secret")
+	}
+	pass, ok := adminSecret.Data["password"]
+	if !ok {
+		return fmt.Errorf("password field not found in gitea secret")
+	}
+
+	token, err := util.GetGiteaReadOnlyToken(ctx, baseUrl, string(user), string(pass))
+	if err != nil {
+		return fmt.Errorf("getting gitea read-only token: %w", err)
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[argocdSecretTypeLabel] = "repo-creds"
+		secret.StringData = map[string]string{
+			"type":     "git",
+			"url":      baseUrl,
+			"username": string(user),
+			"password": token,
+		}
+		return nil
+	})
+	return err
+}
@@ -2,6 +2,8 @@ package localbuild
 
 import (
 	"context"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetGiteaToken(t *testing.T) {
@@ -20,3 +23,49 @@ func TestGetGiteaToken(t *testing.T) {
 	_, err := util.GetGiteaToken(ctx, ts.URL, "", "")
 	require.Error(t, err)
 }
+
+func TestCustomizeGiteaStorage(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	}
+
+	manifests, err := RawGiteaInstallResources(cfg, v1alpha1.PackageCustomization{}, k8s.GetScheme())
+	require.NoError(t, err)
+	objs, err := k8s.ConvertRawResourcesToObjects(k8s.GetScheme(), manifests)
+	require.NoError(t, err)
+	for _, o := range objs {
+		_, ok := o.(*corev1.PersistentVolume)
+		require.False(t, ok)
+	}
+
+	cfg.GiteaDataDir = "/home/user/gitea"
+	manifests, err = RawGiteaInstallResources(cfg, v1alpha1.PackageCustomization{}, k8s.GetScheme())
+	require.NoError(t, err)
+	objs, err = k8s.ConvertRawResourcesToObjects(k8s.GetScheme(), manifests)
+	require.NoError(t, err)
+
+	var pvc *corev1.PersistentVolumeClaim
+	var pv *corev1.PersistentVolume
+	for _, o := range objs {
+		switch v := o.(type) {
+		case *corev1.PersistentVolumeClaim:
+			pvc = v
+		case *corev1.PersistentVolume:
+			pv = v
+		}
+	}
+
+	require.NotNil(t, pvc)
+	require.Equal(t, "", *pvc.Spec.StorageClassName)
+	require.Equal(t, giteaDataPVName, pvc.Spec.VolumeName)
+
+	require.NotNil(t, pv)
+	require.Equal(t, giteaDataPVName, pv.Name)
+	require.Equal(t, "/var/lib/idpbuilder/gitea/data", pv.Spec.Local.Path)
+	require.Equal(t, corev1.PersistentVolumeReclaimRetain, pv.Spec.PersistentVolumeReclaimPolicy)
+	require.True(t, pv.Spec.Capacity.Storage().Equal(pvc.Spec.Resources.Requests[corev1.ResourceStorage]))
+	require.Equal(t, pvc.Name, pv.Spec.ClaimRef.Name)
+	require.Equal(t, pvc.Namespace, pv.Spec.ClaimRef.Namespace)
+}
@@ -3,45 +3,164 @@ package localbuild
 import (
 	"context"
 	"embed"
-	"errors"
 	"fmt"
-	"sync"
-	"time"
+	"strings"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util/fs"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var timeout = time.After(5 * time.Minute)
-
 type EmbeddedInstallation struct {
 	name         string
+	packageName  string
 	resourcePath string
 	namespace    string
 
-	// skips waiting on expected resources to become ready
+	// the deployment and container whose image tag is the version of the package.
+	versionDeployment string
+	versionContainer  string
+
+	// skips waiting on installed resources to become ready. see k8s.HasReadinessCheck for the resources that are waited on.
 	skipReadinessCheck bool
 
-	// name and gvk pair for resources that need to be monitored
-	monitoredResources map[string]schema.GroupVersionKind
-	customization      v1alpha1.PackageCustomization
-	resourceFS         embed.FS
+	customization v1alpha1.PackageCustomization
+	resourceFS    embed.FS
+	// configPath is a directory in resourceFS with manifests that are added to the manifests of every version of the
+	// package. It is used by packages whose upstream manifests are only in the manifest cache.
+	configPath string
+
+	// routes returns the routes that expose the package. They are rendered by the ingress provider selected by the build
+	// settings.
+	routes func(cfg v1alpha1.BuildCustomizationSpec, provider ingress.Provider) []ingress.Route
 
-	// resources that need to be created without using static manifests or gitops
+	// resources that need to be created without using static manifests or gitops. They are only created, never updated or
+	// pruned, because they hold generated values such as passwords.
 	unmanagedResources []client.Object
+
+	// customizes rendered manifests based on build settings, e.g. proxy settings. It is applied after user provided customizations.
+	customizeManifests func(manifests [][]byte, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error)
+}
+
+// NewCorePackageInstallation returns the installation of the embedded app name with customization applied.
+func NewCorePackageInstallation(name string, customization v1alpha1.PackageCustomization) (*EmbeddedInstallation, error) {
+	var e EmbeddedInstallation
+	switch name {
+	case v1alpha1.ArgoCDPackageName:
+		e = newArgocdInstallation()
+	case v1alpha1.GiteaPackageName:
+		e = newGiteaInstallation()
+	case v1alpha1.IngressNginxPackageName:
+		e = newNginxInstallation()
+	case v1alpha1.EnvoyGatewayPackageName:
+		e = newEnvoyGatewayInstallation()
+	case v1alpha1.FluxPackageName:
+		e = newFluxInstallation()
+	default:
+		return nil, fmt.Errorf("unsupported embedded app name %s", name)
+	}
+	e.customization = customization
+	return &e, nil
+}
+
+// Name returns the display name of the package.
+func (e *EmbeddedInstallation) Name() string {
+	return e.name
+}
+
+// Namespace returns the namespace the package is installed in.
+func (e *EmbeddedInstallation) Namespace() string {
+	return e.namespace
+}
+
+// Manifests renders the manifests of the package with the customization and build settings applied. The manifests are
+// read from the manifest cache if the customization selects a version, and from the binary otherwise. The routes of
+// the package are added in the form of the ingress provider selected by templateData.
+func (e *EmbeddedInstallation) Manifests(scheme *runtime.Scheme, templateData v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	var resourceFS fs.FS = e.resourceFS
+	resourcePath := e.resourcePath
+	if e.customization.ManifestsPath != "" {
+		resourceFS, resourcePath = fs.DirFS(e.customization.ManifestsPath), "."
+	}
+	if resourcePath == "" {
+		return nil, fmt.Errorf("manifests of %s are not embedded, select a version from the manifest cache", e.name)
+	}
+
+	var extra [][]byte
+	if e.configPath != "" {
+		config, err := fs.ConvertFSToBytes(e.resourceFS, e.configPath, templateData)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, config...)
+	}
+	if e.routes != nil {
+		provider, err := ingress.ForBuild(templateData)
+		if err != nil {
+			return nil, err
+		}
+		routes, err := provider.RouteManifests(e.routes(templateData, provider), templateData)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, routes...)
+	}
+
+	manifests, err := k8s.BuildCustomizedManifests(e.customization.FilePath, resourcePath, resourceFS, scheme, templateData, extra...)
+	if err != nil {
+		return nil, err
+	}
+	if e.customizeManifests == nil {
+		return manifests, nil
+	}
+	return e.customizeManifests(manifests, templateData)
+}
+
+func (e *EmbeddedInstallation) installResources(scheme *runtime.Scheme, templateData v1alpha1.BuildCustomizationSpec) ([]client.Object, error) {
+	manifests, err := e.Manifests(scheme, templateData)
+	if err != nil {
+		return nil, err
+	}
+	return k8s.ConvertRawResourcesToObjects(scheme, manifests)
+}
+
+// Version returns the version of the package defined by objs, the image tag of its main container. The version selected
+// by the customization takes precedence, because it may not match an image tag, e.g. the Gitea chart version. It
+// returns an empty string if the container is not found.
+func (e *EmbeddedInstallation) Version(objs []client.Object) string {
+	if e.customization.Version != "" {
+		return e.customization.Version
+	}
+	for _, obj := range objs {
+		d, ok := obj.(*appsv1.Deployment)
+		if !ok || d.Name != e.versionDeployment {
+			continue
+		}
+		for _, c := range d.Spec.Template.Spec.Containers {
+			if c.Name == e.versionContainer {
+				return imageTag(c.Image)
+			}
+		}
+	}
+	return ""
 }
 
-func (e *EmbeddedInstallation) installResources(scheme *runtime.Scheme, templateData any) ([]client.Object, error) {
-	return k8s.BuildCustomizedObjects(e.customization.FilePath, e.resourcePath, e.resourceFS, scheme, templateData)
+// imageTag returns the tag of image without the digest, or an empty string if it has none.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
 }
 
 func (e *EmbeddedInstallation) newNamespace(namespace string) *corev1.Namespace {
@@ -52,107 +171,101 @@ func (e *EmbeddedInstallation) newNamespace(namespace string) *corev1.Namespace
 	}
 }
 
-func (e *EmbeddedInstallation) Install(ctx context.Context, resource *v1alpha1.Localbuild, cli client.Client, sc *runtime.Scheme, cfg v1alpha1.BuildCustomizationSpec) (ctrl.Result, error) {
+// Install applies the rendered manifests of the package with InstallManifests and records the installed version in the
+// status of resource.
+func (e *EmbeddedInstallation) Install(ctx context.Context, resource *v1alpha1.Localbuild, cli client.Client, watchClient client.WithWatch, sc *runtime.Scheme, cfg v1alpha1.BuildCustomizationSpec) (ctrl.Result, error) {
+	manifests, err := e.Manifests(sc, cfg)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	version, err := e.InstallManifests(ctx, cli, watchClient, sc, manifests)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	SetCorePackageVersion(&resource.Status, e.packageName, version)
+	return ctrl.Result{}, nil
+}
+
+// InstallManifests applies manifests with server-side apply, prunes resources that were removed from the manifests,
+// then waits for the resources to become ready. The applied manifests are recorded in the cluster, see
+// GetManifestRecord. watchClient is used to watch the resources while waiting. It returns the version of the package
+// found in manifests.
+func (e *EmbeddedInstallation) InstallManifests(ctx context.Context, cli client.Client, watchClient client.WithWatch, sc *runtime.Scheme, manifests [][]byte) (string, error) {
 	logger := log.FromContext(ctx)
 
 	nsClient := client.NewNamespacedClient(cli, e.namespace)
-	installObjs, err := e.installResources(sc, cfg)
+	installObjs, err := k8s.ConvertRawResourcesToObjects(sc, manifests)
 	if err != nil {
-		return ctrl.Result{}, err
+		return "", err
 	}
+	version := e.Version(installObjs)
 
 	if err = k8s.EnsureNamespace(ctx, nsClient, e.namespace); err != nil {
-		return ctrl.Result{}, err
+		return "", err
 	}
 
 	for i := range e.unmanagedResources {
 		err = k8s.EnsureObject(ctx, nsClient, e.unmanagedResources[i], e.namespace)
 		if err != nil {
-			return ctrl.Result{}, err
+			return "", err
 		}
 	}
 
-	sch := runtime.NewScheme()
-	appsv1.AddToScheme(sch)
-
+	applied := make([]corev1.ObjectReference, 0, len(installObjs))
 	for _, obj := range installObjs {
-		// Create object
-		if err = k8s.EnsureObject(ctx, nsClient, obj, e.namespace); err != nil {
-			return ctrl.Result{}, err
+		u, aErr := k8s.ApplyObject(ctx, nsClient, obj)
+		if aErr != nil {
+			return "", aErr
 		}
+		applied = append(applied, k8s.ObjectReferenceFor(u))
+	}
+
+	if err = e.prune(ctx, cli, applied); err != nil {
+		return "", err
+	}
+	if err = SaveManifestRecord(ctx, cli, e.namespace, AppliedManifestsName, ManifestRecord{Version: version, Manifests: manifests}); err != nil {
+		return "", err
 	}
 
 	// return early if readiness check is disabled
 	if e.skipReadinessCheck {
-		return ctrl.Result{}, nil
+		return version, nil
 	}
 
-	// wait for expected resources to become available
-	errCh := make(chan error)
-	var wg sync.WaitGroup
-
-	for _, obj := range installObjs {
-		if gvk, ok := e.monitoredResources[obj.GetName()]; ok {
-			if obj.GetObjectKind().GroupVersionKind() != gvk {
-				continue
-			}
+	timeout := k8s.DefaultReadinessTimeout
+	if e.customization.ReadinessTimeout != nil {
+		timeout = e.customization.ReadinessTimeout.Duration
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-			wg.Add(1)
-			go func(obj client.Object, gvk schema.GroupVersionKind) {
-				defer wg.Done()
-
-				gvkObj, err := sch.New(gvk)
-				if err != nil {
-					errCh <- err
-					return
-				}
-
-				for {
-					if gotObj, ok := gvkObj.(client.Object); ok {
-						if err := cli.Get(ctx, types.NamespacedName{Namespace: e.namespace, Name: obj.GetName()}, gotObj); err != nil {
-							errCh <- err
-							return
-						}
-
-						switch t := gotObj.(type) {
-						case *appsv1.Deployment:
-							if t.Status.AvailableReplicas >= 1 {
-								logger.V(1).Info(t.GetName(), "deployment", t.Status.AvailableReplicas)
-								return
-							}
-						case *appsv1.StatefulSet:
-							if t.Status.AvailableReplicas >= 1 {
-								logger.V(1).Info(t.GetName(), "statefulset", t.Status.AvailableReplicas)
-								return
-							}
-						}
-					}
-
-					logger.Info(fmt.Sprintf("Waiting for %s %s to become ready", gvk.Kind, obj.GetName()))
-					time.Sleep(30 * time.Second)
-				}
-			}(obj, gvk)
-		}
+	logger.V(1).Info(fmt.Sprintf("Waiting for %s resources to become ready", e.name), "timeout", timeout)
+	if err = k8s.WaitForReady(waitCtx, watchClient, e.namespace, installObjs); err != nil {
+		logger.Error(err, fmt.Sprintf("%s resources are not ready", e.name))
+		return "", fmt.Errorf("waiting for %s resources: %w", e.name, err)
 	}
+	logger.V(1).Info(fmt.Sprintf("%s is ready!", e.name))
 
-	go func() {
-		wg.Wait()
-		close(errCh)
-	}()
+	return version, nil
+}
 
-	select {
-	case <-timeout:
-		err := errors.New("Timeout")
-		logger.Error(err, fmt.Sprintf("Didn't reconcile %s on time", e.name))
-		return ctrl.Result{}, err
-	case err, errOccurred := <-errCh:
-		if !errOccurred {
-			logger.V(1).Info(fmt.Sprintf("%s is ready!", e.name))
-		} else {
-			logger.Error(err, fmt.Sprintf("failed to reconcile the %s resources", e.name))
-			return ctrl.Result{}, err
-		}
-	}
+// prune deletes the objects installed by a previous release that are not part of applied, then records applied in the
+// inventory of the package namespace.
+func (e *EmbeddedInstallation) prune(ctx context.Context, cli client.Client, applied []corev1.ObjectReference) error {
+	logger := log.FromContext(ctx)
 
-	return ctrl.Result{}, nil
+	previous, err := k8s.GetInventory(ctx, cli, e.namespace)
+	if err != nil {
+		return err
+	}
+	pruned, err := k8s.Prune(ctx, cli, previous, applied)
+	for _, ref := range pruned {
+		logger.Info(fmt.Sprintf("Pruned %s object removed from the manifests", e.name), "kind", ref.Kind,
+			"namespace", ref.Namespace, "name", ref.Name)
+	}
+	if err != nil {
+		return err
+	}
+	return k8s.UpdateInventory(ctx, cli, e.namespace, applied)
 }
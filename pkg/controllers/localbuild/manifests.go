@@ -0,0 +1,128 @@
+package localbuild
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// AppliedManifestsName is the name of the secret in the namespace of a core package that records the manifests
+	// applied last.
+	AppliedManifestsName = "idpbuilder-manifests"
+	// PreviousManifestsName is the name of the secret that records the manifests applied before the last upgrade.
+	PreviousManifestsName = "idpbuilder-manifests-previous"
+
+	manifestRecordVersionKey   = "version"
+	manifestRecordManifestsKey = "manifests.json.gz"
+)
+
+// ManifestRecord is a set of core package manifests stored in the cluster. It is stored in a secret, because
+// manifests may contain credentials.
+type ManifestRecord struct {
+	Version   string
+	Manifests [][]byte
+}
+
+// GetManifestRecord returns the record stored in the secret name in namespace, or nil if it does not exist.
+func GetManifestRecord(ctx context.Context, kubeClient client.Client, namespace, name string) (*ManifestRecord, error) {
+	sec := corev1.Secret{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &sec)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting manifest record %s/%s: %w", namespace, name, err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(sec.Data[manifestRecordManifestsKey]))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest record %s/%s: %w", namespace, name, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest record %s/%s: %w", namespace, name, err)
+	}
+	rec := &ManifestRecord{Version: string(sec.Data[manifestRecordVersionKey])}
+	if err = json.Unmarshal(data, &rec.Manifests); err != nil {
+		return nil, fmt.Errorf("reading manifest record %s/%s: %w", namespace, name, err)
+	}
+	return rec, nil
+}
+
+// SaveManifestRecord stores rec in the secret name in namespace.
+func SaveManifestRecord(ctx context.Context, kubeClient client.Client, namespace, name string, rec ManifestRecord) error {
+	data, err := json.Marshal(rec.Manifests)
+	if err != nil {
+		return fmt.Errorf("marshaling manifest record %s/%s: %w", namespace, name, err)
+	}
+	buf := bytes.Buffer{}
+	w := gzip.NewWriter(&buf)
+	if _, err = w.Write(data); err != nil {
+		return fmt.Errorf("compressing manifest record %s/%s: %w", namespace, name, err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("compressing manifest record %s/%s: %w", namespace, name, err)
+	}
+
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, kubeClient, sec, func() error {
+		sec.Data = map[string][]byte{
+			manifestRecordVersionKey:   []byte(rec.Version),
+			manifestRecordManifestsKey: buf.Bytes(),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving manifest record %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// CorePackageVersion returns the installed version of the core package name recorded in status.
+func CorePackageVersion(status v1alpha1.LocalbuildStatus, name string) string {
+	switch name {
+	case v1alpha1.ArgoCDPackageName:
+		return status.ArgoCD.Version
+	case v1alpha1.GiteaPackageName:
+		return status.Gitea.Version
+	case v1alpha1.IngressNginxPackageName:
+		return status.Nginx.Version
+	case v1alpha1.EnvoyGatewayPackageName:
+		return status.EnvoyGateway.Version
+	case v1alpha1.FluxPackageName:
+		return status.Flux.Version
+	}
+	return ""
+}
+
+// SetCorePackageVersion records version as the installed version of the core package name in status.
+func SetCorePackageVersion(status *v1alpha1.LocalbuildStatus, name, version string) {
+	switch name {
+	case v1alpha1.ArgoCDPackageName:
+		status.ArgoCD.Version = version
+	case v1alpha1.GiteaPackageName:
+		status.Gitea.Version = version
+	case v1alpha1.IngressNginxPackageName:
+		status.Nginx.Version = version
+	case v1alpha1.EnvoyGatewayPackageName:
+		status.EnvoyGateway.Version = version
+	case v1alpha1.FluxPackageName:
+		status.Flux.Version = version
+	}
+}
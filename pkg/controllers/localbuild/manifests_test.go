@@ -0,0 +1,94 @@
+package localbuild
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestManifestRecord(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).Build()
+
+	rec, err := GetManifestRecord(ctx, kubeClient, "gitea", AppliedManifestsName)
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	manifests, err := RawGiteaInstallResources(v1alpha1.BuildCustomizationSpec{}, v1alpha1.PackageCustomization{}, k8s.GetScheme())
+	require.NoError(t, err)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		require.NoError(t, SaveManifestRecord(ctx, kubeClient, "gitea", AppliedManifestsName, ManifestRecord{Version: version, Manifests: manifests}))
+		rec, err = GetManifestRecord(ctx, kubeClient, "gitea", AppliedManifestsName)
+		require.NoError(t, err)
+		assert.Equal(t, &ManifestRecord{Version: version, Manifests: manifests}, rec)
+	}
+}
+
+func TestCorePackageInstallationVersion(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	}
+	for _, name := range []string{v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName, v1alpha1.IngressNginxPackageName} {
+		t.Run(name, func(t *testing.T) {
+			inst, err := NewCorePackageInstallation(name, v1alpha1.PackageCustomization{})
+			require.NoError(t, err)
+			objs, err := inst.installResources(k8s.GetScheme(), cfg)
+			require.NoError(t, err)
+			assert.NotEmpty(t, inst.Version(objs))
+
+			status := v1alpha1.LocalbuildStatus{}
+			SetCorePackageVersion(&status, name, inst.Version(objs))
+			assert.Equal(t, inst.Version(objs), CorePackageVersion(status, name))
+		})
+	}
+
+	_, err := NewCorePackageInstallation("other", v1alpha1.PackageCustomization{})
+	assert.Error(t, err)
+}
+
+func TestCorePackageInstallationManifestsPath(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	}
+	embedded, err := installNginxFS.ReadFile("resources/nginx/k8s/ingress-nginx.yaml")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	pulled := strings.ReplaceAll(string(embedded), "ingress-nginx/controller:v1.13.0", "ingress-nginx/controller:v1.12.0")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ingress-nginx.yaml"), []byte(pulled), 0644))
+
+	inst, err := NewCorePackageInstallation(v1alpha1.IngressNginxPackageName, v1alpha1.PackageCustomization{
+		Version:       "v1.12.0",
+		ManifestsPath: dir,
+	})
+	require.NoError(t, err)
+	objs, err := inst.installResources(k8s.GetScheme(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.12.0", inst.Version(objs))
+
+	inst.customization.Version = ""
+	assert.Equal(t, "v1.12.0", inst.Version(objs))
+}
+
+func TestImageTag(t *testing.T) {
+	cases := map[string]string{
+		"quay.io/argoproj/argocd:v3.1.7":                              "v3.1.7",
+		"registry.k8s.io/ingress-nginx/controller:v1.13.0@sha256:abc": "v1.13.0",
+		"localhost:5000/gitea":                                        "",
+		"gitea":                                                       "",
+	}
+	for image, expected := range cases {
+		assert.Equal(t, expected, imageTag(image), image)
+	}
+}
@@ -6,40 +6,40 @@ import (
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/globals"
-	"github.com/cnoe-io/idpbuilder/pkg/k8s"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 //go:embed resources/nginx/k8s/*
 var installNginxFS embed.FS
 
-func RawNginxInstallResources(templateData any, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
-	return k8s.BuildCustomizedManifests(config.FilePath, "resources/nginx/k8s", installNginxFS, scheme, templateData)
+func RawNginxInstallResources(templateData v1alpha1.BuildCustomizationSpec, config v1alpha1.PackageCustomization, scheme *runtime.Scheme) ([][]byte, error) {
+	nginx := newNginxInstallation()
+	nginx.customization = config
+	return nginx.Manifests(scheme, templateData)
 }
 
-func (r *LocalbuildReconciler) ReconcileNginx(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
-	nginx := EmbeddedInstallation{
-		name:         "Nginx",
-		resourcePath: "resources/nginx/k8s",
-		resourceFS:   installNginxFS,
-		namespace:    globals.NginxNamespace,
-		monitoredResources: map[string]schema.GroupVersionKind{
-			"ingress-nginx-controller": {
-				Group:   "apps",
-				Version: "v1",
-				Kind:    "Deployment",
-			},
-		},
+func newNginxInstallation() EmbeddedInstallation {
+	return EmbeddedInstallation{
+		name:              "Nginx",
+		packageName:       v1alpha1.IngressNginxPackageName,
+		resourcePath:      "resources/nginx/k8s",
+		resourceFS:        installNginxFS,
+		namespace:         globals.NginxNamespace,
+		versionDeployment: "ingress-nginx-controller",
+		versionContainer:  "controller",
 	}
+}
+
+func (r *LocalbuildReconciler) ReconcileNginx(ctx context.Context, req ctrl.Request, resource *v1alpha1.Localbuild) (ctrl.Result, error) {
+	nginx := newNginxInstallation()
 
 	v, ok := resource.Spec.PackageConfigs.CorePackageCustomization[v1alpha1.IngressNginxPackageName]
 	if ok {
 		nginx.customization = v
 	}
 
-	if result, err := nginx.Install(ctx, resource, r.Client, r.Scheme, r.Config); err != nil {
+	if result, err := nginx.Install(ctx, resource, r.Client, r.WatchClient, r.Scheme, r.Config); err != nil {
 		return result, err
 	}
 
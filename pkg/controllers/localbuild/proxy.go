@@ -0,0 +1,247 @@
+package localbuild
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	extraCACertsConfigMapName = "idpbuilder-extra-ca-certs"
+	extraCACertsKey           = "ca.crt"
+	extraCACertsMountPath     = "/etc/idpbuilder/extra-ca"
+	// the system CA certificates of the image and the extra CA certificates are combined into this bundle by an init
+	// container. git and OpenSSL only read a single bundle, so loose files in /etc/ssl/certs are not enough.
+	caBundleVolumeName    = "idpbuilder-ca-bundle"
+	caBundleMountPath     = "/etc/idpbuilder/certs"
+	caBundleFile          = caBundleMountPath + "/ca-certificates.crt"
+	caBundleContainerName = "idpbuilder-ca-bundle"
+)
+
+// caBundleScript writes the first system bundle found in the image, as the locations differ between distributions,
+// followed by the extra CA certificates.
+var caBundleScript = fmt.Sprintf(`for f in /etc/ssl/certs/ca-certificates.crt /etc/pki/tls/certs/ca-bundle.crt /etc/ssl/cert.pem; do
+  if [ -f "$f" ]; then cat "$f"; break; fi
+done > %[1]s
+cat %[2]s/%[3]s >> %[1]s
+`, caBundleFile, extraCACertsMountPath, extraCACertsKey)
+
+// customizeProxy adds proxy environment variables and extra CA certificates to the containers of the named deployment.
+// It is applied after user provided customizations so the settings are always present.
+func customizeProxy(manifests [][]byte, deployment, namespace string, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	if deployment == "" || (cfg.Proxy.HTTPProxy == "" && cfg.Proxy.HTTPSProxy == "" && cfg.ExtraCACerts == "") {
+		return manifests, nil
+	}
+
+	out := make([][]byte, 0, len(manifests)+1)
+	for i := range manifests {
+		nodes, err := kio.FromBytes(manifests[i])
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifests: %w", err)
+		}
+
+		for _, n := range nodes {
+			if n.GetKind() != "Deployment" || n.GetName() != deployment {
+				continue
+			}
+			if err = customizeDeployment(n, cfg); err != nil {
+				return nil, fmt.Errorf("customizing deployment %s: %w", deployment, err)
+			}
+		}
+
+		s, err := kio.StringAll(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("converting manifests to string: %w", err)
+		}
+		out = append(out, []byte(s))
+	}
+
+	if cfg.ExtraCACerts != "" {
+		cm := corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      extraCACertsConfigMapName,
+				Namespace: namespace,
+			},
+			Data: map[string]string{
+				extraCACertsKey: cfg.ExtraCACerts,
+			},
+		}
+		b, err := yaml.Marshal(cm)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling extra CA certificates config map: %w", err)
+		}
+		out = append(out, b)
+	}
+
+	return out, nil
+}
+
+func customizeDeployment(n *kyaml.RNode, cfg v1alpha1.BuildCustomizationSpec) error {
+	containers, err := n.Pipe(kyaml.Lookup("spec", "template", "spec", "containers"))
+	if err != nil {
+		return err
+	}
+	if containers == nil {
+		return fmt.Errorf("no containers found")
+	}
+
+	elems, err := containers.Elements()
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return fmt.Errorf("no containers found")
+	}
+
+	for _, c := range elems {
+		for _, e := range proxyEnv(cfg.Proxy) {
+			err = setElement(c, "env", map[string]string{"name": e.Name, "value": e.Value})
+			if err != nil {
+				return err
+			}
+		}
+		if cfg.ExtraCACerts == "" {
+			continue
+		}
+		// go based tools read SSL_CERT_FILE, git reads GIT_SSL_CAINFO and ignores the OpenSSL default locations.
+		for _, name := range []string{"SSL_CERT_FILE", "GIT_SSL_CAINFO"} {
+			if err = setElement(c, "env", map[string]string{"name": name, "value": caBundleFile}); err != nil {
+				return err
+			}
+		}
+		err = setElement(c, "volumeMounts", map[string]string{
+			"name":      caBundleVolumeName,
+			"mountPath": caBundleMountPath,
+			"readOnly":  "true",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.ExtraCACerts == "" {
+		return nil
+	}
+
+	initContainer, err := caBundleInitContainer(elems[0])
+	if err != nil {
+		return err
+	}
+	initContainers, err := n.Pipe(kyaml.LookupCreate(kyaml.SequenceNode, "spec", "template", "spec", "initContainers"))
+	if err != nil {
+		return err
+	}
+	_, err = initContainers.Pipe(kyaml.ElementSetter{Keys: []string{"name"}, Values: []string{caBundleContainerName}, Element: initContainer.YNode()})
+	if err != nil {
+		return err
+	}
+
+	volumes, err := n.Pipe(kyaml.LookupCreate(kyaml.SequenceNode, "spec", "template", "spec", "volumes"))
+	if err != nil {
+		return err
+	}
+	for _, v := range []string{
+		fmt.Sprintf("name: %s\nconfigMap:\n  name: %s\n", extraCACertsConfigMapName, extraCACertsConfigMapName),
+		fmt.Sprintf("name: %s\nemptyDir: {}\n", caBundleVolumeName),
+	} {
+		volume, err := kyaml.Parse(v)
+		if err != nil {
+			return err
+		}
+		name, err := volume.GetString("name")
+		if err != nil {
+			return err
+		}
+		_, err = volumes.Pipe(kyaml.ElementSetter{Keys: []string{"name"}, Values: []string{name}, Element: volume.YNode()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// caBundleInitContainer returns the init container writing the CA bundle. It uses the image of container c, so the
+// bundle starts with the same system CA certificates the container would use otherwise.
+func caBundleInitContainer(c *kyaml.RNode) (*kyaml.RNode, error) {
+	image, err := c.GetString("image")
+	if err != nil {
+		return nil, fmt.Errorf("getting container image: %w", err)
+	}
+
+	b, err := yaml.Marshal(corev1.Container{
+		Name:    caBundleContainerName,
+		Image:   image,
+		Command: []string{"sh", "-c", caBundleScript},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: extraCACertsConfigMapName, MountPath: extraCACertsMountPath, ReadOnly: true},
+			{Name: caBundleVolumeName, MountPath: caBundleMountPath},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	initContainer, err := kyaml.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	// run with the same restrictions as the container, e.g. the user of the image.
+	if sc := c.Field("securityContext"); sc != nil {
+		if err = initContainer.PipeE(kyaml.SetField("securityContext", sc.Value.Copy())); err != nil {
+			return nil, err
+		}
+	}
+	return initContainer, nil
+}
+
+// setElement replaces the element with the same name in the given list field of n, or appends it.
+func setElement(n *kyaml.RNode, field string, values map[string]string) error {
+	list, err := n.Pipe(kyaml.LookupCreate(kyaml.SequenceNode, field))
+	if err != nil {
+		return err
+	}
+
+	elem := kyaml.NewMapRNode(nil)
+	for _, k := range []string{"name", "value", "mountPath", "subPath", "readOnly"} {
+		v, ok := values[k]
+		if !ok {
+			continue
+		}
+		node := kyaml.NewStringRNode(v)
+		if k == "readOnly" {
+			node = kyaml.NewScalarRNode(v)
+			node.YNode().Tag = kyaml.NodeTagBool
+		}
+		if err = elem.PipeE(kyaml.SetField(k, node)); err != nil {
+			return err
+		}
+	}
+
+	_, err = list.Pipe(kyaml.ElementSetter{Keys: []string{"name"}, Values: []string{values["name"]}, Element: elem.YNode()})
+	return err
+}
+
+func proxyEnv(p v1alpha1.ProxySpec) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, e := range []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: p.HTTPProxy},
+		{Name: "HTTPS_PROXY", Value: p.HTTPSProxy},
+		{Name: "NO_PROXY", Value: p.NoProxy},
+	} {
+		if e.Value == "" {
+			continue
+		}
+		// not every tool reads the upper case variables.
+		env = append(env, e, corev1.EnvVar{Name: strings.ToLower(e.Name), Value: e.Value})
+	}
+	return env
+}
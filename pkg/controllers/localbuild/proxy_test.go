@@ -0,0 +1,105 @@
+package localbuild
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCustomizeProxy(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Protocol: "https",
+		Host:     "cnoe.localtest.me",
+		Port:     "8443",
+	}
+
+	manifests, err := RawGiteaInstallResources(cfg, v1alpha1.PackageCustomization{}, k8s.GetScheme())
+	assert.NoError(t, err)
+	unchanged, err := customizeProxy(manifests, giteaDeploymentName, "gitea", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, manifests, unchanged)
+
+	cfg.Proxy = v1alpha1.ProxySpec{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128", NoProxy: ".svc"}
+	cfg.ExtraCACerts = "-----BEGIN CERTIFICATE-----\nabc\n-----END CERTIFICATE-----\n"
+
+	e := EmbeddedInstallation{
+		resourcePath:       "resources/gitea/k8s",
+		resourceFS:         installGiteaFS,
+		namespace:          "gitea",
+		customizeManifests: customizeGiteaManifests,
+	}
+	objs, err := e.installResources(k8s.GetScheme(), cfg)
+	assert.NoError(t, err)
+
+	var deployment *appsv1.Deployment
+	var cm *corev1.ConfigMap
+	for _, o := range objs {
+		switch v := o.(type) {
+		case *appsv1.Deployment:
+			if v.Name == giteaDeploymentName {
+				deployment = v
+			}
+		case *corev1.ConfigMap:
+			if v.Name == extraCACertsConfigMapName {
+				cm = v
+			}
+		}
+	}
+
+	assert.NotNil(t, cm)
+	assert.Equal(t, "gitea", cm.Namespace)
+	assert.Equal(t, cfg.ExtraCACerts, cm.Data[extraCACertsKey])
+
+	assert.NotNil(t, deployment)
+	c := deployment.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "HTTP_PROXY", Value: "http://proxy:3128"})
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "https_proxy", Value: "http://proxy:3128"})
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "NO_PROXY", Value: ".svc"})
+	// existing variables are kept
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "SSH_LISTEN_PORT", Value: "2222"})
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "SSL_CERT_FILE", Value: caBundleFile})
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "GIT_SSL_CAINFO", Value: caBundleFile})
+	assert.Contains(t, c.VolumeMounts, corev1.VolumeMount{
+		Name:      caBundleVolumeName,
+		MountPath: caBundleMountPath,
+		ReadOnly:  true,
+	})
+
+	var initContainer *corev1.Container
+	for i := range deployment.Spec.Template.Spec.InitContainers {
+		if deployment.Spec.Template.Spec.InitContainers[i].Name == caBundleContainerName {
+			initContainer = &deployment.Spec.Template.Spec.InitContainers[i]
+		}
+	}
+	assert.NotNil(t, initContainer)
+	assert.Equal(t, c.Image, initContainer.Image)
+	assert.Equal(t, c.SecurityContext, initContainer.SecurityContext)
+	assert.Contains(t, initContainer.Command[2], "cat /etc/idpbuilder/extra-ca/ca.crt >> "+caBundleFile)
+
+	volumes := map[string]corev1.Volume{}
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+	assert.NotNil(t, volumes[extraCACertsConfigMapName].ConfigMap)
+	assert.Equal(t, extraCACertsConfigMapName, volumes[extraCACertsConfigMapName].ConfigMap.Name)
+	assert.NotNil(t, volumes[caBundleVolumeName].EmptyDir)
+
+	// applying twice must not duplicate entries
+	raw, err := RawGiteaInstallResources(cfg, v1alpha1.PackageCustomization{}, k8s.GetScheme())
+	assert.NoError(t, err)
+	twice, err := customizeProxy(raw[:len(raw)-1], giteaDeploymentName, "gitea", cfg)
+	assert.NoError(t, err)
+	objs, err = k8s.ConvertRawResourcesToObjects(k8s.GetScheme(), twice)
+	assert.NoError(t, err)
+	for _, o := range objs {
+		if d, ok := o.(*appsv1.Deployment); ok && d.Name == giteaDeploymentName {
+			assert.Equal(t, len(c.Env), len(d.Spec.Template.Spec.Containers[0].Env))
+			assert.Equal(t, len(deployment.Spec.Template.Spec.Volumes), len(d.Spec.Template.Spec.Volumes))
+			assert.Equal(t, len(deployment.Spec.Template.Spec.InitContainers), len(d.Spec.Template.Spec.InitContainers))
+		}
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/cnoe-io/idpbuilder/pkg/controllers/custompackage"
+	"github.com/cnoe-io/idpbuilder/pkg/state"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 
 	"github.com/cnoe-io/idpbuilder/pkg/controllers/gitrepository"
 	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -19,28 +22,42 @@ func RunControllers(
 	exitCh chan error,
 	ctxCancel context.CancelFunc,
 	exitOnSync bool,
+	name string,
 	cfg v1alpha1.BuildCustomizationSpec,
 	tmpDir string,
+	importState *state.Snapshot,
 ) error {
 	logger := log.FromContext(ctx)
 
 	repoMap := util.NewRepoLock()
+	// several Localbuilds can share a cluster, only reconcile the objects of this one.
+	projectNamespace := globals.GetProjectNamespace(name)
+
+	// the manager client reads from informer caches, which cannot be watched directly.
+	watchClient, err := client.NewWithWatch(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		logger.Error(err, "unable to create watch client")
+		return err
+	}
 
 	// Run Localbuild controller
 	if err := (&localbuild.LocalbuildReconciler{
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
-		ExitOnSync: exitOnSync,
-		CancelFunc: ctxCancel,
-		Config:     cfg,
-		TempDir:    tmpDir,
-		RepoMap:    repoMap,
+		Client:      mgr.GetClient(),
+		WatchClient: watchClient,
+		Name:        name,
+		Scheme:      mgr.GetScheme(),
+		ExitOnSync:  exitOnSync,
+		CancelFunc:  ctxCancel,
+		Config:      cfg,
+		TempDir:     tmpDir,
+		RepoMap:     repoMap,
+		ImportState: importState,
 	}).SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to create localbuild controller")
 		return err
 	}
 
-	err := (&gitrepository.RepositoryReconciler{
+	err = (&gitrepository.RepositoryReconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
 		Recorder:        mgr.GetEventRecorderFor("gitrepository-controller"),
@@ -48,17 +65,20 @@ func RunControllers(
 		GitProviderFunc: gitrepository.GetGitProvider,
 		TempDir:         tmpDir,
 		RepoMap:         repoMap,
+		Namespace:       projectNamespace,
 	}).SetupWithManager(mgr, nil)
 	if err != nil {
 		logger.Error(err, "unable to create repo controller")
 	}
 
 	err = (&custompackage.Reconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("custompackage-controller"),
-		TempDir:  tmpDir,
-		RepoMap:  repoMap,
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("custompackage-controller"),
+		Config:    cfg,
+		TempDir:   tmpDir,
+		RepoMap:   repoMap,
+		Namespace: projectNamespace,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		logger.Error(err, "unable to create custom package controller")
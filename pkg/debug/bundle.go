@@ -0,0 +1,396 @@
+package debug
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	redacted = "REDACTED"
+	// values shorter than this are too likely to match unrelated text.
+	minRedactLength = 4
+)
+
+// LogCollector writes kind node logs to a directory.
+type LogCollector interface {
+	CollectLogs(dir string) error
+}
+
+// Bundle collects diagnostic information about a cluster into a gzipped tarball.
+type Bundle struct {
+	ClusterName string
+	// IncludeSecrets disables redaction of secret values.
+	IncludeSecrets bool
+	// Files are added to the bundle as is, e.g. version and doctor output. The key is the file name in the bundle.
+	Files map[string][]byte
+	// KindConfigPath is the path to the rendered kind config. It is skipped if it does not exist.
+	KindConfigPath string
+
+	// Nodes is nil if the container runtime is not available.
+	Nodes LogCollector
+	// KubeClient and Clientset are nil if the cluster cannot be reached.
+	KubeClient client.Client
+	Clientset  kubernetes.Interface
+	Scheme     *runtime.Scheme
+}
+
+type entry struct {
+	name string
+	data []byte
+}
+
+// Namespaces returns the namespaces whose pods, logs and events are collected: the namespaces of the core packages and
+// the project namespace of each localbuild.
+func Namespaces(localBuildNames ...string) []string {
+	namespaces := []string{
+		globals.ArgoCDNamespace,
+		util.GiteaNamespace,
+		globals.NginxNamespace,
+		globals.EnvoyGatewayNamespace,
+		globals.FluxNamespace,
+	}
+	for _, name := range localBuildNames {
+		namespaces = append(namespaces, globals.GetProjectNamespace(name))
+	}
+	return namespaces
+}
+
+// localBuildNames returns the names of the localbuilds in the cluster. A cluster may have several localbuilds with
+// names other than the cluster name.
+func (b *Bundle) localBuildNames(ctx context.Context) ([]string, error) {
+	list := v1alpha1.LocalbuildList{}
+	if err := b.KubeClient.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("listing localbuilds: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for i := range list.Items {
+		names = append(names, list.Items[i].Name)
+	}
+	return names, nil
+}
+
+// Write collects the bundle and writes it to w. Failures of individual collectors do not stop the collection and are
+// recorded in errors.txt in the bundle.
+func (b *Bundle) Write(ctx context.Context, w io.Writer) error {
+	var entries []entry
+	var errs []string
+	add := func(name string, data []byte) {
+		entries = append(entries, entry{name: name, data: data})
+	}
+	record := func(what string, err error) {
+		errs = append(errs, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	names := make([]string, 0, len(b.Files))
+	for n := range b.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		add(n, b.Files[n])
+	}
+
+	if b.KindConfigPath != "" {
+		data, err := os.ReadFile(b.KindConfigPath)
+		switch {
+		case err == nil:
+			add("kind-config.yaml", data)
+		case !os.IsNotExist(err):
+			record("reading kind config", err)
+		}
+	}
+
+	if b.Nodes != nil {
+		if err := b.collectNodeLogs(add); err != nil {
+			record("collecting node logs", err)
+		}
+	}
+
+	var secretValues []string
+	if b.KubeClient != nil {
+		names, err := b.localBuildNames(ctx)
+		if err != nil {
+			record("collecting localbuilds", err)
+			names = []string{b.ClusterName}
+		}
+		namespaces := Namespaces(names...)
+
+		secretValues, err = b.collectSecrets(ctx, namespaces, add)
+		if err != nil {
+			record("collecting secrets", err)
+		}
+		for _, e := range b.collectObjects(ctx, namespaces, add) {
+			record("collecting resources", e)
+		}
+		for _, e := range b.collectPodLogs(ctx, namespaces, add) {
+			record("collecting pod logs", e)
+		}
+	}
+
+	if len(errs) > 0 {
+		add("errors.txt", []byte(strings.Join(errs, "\n")+"\n"))
+	}
+
+	replacer := newRedactor(secretValues)
+	if b.IncludeSecrets {
+		replacer = strings.NewReplacer()
+	}
+	return writeTarball(w, b.root(), entries, replacer)
+}
+
+func (b *Bundle) root() string {
+	return fmt.Sprintf("idpbuilder-debug-%s", b.ClusterName)
+}
+
+func (b *Bundle) collectNodeLogs(add func(string, []byte)) error {
+	dir, err := os.MkdirTemp("", "idpbuilder-debug-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err = b.Nodes.CollectLogs(dir); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		add(path.Join("kind", filepath.ToSlash(rel)), data)
+		return nil
+	})
+}
+
+// collectSecrets adds the secrets of the collected namespaces with their values redacted unless IncludeSecrets is set.
+// It returns the secret values so they can be redacted from the rest of the bundle.
+func (b *Bundle) collectSecrets(ctx context.Context, namespaces []string, add func(string, []byte)) ([]string, error) {
+	var values []string
+	for _, ns := range namespaces {
+		secrets := corev1.SecretList{}
+		if err := b.KubeClient.List(ctx, &secrets, client.InNamespace(ns)); err != nil {
+			return values, fmt.Errorf("listing secrets in %s: %w", ns, err)
+		}
+		for i := range secrets.Items {
+			s := &secrets.Items[i]
+			for k, v := range s.Data {
+				values = append(values, string(v))
+				if !b.IncludeSecrets {
+					s.Data[k] = []byte(redacted)
+				}
+			}
+			for k, v := range s.StringData {
+				values = append(values, v)
+				if !b.IncludeSecrets {
+					s.StringData[k] = redacted
+				}
+			}
+			// may contain the secret data
+			delete(s.Annotations, corev1.LastAppliedConfigAnnotation)
+		}
+		data, err := b.toYaml(&secrets)
+		if err != nil {
+			return values, err
+		}
+		add(path.Join("namespaces", ns, "secrets.yaml"), data)
+	}
+	return values, nil
+}
+
+func (b *Bundle) collectObjects(ctx context.Context, namespaces []string, add func(string, []byte)) []error {
+	var errs []error
+	lists := map[string]client.ObjectList{
+		"localbuilds.yaml":     &v1alpha1.LocalbuildList{},
+		"gitrepositories.yaml": &v1alpha1.GitRepositoryList{},
+		"custompackages.yaml":  &v1alpha1.CustomPackageList{},
+		"applications.yaml":    &argov1alpha1.ApplicationList{},
+	}
+	for name, list := range lists {
+		if err := b.KubeClient.List(ctx, list); err != nil {
+			errs = append(errs, fmt.Errorf("listing %s: %w", strings.TrimSuffix(name, ".yaml"), err))
+			continue
+		}
+		data, err := b.toYaml(list)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		add(path.Join("resources", name), data)
+	}
+
+	for _, ns := range namespaces {
+		for name, list := range map[string]client.ObjectList{
+			"pods.yaml":   &corev1.PodList{},
+			"events.yaml": &corev1.EventList{},
+		} {
+			if err := b.KubeClient.List(ctx, list, client.InNamespace(ns)); err != nil {
+				errs = append(errs, fmt.Errorf("listing %s in %s: %w", strings.TrimSuffix(name, ".yaml"), ns, err))
+				continue
+			}
+			data, err := b.toYaml(list)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			add(path.Join("namespaces", ns, name), data)
+		}
+	}
+	return errs
+}
+
+func (b *Bundle) collectPodLogs(ctx context.Context, namespaces []string, add func(string, []byte)) []error {
+	if b.Clientset == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, ns := range namespaces {
+		pods := corev1.PodList{}
+		if err := b.KubeClient.List(ctx, &pods, client.InNamespace(ns)); err != nil {
+			errs = append(errs, fmt.Errorf("listing pods in %s: %w", ns, err))
+			continue
+		}
+		for _, pod := range pods.Items {
+			statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+			for _, cs := range statuses {
+				name := path.Join("namespaces", ns, "logs", pod.Name, cs.Name+".log")
+				data, err := b.podLogs(ctx, ns, pod.Name, cs.Name, false)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("getting logs of %s/%s/%s: %w", ns, pod.Name, cs.Name, err))
+				} else {
+					add(name, data)
+				}
+				if cs.RestartCount == 0 {
+					continue
+				}
+				data, err = b.podLogs(ctx, ns, pod.Name, cs.Name, true)
+				if err == nil {
+					add(strings.TrimSuffix(name, ".log")+".previous.log", data)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func (b *Bundle) podLogs(ctx context.Context, ns, pod, container string, previous bool) ([]byte, error) {
+	return b.Clientset.CoreV1().Pods(ns).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+	}).DoRaw(ctx)
+}
+
+// toYaml writes the items of list as a multi document YAML with their type information and without managed fields.
+func (b *Bundle) toYaml(list client.ObjectList) ([]byte, error) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	for i := range items {
+		obj, ok := items[i].(client.Object)
+		if !ok {
+			continue
+		}
+		gvk, err := apiutil.GVKForObject(obj, b.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		obj.SetManagedFields(nil)
+
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+		out = append(out, []byte("---\n")...)
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// newRedactor returns a replacer for the given values and their base64 encodings. Longer values are replaced first.
+func newRedactor(values []string) *strings.Replacer {
+	seen := map[string]struct{}{}
+	var all []string
+	for _, v := range values {
+		if len(v) < minRedactLength {
+			continue
+		}
+		for _, s := range []string{v, base64.StdEncoding.EncodeToString([]byte(v))} {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+			seen[s] = struct{}{}
+			all = append(all, s)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return len(all[i]) > len(all[j])
+	})
+
+	pairs := make([]string, 0, len(all)*2)
+	for _, v := range all {
+		pairs = append(pairs, v, redacted)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func writeTarball(w io.Writer, root string, entries []entry, replacer *strings.Replacer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	for _, e := range entries {
+		data := []byte(replacer.Replace(string(e.data)))
+		err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(root, e.name),
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		})
+		if err != nil {
+			return fmt.Errorf("writing header for %s: %w", e.name, err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			return fmt.Errorf("writing %s: %w", e.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,144 @@
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testPassword = "s3cr3t-passw0rd"
+
+type fakeNodes struct {
+	err error
+}
+
+func (f fakeNodes) CollectLogs(dir string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "localdev-control-plane"), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "localdev-control-plane", "kubelet.log"), []byte("login with "+testPassword), 0600)
+}
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	out := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		b, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		out[h.Name] = string(b)
+	}
+	return out
+}
+
+func testBundle(includeSecrets bool, nodes LogCollector) Bundle {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-gitea-abc", Namespace: "gitea"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "gitea"},
+		}},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "gitea-credential", Namespace: "gitea"},
+			Data:       map[string][]byte{"password": []byte(testPassword)},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-gitea-abc.1", Namespace: "gitea"},
+			Message:    "env GITEA_ADMIN_PASSWORD=" + testPassword,
+		},
+		&v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "localdev"}},
+		&v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+		pod,
+	).Build()
+
+	return Bundle{
+		ClusterName:    "localdev",
+		IncludeSecrets: includeSecrets,
+		Files:          map[string][]byte{"version.json": []byte(`{"idpbuilderVersion":"test"}`)},
+		KindConfigPath: filepath.Join("does", "not", "exist"),
+		Nodes:          nodes,
+		KubeClient:     kubeClient,
+		Clientset:      clientsetfake.NewSimpleClientset(pod),
+		Scheme:         k8s.GetScheme(),
+	}
+}
+
+func TestBundleWrite(t *testing.T) {
+	b := testBundle(false, fakeNodes{})
+	out := bytes.Buffer{}
+	assert.NoError(t, b.Write(context.Background(), &out))
+
+	files := readBundle(t, out.Bytes())
+	for _, name := range []string{
+		"version.json",
+		"kind/localdev-control-plane/kubelet.log",
+		"namespaces/gitea/secrets.yaml",
+		"namespaces/gitea/events.yaml",
+		"namespaces/gitea/pods.yaml",
+		"namespaces/gitea/logs/my-gitea-abc/gitea.log",
+		"namespaces/argocd/events.yaml",
+		// the project namespace of every localbuild of the cluster is collected.
+		"namespaces/idpbuilder-localdev/events.yaml",
+		"namespaces/idpbuilder-team-b/events.yaml",
+		"resources/localbuilds.yaml",
+		"resources/gitrepositories.yaml",
+		"resources/custompackages.yaml",
+		"resources/applications.yaml",
+	} {
+		assert.Contains(t, files, "idpbuilder-debug-localdev/"+name)
+	}
+	// a missing kind config is not an error
+	assert.NotContains(t, files, "idpbuilder-debug-localdev/kind-config.yaml")
+	assert.NotContains(t, files, "idpbuilder-debug-localdev/errors.txt")
+
+	assert.Contains(t, files["idpbuilder-debug-localdev/resources/localbuilds.yaml"], "kind: Localbuild")
+	assert.Contains(t, files["idpbuilder-debug-localdev/namespaces/gitea/secrets.yaml"], "gitea-credential")
+	for name, content := range files {
+		assert.NotContains(t, content, testPassword, name)
+	}
+	assert.Contains(t, files["idpbuilder-debug-localdev/kind/localdev-control-plane/kubelet.log"], "login with REDACTED")
+}
+
+func TestBundleWriteIncludeSecrets(t *testing.T) {
+	b := testBundle(true, fakeNodes{err: fmt.Errorf("no nodes")})
+	out := bytes.Buffer{}
+	assert.NoError(t, b.Write(context.Background(), &out))
+
+	files := readBundle(t, out.Bytes())
+	assert.Contains(t, files["idpbuilder-debug-localdev/namespaces/gitea/events.yaml"], testPassword)
+	assert.Contains(t, files["idpbuilder-debug-localdev/errors.txt"], "collecting node logs: no nodes")
+}
+
+func TestNewRedactor(t *testing.T) {
+	r := newRedactor([]string{"abc", "password", "password-longer"})
+	assert.Equal(t, "abc REDACTED REDACTED REDACTED", r.Replace(strings.Join([]string{
+		"abc", "password-longer", "password", "cGFzc3dvcmQ=",
+	}, " ")))
+}
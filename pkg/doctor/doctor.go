@@ -0,0 +1,339 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"sigs.k8s.io/kind/pkg/cluster"
+)
+
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+
+	CheckProvider   = "provider"
+	CheckResources  = "resources"
+	CheckPorts      = "ports"
+	CheckInotify    = "inotify"
+	CheckDNS        = "dns"
+	CheckKubeConfig = "kubeconfig"
+
+	// values from docs/minimum-requirements.md
+	minCPUs        = 4
+	minMemoryBytes = 4 * 1024 * 1024 * 1024
+
+	// values recommended by https://kind.sigs.k8s.io/docs/user/known-issues/#pod-errors-due-to-too-many-open-files
+	minInotifyWatches   = 524288
+	minInotifyInstances = 512
+
+	inotifyWatchesPath   = "/proc/sys/fs/inotify/max_user_watches"
+	inotifyInstancesPath = "/proc/sys/fs/inotify/max_user_instances"
+)
+
+type Options struct {
+	// ClusterName is the name of the kind cluster. Port checks are skipped when this cluster already exists.
+	ClusterName       string
+	Host              string
+	Port              string
+	ExtraPortsMapping string
+	UsePathRouting    bool
+	KubeConfigPath    string
+}
+
+// Run executes all preflight checks and returns their results in a stable order.
+func Run(ctx context.Context, opts Options) []types.Check {
+	checks := make([]types.Check, 0, 6)
+
+	providerCheck, providerOpt, runtimeName := checkProvider()
+	checks = append(checks, providerCheck)
+
+	checks = append(checks, checkResources(ctx, runtimeName))
+
+	if providerOpt != nil && opts.ClusterName != "" && clusterExists(providerOpt, opts.ClusterName) {
+		checks = append(checks, types.Check{
+			Name:    CheckPorts,
+			Status:  StatusPass,
+			Message: fmt.Sprintf("cluster %s already exists, skipping port checks", opts.ClusterName),
+		})
+	} else if ports, err := hostPorts(opts.Port, opts.ExtraPortsMapping); err != nil {
+		checks = append(checks, types.Check{Name: CheckPorts, Status: StatusFail, Message: err.Error()})
+	} else {
+		checks = append(checks, checkPorts(ports))
+	}
+
+	checks = append(checks, checkInotify(runtime.GOOS, os.ReadFile))
+	checks = append(checks, checkDNS(ctx, net.DefaultResolver, dnsName(opts.Host, opts.UsePathRouting)))
+	checks = append(checks, checkKubeConfig(opts.KubeConfigPath))
+	return checks
+}
+
+// HasFailure returns true if any of the checks failed.
+func HasFailure(checks []types.Check) bool {
+	return slices.ContainsFunc(checks, func(c types.Check) bool {
+		return c.Status == StatusFail
+	})
+}
+
+func checkProvider() (types.Check, cluster.ProviderOption, string) {
+	opt, err := util.DetectKindNodeProvider()
+	if err != nil {
+		return types.Check{Name: CheckProvider, Status: StatusFail, Message: err.Error()}, nil, ""
+	}
+
+	name := util.KindNodeRuntimeBinary()
+	if name == "" {
+		return types.Check{Name: CheckProvider, Status: StatusPass, Message: "node provider detected"}, opt, ""
+	}
+	return types.Check{Name: CheckProvider, Status: StatusPass, Message: fmt.Sprintf("using %s", name)}, opt, name
+}
+
+// clusterExists returns whether a kind cluster with the given name exists. Errors listing clusters are reported by
+// the provider check, so they are treated as a missing cluster here.
+func clusterExists(opt cluster.ProviderOption, name string) bool {
+	clusters, err := cluster.NewProvider(opt).List()
+	if err != nil {
+		return false
+	}
+	return slices.Contains(clusters, name)
+}
+
+type runtimeResources struct {
+	CPUs        int64
+	MemoryBytes int64
+}
+
+func checkResources(ctx context.Context, runtimeName string) types.Check {
+	if runtimeName == "" {
+		return types.Check{Name: CheckResources, Status: StatusWarn, Message: "could not determine container runtime resources"}
+	}
+
+	args := []string{"info", "--format", "{{json .}}"}
+	if runtimeName == "podman" {
+		args = []string{"info", "--format", "json"}
+	}
+
+	cCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(cCtx, runtimeName, args...).Output()
+	if err != nil {
+		return types.Check{Name: CheckResources, Status: StatusWarn, Message: fmt.Sprintf("running %s info: %s", runtimeName, err)}
+	}
+
+	r, err := parseRuntimeInfo(runtimeName, out)
+	if err != nil {
+		return types.Check{Name: CheckResources, Status: StatusWarn, Message: err.Error()}
+	}
+	return evaluateResources(r)
+}
+
+func parseRuntimeInfo(runtimeName string, out []byte) (runtimeResources, error) {
+	if runtimeName == "podman" {
+		info := struct {
+			Host struct {
+				CPUs     int64 `json:"cpus"`
+				MemTotal int64 `json:"memTotal"`
+			} `json:"host"`
+		}{}
+		if err := json.Unmarshal(out, &info); err != nil {
+			return runtimeResources{}, fmt.Errorf("parsing %s info: %w", runtimeName, err)
+		}
+		return runtimeResources{CPUs: info.Host.CPUs, MemoryBytes: info.Host.MemTotal}, nil
+	}
+
+	info := struct {
+		NCPU     int64 `json:"NCPU"`
+		MemTotal int64 `json:"MemTotal"`
+	}{}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return runtimeResources{}, fmt.Errorf("parsing %s info: %w", runtimeName, err)
+	}
+	return runtimeResources{CPUs: info.NCPU, MemoryBytes: info.MemTotal}, nil
+}
+
+func evaluateResources(r runtimeResources) types.Check {
+	msg := fmt.Sprintf("%d CPUs, %.1fGiB memory available", r.CPUs, float64(r.MemoryBytes)/(1024*1024*1024))
+	if r.CPUs < minCPUs || r.MemoryBytes < minMemoryBytes {
+		return types.Check{
+			Name:    CheckResources,
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("%s, at least %d CPUs and 4GiB memory are recommended", msg, minCPUs),
+		}
+	}
+	return types.Check{Name: CheckResources, Status: StatusPass, Message: msg}
+}
+
+func hostPorts(port, extraPortsMapping string) ([]kind.PortMapping, error) {
+	ports := []kind.PortMapping{
+		{HostPort: port, Protocol: "TCP"},
+		{HostPort: kind.GiteaSSHHostPort, Protocol: "TCP"},
+	}
+	extra, err := kind.ParsePortMappings(extraPortsMapping)
+	if err != nil {
+		return nil, err
+	}
+	return append(ports, extra...), nil
+}
+
+func checkPorts(ports []kind.PortMapping) types.Check {
+	var unavailable, checked []string
+	for _, p := range ports {
+		desc := fmt.Sprintf("%s/%s", p.HostPort, strings.ToLower(p.Protocol))
+		addr := net.JoinHostPort(p.ListenAddress, p.HostPort)
+		switch p.Protocol {
+		case "UDP":
+			l, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				unavailable = append(unavailable, desc)
+				continue
+			}
+			l.Close()
+		case "TCP":
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				unavailable = append(unavailable, desc)
+				continue
+			}
+			l.Close()
+		default:
+			// the standard library cannot bind sctp sockets.
+			continue
+		}
+		checked = append(checked, desc)
+	}
+
+	if len(unavailable) > 0 {
+		return types.Check{
+			Name:    CheckPorts,
+			Status:  StatusFail,
+			Message: fmt.Sprintf("host ports not available: %s", strings.Join(unavailable, ",")),
+		}
+	}
+	return types.Check{Name: CheckPorts, Status: StatusPass, Message: fmt.Sprintf("host ports available: %s", strings.Join(checked, ","))}
+}
+
+func checkInotify(goos string, readFile func(string) ([]byte, error)) types.Check {
+	if goos != "linux" {
+		return types.Check{Name: CheckInotify, Status: StatusPass, Message: fmt.Sprintf("not applicable on %s", goos)}
+	}
+
+	watches, err := readIntFile(inotifyWatchesPath, readFile)
+	if err != nil {
+		return types.Check{Name: CheckInotify, Status: StatusWarn, Message: err.Error()}
+	}
+	instances, err := readIntFile(inotifyInstancesPath, readFile)
+	if err != nil {
+		return types.Check{Name: CheckInotify, Status: StatusWarn, Message: err.Error()}
+	}
+
+	msg := fmt.Sprintf("max_user_watches=%d, max_user_instances=%d", watches, instances)
+	if watches < minInotifyWatches || instances < minInotifyInstances {
+		return types.Check{
+			Name:   CheckInotify,
+			Status: StatusWarn,
+			Message: fmt.Sprintf("%s, at least max_user_watches=%d and max_user_instances=%d are recommended",
+				msg, minInotifyWatches, minInotifyInstances),
+		}
+	}
+	return types.Check{Name: CheckInotify, Status: StatusPass, Message: msg}
+}
+
+func readIntFile(path string, readFile func(string) ([]byte, error)) (int64, error) {
+	b, err := readFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// dnsName returns a name that must resolve for the CLI to reach in-cluster services.
+func dnsName(host string, usePathRouting bool) string {
+	if usePathRouting {
+		return host
+	}
+	return fmt.Sprintf("gitea.%s", host)
+}
+
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+func checkDNS(ctx context.Context, resolver hostResolver, name string) types.Check {
+	lCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := resolver.LookupHost(lCtx, name)
+	if err != nil {
+		// some resolvers do not answer for *.localtest.me, the cluster still works with an /etc/hosts entry.
+		return types.Check{Name: CheckDNS, Status: StatusWarn, Message: fmt.Sprintf("resolving %s: %s", name, err)}
+	}
+
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil || !ip.IsLoopback() {
+			return types.Check{
+				Name:    CheckDNS,
+				Status:  StatusWarn,
+				Message: fmt.Sprintf("%s resolves to non-loopback address %s", name, a),
+			}
+		}
+	}
+	return types.Check{Name: CheckDNS, Status: StatusPass, Message: fmt.Sprintf("%s resolves to %s", name, strings.Join(addrs, ","))}
+}
+
+func checkKubeConfig(path string) types.Check {
+	if path == "" {
+		return types.Check{Name: CheckKubeConfig, Status: StatusFail, Message: "kubeconfig path is empty"}
+	}
+
+	_, err := os.Stat(path)
+	if err == nil {
+		f, oErr := os.OpenFile(path, os.O_WRONLY, 0)
+		if oErr != nil {
+			return types.Check{Name: CheckKubeConfig, Status: StatusFail, Message: fmt.Sprintf("%s is not writable: %s", path, oErr)}
+		}
+		f.Close()
+		return types.Check{Name: CheckKubeConfig, Status: StatusPass, Message: fmt.Sprintf("%s is writable", path)}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return types.Check{Name: CheckKubeConfig, Status: StatusFail, Message: err.Error()}
+	}
+
+	// the file is created by kind, so the closest existing parent directory must be writable.
+	dir := filepath.Dir(path)
+	for {
+		if _, sErr := os.Stat(dir); sErr == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".idpbuilder-doctor-")
+	if err != nil {
+		return types.Check{Name: CheckKubeConfig, Status: StatusFail, Message: fmt.Sprintf("%s is not writable: %s", dir, err)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return types.Check{Name: CheckKubeConfig, Status: StatusPass, Message: fmt.Sprintf("%s can be created", path)}
+}
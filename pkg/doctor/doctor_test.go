@@ -0,0 +1,145 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/pkg/kind"
+	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	addrs []string
+	err   error
+}
+
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return f.addrs, f.err
+}
+
+func TestCheckInotify(t *testing.T) {
+	cases := map[string]struct {
+		goos      string
+		watches   string
+		instances string
+		expected  string
+	}{
+		"enough":           {goos: "linux", watches: "524288\n", instances: "512\n", expected: StatusPass},
+		"too few watches":  {goos: "linux", watches: "8192\n", instances: "512\n", expected: StatusWarn},
+		"too few instance": {goos: "linux", watches: "524288\n", instances: "128\n", expected: StatusWarn},
+		"unreadable":       {goos: "linux", watches: "", instances: "512\n", expected: StatusWarn},
+		"darwin":           {goos: "darwin", expected: StatusPass},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			readFile := func(p string) ([]byte, error) {
+				v := tc.instances
+				if p == inotifyWatchesPath {
+					v = tc.watches
+				}
+				if v == "" {
+					return nil, os.ErrNotExist
+				}
+				return []byte(v), nil
+			}
+			c := checkInotify(tc.goos, readFile)
+			assert.Equal(t, CheckInotify, c.Name)
+			assert.Equal(t, tc.expected, c.Status)
+		})
+	}
+}
+
+func TestParseRuntimeInfo(t *testing.T) {
+	r, err := parseRuntimeInfo("docker", []byte(`{"NCPU":8,"MemTotal":8589934592}`))
+	assert.NoError(t, err)
+	assert.Equal(t, runtimeResources{CPUs: 8, MemoryBytes: 8589934592}, r)
+	assert.Equal(t, StatusPass, evaluateResources(r).Status)
+
+	r, err = parseRuntimeInfo("podman", []byte(`{"host":{"cpus":2,"memTotal":2147483648}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, runtimeResources{CPUs: 2, MemoryBytes: 2147483648}, r)
+	assert.Equal(t, StatusWarn, evaluateResources(r).Status)
+
+	_, err = parseRuntimeInfo("docker", []byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestCheckPorts(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer l.Close()
+	used := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	c := checkPorts([]kind.PortMapping{{HostPort: used, Protocol: "TCP"}})
+	assert.Equal(t, StatusFail, c.Status)
+	assert.Contains(t, c.Message, used)
+
+	free, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	freePort := strconv.Itoa(free.Addr().(*net.TCPAddr).Port)
+	free.Close()
+
+	c = checkPorts([]kind.PortMapping{{HostPort: freePort, Protocol: "TCP"}, {HostPort: freePort, Protocol: "UDP"}})
+	assert.Equal(t, StatusPass, c.Status)
+}
+
+func TestHostPorts(t *testing.T) {
+	ports, err := hostPorts("8443", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []kind.PortMapping{{HostPort: "8443", Protocol: "TCP"}, {HostPort: kind.GiteaSSHHostPort, Protocol: "TCP"}}, ports)
+
+	ports, err = hostPorts("443", "22:32222,9090:39090/udp")
+	assert.NoError(t, err)
+	assert.Len(t, ports, 4)
+	assert.Equal(t, kind.PortMapping{HostPort: "9090", ContainerPort: "39090", Protocol: "UDP"}, ports[3])
+
+	_, err = hostPorts("443", "22=32222")
+	assert.Error(t, err)
+}
+
+func TestCheckDNS(t *testing.T) {
+	cases := map[string]struct {
+		resolver fakeResolver
+		expected string
+	}{
+		"loopback":     {resolver: fakeResolver{addrs: []string{"127.0.0.1", "::1"}}, expected: StatusPass},
+		"non loopback": {resolver: fakeResolver{addrs: []string{"10.0.0.1"}}, expected: StatusWarn},
+		"error":        {resolver: fakeResolver{err: fmt.Errorf("no such host")}, expected: StatusWarn},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := checkDNS(context.Background(), tc.resolver, dnsName("cnoe.localtest.me", false))
+			assert.Equal(t, tc.expected, c.Status)
+		})
+	}
+	assert.Equal(t, "gitea.cnoe.localtest.me", dnsName("cnoe.localtest.me", false))
+	assert.Equal(t, "cnoe.localtest.me", dnsName("cnoe.localtest.me", true))
+}
+
+func TestCheckKubeConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	c := checkKubeConfig(filepath.Join(dir, "missing", ".kube", "config"))
+	assert.Equal(t, StatusPass, c.Status)
+
+	existing := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(existing, []byte("{}"), 0600))
+	c = checkKubeConfig(existing)
+	assert.Equal(t, StatusPass, c.Status)
+
+	c = checkKubeConfig("")
+	assert.Equal(t, StatusFail, c.Status)
+}
+
+func TestHasFailure(t *testing.T) {
+	assert.False(t, HasFailure([]types.Check{{Status: StatusPass}, {Status: StatusWarn}}))
+	assert.True(t, HasFailure([]types.Check{{Status: StatusPass}, {Status: StatusFail}}))
+}
@@ -0,0 +1,72 @@
+package gitops
+
+import (
+	"fmt"
+
+	argocdapp "github.com/cnoe-io/argocd-api/api/argo/application"
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/resources/localbuild"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const argocdProjectName = "default"
+
+// argocdEngine syncs packages with Argo CD Applications.
+type argocdEngine struct{}
+
+func (argocdEngine) Name() string {
+	return ArgoCDEngineName
+}
+
+func (argocdEngine) PackageName() string {
+	return v1alpha1.ArgoCDPackageName
+}
+
+func (argocdEngine) Namespace() string {
+	return globals.ArgoCDNamespace
+}
+
+func (argocdEngine) IsPackageKind(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == argocdapp.Group && (gvk.Kind == argocdapp.ApplicationKind || gvk.Kind == argocdapp.ApplicationSetKind)
+}
+
+func (argocdEngine) CorePackageObjects(name string) []client.Object {
+	return []client.Object{&argov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: globals.ArgoCDNamespace,
+		},
+	}}
+}
+
+func (argocdEngine) SetCorePackageSpec(obj client.Object, repoURL, namespace string) error {
+	app, ok := obj.(*argov1alpha1.Application)
+	if !ok {
+		return fmt.Errorf("%s is not an argocd application", obj.GetName())
+	}
+	localbuild.SetApplicationSpec(app, repoURL, ".", argocdProjectName, namespace, nil)
+	return nil
+}
+
+func (argocdEngine) CorePackageList() client.ObjectList {
+	return &argov1alpha1.ApplicationList{}
+}
+
+func (argocdEngine) Healthy(obj runtime.Object) bool {
+	app, ok := obj.(*argov1alpha1.Application)
+	return ok && app.Status.Health.Status == "Healthy"
+}
+
+func (argocdEngine) DisableSync(obj client.Object) bool {
+	app, ok := obj.(*argov1alpha1.Application)
+	if !ok || app.Spec.SyncPolicy == nil || app.Spec.SyncPolicy.Automated == nil {
+		return false
+	}
+	app.Spec.SyncPolicy.Automated = nil
+	return true
+}
@@ -0,0 +1,149 @@
+package gitops
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Flux objects are handled as unstructured objects, the Flux API types are not a dependency of idpbuilder.
+const (
+	FluxSourceGroup    = "source.toolkit.fluxcd.io"
+	FluxKustomizeGroup = "kustomize.toolkit.fluxcd.io"
+	FluxHelmGroup      = "helm.toolkit.fluxcd.io"
+
+	FluxGitRepositoryKind = "GitRepository"
+	FluxKustomizationKind = "Kustomization"
+	FluxHelmReleaseKind   = "HelmRelease"
+
+	// core packages are applied as often as Argo CD refreshes applications by default.
+	fluxSourceInterval = "1m"
+	fluxSyncInterval   = "3m"
+	fluxSyncTimeout    = "5m"
+)
+
+var (
+	FluxGitRepositoryGVK = schema.GroupVersionKind{Group: FluxSourceGroup, Version: "v1", Kind: FluxGitRepositoryKind}
+	FluxKustomizationGVK = schema.GroupVersionKind{Group: FluxKustomizeGroup, Version: "v1", Kind: FluxKustomizationKind}
+	FluxHelmReleaseGVK   = schema.GroupVersionKind{Group: FluxHelmGroup, Version: "v2", Kind: FluxHelmReleaseKind}
+)
+
+// fluxEngine syncs packages with Flux GitRepositories and Kustomizations.
+type fluxEngine struct{}
+
+func (fluxEngine) Name() string {
+	return FluxEngineName
+}
+
+func (fluxEngine) PackageName() string {
+	return v1alpha1.FluxPackageName
+}
+
+func (fluxEngine) Namespace() string {
+	return globals.FluxNamespace
+}
+
+func (fluxEngine) IsPackageKind(gvk schema.GroupVersionKind) bool {
+	switch gvk.GroupKind() {
+	case schema.GroupKind{Group: FluxSourceGroup, Kind: FluxGitRepositoryKind},
+		schema.GroupKind{Group: FluxKustomizeGroup, Kind: FluxKustomizationKind},
+		schema.GroupKind{Group: FluxHelmGroup, Kind: FluxHelmReleaseKind}:
+		return true
+	}
+	return false
+}
+
+// CorePackageObjects returns a GitRepository and the Kustomization that applies it, both named after the package.
+func (fluxEngine) CorePackageObjects(name string) []client.Object {
+	objs := make([]client.Object, 0, 2)
+	for _, gvk := range []schema.GroupVersionKind{FluxGitRepositoryGVK, FluxKustomizationGVK} {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetNamespace(globals.FluxNamespace)
+		objs = append(objs, u)
+	}
+	return objs
+}
+
+// SetCorePackageSpec ignores namespace, Flux applies objects to the namespaces set in the manifests of core packages.
+func (fluxEngine) SetCorePackageSpec(obj client.Object, repoURL, namespace string) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("%s is not an unstructured object", obj.GetName())
+	}
+	switch u.GetKind() {
+	case FluxGitRepositoryKind:
+		u.Object["spec"] = map[string]any{
+			"interval": fluxSourceInterval,
+			"url":      repoURL,
+			"ref":      map[string]any{"branch": DefaultBranch},
+		}
+	case FluxKustomizationKind:
+		u.Object["spec"] = map[string]any{
+			"interval": fluxSyncInterval,
+			"timeout":  fluxSyncTimeout,
+			"path":     "./",
+			"prune":    false,
+			"wait":     true,
+			"suspend":  false,
+			"sourceRef": map[string]any{
+				"kind": FluxGitRepositoryKind,
+				"name": u.GetName(),
+			},
+		}
+	default:
+		return fmt.Errorf("unsupported flux kind %s", u.GetKind())
+	}
+	return nil
+}
+
+func (fluxEngine) CorePackageList() client.ObjectList {
+	return FluxList(FluxKustomizationGVK)
+}
+
+// FluxList returns an empty list of the Flux objects of gvk, one of FluxGitRepositoryGVK, FluxKustomizationGVK, and
+// FluxHelmReleaseGVK.
+func FluxList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return l
+}
+
+// Healthy reports whether the Kustomization obj is ready. It waits for the objects it applied, see spec.wait.
+func (fluxEngine) Healthy(obj runtime.Object) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	return fluxReady(u)
+}
+
+func (fluxEngine) DisableSync(obj client.Object) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u.GetKind() != FluxKustomizationKind {
+		return false
+	}
+	if suspended, _, _ := unstructured.NestedBool(u.Object, "spec", "suspend"); suspended {
+		return false
+	}
+	_ = unstructured.SetNestedField(u.Object, true, "spec", "suspend")
+	return true
+}
+
+// fluxReady reports whether the Ready condition of the Flux object u is true.
+func fluxReady(u *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		m, ok := c.(map[string]any)
+		if ok && m["type"] == "Ready" {
+			return m["status"] == "True"
+		}
+	}
+	return false
+}
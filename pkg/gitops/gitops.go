@@ -0,0 +1,82 @@
+// Package gitops defines the GitOps engine that syncs packages from the git server into the cluster. The engine is
+// either Argo CD with Application objects or Flux with GitRepository and Kustomization objects.
+package gitops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ArgoCDEngineName = "argocd"
+	FluxEngineName   = "flux"
+
+	// DefaultBranch is the branch repositories are created with in the git server, see
+	// gitrepository.DefaultBranchName.
+	DefaultBranch = "main"
+)
+
+// Engine is an implementation of the GitOps engine.
+type Engine interface {
+	// Name returns the name that selects the engine, see v1alpha1.BuildCustomizationSpec.GitOpsEngine.
+	Name() string
+	// PackageName returns the core package that installs the engine.
+	PackageName() string
+	// Namespace returns the namespace the engine is installed in. The objects that sync core packages are created in it.
+	Namespace() string
+	// IsPackageKind reports whether custom packages can be defined with objects of gvk.
+	IsPackageKind(gvk schema.GroupVersionKind) bool
+	// CorePackageObjects returns the objects that sync the core package name, without their spec.
+	CorePackageObjects(name string) []client.Object
+	// SetCorePackageSpec sets the spec of obj, one of CorePackageObjects, to sync the repository at repoURL into
+	// namespace.
+	SetCorePackageSpec(obj client.Object, repoURL, namespace string) error
+	// CorePackageList returns an empty list of the objects whose health is reported by Healthy.
+	CorePackageList() client.ObjectList
+	// Healthy reports whether the package synced by obj, an item of CorePackageList, is healthy.
+	Healthy(obj runtime.Object) bool
+	// DisableSync turns off automated sync of obj, one of CorePackageObjects. It returns false if obj is unchanged.
+	DisableSync(obj client.Object) bool
+}
+
+var engines = []Engine{argocdEngine{}, fluxEngine{}}
+
+// Engines returns all engines.
+func Engines() []Engine {
+	return engines
+}
+
+// ForName returns the engine with name. An empty name selects Argo CD.
+func ForName(name string) (Engine, error) {
+	if name == "" {
+		name = ArgoCDEngineName
+	}
+	names := make([]string, 0, len(engines))
+	for _, e := range engines {
+		if e.Name() == name {
+			return e, nil
+		}
+		names = append(names, e.Name())
+	}
+	return nil, fmt.Errorf("unsupported gitops engine %s, valid values are: %s", name, strings.Join(names, ", "))
+}
+
+// ForBuild returns the engine selected by cfg.
+func ForBuild(cfg v1alpha1.BuildCustomizationSpec) (Engine, error) {
+	return ForName(cfg.GitOpsEngine)
+}
+
+// IsPackageKind reports whether custom packages can be defined with objects of gvk with any engine.
+func IsPackageKind(gvk schema.GroupVersionKind) bool {
+	for _, e := range engines {
+		if e.IsPackageKind(gvk) {
+			return true
+		}
+	}
+	return false
+}
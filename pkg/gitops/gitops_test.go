@@ -0,0 +1,98 @@
+package gitops
+
+import (
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestForName(t *testing.T) {
+	e, err := ForName("")
+	require.NoError(t, err)
+	assert.Equal(t, ArgoCDEngineName, e.Name())
+
+	e, err = ForBuild(v1alpha1.BuildCustomizationSpec{GitOpsEngine: FluxEngineName})
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.FluxPackageName, e.PackageName())
+	assert.Equal(t, globals.FluxNamespace, e.Namespace())
+
+	_, err = ForName("jenkins")
+	assert.ErrorContains(t, err, "valid values are: argocd, flux")
+}
+
+func TestIsPackageKind(t *testing.T) {
+	argocd, flux := argocdEngine{}, fluxEngine{}
+	app := schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"}
+	helmRelease := schema.GroupVersionKind{Group: FluxHelmGroup, Version: "v2", Kind: FluxHelmReleaseKind}
+	// the version of Flux objects is not checked, so packages keep working with new Flux APIs.
+	kustomization := schema.GroupVersionKind{Group: FluxKustomizeGroup, Version: "v1beta2", Kind: FluxKustomizationKind}
+	// kustomize uses the same kind in another group.
+	kustomizeConfig := schema.GroupVersionKind{Group: "kustomize.config.k8s.io", Version: "v1beta1", Kind: "Kustomization"}
+
+	assert.True(t, argocd.IsPackageKind(app))
+	assert.False(t, argocd.IsPackageKind(helmRelease))
+	assert.False(t, flux.IsPackageKind(app))
+	assert.True(t, flux.IsPackageKind(helmRelease))
+	assert.True(t, flux.IsPackageKind(kustomization))
+	assert.False(t, flux.IsPackageKind(kustomizeConfig))
+	assert.True(t, IsPackageKind(app))
+	assert.True(t, IsPackageKind(kustomization))
+	assert.False(t, IsPackageKind(kustomizeConfig))
+}
+
+func TestFluxCorePackage(t *testing.T) {
+	e := fluxEngine{}
+	objs := e.CorePackageObjects(v1alpha1.GiteaPackageName)
+	require.Len(t, objs, 2)
+	for _, obj := range objs {
+		require.NoError(t, e.SetCorePackageSpec(obj, "http://gitea/giteaAdmin/gitea.git", "gitea"))
+		assert.Equal(t, globals.FluxNamespace, obj.GetNamespace())
+	}
+
+	repo := objs[0].(*unstructured.Unstructured)
+	assert.Equal(t, FluxGitRepositoryGVK, repo.GroupVersionKind())
+	url, _, _ := unstructured.NestedString(repo.Object, "spec", "url")
+	assert.Equal(t, "http://gitea/giteaAdmin/gitea.git", url)
+	branch, _, _ := unstructured.NestedString(repo.Object, "spec", "ref", "branch")
+	assert.Equal(t, DefaultBranch, branch)
+
+	kustomization := objs[1].(*unstructured.Unstructured)
+	assert.Equal(t, FluxKustomizationGVK, kustomization.GroupVersionKind())
+	source, _, _ := unstructured.NestedStringMap(kustomization.Object, "spec", "sourceRef")
+	assert.Equal(t, map[string]string{"kind": FluxGitRepositoryKind, "name": v1alpha1.GiteaPackageName}, source)
+
+	assert.False(t, e.Healthy(kustomization))
+	kustomization.Object["status"] = map[string]any{"conditions": []any{
+		map[string]any{"type": "Reconciling", "status": "False"},
+		map[string]any{"type": "Ready", "status": "True"},
+	}}
+	assert.True(t, e.Healthy(kustomization))
+
+	assert.False(t, e.DisableSync(repo))
+	assert.True(t, e.DisableSync(kustomization))
+	suspended, _, _ := unstructured.NestedBool(kustomization.Object, "spec", "suspend")
+	assert.True(t, suspended)
+	assert.False(t, e.DisableSync(kustomization))
+}
+
+func TestArgoCDCorePackage(t *testing.T) {
+	e := argocdEngine{}
+	objs := e.CorePackageObjects(v1alpha1.GiteaPackageName)
+	require.Len(t, objs, 1)
+	require.NoError(t, e.SetCorePackageSpec(objs[0], "http://gitea/giteaAdmin/gitea.git", "gitea"))
+
+	app := objs[0].(*argov1alpha1.Application)
+	assert.Equal(t, globals.ArgoCDNamespace, app.Namespace)
+	assert.Equal(t, "http://gitea/giteaAdmin/gitea.git", app.Spec.Source.RepoURL)
+	assert.Equal(t, "gitea", app.Spec.Destination.Namespace)
+
+	assert.True(t, e.DisableSync(app))
+	assert.Nil(t, app.Spec.SyncPolicy.Automated)
+	assert.False(t, e.DisableSync(app))
+}
@@ -0,0 +1,105 @@
+package ingress
+
+import (
+	"fmt"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// GatewayName is the name of the Gateway that HTTPRoutes attach to, in the namespace of Envoy Gateway.
+	GatewayName = "idpbuilder"
+	// EnvoyProxyName is the name of the deployment and service of the Envoy proxy that serves the Gateway. They are
+	// set in the EnvoyProxy config of the Gateway, so the names do not depend on Envoy Gateway.
+	EnvoyProxyName = "envoy-idpbuilder"
+)
+
+// gatewayAPIProvider routes requests with Envoy Gateway and Gateway API HTTPRoute objects.
+type gatewayAPIProvider struct{}
+
+func (gatewayAPIProvider) Name() string {
+	return GatewayAPIProviderName
+}
+
+func (gatewayAPIProvider) PackageName() string {
+	return v1alpha1.EnvoyGatewayPackageName
+}
+
+func (gatewayAPIProvider) Namespace() string {
+	return globals.EnvoyGatewayNamespace
+}
+
+func (gatewayAPIProvider) Service() types.NamespacedName {
+	return types.NamespacedName{Namespace: globals.EnvoyGatewayNamespace, Name: EnvoyProxyName}
+}
+
+func (gatewayAPIProvider) Deployment() types.NamespacedName {
+	return types.NamespacedName{Namespace: globals.EnvoyGatewayNamespace, Name: EnvoyProxyName}
+}
+
+// TLSPassthrough is not supported, because passing TLS connections through requires a TLSRoute, which is not part of
+// the standard Gateway API channel. TLS is terminated by the Gateway.
+func (gatewayAPIProvider) TLSPassthrough() bool {
+	return false
+}
+
+func (p gatewayAPIProvider) RouteManifests(routes []Route, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	out := make([][]byte, 0, len(routes))
+	for _, r := range routes {
+		b, err := yaml.Marshal(p.httpRoute(r, cfg))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling httproute %s: %w", r.Name, err)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// httpRoute returns the HTTPRoute of r. The Gateway API types are not a dependency of idpbuilder, so it is built as
+// a map. MaxBodySize is not set, because Envoy does not limit request bodies that are streamed to services.
+func (gatewayAPIProvider) httpRoute(r Route, cfg v1alpha1.BuildCustomizationSpec) map[string]any {
+	rule := map[string]any{
+		"matches": []any{
+			map[string]any{
+				"path": map[string]any{"type": "PathPrefix", "value": r.Path},
+			},
+		},
+		"backendRefs": []any{
+			map[string]any{"name": r.Service, "port": r.Port},
+		},
+	}
+	if r.Rewrite != "" {
+		rule["filters"] = []any{
+			map[string]any{
+				"type": "URLRewrite",
+				"urlRewrite": map[string]any{
+					"path": map[string]any{"type": "ReplacePrefixMatch", "replacePrefixMatch": r.Rewrite},
+				},
+			},
+		}
+	}
+
+	hosts := hostNames(r, cfg)
+	hostnames := make([]any, 0, len(hosts))
+	for _, h := range hosts {
+		hostnames = append(hostnames, h)
+	}
+	return map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]any{
+			"name":      r.Name,
+			"namespace": r.Namespace,
+		},
+		"spec": map[string]any{
+			"parentRefs": []any{
+				map[string]any{"name": GatewayName, "namespace": globals.EnvoyGatewayNamespace},
+			},
+			"hostnames": hostnames,
+			"rules":     []any{rule},
+		},
+	}
+}
@@ -0,0 +1,109 @@
+// Package ingress defines the ingress layer that routes requests from the host to packages in the cluster. The layer
+// is either ingress-nginx with Ingress objects or Envoy Gateway with Gateway API HTTPRoute objects.
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	NginxProviderName      = "nginx"
+	GatewayAPIProviderName = "gateway-api"
+
+	// NodeLabelKey and NodeLabelValue label the kind node that receives requests from the host. The proxy of every
+	// provider runs on it with host ports, see pkg/kind.
+	NodeLabelKey   = "ingress-ready"
+	NodeLabelValue = "true"
+)
+
+// Route exposes a port of a service on the host names of the cluster.
+type Route struct {
+	// Name is the name of the object that exposes the route.
+	Name      string
+	Namespace string
+	// Subdomain is prepended to the host names, e.g. argocd routes argocd.cnoe.localtest.me. Empty routes the host
+	// names themselves, as used with path routing.
+	Subdomain string
+	// Path is the path prefix that is routed.
+	Path string
+	// Rewrite replaces Path in the requests sent to the service. Empty keeps the path.
+	Rewrite string
+	// Service is the name of the service in Namespace that receives the requests.
+	Service string
+	Port    int32
+	// TLSPassthrough passes TLS connections to the service instead of terminating them. It is ignored by providers
+	// that do not support it, see Provider.TLSPassthrough.
+	TLSPassthrough bool
+	// MaxBodySize is the largest request body accepted, e.g. 1024m. Empty uses the default of the provider.
+	MaxBodySize string
+}
+
+// Provider is an implementation of the ingress layer.
+type Provider interface {
+	// Name returns the name that selects the provider, see v1alpha1.BuildCustomizationSpec.Ingress.
+	Name() string
+	// PackageName returns the core package that installs the provider.
+	PackageName() string
+	// Namespace returns the namespace the provider is installed in. The TLS certificate of the cluster is stored in it.
+	Namespace() string
+	// Service returns the service that receives requests. Host names of the cluster resolve to it in the cluster.
+	Service() types.NamespacedName
+	// Deployment returns the deployment that serves requests.
+	Deployment() types.NamespacedName
+	// TLSPassthrough reports whether routes can pass TLS connections to services.
+	TLSPassthrough() bool
+	// RouteManifests returns the manifests of the objects that expose routes, using the host names in cfg.
+	RouteManifests(routes []Route, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error)
+}
+
+var providers = []Provider{nginxProvider{}, gatewayAPIProvider{}}
+
+// Providers returns all providers.
+func Providers() []Provider {
+	return providers
+}
+
+// ForName returns the provider with name. An empty name selects ingress-nginx.
+func ForName(name string) (Provider, error) {
+	if name == "" {
+		name = NginxProviderName
+	}
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		if p.Name() == name {
+			return p, nil
+		}
+		names = append(names, p.Name())
+	}
+	return nil, fmt.Errorf("unsupported ingress %s, valid values are: %s", name, strings.Join(names, ", "))
+}
+
+// ForBuild returns the provider selected by cfg.
+func ForBuild(cfg v1alpha1.BuildCustomizationSpec) (Provider, error) {
+	return ForName(cfg.Ingress)
+}
+
+// hostNames returns the host names of route.
+func hostNames(route Route, cfg v1alpha1.BuildCustomizationSpec) []string {
+	hosts := []string{cfg.IngressHost}
+	if cfg.IngressHost != cfg.Host {
+		hosts = append(hosts, cfg.Host)
+	}
+	if route.Subdomain == "" {
+		return hosts
+	}
+	for i := range hosts {
+		hosts[i] = route.Subdomain + "." + hosts[i]
+	}
+	return hosts
+}
+
+// ServiceHost returns the DNS name of the service of p in the cluster.
+func ServiceHost(p Provider) string {
+	s := p.Service()
+	return fmt.Sprintf("%s.%s.svc.cluster.local", s.Name, s.Namespace)
+}
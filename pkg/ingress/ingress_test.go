@@ -0,0 +1,113 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+var testCfg = v1alpha1.BuildCustomizationSpec{
+	Protocol:    "https",
+	Host:        "cnoe.localtest.me",
+	IngressHost: "idp.example.com",
+	Port:        "8443",
+}
+
+func TestForName(t *testing.T) {
+	p, err := ForName("")
+	require.NoError(t, err)
+	assert.Equal(t, NginxProviderName, p.Name())
+
+	p, err = ForName(GatewayAPIProviderName)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.EnvoyGatewayPackageName, p.PackageName())
+	assert.Equal(t, "envoy-idpbuilder.envoy-gateway-system.svc.cluster.local", ServiceHost(p))
+
+	_, err = ForName("traefik")
+	assert.ErrorContains(t, err, "valid values are: nginx, gateway-api")
+}
+
+func TestNginxRouteManifests(t *testing.T) {
+	routes := []Route{
+		{Name: "argocd-server-ingress", Namespace: "argocd", Subdomain: "argocd", Path: "/", Service: "argocd-server", Port: 443, TLSPassthrough: true},
+		{Name: "my-gitea-path", Namespace: "gitea", Path: "/gitea", Rewrite: "/", Service: "my-gitea-http", Port: 3000, MaxBodySize: "1024m"},
+	}
+	manifests, err := nginxProvider{}.RouteManifests(routes, testCfg)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	subdomain := networkingv1.Ingress{}
+	require.NoError(t, yaml.Unmarshal(manifests[0], &subdomain))
+	assert.Equal(t, "nginx", *subdomain.Spec.IngressClassName)
+	assert.Equal(t, "true", subdomain.Annotations["nginx.ingress.kubernetes.io/ssl-passthrough"])
+	require.Len(t, subdomain.Spec.Rules, 2)
+	assert.Equal(t, "argocd.idp.example.com", subdomain.Spec.Rules[0].Host)
+	assert.Equal(t, "argocd.cnoe.localtest.me", subdomain.Spec.Rules[1].Host)
+	path := subdomain.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, "/", path.Path)
+	assert.Equal(t, networkingv1.PathTypePrefix, *path.PathType)
+	assert.Equal(t, int32(443), path.Backend.Service.Port.Number)
+
+	rewrite := networkingv1.Ingress{}
+	require.NoError(t, yaml.Unmarshal(manifests[1], &rewrite))
+	assert.Equal(t, map[string]string{
+		"nginx.ingress.kubernetes.io/proxy-body-size": "1024m",
+		"nginx.ingress.kubernetes.io/use-regex":       "true",
+		"nginx.ingress.kubernetes.io/rewrite-target":  "/$2",
+	}, rewrite.Annotations)
+	assert.Equal(t, "idp.example.com", rewrite.Spec.Rules[0].Host)
+	path = rewrite.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, "/gitea(/|$)(.*)", path.Path)
+	assert.Equal(t, networkingv1.PathTypeImplementationSpecific, *path.PathType)
+	assert.Equal(t, "my-gitea-http", path.Backend.Service.Name)
+}
+
+func TestGatewayAPIRouteManifests(t *testing.T) {
+	cfg := testCfg
+	cfg.IngressHost = cfg.Host
+	routes := []Route{
+		{Name: "my-gitea-custom", Namespace: "gitea", Subdomain: "gitea", Path: "/", Service: "my-gitea-http", Port: 3000},
+		{Name: "my-gitea-path-oci-repo", Namespace: "gitea", Path: "/v2/gitea", Rewrite: "/v2", Service: "my-gitea-http", Port: 3000},
+	}
+	manifests, err := gatewayAPIProvider{}.RouteManifests(routes, cfg)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	subdomain := unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal(manifests[0], &subdomain.Object))
+	assert.Equal(t, "HTTPRoute", subdomain.GetKind())
+	assert.Equal(t, "gitea", subdomain.GetNamespace())
+	hostnames, _, err := unstructured.NestedStringSlice(subdomain.Object, "spec", "hostnames")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gitea.cnoe.localtest.me"}, hostnames)
+	parents, _, err := unstructured.NestedSlice(subdomain.Object, "spec", "parentRefs")
+	require.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"name": GatewayName, "namespace": "envoy-gateway-system"}}, parents)
+	rules, _, err := unstructured.NestedSlice(subdomain.Object, "spec", "rules")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	rule := rules[0].(map[string]any)
+	assert.NotContains(t, rule, "filters")
+	assert.Equal(t, []any{map[string]any{"name": "my-gitea-http", "port": float64(3000)}}, rule["backendRefs"])
+
+	rewrite := unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal(manifests[1], &rewrite.Object))
+	hostnames, _, err = unstructured.NestedStringSlice(rewrite.Object, "spec", "hostnames")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cnoe.localtest.me"}, hostnames)
+	rules, _, err = unstructured.NestedSlice(rewrite.Object, "spec", "rules")
+	require.NoError(t, err)
+	rule = rules[0].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"path": map[string]any{"type": "PathPrefix", "value": "/v2/gitea"}}}, rule["matches"])
+	assert.Equal(t, []any{map[string]any{
+		"type": "URLRewrite",
+		"urlRewrite": map[string]any{
+			"path": map[string]any{"type": "ReplacePrefixMatch", "replacePrefixMatch": "/v2"},
+		},
+	}}, rule["filters"])
+}
@@ -0,0 +1,111 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const nginxIngressClassName = "nginx"
+
+// nginxProvider routes requests with ingress-nginx and Ingress objects.
+type nginxProvider struct{}
+
+func (nginxProvider) Name() string {
+	return NginxProviderName
+}
+
+func (nginxProvider) PackageName() string {
+	return v1alpha1.IngressNginxPackageName
+}
+
+func (nginxProvider) Namespace() string {
+	return globals.NginxNamespace
+}
+
+func (nginxProvider) Service() types.NamespacedName {
+	return types.NamespacedName{Namespace: globals.NginxNamespace, Name: "ingress-nginx-controller"}
+}
+
+func (nginxProvider) Deployment() types.NamespacedName {
+	return types.NamespacedName{Namespace: globals.NginxNamespace, Name: "ingress-nginx-controller"}
+}
+
+func (nginxProvider) TLSPassthrough() bool {
+	return true
+}
+
+func (p nginxProvider) RouteManifests(routes []Route, cfg v1alpha1.BuildCustomizationSpec) ([][]byte, error) {
+	out := make([][]byte, 0, len(routes))
+	for _, r := range routes {
+		b, err := yaml.Marshal(p.ingress(r, cfg))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling ingress %s: %w", r.Name, err)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func (nginxProvider) ingress(r Route, cfg v1alpha1.BuildCustomizationSpec) networkingv1.Ingress {
+	annotations := map[string]string{}
+	if r.MaxBodySize != "" {
+		annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = r.MaxBodySize
+	}
+	if r.TLSPassthrough {
+		annotations["nginx.ingress.kubernetes.io/force-ssl-redirect"] = "true"
+		annotations["nginx.ingress.kubernetes.io/ssl-passthrough"] = "true"
+	}
+
+	path := r.Path
+	pathType := networkingv1.PathTypePrefix
+	if r.Rewrite != "" {
+		// the rest of the path is captured in $2 and appended to the rewritten prefix.
+		path = strings.TrimSuffix(r.Path, "/") + "(/|$)(.*)"
+		pathType = networkingv1.PathTypeImplementationSpecific
+		annotations["nginx.ingress.kubernetes.io/use-regex"] = "true"
+		annotations["nginx.ingress.kubernetes.io/rewrite-target"] = strings.TrimSuffix(r.Rewrite, "/") + "/$2"
+	}
+
+	className := nginxIngressClassName
+	ing := networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "Ingress",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        r.Name,
+			Namespace:   r.Namespace,
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &className,
+		},
+	}
+	for _, host := range hostNames(r, cfg) {
+		ing.Spec.Rules = append(ing.Spec.Rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     path,
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{
+							Service: &networkingv1.IngressServiceBackend{
+								Name: r.Service,
+								Port: networkingv1.ServiceBackendPort{Number: r.Port},
+							},
+						},
+					}},
+				},
+			},
+		})
+	}
+	return ing
+}
@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// InventoryName is the name of the ConfigMap that records the objects applied to a namespace, so objects removed
+	// from the manifests can be pruned.
+	InventoryName = "idpbuilder-inventory"
+	inventoryKey  = "objects"
+)
+
+// ApplyObject creates or updates obj with server-side apply using the idpbuilder field manager. Fields changed by
+// others, e.g. by manual edits, are taken back. It returns the object returned by the API server. Pass
+// client.DryRunAll to see the result without changing the cluster.
+func ApplyObject(ctx context.Context, kubeClient client.Client, obj client.Object, opts ...client.PatchOption) (*unstructured.Unstructured, error) {
+	u, err := toUnstructured(obj, kubeClient.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]client.PatchOption{client.ForceOwnership, client.FieldOwner(v1alpha1.FieldManager)}, opts...)
+	if err = kubeClient.Patch(ctx, u, client.Apply, opts...); err != nil {
+		return nil, fmt.Errorf("applying %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return u, nil
+}
+
+// toUnstructured converts obj to the apply configuration sent to the API server. Status and the creation timestamp are
+// dropped, because they are set by the API server and controllers.
+func toUnstructured(obj client.Object, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("getting kind of %s: %w", obj.GetName(), err)
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	unstructured.RemoveNestedField(u.Object, "status")
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	return u, nil
+}
+
+// ObjectReferenceFor returns the reference recorded in the inventory for obj.
+func ObjectReferenceFor(obj *unstructured.Unstructured) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// GetInventory returns the objects recorded in the inventory of namespace. It returns nothing if there is no inventory.
+func GetInventory(ctx context.Context, kubeClient client.Client, namespace string) ([]corev1.ObjectReference, error) {
+	cm := corev1.ConfigMap{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: InventoryName, Namespace: namespace}, &cm)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting inventory of %s: %w", namespace, err)
+	}
+
+	var refs []corev1.ObjectReference
+	if err = json.Unmarshal([]byte(cm.Data[inventoryKey]), &refs); err != nil {
+		return nil, fmt.Errorf("reading inventory of %s: %w", namespace, err)
+	}
+	return refs, nil
+}
+
+// UpdateInventory records refs in the inventory of namespace, replacing the objects recorded before.
+func UpdateInventory(ctx context.Context, kubeClient client.Client, namespace string, refs []corev1.ObjectReference) error {
+	sorted := append([]corev1.ObjectReference(nil), refs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return referenceID(sorted[i]) < referenceID(sorted[j])
+	})
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return fmt.Errorf("marshaling inventory of %s: %w", namespace, err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      InventoryName,
+			Namespace: namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, kubeClient, cm, func() error {
+		cm.Data = map[string]string{inventoryKey: string(data)}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("updating inventory of %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// PruneCandidates returns the objects in previous that are not in current. Namespaces and CRDs are never pruned,
+// because deleting them deletes everything in them.
+func PruneCandidates(previous, current []corev1.ObjectReference) []corev1.ObjectReference {
+	keep := make(map[string]bool, len(current))
+	for i := range current {
+		keep[referenceID(current[i])] = true
+	}
+
+	var out []corev1.ObjectReference
+	for i := range previous {
+		ref := previous[i]
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		if keep[referenceID(ref)] || gvk.GroupKind() == (schema.GroupKind{Kind: "Namespace"}) ||
+			gvk.GroupKind() == (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+		out = append(out, ref)
+	}
+	return out
+}
+
+// Prune deletes the objects returned by PruneCandidates. Objects that no longer exist are ignored. It returns the
+// objects that were deleted.
+func Prune(ctx context.Context, kubeClient client.Client, previous, current []corev1.ObjectReference) ([]corev1.ObjectReference, error) {
+	var pruned []corev1.ObjectReference
+	for _, ref := range PruneCandidates(previous, current) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		u.SetNamespace(ref.Namespace)
+		u.SetName(ref.Name)
+
+		err := kubeClient.Delete(ctx, u, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil {
+			// the kind may have been removed together with the object.
+			if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return pruned, fmt.Errorf("pruning %s: %w", referenceID(ref), err)
+		}
+		pruned = append(pruned, ref)
+	}
+	return pruned, nil
+}
+
+// referenceID identifies an object across API versions of its kind.
+func referenceID(ref corev1.ObjectReference) string {
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s %s", gvk.GroupKind(), ref.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", gvk.GroupKind(), ref.Namespace, ref.Name)
+}
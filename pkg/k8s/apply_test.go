@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	ref := func(apiVersion, kind, namespace, name string) corev1.ObjectReference {
+		return corev1.ObjectReference{APIVersion: apiVersion, Kind: kind, Namespace: namespace, Name: name}
+	}
+	kept := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: "gitea"}}
+	removed := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "removed", Namespace: "gitea"}}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gitea"}}
+	kubeClient := fake.NewClientBuilder().WithScheme(GetScheme()).WithObjects(kept, removed, ns).Build()
+
+	previous := []corev1.ObjectReference{
+		ref("apps/v1", "Deployment", "gitea", "kept"),
+		ref("apps/v1", "Deployment", "gitea", "removed"),
+		ref("apps/v1", "Deployment", "gitea", "already-deleted"),
+		ref("v1", "Namespace", "", "gitea"),
+		ref("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "things.example.com"),
+	}
+	current := []corev1.ObjectReference{
+		// a new API version of the same kind is the same object.
+		ref("apps/v1beta1", "Deployment", "gitea", "kept"),
+	}
+
+	require.NoError(t, UpdateInventory(ctx, kubeClient, "gitea", previous))
+	inventory, err := GetInventory(ctx, kubeClient, "gitea")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, previous, inventory)
+
+	assert.ElementsMatch(t, previous[1:3], PruneCandidates(inventory, current))
+
+	pruned, err := Prune(ctx, kubeClient, inventory, current)
+	require.NoError(t, err)
+	assert.Equal(t, previous[1:2], pruned)
+
+	err = kubeClient.Get(ctx, client.ObjectKeyFromObject(removed), &appsv1.Deployment{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	assert.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(kept), &appsv1.Deployment{}))
+	assert.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(ns), &corev1.Namespace{}))
+
+	require.NoError(t, UpdateInventory(ctx, kubeClient, "gitea", current))
+	inventory, err = GetInventory(ctx, kubeClient, "gitea")
+	require.NoError(t, err)
+	assert.Equal(t, current, inventory)
+
+	inventory, err = GetInventory(ctx, kubeClient, "argocd")
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+}
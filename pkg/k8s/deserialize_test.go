@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -62,6 +63,27 @@ spec:`,
 			newDeployment("test-deployment1"),
 			newDeployment("test-deployment2"),
 		},
+	}, {
+		name:          "Kind not in scheme",
+		schemeBuilder: appsv1.SchemeBuilder,
+		input: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: test-route
+  namespace: test
+`,
+		expectErr: nil,
+		expectObjects: []client.Object{
+			&unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "gateway.networking.k8s.io/v1",
+				"kind":       "HTTPRoute",
+				"metadata": map[string]any{
+					"name":      "test-route",
+					"namespace": "test",
+				},
+			}},
+		},
 	}}
 
 	for _, tc := range cases {
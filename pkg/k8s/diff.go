@@ -0,0 +1,251 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// lines of unchanged context around changes, as in diff -u.
+	diffContext = 3
+	// above this many line pairs, changed regions are shown as replaced instead of computing the smallest diff.
+	maxDiffCells = 4_000_000
+)
+
+// ObjectDiff is the result of DiffObject.
+type ObjectDiff struct {
+	// Object is the applied object.
+	Object corev1.ObjectReference
+	// Diff is the unified diff between the object in the cluster and the applied object. It is empty if applying the
+	// object changes nothing.
+	Diff string
+}
+
+// DiffObject compares obj in the cluster with obj after ApplyObject, computed with a dry run. Fields that are always
+// set by the API server, such as the resource version and managed fields, and status are ignored.
+func DiffObject(ctx context.Context, kubeClient client.Client, obj client.Object) (ObjectDiff, error) {
+	applied, err := ApplyObject(ctx, kubeClient, obj, client.DryRunAll)
+	if err != nil {
+		return ObjectDiff{}, err
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(applied.GroupVersionKind())
+	err = kubeClient.Get(ctx, client.ObjectKeyFromObject(applied), live)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return ObjectDiff{}, fmt.Errorf("getting %s %s: %w", applied.GetKind(), applied.GetName(), err)
+		}
+		live = nil
+	}
+
+	ref := ObjectReferenceFor(applied)
+	id := objectID(ref)
+	diff, err := diffUnstructured("live "+id, "applied "+id, live, applied)
+	if err != nil {
+		return ObjectDiff{}, err
+	}
+	return ObjectDiff{Object: ref, Diff: diff}, nil
+}
+
+// DiffPruned returns the unified diff that shows the deletion of ref, or an empty string if it does not exist.
+func DiffPruned(ctx context.Context, kubeClient client.Client, ref corev1.ObjectReference) (string, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	err := kubeClient.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, live)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting %s: %w", referenceID(ref), err)
+	}
+
+	id := objectID(ref)
+	return diffUnstructured("live "+id, "pruned "+id, live, nil)
+}
+
+func diffUnstructured(from, to string, a, b *unstructured.Unstructured) (string, error) {
+	aYAML, err := diffYAML(a)
+	if err != nil {
+		return "", err
+	}
+	bYAML, err := diffYAML(b)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(from, to, aYAML, bYAML), nil
+}
+
+func diffYAML(u *unstructured.Unstructured) (string, error) {
+	if u == nil {
+		return "", nil
+	}
+	c := u.DeepCopy()
+	unstructured.RemoveNestedField(c.Object, "status")
+	for _, f := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp"} {
+		unstructured.RemoveNestedField(c.Object, "metadata", f)
+	}
+	out, err := yaml.Marshal(c.Object)
+	if err != nil {
+		return "", fmt.Errorf("marshaling %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return string(out), nil
+}
+
+func objectID(ref corev1.ObjectReference) string {
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+}
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the line differences between a and b in unified format with from and to as file names. It
+// returns an empty string if they are equal.
+func UnifiedDiff(from, to, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	// line numbers in a and b before each op.
+	aLines := make([]int, len(ops)+1)
+	bLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if op.kind != '+' {
+			aLines[i+1]++
+		}
+		if op.kind != '-' {
+			bLines[i+1]++
+		}
+	}
+
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// a hunk ends when the next change is too far away to share context.
+		end := i
+		for j := i; j < len(ops) && j-end <= 2*diffContext+1; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		start := max(i-diffContext, 0)
+		stop := min(end+diffContext+1, len(ops))
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aLines[start], aLines[stop]-aLines[start]),
+			hunkRange(bLines[start], bLines[stop]-bLines[start]))
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		i = stop
+	}
+	return sb.String()
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the edit script from a to b based on their longest common subsequence. Common prefixes and
+// suffixes are skipped first, so small changes to large objects stay cheap.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
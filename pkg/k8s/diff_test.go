@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int, change map[int]string) string {
+		out := make([]string, 0, n)
+		for i := 1; i <= n; i++ {
+			if l, ok := change[i]; ok {
+				if l != "" {
+					out = append(out, l)
+				}
+				continue
+			}
+			out = append(out, "line"+string(rune('a'+i-1)))
+		}
+		return strings.Join(out, "\n") + "\n"
+	}
+
+	cases := map[string]struct {
+		a, b     string
+		expected string
+	}{
+		"equal": {
+			a: lines(3, nil), b: lines(3, nil),
+		},
+		"changed line": {
+			a: lines(10, nil), b: lines(10, map[int]string{5: "changed"}),
+			expected: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n" +
+				" lineb\n linec\n lined\n-linee\n+changed\n linef\n lineg\n lineh\n",
+		},
+		"created": {
+			a: "", b: "x: 1\ny: 2\n",
+			expected: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x: 1\n+y: 2\n",
+		},
+		"deleted": {
+			a: "x: 1\n", b: "",
+			expected: "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-x: 1\n",
+		},
+		"separate hunks": {
+			a: lines(20, nil), b: lines(20, map[int]string{2: "changed", 18: ""}),
+			expected: "--- a\n+++ b\n@@ -1,5 +1,5 @@\n linea\n-lineb\n+changed\n linec\n lined\n linee\n" +
+				"@@ -15,6 +15,5 @@\n lineo\n linep\n lineq\n-liner\n lines\n linet\n",
+		},
+		"close changes share a hunk": {
+			a: lines(12, nil), b: lines(12, map[int]string{2: "x", 9: "y"}),
+			expected: "--- a\n+++ b\n@@ -1,12 +1,12 @@\n linea\n-lineb\n+x\n linec\n lined\n linee\n linef\n" +
+				" lineg\n lineh\n-linei\n+y\n linej\n linek\n linel\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, UnifiedDiff("a", "b", c.a, c.b))
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package k8s
+
+import (
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessStatus is the readiness of a resource. The values follow the statuses computed by kstatus.
+type ReadinessStatus string
+
+const (
+	// ReadinessInProgress means the resource is being created or updated.
+	ReadinessInProgress ReadinessStatus = "InProgress"
+	// ReadinessCurrent means the resource is ready.
+	ReadinessCurrent ReadinessStatus = "Current"
+	// ReadinessFailed means the resource will not become ready without intervention, e.g. a Job that failed.
+	ReadinessFailed ReadinessStatus = "Failed"
+)
+
+type Readiness struct {
+	Status  ReadinessStatus
+	Message string
+}
+
+func inProgress(format string, a ...any) Readiness {
+	return Readiness{Status: ReadinessInProgress, Message: fmt.Sprintf(format, a...)}
+}
+
+func current(format string, a ...any) Readiness {
+	return Readiness{Status: ReadinessCurrent, Message: fmt.Sprintf(format, a...)}
+}
+
+func failed(format string, a ...any) Readiness {
+	return Readiness{Status: ReadinessFailed, Message: fmt.Sprintf(format, a...)}
+}
+
+// HasReadinessCheck reports whether ComputeReadiness supports obj. Other objects are ready once they exist.
+func HasReadinessCheck(obj client.Object) bool {
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet, *batchv1.Job, *corev1.Endpoints,
+		*apiextensionsv1.CustomResourceDefinition,
+		*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration:
+		return true
+	}
+	return false
+}
+
+// ComputeReadiness returns the readiness of obj. Workloads are ready when all replicas of the current generation are
+// available, Jobs when they complete, CRDs when they are established, and webhook configurations when every webhook
+// served by a Service has a CA bundle. See readinessDependencies for the Services backing webhooks.
+func ComputeReadiness(obj client.Object) Readiness {
+	switch t := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentReadiness(t)
+	case *appsv1.StatefulSet:
+		return statefulSetReadiness(t)
+	case *appsv1.DaemonSet:
+		return daemonSetReadiness(t)
+	case *batchv1.Job:
+		return jobReadiness(t)
+	case *corev1.Endpoints:
+		return endpointsReadiness(t)
+	case *apiextensionsv1.CustomResourceDefinition:
+		return crdReadiness(t)
+	case *admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration:
+		return webhookReadiness(webhookClientConfigs(t))
+	}
+	return current("exists")
+}
+
+func deploymentReadiness(d *appsv1.Deployment) Readiness {
+	if d.Generation > d.Status.ObservedGeneration {
+		return inProgress("waiting for generation %d to be observed", d.Generation)
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return failed("progress deadline exceeded: %s", c.Message)
+		}
+	}
+	replicas := replicasOrDefault(d.Spec.Replicas)
+	switch {
+	case d.Status.UpdatedReplicas < replicas:
+		return inProgress("%d/%d replicas updated", d.Status.UpdatedReplicas, replicas)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return inProgress("%d old replicas pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < replicas:
+		return inProgress("%d/%d replicas available", d.Status.AvailableReplicas, replicas)
+	}
+	return current("%d/%d replicas available", d.Status.AvailableReplicas, replicas)
+}
+
+func statefulSetReadiness(s *appsv1.StatefulSet) Readiness {
+	if s.Generation > s.Status.ObservedGeneration {
+		return inProgress("waiting for generation %d to be observed", s.Generation)
+	}
+	replicas := replicasOrDefault(s.Spec.Replicas)
+	if s.Status.ReadyReplicas < replicas {
+		return inProgress("%d/%d replicas ready", s.Status.ReadyReplicas, replicas)
+	}
+	// partitioned and OnDelete updates are finished by the user, not the controller.
+	rolling := s.Spec.UpdateStrategy.Type == "" || s.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType
+	partitioned := s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil &&
+		*s.Spec.UpdateStrategy.RollingUpdate.Partition > 0
+	if rolling && !partitioned && s.Status.UpdateRevision != "" && s.Status.CurrentRevision != s.Status.UpdateRevision {
+		return inProgress("%d/%d replicas updated", s.Status.UpdatedReplicas, replicas)
+	}
+	return current("%d/%d replicas ready", s.Status.ReadyReplicas, replicas)
+}
+
+func daemonSetReadiness(d *appsv1.DaemonSet) Readiness {
+	if d.Generation > d.Status.ObservedGeneration {
+		return inProgress("waiting for generation %d to be observed", d.Generation)
+	}
+	desired := d.Status.DesiredNumberScheduled
+	switch {
+	case d.Status.UpdatedNumberScheduled < desired:
+		return inProgress("%d/%d pods updated", d.Status.UpdatedNumberScheduled, desired)
+	case d.Status.NumberAvailable < desired:
+		return inProgress("%d/%d pods available", d.Status.NumberAvailable, desired)
+	}
+	return current("%d/%d pods available", d.Status.NumberAvailable, desired)
+}
+
+func jobReadiness(j *batchv1.Job) Readiness {
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobFailed:
+			return failed("job failed: %s: %s", c.Reason, c.Message)
+		case batchv1.JobComplete:
+			return current("job completed")
+		}
+	}
+	return inProgress("%d active, %d succeeded, %d failed pods", j.Status.Active, j.Status.Succeeded, j.Status.Failed)
+}
+
+func endpointsReadiness(e *corev1.Endpoints) Readiness {
+	addresses := 0
+	for i := range e.Subsets {
+		addresses += len(e.Subsets[i].Addresses)
+	}
+	if addresses == 0 {
+		return inProgress("no ready endpoints")
+	}
+	return current("%d ready endpoints", addresses)
+}
+
+func crdReadiness(c *apiextensionsv1.CustomResourceDefinition) Readiness {
+	for _, cond := range c.Status.Conditions {
+		switch {
+		case cond.Type == apiextensionsv1.NamesAccepted && cond.Status == apiextensionsv1.ConditionFalse:
+			return failed("names not accepted: %s", cond.Message)
+		case cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue:
+			return current("established")
+		}
+	}
+	return inProgress("waiting to be established")
+}
+
+func webhookReadiness(configs []admissionregistrationv1.WebhookClientConfig) Readiness {
+	for i := range configs {
+		if configs[i].Service != nil && len(configs[i].CABundle) == 0 {
+			return inProgress("waiting for the CA bundle of service %s/%s", configs[i].Service.Namespace, configs[i].Service.Name)
+		}
+	}
+	return current("CA bundles set")
+}
+
+// readinessDependencies returns objects that must be ready for obj to be usable, but whose changes do not change obj.
+// Webhooks can only be called once their Service has endpoints.
+func readinessDependencies(obj client.Object) []client.Object {
+	configs := webhookClientConfigs(obj)
+	var deps []client.Object
+	for i := range configs {
+		if s := configs[i].Service; s != nil {
+			deps = append(deps, &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace}})
+		}
+	}
+	return deps
+}
+
+func webhookClientConfigs(obj client.Object) []admissionregistrationv1.WebhookClientConfig {
+	var configs []admissionregistrationv1.WebhookClientConfig
+	switch t := obj.(type) {
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for i := range t.Webhooks {
+			configs = append(configs, t.Webhooks[i].ClientConfig)
+		}
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for i := range t.Webhooks {
+			configs = append(configs, t.Webhooks[i].ClientConfig)
+		}
+	}
+	return configs
+}
+
+// isClusterScoped reports whether obj, which must have a readiness check, is cluster scoped.
+func isClusterScoped(obj client.Object) bool {
+	switch obj.(type) {
+	case *apiextensionsv1.CustomResourceDefinition,
+		*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration:
+		return true
+	}
+	return false
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestComputeReadiness(t *testing.T) {
+	two := int32(2)
+	cases := map[string]struct {
+		obj    client.Object
+		status ReadinessStatus
+		msg    string
+	}{
+		"deployment generation not observed": {
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, AvailableReplicas: 1, UpdatedReplicas: 1},
+			},
+			status: ReadinessInProgress,
+			msg:    "waiting for generation 2 to be observed",
+		},
+		"deployment unavailable": {
+			obj: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &two},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+			},
+			status: ReadinessInProgress,
+			msg:    "1/2 replicas available",
+		},
+		"deployment old replicas": {
+			obj: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 2},
+			},
+			status: ReadinessInProgress,
+			msg:    "1 old replicas pending termination",
+		},
+		"deployment progress deadline exceeded": {
+			obj: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{
+					Type:    appsv1.DeploymentProgressing,
+					Status:  corev1.ConditionFalse,
+					Reason:  "ProgressDeadlineExceeded",
+					Message: "timed out",
+				}}},
+			},
+			status: ReadinessFailed,
+			msg:    "progress deadline exceeded: timed out",
+		},
+		"deployment ready": {
+			obj:    &appsv1.Deployment{Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}},
+			status: ReadinessCurrent,
+		},
+		"statefulset rolling update": {
+			obj: &appsv1.StatefulSet{
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 1, CurrentRevision: "a", UpdateRevision: "b"},
+			},
+			status: ReadinessInProgress,
+			msg:    "0/1 replicas updated",
+		},
+		"statefulset ready": {
+			obj:    &appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{ReadyReplicas: 1, CurrentRevision: "a", UpdateRevision: "a"}},
+			status: ReadinessCurrent,
+		},
+		"daemonset unavailable": {
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			},
+			status: ReadinessInProgress,
+			msg:    "2/3 pods available",
+		},
+		"job running": {
+			obj:    &batchv1.Job{Status: batchv1.JobStatus{Active: 1}},
+			status: ReadinessInProgress,
+			msg:    "1 active, 0 succeeded, 0 failed pods",
+		},
+		"job failed": {
+			obj: &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "too many retries",
+			}}}},
+			status: ReadinessFailed,
+			msg:    "job failed: BackoffLimitExceeded: too many retries",
+		},
+		"job complete": {
+			obj: &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobComplete, Status: corev1.ConditionTrue,
+			}}}},
+			status: ReadinessCurrent,
+		},
+		"crd not established": {
+			obj:    &apiextensionsv1.CustomResourceDefinition{},
+			status: ReadinessInProgress,
+			msg:    "waiting to be established",
+		},
+		"crd established": {
+			obj: &apiextensionsv1.CustomResourceDefinition{Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{
+					Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue,
+				}},
+			}},
+			status: ReadinessCurrent,
+		},
+		"webhook without ca bundle": {
+			obj: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{Namespace: "ingress-nginx", Name: "admission"},
+					},
+				}},
+			},
+			status: ReadinessInProgress,
+			msg:    "waiting for the CA bundle of service ingress-nginx/admission",
+		},
+		"endpoints without addresses": {
+			obj:    &corev1.Endpoints{Subsets: []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}}},
+			status: ReadinessInProgress,
+			msg:    "no ready endpoints",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := ComputeReadiness(c.obj)
+			assert.Equal(t, c.status, r.Status)
+			if c.msg != "" {
+				assert.Equal(t, c.msg, r.Message)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package k8s
 
 import (
-	"embed"
 	"github.com/cnoe-io/idpbuilder/pkg/util/files"
 	"github.com/cnoe-io/idpbuilder/pkg/util/fs"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -9,11 +8,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func BuildCustomizedManifests(filePath, fsPath string, resourceFS embed.FS, scheme *runtime.Scheme, templateData any) ([][]byte, error) {
+// BuildCustomizedManifests renders the templates in fsPath of resourceFS with templateData and applies the customization
+// file at filePath to them. The extra manifests, e.g. generated ones, are added before the customization is applied,
+// so they can be customized too.
+func BuildCustomizedManifests(filePath, fsPath string, resourceFS fs.FS, scheme *runtime.Scheme, templateData any, extra ...[]byte) ([][]byte, error) {
 	rawResources, err := fs.ConvertFSToBytes(resourceFS, fsPath, templateData)
 	if err != nil {
 		return nil, err
 	}
+	rawResources = append(rawResources, extra...)
 
 	if filePath == "" {
 		return rawResources, nil
@@ -27,7 +30,7 @@ func BuildCustomizedManifests(filePath, fsPath string, resourceFS embed.FS, sche
 	return bs, nil
 }
 
-func BuildCustomizedObjects(filePath, fsPath string, resourceFS embed.FS, scheme *runtime.Scheme, templateData any) ([]client.Object, error) {
+func BuildCustomizedObjects(filePath, fsPath string, resourceFS fs.FS, scheme *runtime.Scheme, templateData any) ([]client.Object, error) {
 	rawResources, err := fs.ConvertFSToBytes(resourceFS, fsPath, templateData)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,316 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultReadinessTimeout is how long to wait for resources to become ready when no timeout is configured.
+const DefaultReadinessTimeout = 5 * time.Minute
+
+// time to wait before listing again when a watch ends, e.g. because the API server closed it.
+var rewatchInterval = time.Second
+
+var errResourceFailed = errors.New("resource failed")
+
+// ResourceReadiness is the readiness of a single resource.
+type ResourceReadiness struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Readiness
+}
+
+func (r ResourceReadiness) String() string {
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + r.Name
+	}
+	return fmt.Sprintf("%s %s (%s): %s", r.Kind, name, r.Status, r.Message)
+}
+
+// NotReadyError is returned by WaitForReady when a resource failed, or when resources are not ready before the
+// context is done.
+type NotReadyError struct {
+	// Resources are the resources that are not ready, sorted by kind, namespace, and name.
+	Resources []ResourceReadiness
+	// Err is the context error that stopped waiting. It is nil if a resource failed.
+	Err error
+}
+
+func (e *NotReadyError) Error() string {
+	msgs := make([]string, 0, len(e.Resources))
+	for i := range e.Resources {
+		msgs = append(msgs, e.Resources[i].String())
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("resources not ready: %v: %s", e.Err, strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("resources failed: %s", strings.Join(msgs, "; "))
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+type resourceKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// resources of the same kind in the same namespace are watched together.
+type groupKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+type readinessWaiter struct {
+	kubeClient client.WithWatch
+
+	mu        sync.Mutex
+	resources map[resourceKey]*ResourceReadiness
+	groups    map[groupKey][]string
+}
+
+// WaitForReady watches objs until all of them are ready. Only objects with a readiness check are watched, see
+// HasReadinessCheck. Namespaced objects without a namespace are looked up in namespace. It returns a *NotReadyError
+// naming the resources that are not ready if one of them fails or ctx is done first.
+func WaitForReady(ctx context.Context, kubeClient client.WithWatch, namespace string, objs []client.Object) error {
+	w := &readinessWaiter{
+		kubeClient: kubeClient,
+		resources:  map[resourceKey]*ResourceReadiness{},
+		groups:     map[groupKey][]string{},
+	}
+	for _, obj := range objs {
+		if err := w.add(obj, namespace); err != nil {
+			return err
+		}
+		for _, dep := range readinessDependencies(obj) {
+			if err := w.add(dep, namespace); err != nil {
+				return err
+			}
+		}
+	}
+	return w.wait(ctx)
+}
+
+func (w *readinessWaiter) add(obj client.Object, namespace string) error {
+	if !HasReadinessCheck(obj) {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, w.kubeClient.Scheme())
+	if err != nil {
+		return fmt.Errorf("getting kind of %s: %w", obj.GetName(), err)
+	}
+
+	ns := obj.GetNamespace()
+	if isClusterScoped(obj) {
+		ns = ""
+	} else if ns == "" {
+		ns = namespace
+	}
+
+	key := resourceKey{gvk: gvk, namespace: ns, name: obj.GetName()}
+	if _, ok := w.resources[key]; ok {
+		return nil
+	}
+	w.resources[key] = &ResourceReadiness{Kind: gvk.Kind, Namespace: ns, Name: obj.GetName(), Readiness: inProgress("not found")}
+	group := groupKey{gvk: gvk, namespace: ns}
+	w.groups[group] = append(w.groups[group], obj.GetName())
+	return nil
+}
+
+func (w *readinessWaiter) wait(ctx context.Context) error {
+	if len(w.groups) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(w.groups))
+	for k := range w.groups {
+		go func(k groupKey) {
+			errCh <- w.watchGroup(ctx, k)
+		}(k)
+	}
+
+	var firstErr error
+	for range w.groups {
+		if err := <-errCh; err != nil && firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	switch {
+	case firstErr == nil:
+		return nil
+	case errors.Is(firstErr, errResourceFailed):
+		return &NotReadyError{Resources: w.notReady()}
+	case errors.Is(firstErr, context.Canceled), errors.Is(firstErr, context.DeadlineExceeded):
+		return &NotReadyError{Resources: w.notReady(), Err: firstErr}
+	}
+	return firstErr
+}
+
+// watchGroup lists the resources of a group, then watches them until all are ready. The list is repeated when the
+// watch ends before that.
+func (w *readinessWaiter) watchGroup(ctx context.Context, k groupKey) error {
+	listGVK := k.gvk.GroupVersion().WithKind(k.gvk.Kind + "List")
+	var opts []client.ListOption
+	if k.namespace != "" {
+		opts = append(opts, client.InNamespace(k.namespace))
+	}
+
+	for {
+		obj, err := w.kubeClient.Scheme().New(listGVK)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", listGVK.Kind, err)
+		}
+		list, ok := obj.(client.ObjectList)
+		if !ok {
+			return fmt.Errorf("%s is not a list", listGVK.Kind)
+		}
+
+		if err = w.kubeClient.List(ctx, list, opts...); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("listing %s: %w", k.gvk.Kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", listGVK.Kind, err)
+		}
+		found := map[string]bool{}
+		for i := range items {
+			if o, ok := items[i].(client.Object); ok {
+				found[o.GetName()] = true
+				w.update(ctx, k, o.GetName(), ComputeReadiness(o))
+			}
+		}
+		for _, name := range w.groups[k] {
+			if !found[name] {
+				w.update(ctx, k, name, inProgress("not found"))
+			}
+		}
+		if done, dErr := w.groupDone(k); done {
+			return dErr
+		}
+
+		watchOpts := append([]client.ListOption{&client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: list.GetResourceVersion()}}}, opts...)
+		watcher, err := w.kubeClient.Watch(ctx, list, watchOpts...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("watching %s: %w", k.gvk.Kind, err)
+		}
+		done, err := w.consume(ctx, k, watcher)
+		watcher.Stop()
+		if done || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rewatchInterval):
+		}
+	}
+}
+
+// consume updates the readiness of a group from watch events. It returns false without an error when the watch ends.
+func (w *readinessWaiter) consume(ctx context.Context, k groupKey, watcher watch.Interface) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case ev, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			o, isObj := ev.Object.(client.Object)
+			switch {
+			case ev.Type == watch.Error:
+				return false, nil
+			case !isObj:
+				continue
+			case ev.Type == watch.Deleted:
+				w.update(ctx, k, o.GetName(), inProgress("deleted"))
+			default:
+				w.update(ctx, k, o.GetName(), ComputeReadiness(o))
+			}
+			if done, err := w.groupDone(k); done {
+				return true, err
+			}
+		}
+	}
+}
+
+func (w *readinessWaiter) update(ctx context.Context, k groupKey, name string, r Readiness) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	res, ok := w.resources[resourceKey{gvk: k.gvk, namespace: k.namespace, name: name}]
+	if !ok || res.Readiness == r {
+		return
+	}
+	res.Readiness = r
+	log.FromContext(ctx).V(1).Info("resource readiness changed", "kind", res.Kind, "namespace", res.Namespace,
+		"name", res.Name, "status", r.Status, "message", r.Message)
+}
+
+// groupDone reports whether waiting on a group is finished. It returns errResourceFailed if a resource failed.
+func (w *readinessWaiter) groupDone(k groupKey) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ready := true
+	for _, name := range w.groups[k] {
+		switch w.resources[resourceKey{gvk: k.gvk, namespace: k.namespace, name: name}].Status {
+		case ReadinessFailed:
+			return true, errResourceFailed
+		case ReadinessInProgress:
+			ready = false
+		}
+	}
+	return ready, nil
+}
+
+func (w *readinessWaiter) notReady() []ResourceReadiness {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var out []ResourceReadiness
+	for _, r := range w.resources {
+		if r.Status != ReadinessCurrent {
+			out = append(out, *r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWaitForReady(t *testing.T) {
+	deployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "server", Namespace: "test"}}
+	}
+
+	t.Run("becomes ready", func(t *testing.T) {
+		kubeClient := fake.NewClientBuilder().WithScheme(GetScheme()).WithObjects(deployment()).Build()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// keep updating, so the change is seen however the watch and the update are ordered.
+		go func() {
+			for ctx.Err() == nil {
+				d := deployment()
+				if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(d), d); err == nil {
+					d.Status = appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+					_ = kubeClient.Status().Update(ctx, d)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}()
+
+		// the namespace of the deployment comes from the default namespace.
+		d := deployment()
+		d.Namespace = ""
+		err := WaitForReady(ctx, kubeClient, "test", []client.Object{d, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("timeout names resources", func(t *testing.T) {
+		kubeClient := fake.NewClientBuilder().WithScheme(GetScheme()).WithObjects(deployment()).Build()
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		webhook := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "admission"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name: "validate.cnoe.io",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service:  &admissionregistrationv1.ServiceReference{Namespace: "test", Name: "admission"},
+					CABundle: []byte("ca"),
+				},
+			}},
+		}
+		require.NoError(t, kubeClient.Create(ctx, webhook))
+
+		err := WaitForReady(ctx, kubeClient, "test", []client.Object{deployment(), webhook})
+		var notReady *NotReadyError
+		require.ErrorAs(t, err, &notReady)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, []ResourceReadiness{
+			{Kind: "Deployment", Namespace: "test", Name: "server", Readiness: inProgress("0/1 replicas updated")},
+			{Kind: "Endpoints", Namespace: "test", Name: "admission", Readiness: inProgress("not found")},
+		}, notReady.Resources)
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "create-cert", Namespace: "test"},
+			Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded",
+			}}},
+		}
+		kubeClient := fake.NewClientBuilder().WithScheme(GetScheme()).WithObjects(job, deployment()).Build()
+
+		err := WaitForReady(context.Background(), kubeClient, "test", []client.Object{job, deployment()})
+		var notReady *NotReadyError
+		require.ErrorAs(t, err, &notReady)
+		assert.NoError(t, notReady.Err)
+		assert.Contains(t, err.Error(), "Job test/create-cert (Failed): job failed: BackoffLimitExceeded")
+	})
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
 	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/cnoe-io/idpbuilder/pkg/util/files"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
@@ -19,11 +24,6 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
-const (
-	ingressNginxNodeLabelKey   = "ingress-ready"
-	ingressNginxNodeLabelValue = "true"
-)
-
 var (
 	setupLog = log.Log.WithName("setup")
 )
@@ -34,11 +34,13 @@ type HttpClient interface {
 
 type Cluster struct {
 	provider          IProvider
+	runtime           nodeRuntime
 	httpClient        HttpClient
 	name              string
 	kubeVersion       string
 	kubeConfigPath    string
 	kindConfigPath    string
+	kindConfigPatches []string
 	extraPortsMapping string
 	registryConfig    []string
 	cfg               v1alpha1.BuildCustomizationSpec
@@ -59,7 +61,13 @@ func (c *Cluster) getConfig() ([]byte, error) {
 		return nil, fmt.Errorf("loading config template: %w", err)
 	}
 
-	portMappingPairs := parsePortMappings(c.extraPortsMapping)
+	portMappingPairs, err := ParsePortMappings(c.extraPortsMapping)
+	if err != nil {
+		return nil, fmt.Errorf("parsing extra port mappings: %w", err)
+	}
+	if err = ValidatePortMappings(portMappingPairs, c.cfg.Port); err != nil {
+		return nil, fmt.Errorf("validating extra port mappings: %w", err)
+	}
 
 	registryConfig := findRegistryConfig(c.registryConfig)
 
@@ -73,34 +81,66 @@ func (c *Cluster) getConfig() ([]byte, error) {
 		return nil, errors.New("--registry-config flag used but no registry config was found")
 	}
 
-	var retBuff []byte
-	if retBuff, err = files.ApplyTemplate(rawConfigTempl, TemplateConfig{
+	templateConfig := TemplateConfig{
 		BuildCustomizationSpec: c.cfg,
 		KubernetesVersion:      c.kubeVersion,
 		ExtraPortsMapping:      portMappingPairs,
 		RegistryConfig:         registryConfig,
 		RegistryCertsDir:       registryCertsDir,
-	}); err != nil {
+		GiteaDataNodeDir:       util.GiteaDataNodeDir,
+	}
+
+	var retBuff []byte
+	if retBuff, err = files.ApplyTemplate(rawConfigTempl, templateConfig); err != nil {
 		return nil, err
 	}
 
+	if c.kindConfigPath == "" && len(c.kindConfigPatches) == 0 {
+		return retBuff, nil
+	}
+
+	parsedCluster := kindv1alpha4.Cluster{}
 	if c.kindConfigPath != "" {
-		parsedCluster, err := c.ensureCorrectConfig(retBuff)
+		parsedCluster, err = c.ensureCorrectConfig(retBuff, portMappingPairs)
 		if err != nil {
 			return nil, fmt.Errorf("ensuring custom kind config is correct: %w", err)
 		}
+	} else if err = yaml.Unmarshal(retBuff, &parsedCluster); err != nil {
+		return nil, fmt.Errorf("parsing kind config: %w", err)
+	}
 
-		out, err := yaml.Marshal(parsedCluster)
+	for _, p := range c.kindConfigPatches {
+		rawPatch, err := loadConfig(p, c.httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("loading kind config patch %s: %w", p, err)
+		}
+		renderedPatch, err := files.ApplyTemplate(rawPatch, templateConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rendering kind config patch %s: %w", p, err)
+		}
+		patch, err := parseKindConfigPatch(renderedPatch)
 		if err != nil {
-			return nil, fmt.Errorf("marshaling custom kind cluster config: %w", err)
+			return nil, fmt.Errorf("%s: %w", p, err)
 		}
-		return out, nil
+		parsedCluster = mergeKindConfig(parsedCluster, patch)
 	}
 
-	return retBuff, nil
+	if c.cfg.GiteaDataDir != "" {
+		ensureGiteaDataMount(&parsedCluster, c.cfg.GiteaDataDir)
+	}
+
+	if err = validateKindPortMappings(parsedCluster); err != nil {
+		return nil, fmt.Errorf("validating kind config: %w", err)
+	}
+
+	out, err := yaml.Marshal(parsedCluster)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling kind cluster config: %w", err)
+	}
+	return out, nil
 }
 
-func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath, extraPortsMapping string, registryConfig []string, cfg v1alpha1.BuildCustomizationSpec, cliLogger logr.Logger) (*Cluster, error) {
+func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath string, kindConfigPatches []string, extraPortsMapping string, registryConfig []string, cfg v1alpha1.BuildCustomizationSpec, cliLogger logr.Logger) (*Cluster, error) {
 	detectOpt, err := util.DetectKindNodeProvider()
 	if err != nil {
 		return nil, err
@@ -110,9 +150,11 @@ func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath, extraPortsMap
 
 	return &Cluster{
 		provider:          provider,
-		httpClient:        util.GetHttpClient(),
+		runtime:           cliNodeRuntime{binary: util.KindNodeRuntimeBinary()},
+		httpClient:        util.GetRemoteHttpClient(),
 		name:              name,
 		kindConfigPath:    kindConfigPath,
+		kindConfigPatches: kindConfigPatches,
 		kubeVersion:       kubeVersion,
 		kubeConfigPath:    kubeConfigPath,
 		extraPortsMapping: extraPortsMapping,
@@ -121,6 +163,22 @@ func NewCluster(name, kubeVersion, kubeConfigPath, kindConfigPath, extraPortsMap
 	}, nil
 }
 
+// RenderConfig returns the kind config a cluster with the given options is created with. It does not require a
+// container runtime.
+func RenderConfig(name, kubeVersion, kindConfigPath string, kindConfigPatches []string, extraPortsMapping string, registryConfig []string, cfg v1alpha1.BuildCustomizationSpec) ([]byte, error) {
+	c := &Cluster{
+		httpClient:        util.GetRemoteHttpClient(),
+		name:              name,
+		kindConfigPath:    kindConfigPath,
+		kindConfigPatches: kindConfigPatches,
+		kubeVersion:       kubeVersion,
+		extraPortsMapping: extraPortsMapping,
+		registryConfig:    registryConfig,
+		cfg:               cfg,
+	}
+	return c.getConfig()
+}
+
 func (c *Cluster) Exists() (bool, error) {
 	providerClusters, err := c.provider.List()
 	if err != nil {
@@ -175,6 +233,15 @@ func (c *Cluster) Reconcile(ctx context.Context, recreate bool) error {
 			}
 		} else {
 			setupLog.Info("Cluster already exists", "cluster", c.name)
+			state, err := c.State(ctx)
+			if err != nil {
+				setupLog.V(1).Info("Failed to get cluster state", "cluster", c.name, "error", err)
+			} else if state != ClusterStateRunning {
+				// the build waits for the core packages so only the nodes need to be started here.
+				if _, err = c.startNodes(ctx); err != nil {
+					return err
+				}
+			}
 			if !c.isHealthy() {
 				return c.getClusterHealthError("Cluster exists but is not healthy")
 			}
@@ -187,6 +254,10 @@ func (c *Cluster) Reconcile(ctx context.Context, recreate bool) error {
 		return err
 	}
 
+	if err = saveRenderedConfig(c.name, rawConfig); err != nil {
+		setupLog.V(1).Info("Failed to save kind config", "cluster", c.name, "error", err)
+	}
+
 	fmt.Print("########################### Our kind config ############################\n")
 	fmt.Printf("%s", rawConfig)
 	fmt.Print("\n#########################   config end    ############################\n")
@@ -208,6 +279,31 @@ func (c *Cluster) Reconcile(ctx context.Context, recreate bool) error {
 	return nil
 }
 
+// CollectLogs writes the kind node logs of the cluster to dir.
+func (c *Cluster) CollectLogs(dir string) error {
+	return c.provider.CollectLogs(c.name, dir)
+}
+
+// RenderedConfigPath returns where the kind config used to create the named cluster is saved.
+func RenderedConfigPath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, globals.ProjectName, "clusters", name, "kind.yaml"), nil
+}
+
+func saveRenderedConfig(name string, rawConfig []byte) error {
+	p, err := RenderedConfigPath(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(p, rawConfig, 0600)
+}
+
 func (c *Cluster) ExportKubeConfig(name string, internal bool) error {
 	// Verify cluster is healthy before exporting kubeconfig
 	if !c.isHealthy() {
@@ -221,8 +317,9 @@ func (c *Cluster) ExportKubeConfig(name string, internal bool) error {
 	return nil
 }
 
-func (c *Cluster) ensureCorrectConfig(in []byte) (kindv1alpha4.Cluster, error) {
-	// see pkg/kind/resources/kind.yaml.tmpl and pkg/controllers/localbuild/resources/nginx/k8s/ingress-nginx.yaml
+func (c *Cluster) ensureCorrectConfig(in []byte, extraPortsMapping []PortMapping) (kindv1alpha4.Cluster, error) {
+	// see pkg/kind/resources/kind.yaml.tmpl, pkg/controllers/localbuild/resources/nginx/k8s/ingress-nginx.yaml and
+	// pkg/controllers/localbuild/resources/envoy-gateway/gateway.yaml
 	// defines which container port we should be looking for.
 	containerPort := "443"
 	if c.cfg.Protocol == "http" {
@@ -233,10 +330,10 @@ func (c *Cluster) ensureCorrectConfig(in []byte) (kindv1alpha4.Cluster, error) {
 	if err != nil {
 		return kindv1alpha4.Cluster{}, fmt.Errorf("parsing kind config: %w", err)
 	}
-	// the port and ingress-nginx label must be on the same node to ensure nginx runs on the node with the right port.
+	// the port and ingress label must be on the same node to ensure the ingress runs on the node with the right port.
 	appendNecessaryPort := true
 	appendIngressNodeLabel := true
-	// pick the first node for the ingress-nginx if we need to configure node port.
+	// pick the first node for the ingress if we need to configure node port.
 	nodePosition := 0
 
 	if parsedCluster.Nodes == nil || len(parsedCluster.Nodes) == 0 {
@@ -251,8 +348,8 @@ nodes:
 				appendNecessaryPort = false
 				nodePosition = i
 				if node.Labels != nil {
-					v, ok := node.Labels[ingressNginxNodeLabelKey]
-					if ok && v == ingressNginxNodeLabelValue {
+					v, ok := node.Labels[ingress.NodeLabelKey]
+					if ok && v == ingress.NodeLabelValue {
 						appendIngressNodeLabel = false
 					}
 				}
@@ -260,8 +357,8 @@ nodes:
 			}
 		}
 		if node.Labels != nil {
-			v, ok := node.Labels[ingressNginxNodeLabelKey]
-			if ok && v == ingressNginxNodeLabelValue {
+			v, ok := node.Labels[ingress.NodeLabelKey]
+			if ok && v == ingress.NodeLabelValue {
 				appendIngressNodeLabel = false
 				nodePosition = i
 				break nodes
@@ -280,15 +377,107 @@ nodes:
 		if parsedCluster.Nodes[nodePosition].ExtraPortMappings == nil {
 			parsedCluster.Nodes[nodePosition].ExtraPortMappings = make([]kindv1alpha4.PortMapping, 0, 1)
 		}
-		parsedCluster.Nodes[nodePosition].ExtraPortMappings =
-			append(parsedCluster.Nodes[nodePosition].ExtraPortMappings, kindv1alpha4.PortMapping{ContainerPort: int32(cp), HostPort: int32(hp), Protocol: "TCP"})
+		parsedCluster.Nodes[nodePosition].ExtraPortMappings = append(parsedCluster.Nodes[nodePosition].ExtraPortMappings,
+			kindv1alpha4.PortMapping{ContainerPort: int32(cp), HostPort: int32(hp), Protocol: kindv1alpha4.PortMappingProtocolTCP})
+	}
+
+	// extra port mappings go on the same node unless the config already maps them, e.g. when the custom template uses .ExtraPortsMapping.
+	for _, m := range extraPortsMapping {
+		pm, err := m.toKindPortMapping()
+		if err != nil {
+			return kindv1alpha4.Cluster{}, err
+		}
+		if !hasPortMapping(parsedCluster, pm) {
+			parsedCluster.Nodes[nodePosition].ExtraPortMappings = append(parsedCluster.Nodes[nodePosition].ExtraPortMappings, pm)
+		}
 	}
+
 	if appendIngressNodeLabel {
 		if parsedCluster.Nodes[nodePosition].Labels == nil {
 			parsedCluster.Nodes[nodePosition].Labels = make(map[string]string)
 		}
-		parsedCluster.Nodes[nodePosition].Labels[ingressNginxNodeLabelKey] = ingressNginxNodeLabelValue
+		parsedCluster.Nodes[nodePosition].Labels[ingress.NodeLabelKey] = ingress.NodeLabelValue
 	}
 
 	return parsedCluster, nil
 }
+
+// ensureGiteaDataMount mounts the gitea data directory on the node running the ingress. The gitea volume is pinned to
+// that node, see pkg/controllers/localbuild.
+func ensureGiteaDataMount(in *kindv1alpha4.Cluster, hostPath string) {
+	nodePosition := 0
+	for i := range in.Nodes {
+		if in.Nodes[i].Labels[ingress.NodeLabelKey] == ingress.NodeLabelValue {
+			nodePosition = i
+			break
+		}
+	}
+
+	node := &in.Nodes[nodePosition]
+	for _, m := range node.ExtraMounts {
+		if m.ContainerPath == util.GiteaDataNodeDir {
+			return
+		}
+	}
+	node.ExtraMounts = append(node.ExtraMounts, kindv1alpha4.Mount{
+		HostPath:      hostPath,
+		ContainerPath: util.GiteaDataNodeDir,
+	})
+}
+
+func (p PortMapping) toKindPortMapping() (kindv1alpha4.PortMapping, error) {
+	hp, err := strconv.Atoi(p.HostPort)
+	if err != nil {
+		return kindv1alpha4.PortMapping{}, fmt.Errorf("converting host port, %s, to int: %w", p.HostPort, err)
+	}
+	cp, err := strconv.Atoi(p.ContainerPort)
+	if err != nil {
+		return kindv1alpha4.PortMapping{}, fmt.Errorf("converting container port, %s, to int: %w", p.ContainerPort, err)
+	}
+	return kindv1alpha4.PortMapping{
+		ListenAddress: p.ListenAddress,
+		HostPort:      int32(hp),
+		ContainerPort: int32(cp),
+		Protocol:      kindv1alpha4.PortMappingProtocol(p.Protocol),
+	}, nil
+}
+
+func portMappingKey(pm kindv1alpha4.PortMapping) string {
+	// kind defaults the protocol to TCP
+	protocol := pm.Protocol
+	if protocol == "" {
+		protocol = kindv1alpha4.PortMappingProtocolTCP
+	}
+	return fmt.Sprintf("%d/%s", pm.HostPort, strings.ToUpper(string(protocol)))
+}
+
+func hasPortMapping(cluster kindv1alpha4.Cluster, pm kindv1alpha4.PortMapping) bool {
+	key := portMappingKey(pm)
+	for _, n := range cluster.Nodes {
+		for _, existing := range n.ExtraPortMappings {
+			if portMappingKey(existing) == key && existing.ContainerPort == pm.ContainerPort {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateKindPortMappings returns an error if a host port is mapped more than once for the same protocol.
+// Host port 0 lets the container runtime pick a random port and is allowed to repeat.
+func validateKindPortMappings(cluster kindv1alpha4.Cluster) error {
+	seen := map[string]struct{}{}
+	for _, n := range cluster.Nodes {
+		for _, pm := range n.ExtraPortMappings {
+			if pm.HostPort == 0 {
+				continue
+			}
+			key := portMappingKey(pm)
+			if _, ok := seen[key]; ok {
+				return fmt.Errorf("host port %s is mapped more than once", key)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"regexp"
+	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 	"sigs.k8s.io/kind/pkg/cluster/nodes"
 	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/yaml"
 )
 
 func TestGetConfig(t *testing.T) {
@@ -88,7 +93,7 @@ containerdConfigPatches:
 
 	for i := range tcs {
 		c := tcs[i]
-		cluster, err := NewCluster("testcase", "v1.26.3", "", "", "", c.registryConfig, v1alpha1.BuildCustomizationSpec{
+		cluster, err := NewCluster("testcase", "v1.26.3", "", "", nil, "", c.registryConfig, v1alpha1.BuildCustomizationSpec{
 			Host:           c.host,
 			Port:           c.port,
 			UsePathRouting: c.usePathRouting,
@@ -104,7 +109,7 @@ containerdConfigPatches:
 
 func TestExtraPortMappings(t *testing.T) {
 
-	cluster, err := NewCluster("testcase", "v1.26.3", "", "", "22:32222", nil, v1alpha1.BuildCustomizationSpec{
+	cluster, err := NewCluster("testcase", "v1.26.3", "", "", nil, "22:32222", nil, v1alpha1.BuildCustomizationSpec{
 		Host: "cnoe.localtest.me",
 		Port: "8443",
 	}, logr.Discard())
@@ -188,7 +193,7 @@ func TestGetConfigCustom(t *testing.T) {
 	}
 
 	for _, v := range cases {
-		c, _ := NewCluster("testcase", "v1.26.3", "", v.inputPath, "", nil, v1alpha1.BuildCustomizationSpec{
+		c, _ := NewCluster("testcase", "v1.26.3", "", v.inputPath, nil, "", nil, v1alpha1.BuildCustomizationSpec{
 			Host:     "cnoe.localtest.me",
 			Port:     v.hostPort,
 			Protocol: v.protocol,
@@ -205,6 +210,103 @@ func TestGetConfigCustom(t *testing.T) {
 	}
 }
 
+func TestGetConfigCustomExtraPorts(t *testing.T) {
+	type testCase struct {
+		inputPath         string
+		outputPath        string
+		extraPortsMapping string
+		error             bool
+	}
+
+	cases := []testCase{
+		{
+			inputPath:         "testdata/no-port.yaml",
+			outputPath:        "testdata/expected/no-port-extra-ports.yaml",
+			extraPortsMapping: "127.0.0.1:53:30053/udp,30000-30001:30000-30001",
+		},
+		{
+			// already present in the custom config, should not be added twice
+			inputPath:         "testdata/no-port.yaml",
+			outputPath:        "testdata/expected/no-port.yaml",
+			extraPortsMapping: "31337:31337",
+		},
+		{
+			inputPath:         "testdata/no-port.yaml",
+			extraPortsMapping: "22=32222",
+			error:             true,
+		},
+		{
+			inputPath:         "testdata/no-port.yaml",
+			extraPortsMapping: "8443:30443",
+			error:             true,
+		},
+		{
+			// collides with the port the custom config maps to a different container port
+			inputPath:         "testdata/no-port-multi.yaml",
+			extraPortsMapping: "31340:30000",
+			error:             true,
+		},
+	}
+
+	for _, v := range cases {
+		c := &Cluster{
+			name:              "testcase",
+			kubeVersion:       "v1.26.3",
+			kindConfigPath:    v.inputPath,
+			extraPortsMapping: v.extraPortsMapping,
+			cfg: v1alpha1.BuildCustomizationSpec{
+				Host:     "cnoe.localtest.me",
+				Port:     "8443",
+				Protocol: "https",
+			},
+		}
+
+		b, err := c.getConfig()
+		if v.error {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		expected, _ := os.ReadFile(v.outputPath)
+		assert.YAMLEq(t, string(expected), string(b))
+	}
+}
+
+func TestGetConfigWithPatches(t *testing.T) {
+	c := &Cluster{
+		name:        "testcase",
+		kubeVersion: "v1.26.3",
+		kindConfigPatches: []string{
+			"testdata/patches/mounts-feature-gates.yaml",
+			"testdata/patches/templated-kubeadm.yaml",
+		},
+		cfg: v1alpha1.BuildCustomizationSpec{
+			Host:     "cnoe.localtest.me",
+			Port:     "8443",
+			Protocol: "https",
+		},
+	}
+
+	b, err := c.getConfig()
+	assert.NoError(t, err)
+
+	parsed := kindv1alpha4.Cluster{}
+	assert.NoError(t, yaml.Unmarshal(b, &parsed))
+	certsDir := parsed.Nodes[0].ExtraMounts[0].HostPath
+	assert.Regexp(t, "idpbuilder-registry-certs.d-", certsDir)
+
+	expected, _ := os.ReadFile("testdata/expected/default-patched.yaml")
+	assert.YAMLEq(t, strings.ReplaceAll(string(expected), "CERTS_DIR", certsDir), string(b))
+
+	c.kindConfigPatches = []string{"testdata/patches/invalid-field.yaml"}
+	_, err = c.getConfig()
+	assert.Error(t, err)
+
+	c.kindConfigPatches = []string{"testdata/patches/does-not-exist.yaml"}
+	_, err = c.getConfig()
+	assert.Error(t, err)
+}
+
 // Mock provider for testing
 type mockProvider struct {
 	mock.Mock
@@ -225,6 +327,19 @@ func (m *mockRuntime) ContainerWithPort(ctx context.Context, name string, port s
 	return args.Get(0).(bool), args.Error(1)
 }
 
+func (m *mockRuntime) Start(ctx context.Context, names ...string) error {
+	return m.Called(names).Error(0)
+}
+
+func (m *mockRuntime) Stop(ctx context.Context, names ...string) error {
+	return m.Called(names).Error(0)
+}
+
+func (m *mockRuntime) IsRunning(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
 // Mock Docker client for testing
 type DockerClientMock struct {
 	client.APIClient
@@ -270,3 +385,42 @@ func (n *NodeMock) CommandContext(ctx context.Context, cmd string, args ...strin
 	mockArgs := n.Called(nil)
 	return mockArgs.Get(0).(exec.Cmd)
 }
+
+func TestGetConfigGiteaDataDir(t *testing.T) {
+	expected := kindv1alpha4.Mount{HostPath: "/home/user/gitea", ContainerPath: util.GiteaDataNodeDir}
+	cfg := v1alpha1.BuildCustomizationSpec{
+		Host:         "cnoe.localtest.me",
+		Port:         "8443",
+		Protocol:     "https",
+		GiteaDataDir: "/home/user/gitea",
+	}
+
+	cases := map[string]*Cluster{
+		"default": {name: "testcase", kubeVersion: "v1.26.3", cfg: cfg},
+		"patched": {name: "testcase", kubeVersion: "v1.26.3", cfg: cfg, kindConfigPatches: []string{"testdata/patches/mounts-feature-gates.yaml"}},
+		"custom":  {name: "testcase", kubeVersion: "v1.26.3", cfg: cfg, kindConfigPath: "testdata/no-port-multi.yaml"},
+		"labeled": {name: "testcase", kubeVersion: "v1.26.3", cfg: cfg, kindConfigPath: "testdata/label-only.yaml"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := c.getConfig()
+			assert.NoError(t, err)
+
+			parsed := kindv1alpha4.Cluster{}
+			assert.NoError(t, yaml.Unmarshal(b, &parsed))
+
+			count := 0
+			for _, n := range parsed.Nodes {
+				for _, m := range n.ExtraMounts {
+					if m.ContainerPath == util.GiteaDataNodeDir {
+						count++
+						assert.Equal(t, expected, m)
+						assert.Equal(t, ingress.NodeLabelValue, n.Labels[ingress.NodeLabelKey])
+					}
+				}
+			}
+			assert.Equal(t, 1, count)
+		})
+	}
+}
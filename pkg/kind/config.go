@@ -5,17 +5,43 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/cnoe-io/idpbuilder/pkg/util/files"
+	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/yaml"
 )
 
+const (
+	protocolTCP  = "TCP"
+	protocolUDP  = "UDP"
+	protocolSCTP = "SCTP"
+
+	// GiteaSSHHostPort is the host port always mapped by resources/kind.yaml.tmpl to reach Gitea SSH.
+	GiteaSSHHostPort = "32222"
+)
+
+const (
+	kindAPIVersion = "kind.x-k8s.io/v1alpha4"
+
+	containerdCertsDir = "/etc/containerd/certs.d"
+	extraCACertsFile   = "extra-ca.crt"
+)
+
+var supportedProtocols = []string{protocolTCP, protocolUDP, protocolSCTP}
+
 type PortMapping struct {
+	ListenAddress string
 	HostPort      string
 	ContainerPort string
+	Protocol      string
 }
 
 type TemplateConfig struct {
@@ -24,6 +50,7 @@ type TemplateConfig struct {
 	ExtraPortsMapping []PortMapping
 	RegistryConfig    string
 	RegistryCertsDir  string
+	GiteaDataNodeDir  string
 }
 
 //go:embed resources/* testdata/custom-kind.yaml.tmpl
@@ -59,22 +86,248 @@ func loadConfig(path string, httpClient HttpClient) ([]byte, error) {
 	return rawConfigTempl, nil
 }
 
-func parsePortMappings(extraPortsMapping string) []PortMapping {
+// ParsePortMappings parses a comma separated list of port mappings.
+// Each entry has the form [listenAddress:]hostPort:containerPort[/tcp|udp|sctp] where ports may be ranges
+// of equal length, e.g. 30000-30010:30000-30010. IPv6 listen addresses must be enclosed in brackets.
+func ParsePortMappings(extraPortsMapping string) ([]PortMapping, error) {
 	var portMappingPairs []PortMapping
-	if len(extraPortsMapping) > 0 {
-		// Split pairs of ports "11=1111","22=2222",etc
-		pairs := strings.Split(extraPortsMapping, ",")
-		// Create a slice to store PortMapping pairs.
-		portMappingPairs = make([]PortMapping, len(pairs))
-		// Parse each pair into PortPair objects.
-		for i, pair := range pairs {
-			parts := strings.Split(pair, ":")
-			if len(parts) == 2 {
-				portMappingPairs[i] = PortMapping{parts[0], parts[1]}
-			}
+	if len(extraPortsMapping) == 0 {
+		return portMappingPairs, nil
+	}
+
+	for _, entry := range strings.Split(extraPortsMapping, ",") {
+		mappings, err := parsePortMapping(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port mapping %q: %w", entry, err)
+		}
+		portMappingPairs = append(portMappingPairs, mappings...)
+	}
+	return portMappingPairs, nil
+}
+
+func parsePortMapping(entry string) ([]PortMapping, error) {
+	protocol := protocolTCP
+	if i := strings.LastIndex(entry, "/"); i != -1 {
+		p := strings.ToUpper(entry[i+1:])
+		if !slices.Contains(supportedProtocols, p) {
+			return nil, fmt.Errorf("protocol must be one of tcp, udp or sctp")
+		}
+		protocol = p
+		entry = entry[:i]
+	}
+
+	var listenAddress string
+	if strings.HasPrefix(entry, "[") {
+		i := strings.Index(entry, "]:")
+		if i == -1 {
+			return nil, fmt.Errorf("missing closing bracket for listen address")
+		}
+		listenAddress = entry[1:i]
+		entry = entry[i+2:]
+		if ip := net.ParseIP(listenAddress); ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("%s is not a valid IPv6 address", listenAddress)
+		}
+	}
+
+	parts := strings.Split(entry, ":")
+	switch {
+	case len(parts) == 3 && listenAddress == "":
+		listenAddress = parts[0]
+		if net.ParseIP(listenAddress) == nil {
+			return nil, fmt.Errorf("%s is not a valid listen address", listenAddress)
+		}
+		parts = parts[1:]
+	case len(parts) != 2:
+		return nil, fmt.Errorf("expected format [listenAddress:]hostPort:containerPort[/protocol]")
+	}
+
+	hostStart, hostEnd, err := parsePortRange(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("host port: %w", err)
+	}
+	containerStart, containerEnd, err := parsePortRange(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("container port: %w", err)
+	}
+	if hostEnd-hostStart != containerEnd-containerStart {
+		return nil, fmt.Errorf("host and container port ranges must be the same size")
+	}
+
+	mappings := make([]PortMapping, 0, hostEnd-hostStart+1)
+	for i := 0; i <= hostEnd-hostStart; i++ {
+		mappings = append(mappings, PortMapping{
+			ListenAddress: listenAddress,
+			HostPort:      strconv.Itoa(hostStart + i),
+			ContainerPort: strconv.Itoa(containerStart + i),
+			Protocol:      protocol,
+		})
+	}
+	return mappings, nil
+}
+
+func parsePortRange(in string) (int, int, error) {
+	startStr, endStr, isRange := strings.Cut(in, "-")
+	start, err := parsePort(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := parsePort(endStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("range %s is not in ascending order", in)
+	}
+	return start, end, nil
+}
+
+func parsePort(in string) (int, error) {
+	p, err := strconv.Atoi(in)
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("%q is not a valid port number", in)
+	}
+	return p, nil
+}
+
+// ValidatePortMappings returns an error if a host port is mapped more than once for the same protocol
+// or if a mapping collides with the port used to access web UIs or the Gitea SSH port.
+func ValidatePortMappings(mappings []PortMapping, port string) error {
+	seen := make(map[string]struct{}, len(mappings))
+	for _, m := range mappings {
+		if m.Protocol == protocolTCP && m.HostPort == port {
+			return fmt.Errorf("host port %s is already used by --port", m.HostPort)
+		}
+		if m.Protocol == protocolTCP && m.HostPort == GiteaSSHHostPort {
+			return fmt.Errorf("host port %s is reserved for Gitea SSH", m.HostPort)
+		}
+		key := fmt.Sprintf("%s/%s", m.HostPort, m.Protocol)
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("host port %s is mapped more than once", key)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// mergeKindConfig merges a partial kind Cluster document into base using the following rules:
+//   - name, node role and image, and networking fields are replaced when set in the patch.
+//   - featureGates, runtimeConfig and node labels are merged key by key. The patch wins on conflicts.
+//   - kubeadm and containerd config patches are appended.
+//   - nodes are matched by position. Patch nodes without a counterpart in base are appended.
+//   - node extraMounts replace the base mount with the same containerPath and are appended otherwise.
+//   - node extraPortMappings are appended unless an identical mapping already exists.
+func mergeKindConfig(base, patch kindv1alpha4.Cluster) kindv1alpha4.Cluster {
+	if patch.Name != "" {
+		base.Name = patch.Name
+	}
+
+	base.FeatureGates = mergeMap(base.FeatureGates, patch.FeatureGates)
+	base.RuntimeConfig = mergeMap(base.RuntimeConfig, patch.RuntimeConfig)
+	base.Networking = mergeNetworking(base.Networking, patch.Networking)
+
+	base.KubeadmConfigPatches = append(base.KubeadmConfigPatches, patch.KubeadmConfigPatches...)
+	base.KubeadmConfigPatchesJSON6902 = append(base.KubeadmConfigPatchesJSON6902, patch.KubeadmConfigPatchesJSON6902...)
+	base.ContainerdConfigPatches = append(base.ContainerdConfigPatches, patch.ContainerdConfigPatches...)
+	base.ContainerdConfigPatchesJSON6902 = append(base.ContainerdConfigPatchesJSON6902, patch.ContainerdConfigPatchesJSON6902...)
+
+	for i := range patch.Nodes {
+		if i >= len(base.Nodes) {
+			base.Nodes = append(base.Nodes, patch.Nodes[i])
+			continue
+		}
+		base.Nodes[i] = mergeNode(base.Nodes[i], patch.Nodes[i])
+	}
+	return base
+}
+
+func mergeNode(base, patch kindv1alpha4.Node) kindv1alpha4.Node {
+	if patch.Role != "" {
+		base.Role = patch.Role
+	}
+	if patch.Image != "" {
+		base.Image = patch.Image
+	}
+	base.Labels = mergeMap(base.Labels, patch.Labels)
+
+	for _, m := range patch.ExtraMounts {
+		i := slices.IndexFunc(base.ExtraMounts, func(e kindv1alpha4.Mount) bool {
+			return e.ContainerPath == m.ContainerPath
+		})
+		if i == -1 {
+			base.ExtraMounts = append(base.ExtraMounts, m)
+			continue
+		}
+		base.ExtraMounts[i] = m
+	}
+
+	for _, pm := range patch.ExtraPortMappings {
+		if !slices.Contains(base.ExtraPortMappings, pm) {
+			base.ExtraPortMappings = append(base.ExtraPortMappings, pm)
 		}
 	}
-	return portMappingPairs
+
+	base.KubeadmConfigPatches = append(base.KubeadmConfigPatches, patch.KubeadmConfigPatches...)
+	base.KubeadmConfigPatchesJSON6902 = append(base.KubeadmConfigPatchesJSON6902, patch.KubeadmConfigPatchesJSON6902...)
+	return base
+}
+
+func mergeNetworking(base, patch kindv1alpha4.Networking) kindv1alpha4.Networking {
+	if patch.IPFamily != "" {
+		base.IPFamily = patch.IPFamily
+	}
+	if patch.APIServerPort != 0 {
+		base.APIServerPort = patch.APIServerPort
+	}
+	if patch.APIServerAddress != "" {
+		base.APIServerAddress = patch.APIServerAddress
+	}
+	if patch.PodSubnet != "" {
+		base.PodSubnet = patch.PodSubnet
+	}
+	if patch.ServiceSubnet != "" {
+		base.ServiceSubnet = patch.ServiceSubnet
+	}
+	if patch.DisableDefaultCNI {
+		base.DisableDefaultCNI = true
+	}
+	if patch.KubeProxyMode != "" {
+		base.KubeProxyMode = patch.KubeProxyMode
+	}
+	if patch.DNSSearch != nil {
+		base.DNSSearch = patch.DNSSearch
+	}
+	return base
+}
+
+func mergeMap[V any](base, patch map[string]V) map[string]V {
+	if len(patch) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]V, len(patch))
+	}
+	for k, v := range patch {
+		base[k] = v
+	}
+	return base
+}
+
+// parseKindConfigPatch parses a partial kind Cluster document. Unknown fields are rejected to catch typos early.
+func parseKindConfigPatch(in []byte) (kindv1alpha4.Cluster, error) {
+	patch := kindv1alpha4.Cluster{}
+	if err := yaml.UnmarshalStrict(in, &patch); err != nil {
+		return kindv1alpha4.Cluster{}, fmt.Errorf("parsing kind config patch: %w", err)
+	}
+	if patch.Kind != "" && patch.Kind != "Cluster" {
+		return kindv1alpha4.Cluster{}, fmt.Errorf("kind config patch must be of kind Cluster, got %s", patch.Kind)
+	}
+	if patch.APIVersion != "" && patch.APIVersion != kindAPIVersion {
+		return kindv1alpha4.Cluster{}, fmt.Errorf("kind config patch must use apiVersion %s, got %s", kindAPIVersion, patch.APIVersion)
+	}
+	return patch, nil
 }
 
 func findRegistryConfig(registryConfigPaths []string) string {
@@ -87,6 +340,24 @@ func findRegistryConfig(registryConfigPaths []string) string {
 	return ""
 }
 
+// renderDefaultRegistryCA makes containerd trust the extra CA certificates for all registries.
+// The certificates are also mounted into the node trust store from the same file. See resources/kind.yaml.tmpl
+func renderDefaultRegistryCA(certsDir, caCerts string) error {
+	defaultDir := filepath.Join(certsDir, "_default")
+	if err := os.Mkdir(defaultDir, 0700); err != nil {
+		return fmt.Errorf("creating default registry config dir %w", err)
+	}
+
+	hosts := fmt.Sprintf("ca = %q\n", path.Join(containerdCertsDir, "_default", extraCACertsFile))
+	if err := os.WriteFile(filepath.Join(defaultDir, "hosts.toml"), []byte(hosts), 0600); err != nil {
+		return fmt.Errorf("writing default registry config %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(defaultDir, extraCACertsFile), []byte(caCerts), 0644); err != nil {
+		return fmt.Errorf("writing extra CA certificates %w", err)
+	}
+	return nil
+}
+
 func renderRegistryCertsDir(cfg v1alpha1.BuildCustomizationSpec) (string, error) {
 	// Render out the template
 	rawConfigTempl, err := fs.ReadFile(configFS, "resources/hosts.toml.tmpl")
@@ -123,5 +394,11 @@ func renderRegistryCertsDir(cfg v1alpha1.BuildCustomizationSpec) (string, error)
 		return "", fmt.Errorf("writing insecure registry config %w", err)
 	}
 
+	if cfg.ExtraCACerts != "" {
+		if err = renderDefaultRegistryCA(dir, cfg.ExtraCACerts); err != nil {
+			return "", err
+		}
+	}
+
 	return dir, nil
 }
@@ -5,9 +5,14 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+
+	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/yaml"
 )
 
 type MockHttpClient struct{}
@@ -115,6 +120,7 @@ func TestExtraPortMappingsUtilFunc(t *testing.T) {
 	type test struct {
 		extraPortMappings string
 		expected          []PortMapping
+		err               bool
 	}
 	tests := []test{
 		{
@@ -127,6 +133,7 @@ func TestExtraPortMappingsUtilFunc(t *testing.T) {
 				{
 					HostPort:      "22",
 					ContainerPort: "32222",
+					Protocol:      "TCP",
 				},
 			},
 		},
@@ -136,27 +143,126 @@ func TestExtraPortMappingsUtilFunc(t *testing.T) {
 				{
 					HostPort:      "11",
 					ContainerPort: "1111",
+					Protocol:      "TCP",
 				},
 				{
 					HostPort:      "33",
 					ContainerPort: "3333",
+					Protocol:      "TCP",
 				},
 				{
 					HostPort:      "4444",
 					ContainerPort: "4444",
+					Protocol:      "TCP",
+				},
+			},
+		},
+		{
+			extraPortMappings: "127.0.0.1:53:30053/udp, 9000:39000/SCTP",
+			expected: []PortMapping{
+				{
+					ListenAddress: "127.0.0.1",
+					HostPort:      "53",
+					ContainerPort: "30053",
+					Protocol:      "UDP",
+				},
+				{
+					HostPort:      "9000",
+					ContainerPort: "39000",
+					Protocol:      "SCTP",
+				},
+			},
+		},
+		{
+			extraPortMappings: "[::1]:8080:30080",
+			expected: []PortMapping{
+				{
+					ListenAddress: "::1",
+					HostPort:      "8080",
+					ContainerPort: "30080",
+					Protocol:      "TCP",
 				},
 			},
 		},
+		{
+			extraPortMappings: "30000-30002:31000-31002/udp",
+			expected: []PortMapping{
+				{
+					HostPort:      "30000",
+					ContainerPort: "31000",
+					Protocol:      "UDP",
+				},
+				{
+					HostPort:      "30001",
+					ContainerPort: "31001",
+					Protocol:      "UDP",
+				},
+				{
+					HostPort:      "30002",
+					ContainerPort: "31002",
+					Protocol:      "UDP",
+				},
+			},
+		},
+		{extraPortMappings: "22=32222", err: true},
+		{extraPortMappings: "80", err: true},
+		{extraPortMappings: "22:32222,", err: true},
+		{extraPortMappings: "0:32222", err: true},
+		{extraPortMappings: "22:70000", err: true},
+		{extraPortMappings: "22:32222/http", err: true},
+		{extraPortMappings: "localhost:22:32222", err: true},
+		{extraPortMappings: "[::1:22:32222", err: true},
+		{extraPortMappings: "30000-30010:30000-30005", err: true},
+		{extraPortMappings: "30010-30000:30010-30000", err: true},
 	}
 
 	for _, tc := range tests {
-		pmOutput := parsePortMappings(tc.extraPortMappings)
+		pmOutput, err := ParsePortMappings(tc.extraPortMappings)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected error for %q", tc.extraPortMappings)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", tc.extraPortMappings, err)
+		}
 		if !reflect.DeepEqual(tc.expected, pmOutput) {
 			t.Errorf("expected: %v, got: %v", tc.expected, pmOutput)
 		}
 	}
 }
 
+func TestValidatePortMappings(t *testing.T) {
+	type test struct {
+		extraPortMappings string
+		port              string
+		err               bool
+	}
+	tests := []test{
+		{extraPortMappings: "22:32222,9090:39090", port: "8443"},
+		{extraPortMappings: "53:30053/tcp,53:30053/udp", port: "8443"},
+		{extraPortMappings: "8443:30443/udp", port: "8443"},
+		{extraPortMappings: "22:32222,22:32223", port: "8443", err: true},
+		{extraPortMappings: "30000-30010:30000-30010,30005:31005", port: "8443", err: true},
+		{extraPortMappings: "8443:30443", port: "8443", err: true},
+		{extraPortMappings: "32222:30022", port: "8443", err: true},
+		{extraPortMappings: "32220-32225:30020-30025", port: "8443", err: true},
+		{extraPortMappings: "32222:30022/udp", port: "8443"},
+	}
+
+	for _, tc := range tests {
+		pm, err := ParsePortMappings(tc.extraPortMappings)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", tc.extraPortMappings, err)
+		}
+		err = ValidatePortMappings(pm, tc.port)
+		if tc.err != (err != nil) {
+			t.Errorf("%q: expected error: %t, got: %v", tc.extraPortMappings, tc.err, err)
+		}
+	}
+}
+
 func TestFindRegistryConfig(t *testing.T) {
 	type test struct {
 		paths    []string
@@ -184,3 +290,241 @@ func TestFindRegistryConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeKindConfig(t *testing.T) {
+	type test struct {
+		name     string
+		base     string
+		patch    string
+		expected string
+	}
+	tests := []test{
+		{
+			name: "scalars are replaced and maps are merged",
+			base: `
+name: base
+featureGates:
+  A: true
+  B: true
+runtimeConfig:
+  api/alpha: "false"
+networking:
+  podSubnet: 10.244.0.0/16
+  apiServerPort: 6443
+nodes:
+- role: control-plane
+  labels:
+    ingress-ready: "true"
+    a: b
+`,
+			patch: `
+name: patched
+featureGates:
+  B: false
+  C: true
+networking:
+  podSubnet: 10.245.0.0/16
+  disableDefaultCNI: true
+nodes:
+- image: kindest/node:custom
+  labels:
+    a: c
+`,
+			expected: `
+name: patched
+featureGates:
+  A: true
+  B: false
+  C: true
+runtimeConfig:
+  api/alpha: "false"
+networking:
+  podSubnet: 10.245.0.0/16
+  apiServerPort: 6443
+  disableDefaultCNI: true
+nodes:
+- role: control-plane
+  image: kindest/node:custom
+  labels:
+    ingress-ready: "true"
+    a: c
+`,
+		},
+		{
+			name: "config patches are appended",
+			base: `
+kubeadmConfigPatches:
+- base
+containerdConfigPatches:
+- base
+nodes:
+- role: control-plane
+  kubeadmConfigPatches:
+  - base
+`,
+			patch: `
+kubeadmConfigPatches:
+- patch
+containerdConfigPatches:
+- patch
+kubeadmConfigPatchesJSON6902:
+- group: kubeadm.k8s.io
+  version: v1beta3
+  kind: ClusterConfiguration
+  patch: "[]"
+nodes:
+- kubeadmConfigPatches:
+  - patch
+`,
+			expected: `
+kubeadmConfigPatches:
+- base
+- patch
+containerdConfigPatches:
+- base
+- patch
+kubeadmConfigPatchesJSON6902:
+- group: kubeadm.k8s.io
+  version: v1beta3
+  kind: ClusterConfiguration
+  patch: "[]"
+nodes:
+- role: control-plane
+  kubeadmConfigPatches:
+  - base
+  - patch
+`,
+		},
+		{
+			name: "mounts are replaced by container path and port mappings are deduplicated",
+			base: `
+nodes:
+- role: control-plane
+  extraMounts:
+  - containerPath: /etc/containerd/certs.d
+    hostPath: /tmp/certs
+  extraPortMappings:
+  - containerPort: 443
+    hostPort: 8443
+    protocol: TCP
+`,
+			patch: `
+nodes:
+- extraMounts:
+  - containerPath: /etc/containerd/certs.d
+    hostPath: /tmp/other-certs
+    readOnly: true
+  - containerPath: /data
+    hostPath: /tmp/data
+  extraPortMappings:
+  - containerPort: 443
+    hostPort: 8443
+    protocol: TCP
+  - containerPort: 30080
+    hostPort: 8080
+`,
+			expected: `
+nodes:
+- role: control-plane
+  extraMounts:
+  - containerPath: /etc/containerd/certs.d
+    hostPath: /tmp/other-certs
+    readOnly: true
+  - containerPath: /data
+    hostPath: /tmp/data
+  extraPortMappings:
+  - containerPort: 443
+    hostPort: 8443
+    protocol: TCP
+  - containerPort: 30080
+    hostPort: 8080
+`,
+		},
+		{
+			name: "nodes are matched by position and extra nodes are appended",
+			base: `
+nodes:
+- role: control-plane
+`,
+			patch: `
+nodes:
+- {}
+- role: worker
+  image: kindest/node:worker
+`,
+			expected: `
+nodes:
+- role: control-plane
+- role: worker
+  image: kindest/node:worker
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			base := kindv1alpha4.Cluster{}
+			if err := yaml.Unmarshal([]byte(tc.base), &base); err != nil {
+				t.Fatalf("parsing base: %v", err)
+			}
+			patch, err := parseKindConfigPatch([]byte(tc.patch))
+			if err != nil {
+				t.Fatalf("parsing patch: %v", err)
+			}
+			expected := kindv1alpha4.Cluster{}
+			if err := yaml.Unmarshal([]byte(tc.expected), &expected); err != nil {
+				t.Fatalf("parsing expected: %v", err)
+			}
+
+			out := mergeKindConfig(base, patch)
+			if !reflect.DeepEqual(expected, out) {
+				t.Errorf("expected:\n%+v\ngot:\n%+v", expected, out)
+			}
+		})
+	}
+}
+
+func TestParseKindConfigPatch(t *testing.T) {
+	type test struct {
+		patch string
+		err   bool
+	}
+	tests := []test{
+		{patch: "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: worker\n"},
+		{patch: "featureGates:\n  A: true\n"},
+		{patch: "kind: Pod\n", err: true},
+		{patch: "apiVersion: kind.x-k8s.io/v1alpha3\n", err: true},
+		{patch: "nodes:\n- extraMount: []\n", err: true},
+		{patch: "nodes: {}\n", err: true},
+	}
+
+	for _, tc := range tests {
+		_, err := parseKindConfigPatch([]byte(tc.patch))
+		if tc.err != (err != nil) {
+			t.Errorf("%q: expected error: %t, got: %v", tc.patch, tc.err, err)
+		}
+	}
+}
+
+func TestRenderDefaultRegistryCA(t *testing.T) {
+	dir := t.TempDir()
+	if err := renderDefaultRegistryCA(dir, "my-ca"); err != nil {
+		t.Fatalf("failed to render default registry CA: %v", err)
+	}
+
+	hosts, err := os.ReadFile(filepath.Join(dir, "_default", "hosts.toml"))
+	if err != nil {
+		t.Fatalf("failed to read hosts.toml: %v", err)
+	}
+	if expected := "ca = \"/etc/containerd/certs.d/_default/extra-ca.crt\"\n"; string(hosts) != expected {
+		t.Errorf("expected hosts.toml %q, found %q", expected, string(hosts))
+	}
+
+	ca, err := os.ReadFile(filepath.Join(dir, "_default", extraCACertsFile))
+	if err != nil {
+		t.Fatalf("failed to read CA file: %v", err)
+	}
+	if string(ca) != "my-ca" {
+		t.Errorf("expected CA file content %q, found %q", "my-ca", string(ca))
+	}
+}
@@ -0,0 +1,327 @@
+package kind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	kindexec "sigs.k8s.io/kind/pkg/exec"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+)
+
+type ClusterState string
+
+const (
+	ClusterStateRunning ClusterState = "running"
+	ClusterStateStopped ClusterState = "stopped"
+	// ClusterStatePartial means some node containers are running and others are not.
+	ClusterStatePartial ClusterState = "partial"
+	// ClusterStateUnknown means the node containers of the cluster could not be inspected.
+	ClusterStateUnknown ClusterState = "unknown"
+
+	lifecyclePollInterval = 2 * time.Second
+)
+
+// deployments that must be available before a started cluster is considered ready. The deployment of the ingress
+// installed in the cluster must be available too, see ingress.Providers, and Gitea unless it is skipped.
+var coreDeployments = []types.NamespacedName{
+	{Namespace: "kube-system", Name: "coredns"},
+}
+
+var giteaDeployment = types.NamespacedName{Namespace: util.GiteaNamespace, Name: "my-gitea"}
+
+// nodeRuntime starts and stops node containers. kind does not support this so the container runtime is called directly.
+type nodeRuntime interface {
+	Start(ctx context.Context, names ...string) error
+	Stop(ctx context.Context, names ...string) error
+	IsRunning(ctx context.Context, name string) (bool, error)
+}
+
+type cliNodeRuntime struct {
+	binary string
+}
+
+func (r cliNodeRuntime) Start(ctx context.Context, names ...string) error {
+	_, err := r.run(ctx, append([]string{"start"}, names...)...)
+	return err
+}
+
+func (r cliNodeRuntime) Stop(ctx context.Context, names ...string) error {
+	_, err := r.run(ctx, append([]string{"stop"}, names...)...)
+	return err
+}
+
+func (r cliNodeRuntime) IsRunning(ctx context.Context, name string) (bool, error) {
+	lines, err := r.run(ctx, "inspect", "--format", "{{.State.Running}}", name)
+	if err != nil {
+		return false, err
+	}
+	if len(lines) != 1 {
+		return false, fmt.Errorf("unexpected output inspecting %s: %s", name, strings.Join(lines, "\n"))
+	}
+	return strings.TrimSpace(lines[0]) == "true", nil
+}
+
+func (r cliNodeRuntime) run(ctx context.Context, args ...string) ([]string, error) {
+	if r.binary == "" {
+		return nil, fmt.Errorf("no container runtime found")
+	}
+	lines, err := kindexec.OutputLines(kindexec.CommandContext(ctx, r.binary, args...))
+	if err != nil {
+		t := &kindexec.RunError{}
+		if errors.As(err, &t) {
+			return nil, fmt.Errorf("%w: %s", err, t.Output)
+		}
+		return nil, err
+	}
+	return lines, nil
+}
+
+func (c *Cluster) listNodes() ([]nodes.Node, error) {
+	n, err := c.provider.ListNodes(c.name)
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes of cluster %s: %w", c.name, err)
+	}
+	if len(n) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", c.name)
+	}
+	return n, nil
+}
+
+// State returns whether the node containers of the cluster are running.
+func (c *Cluster) State(ctx context.Context) (ClusterState, error) {
+	n, err := c.listNodes()
+	if err != nil {
+		return "", err
+	}
+
+	running := 0
+	for i := range n {
+		ok, err := c.runtime.IsRunning(ctx, n[i].String())
+		if err != nil {
+			return "", fmt.Errorf("getting state of node %s: %w", n[i].String(), err)
+		}
+		if ok {
+			running++
+		}
+	}
+
+	switch running {
+	case len(n):
+		return ClusterStateRunning, nil
+	case 0:
+		return ClusterStateStopped, nil
+	default:
+		return ClusterStatePartial, nil
+	}
+}
+
+// Stop stops the node containers of the cluster. The cluster and its data are kept.
+func (c *Cluster) Stop(ctx context.Context) error {
+	n, err := c.listNodes()
+	if err != nil {
+		return err
+	}
+
+	setupLog.Info("Stopping cluster", "cluster", c.name)
+	if err = c.runtime.Stop(ctx, nodeNames(n)...); err != nil {
+		return fmt.Errorf("stopping nodes of cluster %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// Start starts the node containers of a stopped cluster, exports its kubeconfig and waits for the nodes and core
+// workloads to become ready.
+func (c *Cluster) Start(ctx context.Context) error {
+	startedAt, err := c.startNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the API server port may change when containers are restarted.
+	if err = c.ExportKubeConfig(c.name, false); err != nil {
+		return err
+	}
+
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", c.kubeConfigPath)
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+	kubeClient, err := client.New(kubeConfig, client.Options{Scheme: k8s.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	setupLog.Info("Waiting for cluster to become ready", "cluster", c.name)
+	return waitForReady(ctx, kubeClient, startedAt)
+}
+
+// startNodes starts the node containers and waits until kind reports the cluster as healthy.
+// It returns the time the containers were started.
+func (c *Cluster) startNodes(ctx context.Context) (time.Time, error) {
+	n, err := c.listNodes()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	setupLog.Info("Starting cluster", "cluster", c.name)
+	// conditions are stored with second precision.
+	startedAt := time.Now().Truncate(time.Second)
+	if err = c.runtime.Start(ctx, nodeNames(n)...); err != nil {
+		return time.Time{}, fmt.Errorf("starting nodes of cluster %s: %w", c.name, err)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, lifecyclePollInterval, true, func(ctx context.Context) (bool, error) {
+		state, err := c.State(ctx)
+		if err != nil {
+			return false, err
+		}
+		return state == ClusterStateRunning && c.isHealthy(), nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w\n%w", err, c.getClusterHealthError("Failed to start cluster"))
+	}
+	return startedAt, nil
+}
+
+// waitForReady waits for all nodes and core deployments to report ready after the given time.
+// Status written before the cluster was stopped is ignored because it may be stale.
+func waitForReady(ctx context.Context, kubeClient client.Client, since time.Time) error {
+	var notReady string
+	err := wait.PollUntilContextCancel(ctx, lifecyclePollInterval, true, func(ctx context.Context) (bool, error) {
+		var err error
+		notReady, err = findNotReady(ctx, kubeClient, since)
+		if err != nil {
+			setupLog.V(1).Info("Failed to check readiness", "error", err)
+			return false, nil
+		}
+		return notReady == "", nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s to become ready: %w", notReady, err)
+	}
+	return nil
+}
+
+// findNotReady returns the name of the first node or core deployment that is not ready, or an empty string.
+func findNotReady(ctx context.Context, kubeClient client.Client, since time.Time) (string, error) {
+	nodeList := corev1.NodeList{}
+	if err := kubeClient.List(ctx, &nodeList); err != nil {
+		return "", err
+	}
+	for i := range nodeList.Items {
+		if !isNodeReady(nodeList.Items[i], since) {
+			return fmt.Sprintf("node %s", nodeList.Items[i].Name), nil
+		}
+	}
+
+	required, err := requiredDeployments(ctx, kubeClient)
+	if err != nil {
+		return "", err
+	}
+	deployments := slices.Clone(required)
+	for _, p := range ingress.Providers() {
+		deployments = append(deployments, p.Deployment())
+	}
+	for _, nn := range deployments {
+		d := appsv1.Deployment{}
+		if err := kubeClient.Get(ctx, nn, &d); err != nil {
+			// only the selected ingress is installed.
+			if k8serrors.IsNotFound(err) && !slices.Contains(required, nn) {
+				continue
+			}
+			return "", err
+		}
+		ready, err := isDeploymentReady(ctx, kubeClient, d, since)
+		if err != nil {
+			return "", err
+		}
+		if !ready {
+			return fmt.Sprintf("deployment %s/%s", nn.Namespace, nn.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// requiredDeployments returns the deployments that must exist in the cluster. Gitea is required unless every
+// Localbuild in the cluster skips it.
+func requiredDeployments(ctx context.Context, kubeClient client.Client) ([]types.NamespacedName, error) {
+	localBuilds := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &localBuilds); err != nil {
+		return nil, fmt.Errorf("listing localbuilds: %w", err)
+	}
+	skipGitea := len(localBuilds.Items) > 0
+	for i := range localBuilds.Items {
+		skipGitea = skipGitea && localBuilds.Items[i].Spec.BuildCustomization.SkipGitea
+	}
+	if skipGitea {
+		return coreDeployments, nil
+	}
+	return append(slices.Clone(coreDeployments), giteaDeployment), nil
+}
+
+func isNodeReady(n corev1.Node, since time.Time) bool {
+	for _, cond := range n.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue && !cond.LastHeartbeatTime.Time.Before(since)
+		}
+	}
+	return false
+}
+
+func isDeploymentReady(ctx context.Context, kubeClient client.Client, d appsv1.Deployment, since time.Time) (bool, error) {
+	selector := labels.SelectorFromSet(d.Spec.Selector.MatchLabels)
+	pods := corev1.PodList{}
+	if err := kubeClient.List(ctx, &pods, client.InNamespace(d.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, err
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	var ready int32
+	for i := range pods.Items {
+		if isPodReady(pods.Items[i], since) {
+			ready++
+		}
+	}
+	return ready >= replicas, nil
+}
+
+func isPodReady(p corev1.Pod, since time.Time) bool {
+	if p.DeletionTimestamp != nil {
+		return false
+	}
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue && !cond.LastTransitionTime.Time.Before(since)
+		}
+	}
+	return false
+}
+
+func nodeNames(n []nodes.Node) []string {
+	names := make([]string, 0, len(n))
+	for i := range n {
+		names = append(names, n[i].String())
+	}
+	return names
+}
@@ -0,0 +1,150 @@
+package kind
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+func testNodes(names ...string) []nodes.Node {
+	out := make([]nodes.Node, 0, len(names))
+	for _, n := range names {
+		node := &NodeMock{}
+		node.On("String").Return(n)
+		out = append(out, node)
+	}
+	return out
+}
+
+func TestClusterState(t *testing.T) {
+	cases := map[string]struct {
+		running  map[string]bool
+		expected ClusterState
+	}{
+		"running": {running: map[string]bool{"a": true, "b": true}, expected: ClusterStateRunning},
+		"stopped": {running: map[string]bool{"a": false, "b": false}, expected: ClusterStateStopped},
+		"partial": {running: map[string]bool{"a": true, "b": false}, expected: ClusterStatePartial},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := &mockProvider{}
+			p.On("ListNodes", "test").Return(testNodes("a", "b"), nil)
+			r := &mockRuntime{}
+			for n, running := range tc.running {
+				r.On("IsRunning", n).Return(running, nil)
+			}
+
+			c := &Cluster{name: "test", provider: p, runtime: r}
+			state, err := c.State(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, state)
+		})
+	}
+
+	p := &mockProvider{}
+	p.On("ListNodes", "missing").Return([]nodes.Node{}, nil)
+	c := &Cluster{name: "missing", provider: p, runtime: &mockRuntime{}}
+	_, err := c.State(context.Background())
+	assert.Error(t, err)
+}
+
+func TestClusterStopStart(t *testing.T) {
+	p := &mockProvider{}
+	p.On("ListNodes", "test").Return(testNodes("test-control-plane", "test-worker"), nil)
+	r := &mockRuntime{}
+	r.On("Stop", []string{"test-control-plane", "test-worker"}).Return(nil)
+	r.On("Start", []string{"test-control-plane", "test-worker"}).Return(nil)
+	r.On("IsRunning", "test-control-plane").Return(true, nil)
+	r.On("IsRunning", "test-worker").Return(true, nil)
+
+	c := &Cluster{name: "test", provider: p, runtime: r}
+	assert.NoError(t, c.Stop(context.Background()))
+
+	before := time.Now().Truncate(time.Second)
+	startedAt, err := c.startNodes(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, startedAt.Before(before))
+	r.AssertExpectations(t)
+
+	r = &mockRuntime{}
+	r.On("Start", []string{"test-control-plane", "test-worker"}).Return(fmt.Errorf("boom"))
+	c.runtime = r
+	_, err = c.startNodes(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFindNotReady(t *testing.T) {
+	since := time.Now().Truncate(time.Second)
+	fresh := metav1.NewTime(since.Add(time.Second))
+	stale := metav1.NewTime(since.Add(-time.Hour))
+
+	node := func(heartbeat metav1.Time) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastHeartbeatTime: heartbeat},
+			}},
+		}
+	}
+	objects := func(nodeHeartbeat, podTransition metav1.Time, deployments ...types.NamespacedName) []client.Object {
+		objs := []client.Object{node(nodeHeartbeat)}
+		for _, nn := range deployments {
+			labels := map[string]string{"app": nn.Name}
+			objs = append(objs,
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace},
+					Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: nn.Name + "-abc", Namespace: nn.Namespace, Labels: labels},
+					Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+						{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: podTransition},
+					}},
+				},
+			)
+		}
+		return objs
+	}
+
+	cases := map[string]struct {
+		objects  []client.Object
+		expected string
+	}{
+		"ready":      {objects: objects(fresh, fresh, append(coreDeployments, giteaDeployment)...), expected: ""},
+		"stale node": {objects: objects(stale, fresh, append(coreDeployments, giteaDeployment)...), expected: "node test-control-plane"},
+		"stale pods": {objects: objects(fresh, stale, append(coreDeployments, giteaDeployment)...), expected: "deployment kube-system/coredns"},
+		"skip gitea": {
+			objects: append(objects(fresh, fresh, coreDeployments...), &v1alpha1.Localbuild{
+				ObjectMeta: metav1.ObjectMeta{Name: "localdev"},
+				Spec:       v1alpha1.LocalbuildSpec{BuildCustomization: v1alpha1.BuildCustomizationSpec{SkipGitea: true}},
+			}),
+			expected: "",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(tc.objects...).Build()
+			notReady, err := findNotReady(context.Background(), kubeClient, since)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, notReady)
+		})
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(node(fresh)).Build()
+	_, err := findNotReady(context.Background(), kubeClient, since)
+	assert.Error(t, err)
+}
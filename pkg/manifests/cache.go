@@ -0,0 +1,351 @@
+// Package manifests manages a local cache of core package manifests, so versions of Argo CD, Gitea and ingress-nginx
+// other than the ones embedded in idpbuilder can be installed. Envoy Gateway and Flux are not embedded, so their
+// manifests are only installed from the cache. The cache holds one directory per package and version,
+// laid out like the embedded resources, so they are rendered with the same templating and customizations.
+package manifests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/hack"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	giteaChartRepo    = "https://dl.gitea.com/charts/"
+	giteaReleaseName  = "my-gitea"
+	generatedByHeader = "# This file is auto-generated with 'idpbuilder manifests pull'\n"
+
+	// DefaultEnvoyGatewayVersion is the version of Envoy Gateway installed when the ingress is gateway-api and no
+	// version is selected.
+	DefaultEnvoyGatewayVersion = "v1.5.1"
+	// DefaultFluxVersion is the version of Flux installed when the GitOps engine is flux and no version is selected.
+	DefaultFluxVersion = "v2.6.4"
+
+	// fluxComponentLabel is set on the objects of each Flux controller. Objects without it are shared, like the
+	// namespace.
+	fluxComponentLabel = "app.kubernetes.io/component"
+)
+
+var (
+	validVersion = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+	argocdManifestURL = regexp.MustCompile(`(argoproj/argo-cd/)[^/]+(/manifests/)`)
+	nginxManifestURL  = regexp.MustCompile(`(kubernetes/ingress-nginx/)controller-[^/]+(/deploy/)`)
+)
+
+// PackageNames are the core packages whose manifests can be pulled.
+var PackageNames = []string{v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName, v1alpha1.IngressNginxPackageName,
+	v1alpha1.EnvoyGatewayPackageName, v1alpha1.FluxPackageName}
+
+// envoyGatewayInstallURL is the release asset with the manifests of a version of Envoy Gateway.
+var envoyGatewayInstallURL = "https://github.com/envoyproxy/gateway/releases/download/%s/install.yaml"
+
+// fluxInstallURL is the release asset with the manifests of a version of Flux.
+var fluxInstallURL = "https://github.com/fluxcd/flux2/releases/download/%s/install.yaml"
+
+// fluxComponents are the Flux controllers that are installed. Packages are synced with GitRepositories,
+// Kustomizations, and HelmReleases, so notification and image automation controllers are left out.
+var fluxComponents = map[string]bool{"source-controller": true, "kustomize-controller": true, "helm-controller": true}
+
+// runCommand runs an external tool and returns its standard output.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("running %s: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	return out, nil
+}
+
+// DefaultCacheDir returns the manifest cache in the cache directory of the user.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "idpbuilder", "manifests"), nil
+}
+
+// Resolve returns the absolute path to the manifests of version of the core package name in cacheDir.
+func Resolve(cacheDir, name, version string) (string, error) {
+	if err := validate(name, version); err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(filepath.Join(cacheDir, name, version))
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path of manifest cache: %w", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("manifests of %s %s are not in the manifest cache %s, run: idpbuilder manifests pull --%s-version %s",
+				name, version, cacheDir, name, version)
+		}
+		return "", fmt.Errorf("reading manifest cache: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// Pull generates the manifests of version of the core package name and stores them in cacheDir, replacing manifests
+// pulled before. They are generated like the embedded manifests, see the scripts in the hack directory: upstream
+// manifests are patched with kustomize, and the Gitea helm chart is rendered with helm, so both need to be installed.
+// Envoy Gateway and Flux manifests are downloaded from their releases.
+// It returns the directory the manifests are stored in.
+func Pull(ctx context.Context, cacheDir, name, version string) (string, error) {
+	if err := validate(name, version); err != nil {
+		return "", err
+	}
+
+	workDir, err := os.MkdirTemp("", "idpbuilder-manifests-")
+	if err != nil {
+		return "", fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	var files map[string][]byte
+	switch name {
+	case v1alpha1.ArgoCDPackageName:
+		files, err = generateArgocd(ctx, workDir, version)
+	case v1alpha1.GiteaPackageName:
+		files, err = generateGitea(ctx, workDir, version)
+	case v1alpha1.IngressNginxPackageName:
+		files, err = generateNginx(ctx, workDir, version)
+	case v1alpha1.EnvoyGatewayPackageName:
+		files, err = generateEnvoyGateway(ctx, version)
+	case v1alpha1.FluxPackageName:
+		files, err = generateFlux(ctx, version)
+	}
+	if err != nil {
+		return "", fmt.Errorf("generating manifests of %s %s: %w", name, version, err)
+	}
+	return store(cacheDir, name, version, files)
+}
+
+func validate(name, version string) error {
+	known := false
+	for _, n := range PackageNames {
+		known = known || n == name
+	}
+	if !known {
+		return fmt.Errorf("unsupported core package %s, valid package names are: %s", name, strings.Join(PackageNames, ", "))
+	}
+	if !validVersion.MatchString(version) {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+func generateArgocd(ctx context.Context, workDir, version string) (map[string][]byte, error) {
+	if err := copyHackDir("argo-cd", workDir); err != nil {
+		return nil, err
+	}
+	if err := setKustomizeResource(workDir, argocdManifestURL, version); err != nil {
+		return nil, err
+	}
+	out, err := runCommand(ctx, "kustomize", "build", workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		"install.yaml": concat([]byte("# UCP ARGO INSTALL RESOURCES\n"+generatedByHeader), out),
+	}, nil
+}
+
+func generateNginx(ctx context.Context, workDir, version string) (map[string][]byte, error) {
+	if err := copyHackDir("ingress-nginx", workDir); err != nil {
+		return nil, err
+	}
+	if err := setKustomizeResource(workDir, nginxManifestURL, "controller-"+version); err != nil {
+		return nil, err
+	}
+	out, err := runCommand(ctx, "kustomize", "build", workDir)
+	if err != nil {
+		return nil, err
+	}
+	service, err := hack.FS.ReadFile("ingress-nginx/service-ingress-nginx.yaml.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		"ingress-nginx.yaml": concat([]byte("# INGRESS-NGINX INSTALL RESOURCES\n"+generatedByHeader), out, service),
+	}, nil
+}
+
+func generateGitea(ctx context.Context, workDir, version string) (map[string][]byte, error) {
+	if err := copyHackDir("gitea", workDir); err != nil {
+		return nil, err
+	}
+	out, err := runCommand(ctx, "helm", "template", giteaReleaseName, "gitea", "--repo", giteaChartRepo,
+		"--version", version, "-f", filepath.Join(workDir, "values.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	// helm template does not set the release namespace, see hack/gitea/generate-manifests.sh.
+	out = []byte(strings.ReplaceAll(string(out), "namespace: default", "namespace: "+util.GiteaNamespace))
+
+	return map[string][]byte{
+		"install.yaml": concat([]byte("# GITEA INSTALL RESOURCES\n"+generatedByHeader), out),
+	}, nil
+}
+
+func generateEnvoyGateway(ctx context.Context, version string) (map[string][]byte, error) {
+	out, err := download(ctx, fmt.Sprintf(envoyGatewayInstallURL, version))
+	if err != nil {
+		return nil, err
+	}
+	// manifests are rendered as templates, and descriptions of the Envoy Gateway CRDs contain template actions.
+	out = []byte(strings.ReplaceAll(string(out), "{{", `{{"{{"}}`))
+
+	return map[string][]byte{
+		"install.yaml": concat([]byte("# ENVOY GATEWAY INSTALL RESOURCES\n"+generatedByHeader), out),
+	}, nil
+}
+
+func generateFlux(ctx context.Context, version string) (map[string][]byte, error) {
+	out, err := download(ctx, fmt.Sprintf(fluxInstallURL, version))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := kio.FromBytes(out)
+	if err != nil {
+		return nil, fmt.Errorf("parsing flux manifests: %w", err)
+	}
+	kept := make([]*yaml.RNode, 0, len(nodes))
+	for _, n := range nodes {
+		c, ok := n.GetLabels()[fluxComponentLabel]
+		if !ok || fluxComponents[c] {
+			kept = append(kept, n)
+		}
+	}
+	s, err := kio.StringAll(kept)
+	if err != nil {
+		return nil, fmt.Errorf("serializing flux manifests: %w", err)
+	}
+	// manifests are rendered as templates, and descriptions of the Flux CRDs may contain template actions.
+	s = strings.ReplaceAll(s, "{{", `{{"{{"}}`)
+
+	return map[string][]byte{
+		"install.yaml": concat([]byte("# FLUX INSTALL RESOURCES\n"+generatedByHeader), []byte(s)),
+	}, nil
+}
+
+// download returns the body of url.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: unexpected status %s", url, resp.Status)
+	}
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	return out, nil
+}
+
+// copyHackDir copies the generation inputs of a core package to dst.
+func copyHackDir(dir, dst string) error {
+	entries, err := hack.FS.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		b, err := hack.FS.ReadFile(dir + "/" + e.Name())
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(dst, e.Name()), b, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// setKustomizeResource replaces the version in the upstream manifest URL of the kustomization in dir, matched by
+// urlPattern, with version.
+func setKustomizeResource(dir string, urlPattern *regexp.Regexp, version string) error {
+	p := filepath.Join(dir, "kustomization.yaml")
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if !urlPattern.Match(b) {
+		return fmt.Errorf("upstream manifest URL not found in kustomization")
+	}
+	b = urlPattern.ReplaceAll(b, []byte("${1}"+version+"${2}"))
+	return os.WriteFile(p, b, 0644)
+}
+
+// store writes files to the cache directory of the package version, replacing its contents.
+func store(cacheDir, name, version string, files map[string][]byte) (string, error) {
+	pkgDir := filepath.Join(cacheDir, name)
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		return "", fmt.Errorf("creating manifest cache: %w", err)
+	}
+	// files are written to a temp dir first, so manifests pulled before stay intact if writing fails.
+	tmpDir, err := os.MkdirTemp(pkgDir, "."+version+"-")
+	if err != nil {
+		return "", fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for f, b := range files {
+		if err = os.WriteFile(filepath.Join(tmpDir, f), b, 0644); err != nil {
+			return "", fmt.Errorf("writing %s: %w", f, err)
+		}
+	}
+	if err = os.Chmod(tmpDir, 0755); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(pkgDir, version)
+	if err = os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("removing manifests pulled before: %w", err)
+	}
+	if err = os.Rename(tmpDir, dir); err != nil {
+		return "", fmt.Errorf("storing manifests: %w", err)
+	}
+	return dir, nil
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+		if len(p) > 0 && p[len(p)-1] != '\n' {
+			out = append(out, '\n')
+		}
+	}
+	return out
+}
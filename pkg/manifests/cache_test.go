@@ -0,0 +1,152 @@
+package manifests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPull(t *testing.T) {
+	var kustomization string
+	var commands []string
+	run := runCommand
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if name == "kustomize" {
+			b, err := os.ReadFile(filepath.Join(args[1], "kustomization.yaml"))
+			require.NoError(t, err)
+			kustomization = string(b)
+		}
+		return []byte("kind: ConfigMap\nmetadata:\n  namespace: default\n"), nil
+	}
+	t.Cleanup(func() {
+		runCommand = run
+	})
+
+	cacheDir := t.TempDir()
+
+	_, err := Resolve(cacheDir, v1alpha1.ArgoCDPackageName, "v2.14.0")
+	assert.ErrorContains(t, err, "idpbuilder manifests pull --argocd-version v2.14.0")
+
+	dir, err := Pull(context.Background(), cacheDir, v1alpha1.ArgoCDPackageName, "v2.14.0")
+	require.NoError(t, err)
+	assert.Contains(t, kustomization, "https://raw.githubusercontent.com/argoproj/argo-cd/v2.14.0/manifests/install.yaml")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"install.yaml"}, names)
+
+	resolved, err := Resolve(cacheDir, v1alpha1.ArgoCDPackageName, "v2.14.0")
+	require.NoError(t, err)
+	assert.Equal(t, dir, resolved)
+
+	dir, err = Pull(context.Background(), cacheDir, v1alpha1.IngressNginxPackageName, "v1.12.0")
+	require.NoError(t, err)
+	assert.Contains(t, kustomization, "https://raw.githubusercontent.com/kubernetes/ingress-nginx/controller-v1.12.0/deploy/")
+	b, err := os.ReadFile(filepath.Join(dir, "ingress-nginx.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "name: ingress-nginx-controller")
+
+	dir, err = Pull(context.Background(), cacheDir, v1alpha1.GiteaPackageName, "11.0.0")
+	require.NoError(t, err)
+	assert.Contains(t, commands[len(commands)-1], "helm template my-gitea gitea --repo https://dl.gitea.com/charts/ --version 11.0.0")
+	b, err = os.ReadFile(filepath.Join(dir, "install.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "namespace: gitea")
+	assert.NotContains(t, string(b), "namespace: default")
+
+	// pulling again replaces the manifests.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stale.yaml"), nil, 0644))
+	_, err = Pull(context.Background(), cacheDir, v1alpha1.GiteaPackageName, "11.0.0")
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "stale.yaml"))
+}
+
+func TestPullEnvoyGateway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.5.1/install.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("kind: CustomResourceDefinition\ndescription: '{{ .Name }}'\n"))
+	}))
+	defer server.Close()
+	u := envoyGatewayInstallURL
+	envoyGatewayInstallURL = server.URL + "/%s/install.yaml"
+	t.Cleanup(func() {
+		envoyGatewayInstallURL = u
+	})
+
+	cacheDir := t.TempDir()
+	dir, err := Pull(context.Background(), cacheDir, v1alpha1.EnvoyGatewayPackageName, "v1.5.1")
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(dir, "install.yaml"))
+	require.NoError(t, err)
+	// template actions in the manifests are escaped, so they are kept when rendered.
+	assert.Contains(t, string(b), `description: '{{"{{"}} .Name }}'`)
+
+	_, err = Pull(context.Background(), cacheDir, v1alpha1.EnvoyGatewayPackageName, "v0.0.0")
+	assert.ErrorContains(t, err, "unexpected status 404 Not Found")
+}
+
+func TestPullFlux(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: flux-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: source-controller
+  labels:
+    app.kubernetes.io/component: source-controller
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: notification-controller
+  labels:
+    app.kubernetes.io/component: notification-controller
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2.6.4/install.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(manifests))
+	}))
+	defer server.Close()
+	u := fluxInstallURL
+	fluxInstallURL = server.URL + "/%s/install.yaml"
+	t.Cleanup(func() {
+		fluxInstallURL = u
+	})
+
+	dir, err := Pull(context.Background(), t.TempDir(), v1alpha1.FluxPackageName, "v2.6.4")
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(dir, "install.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "name: flux-system")
+	assert.Contains(t, string(b), "name: source-controller")
+	assert.NotContains(t, string(b), "notification-controller")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validate(v1alpha1.GiteaPackageName, "12.1.2"))
+	assert.NoError(t, validate(v1alpha1.ArgoCDPackageName, "v3.0.0-rc1"))
+	assert.Error(t, validate("backstage", "v1.0.0"))
+	assert.Error(t, validate(v1alpha1.ArgoCDPackageName, "../v1.0.0"))
+	assert.Error(t, validate(v1alpha1.ArgoCDPackageName, ""))
+}
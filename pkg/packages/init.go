@@ -0,0 +1,217 @@
+package packages
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/cnoe-io/idpbuilder/pkg/util/files"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type PackageType string
+
+const (
+	PackageTypeKustomize PackageType = "kustomize"
+	PackageTypeHelm      PackageType = "helm"
+	PackageTypeManifests PackageType = "manifests"
+
+	templatesDir = "templates"
+	// files with this suffix are rendered with InitOptions, others are copied as is. Helm chart templates are copied.
+	templateSuffix = ".tmpl"
+	// routeFile holds the ingress or HTTPRoute of manifests and kustomize packages.
+	routeFile   = "ingress.yaml"
+	servicePort = 80
+)
+
+var PackageTypes = []PackageType{PackageTypeKustomize, PackageTypeHelm, PackageTypeManifests}
+
+//go:embed templates
+var templates embed.FS
+
+type InitOptions struct {
+	Name    string
+	Type    PackageType
+	Ingress bool
+	// BuildCustomization determines the ingress host and path so the package works with the cluster.
+	BuildCustomization v1alpha1.BuildCustomizationSpec
+}
+
+type initTemplateData struct {
+	Name           string
+	Namespace      string
+	Type           PackageType
+	SourceDir      string
+	Ingress        bool
+	UsePathRouting bool
+	Hosts          []string
+	Path           string
+	// IngressProvider, GatewayName and GatewayNamespace select the route kind of Helm charts.
+	IngressProvider  string
+	GatewayName      string
+	GatewayNamespace string
+}
+
+// Init writes a package named opts.Name to dir/opts.Name. The package contains an Argo CD Application whose cnoe://
+// source points to a directory with manifests, a kustomization, or a Helm chart. It returns the created directory.
+func Init(dir string, opts InitOptions) (string, error) {
+	if errs := validation.IsDNS1123Label(opts.Name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid package name %s: %s", opts.Name, strings.Join(errs, ", "))
+	}
+	data, err := newInitTemplateData(opts)
+	if err != nil {
+		return "", err
+	}
+
+	pkgDir := filepath.Join(dir, opts.Name)
+	if _, err = os.Stat(pkgDir); err == nil {
+		return "", fmt.Errorf("%s already exists", pkgDir)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err = writeTemplate(path.Join(templatesDir, "application.yaml"+templateSuffix), filepath.Join(pkgDir, opts.Name+".yaml"), data); err != nil {
+		return "", err
+	}
+
+	sources := []string{path.Join(templatesDir, string(PackageTypeManifests))}
+	switch opts.Type {
+	case PackageTypeKustomize:
+		sources = append(sources, path.Join(templatesDir, string(PackageTypeKustomize)))
+	case PackageTypeHelm:
+		sources = []string{path.Join(templatesDir, string(PackageTypeHelm))}
+	}
+
+	for _, src := range sources {
+		err = fs.WalkDir(templates, src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel := strings.TrimPrefix(p, src+"/")
+			return writeTemplate(p, filepath.Join(pkgDir, data.SourceDir, filepath.FromSlash(rel)), data)
+		})
+		if err != nil {
+			return "", fmt.Errorf("writing package files: %w", err)
+		}
+	}
+
+	if data.Ingress && opts.Type != PackageTypeHelm {
+		if err = writeRoute(filepath.Join(pkgDir, data.SourceDir, routeFile), opts, data); err != nil {
+			return "", fmt.Errorf("writing package route: %w", err)
+		}
+	}
+	return pkgDir, nil
+}
+
+// writeRoute writes the route of the package service the way the ingress provider of the cluster routes core packages.
+func writeRoute(dst string, opts InitOptions, data initTemplateData) error {
+	cfg := opts.BuildCustomization
+	if cfg.IngressHost == "" {
+		cfg.IngressHost = cfg.Host
+	}
+	provider, err := ingress.ForBuild(cfg)
+	if err != nil {
+		return err
+	}
+
+	route := ingress.Route{
+		Name:      opts.Name,
+		Namespace: data.Namespace,
+		Path:      data.Path,
+		Service:   opts.Name,
+		Port:      servicePort,
+	}
+	if data.UsePathRouting {
+		route.Rewrite = "/"
+	} else {
+		route.Subdomain = opts.Name
+	}
+	manifests, err := provider.RouteManifests([]ingress.Route{route}, cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, bytes.Join(manifests, []byte("---\n")), 0644)
+}
+
+func newInitTemplateData(opts InitOptions) (initTemplateData, error) {
+	data := initTemplateData{
+		Name:           opts.Name,
+		Namespace:      opts.Name,
+		Type:           opts.Type,
+		Ingress:        opts.Ingress,
+		UsePathRouting: opts.BuildCustomization.UsePathRouting,
+		Path:           "/",
+
+		GatewayName:      ingress.GatewayName,
+		GatewayNamespace: globals.EnvoyGatewayNamespace,
+	}
+
+	provider, err := ingress.ForBuild(opts.BuildCustomization)
+	if err != nil {
+		return data, err
+	}
+	data.IngressProvider = provider.Name()
+
+	switch opts.Type {
+	case PackageTypeKustomize, PackageTypeManifests:
+		data.SourceDir = "manifests"
+	case PackageTypeHelm:
+		data.SourceDir = "chart"
+	default:
+		return data, fmt.Errorf("unsupported package type %s, must be one of %v", opts.Type, PackageTypes)
+	}
+
+	// same hosts as the core package ingresses
+	hosts := []string{opts.BuildCustomization.IngressHost}
+	if opts.BuildCustomization.IngressHost == "" {
+		hosts = []string{opts.BuildCustomization.Host}
+	} else if opts.BuildCustomization.Host != opts.BuildCustomization.IngressHost {
+		hosts = append(hosts, opts.BuildCustomization.Host)
+	}
+	for _, h := range hosts {
+		if data.UsePathRouting {
+			data.Hosts = append(data.Hosts, h)
+		} else {
+			data.Hosts = append(data.Hosts, fmt.Sprintf("%s.%s", opts.Name, h))
+		}
+	}
+	if data.UsePathRouting {
+		data.Path = "/" + opts.Name
+	}
+	return data, nil
+}
+
+// URL returns the address the ingress of a package created with opts is served on.
+func (opts InitOptions) URL() string {
+	c := opts.BuildCustomization
+	if c.UsePathRouting {
+		return fmt.Sprintf("%s://%s:%s/%s", c.Protocol, c.Host, c.Port, opts.Name)
+	}
+	return fmt.Sprintf("%s://%s.%s:%s", c.Protocol, opts.Name, c.Host, c.Port)
+}
+
+func writeTemplate(src, dst string, data initTemplateData) error {
+	b, err := templates.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(src, templateSuffix) {
+		dst = strings.TrimSuffix(dst, templateSuffix)
+		b, err = files.ApplyTemplate(b, data)
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", src, err)
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, b, 0644)
+}
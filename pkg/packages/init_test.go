@@ -0,0 +1,141 @@
+package packages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/ingress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestInit(t *testing.T) {
+	subdomain := v1alpha1.BuildCustomizationSpec{Protocol: "https", Host: "cnoe.localtest.me", IngressHost: "cnoe.localtest.me", Port: "8443"}
+	path := v1alpha1.BuildCustomizationSpec{Protocol: "http", Host: "idp.example.com", IngressHost: "internal.example.com", Port: "80", UsePathRouting: true}
+	gateway := subdomain
+	gateway.Ingress = ingress.GatewayAPIProviderName
+
+	cases := []struct {
+		opts        InitOptions
+		files       []string
+		ingressFile string
+		routeFile   string
+		hosts       []string
+		path        string
+		url         string
+	}{
+		{
+			opts:        InitOptions{Name: "app", Type: PackageTypeManifests, Ingress: true, BuildCustomization: subdomain},
+			files:       []string{"app.yaml", "manifests/deployment.yaml", "manifests/service.yaml", "manifests/ingress.yaml"},
+			ingressFile: "manifests/ingress.yaml",
+			hosts:       []string{"app.cnoe.localtest.me"},
+			path:        "/",
+			url:         "https://app.cnoe.localtest.me:8443",
+		},
+		{
+			opts:        InitOptions{Name: "app", Type: PackageTypeKustomize, Ingress: true, BuildCustomization: path},
+			files:       []string{"app.yaml", "manifests/kustomization.yaml", "manifests/deployment.yaml", "manifests/service.yaml", "manifests/ingress.yaml"},
+			ingressFile: "manifests/ingress.yaml",
+			hosts:       []string{"internal.example.com", "idp.example.com"},
+			path:        "/app(/|$)(.*)",
+			url:         "http://idp.example.com:80/app",
+		},
+		{
+			opts:  InitOptions{Name: "app", Type: PackageTypeKustomize, BuildCustomization: subdomain},
+			files: []string{"app.yaml", "manifests/kustomization.yaml", "manifests/deployment.yaml", "manifests/service.yaml"},
+		},
+		{
+			opts:  InitOptions{Name: "app", Type: PackageTypeHelm, BuildCustomization: path},
+			files: []string{"app.yaml", "chart/Chart.yaml", "chart/values.yaml", "chart/templates/deployment.yaml", "chart/templates/service.yaml", "chart/templates/ingress.yaml", "chart/templates/httproute.yaml"},
+		},
+		{
+			opts:      InitOptions{Name: "app", Type: PackageTypeManifests, Ingress: true, BuildCustomization: gateway},
+			files:     []string{"app.yaml", "manifests/deployment.yaml", "manifests/service.yaml", "manifests/ingress.yaml"},
+			routeFile: "manifests/ingress.yaml",
+			hosts:     []string{"app.cnoe.localtest.me"},
+			path:      "/",
+			url:       "https://app.cnoe.localtest.me:8443",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.opts.Type), func(t *testing.T) {
+			dir, err := Init(t.TempDir(), c.opts)
+			require.NoError(t, err)
+
+			var got []string
+			err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, p)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, c.files, got)
+
+			findings, err := Validate(context.Background(), dir)
+			require.NoError(t, err)
+			assert.Empty(t, findings)
+
+			if c.routeFile != "" {
+				b, err := os.ReadFile(filepath.Join(dir, c.routeFile))
+				require.NoError(t, err)
+				route := struct {
+					Kind string
+					Spec struct {
+						ParentRefs []struct{ Name, Namespace string }
+						Hostnames  []string
+						Rules      []struct {
+							Matches []struct {
+								Path struct{ Value string }
+							}
+							BackendRefs []struct{ Name string }
+						}
+					}
+				}{}
+				require.NoError(t, yaml.Unmarshal(b, &route))
+				assert.Equal(t, "HTTPRoute", route.Kind)
+				assert.Equal(t, ingress.GatewayName, route.Spec.ParentRefs[0].Name)
+				assert.Equal(t, c.hosts, route.Spec.Hostnames)
+				assert.Equal(t, c.path, route.Spec.Rules[0].Matches[0].Path.Value)
+				assert.Equal(t, "app", route.Spec.Rules[0].BackendRefs[0].Name)
+				assert.Equal(t, c.url, c.opts.URL())
+			}
+			if c.ingressFile == "" {
+				return
+			}
+			b, err := os.ReadFile(filepath.Join(dir, c.ingressFile))
+			require.NoError(t, err)
+			ing := networkingv1.Ingress{}
+			require.NoError(t, yaml.UnmarshalStrict(b, &ing))
+			require.Len(t, ing.Spec.Rules, len(c.hosts))
+			for i, h := range c.hosts {
+				assert.Equal(t, h, ing.Spec.Rules[i].Host)
+				assert.Equal(t, c.path, ing.Spec.Rules[i].HTTP.Paths[0].Path)
+				assert.Equal(t, "app", ing.Spec.Rules[i].HTTP.Paths[0].Backend.Service.Name)
+			}
+			assert.Equal(t, c.url, c.opts.URL())
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Init(dir, InitOptions{Name: "Invalid_Name", Type: PackageTypeHelm})
+	assert.Error(t, err)
+
+	_, err = Init(dir, InitOptions{Name: "app", Type: "jsonnet"})
+	assert.Error(t, err)
+
+	_, err = Init(dir, InitOptions{Name: "app", Type: PackageTypeHelm})
+	require.NoError(t, err)
+	_, err = Init(dir, InitOptions{Name: "app", Type: PackageTypeHelm})
+	assert.ErrorContains(t, err, "already exists")
+}
@@ -0,0 +1,194 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	argocdapp "github.com/cnoe-io/argocd-api/api/argo/application"
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/gitops"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Argo CD deletes the resources of an Application with this finalizer before deleting the Application.
+const argoCDResourcesFinalizer = "resources-finalizer.argocd.argoproj.io"
+
+// AddSources appends package locations to the list of their kind. Appended locations have the highest priority, the
+// same as passing them last to create -p. Locations that are already configured keep their priority. It returns the
+// added locations.
+func AddSources(spec *v1alpha1.PackageConfigsSpec, remotes, files, dirs []string) []string {
+	var added []string
+	add := func(list *[]string, locations []string) {
+		for _, l := range locations {
+			if !slices.Contains(*list, l) {
+				*list = append(*list, l)
+				added = append(added, l)
+			}
+		}
+	}
+	add(&spec.CustomPackageUrls, remotes)
+	add(&spec.CustomPackageFiles, files)
+	add(&spec.CustomPackageDirs, dirs)
+	return added
+}
+
+// RemoveSource removes a package location from spec. Locations after it keep their order relative to each other.
+// It returns false if the location is not configured.
+func RemoveSource(spec *v1alpha1.PackageConfigsSpec, source string) bool {
+	removed := false
+	for _, list := range []*[]string{&spec.CustomPackageUrls, &spec.CustomPackageFiles, &spec.CustomPackageDirs} {
+		if i := slices.Index(*list, source); i >= 0 {
+			*list = slices.Delete(*list, i, i+1)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Find returns the CustomPackage of the Argo CD Application or ApplicationSet with the given name. If more than one
+// exists, the one with the highest priority is returned.
+func Find(ctx context.Context, kubeClient client.Client, localBuildName, name string) (*v1alpha1.CustomPackage, error) {
+	pkgs := v1alpha1.CustomPackageList{}
+	if err := kubeClient.List(ctx, &pkgs, client.InNamespace(globals.GetProjectNamespace(localBuildName))); err != nil {
+		return nil, fmt.Errorf("listing custom packages: %w", err)
+	}
+
+	var found *v1alpha1.CustomPackage
+	for i := range pkgs.Items {
+		pkg := &pkgs.Items[i]
+		if pkg.Spec.ArgoCD.Name != name {
+			continue
+		}
+		if found == nil || priority(pkg) > priority(found) {
+			found = pkg
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("package %s not found", name)
+	}
+	return found, nil
+}
+
+// FromSameSource returns the names of the other packages that were created from the same location as pkg.
+func FromSameSource(ctx context.Context, kubeClient client.Client, pkg *v1alpha1.CustomPackage) ([]string, error) {
+	pkgs := v1alpha1.CustomPackageList{}
+	if err := kubeClient.List(ctx, &pkgs, client.InNamespace(pkg.Namespace)); err != nil {
+		return nil, fmt.Errorf("listing custom packages: %w", err)
+	}
+
+	source := pkg.Annotations[v1alpha1.PackageSourcePathAnnotation]
+	var names []string
+	for i := range pkgs.Items {
+		other := pkgs.Items[i]
+		if other.Name != pkg.Name && other.Annotations[v1alpha1.PackageSourcePathAnnotation] == source &&
+			!slices.Contains(names, other.Spec.ArgoCD.Name) {
+			names = append(names, other.Spec.ArgoCD.Name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Remove deletes the Argo CD Application or ApplicationSet of pkg, or its Flux objects, the GitRepositories it owns,
+// and pkg. Applications are deleted with the resources they deployed.
+func Remove(ctx context.Context, kubeClient client.Client, pkg *v1alpha1.CustomPackage) error {
+	ns := pkg.Spec.ArgoCD.Namespace
+	if ns == "" {
+		ns = globals.ArgoCDNamespace
+	}
+	meta := metav1.ObjectMeta{Name: pkg.Spec.ArgoCD.Name, Namespace: ns}
+
+	switch pkg.Spec.ArgoCD.Type {
+	case gitops.FluxGitRepositoryKind, gitops.FluxKustomizationKind, gitops.FluxHelmReleaseKind:
+		if err := removeFluxObjects(ctx, kubeClient, pkg.Spec.ArgoCD.Name); err != nil {
+			return err
+		}
+	case argocdapp.ApplicationSetKind:
+		appSet := &argov1alpha1.ApplicationSet{ObjectMeta: meta}
+		if err := kubeClient.Delete(ctx, appSet); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting application set %s: %w", meta.Name, err)
+		}
+	default:
+		app := &argov1alpha1.Application{ObjectMeta: meta}
+		err := kubeClient.Get(ctx, client.ObjectKeyFromObject(app), app)
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("getting application %s: %w", meta.Name, err)
+		}
+		if err == nil {
+			if controllerutil.AddFinalizer(app, argoCDResourcesFinalizer) {
+				if err = kubeClient.Update(ctx, app); err != nil {
+					return fmt.Errorf("adding finalizer to application %s: %w", meta.Name, err)
+				}
+			}
+			if err = kubeClient.Delete(ctx, app); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("deleting application %s: %w", meta.Name, err)
+			}
+		}
+	}
+
+	repos := v1alpha1.GitRepositoryList{}
+	if err := kubeClient.List(ctx, &repos, client.InNamespace(pkg.Namespace)); err != nil {
+		return fmt.Errorf("listing git repositories: %w", err)
+	}
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if !isOwnedBy(repo, pkg) {
+			continue
+		}
+		if err := kubeClient.Delete(ctx, repo); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting git repository %s: %w", repo.Name, err)
+		}
+	}
+
+	if err := kubeClient.Delete(ctx, pkg); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("deleting custom package %s: %w", pkg.Name, err)
+	}
+	return nil
+}
+
+// removeFluxObjects deletes the Flux objects of the package name, which are all labeled with it.
+func removeFluxObjects(ctx context.Context, kubeClient client.Client, name string) error {
+	for _, gvk := range []schema.GroupVersionKind{gitops.FluxKustomizationGVK, gitops.FluxHelmReleaseGVK, gitops.FluxGitRepositoryGVK} {
+		list := gitops.FluxList(gvk)
+		err := kubeClient.List(ctx, list, client.MatchingLabels{v1alpha1.PackageNameLabelKey: name})
+		if err != nil {
+			if apimeta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("listing flux %s objects: %w", gvk.Kind, err)
+		}
+		for i := range list.Items {
+			if err = kubeClient.Delete(ctx, &list.Items[i]); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("deleting flux %s %s: %w", gvk.Kind, list.Items[i].GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func isOwnedBy(repo *v1alpha1.GitRepository, pkg *v1alpha1.CustomPackage) bool {
+	for _, ref := range repo.GetOwnerReferences() {
+		if ref.Kind == "CustomPackage" && ref.Name == pkg.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// priority returns the priority of pkg, or -1 if it does not have one.
+func priority(pkg *v1alpha1.CustomPackage) int {
+	p, err := strconv.Atoi(pkg.Annotations[v1alpha1.PackagePriorityAnnotation])
+	if err != nil {
+		return -1
+	}
+	return p
+}
@@ -0,0 +1,102 @@
+package packages
+
+import (
+	"context"
+	"testing"
+
+	argov1alpha1 "github.com/cnoe-io/argocd-api/api/argo/application/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAddRemoveSources(t *testing.T) {
+	spec := v1alpha1.PackageConfigsSpec{
+		CustomPackageDirs: []string{"/a", "/b"},
+		CustomPackageUrls: []string{"https://github.com/org/repo//pkgs"},
+	}
+
+	added := AddSources(&spec, []string{"https://github.com/org/repo//pkgs"}, []string{"/f.yaml"}, []string{"/b", "/c"})
+	assert.Equal(t, []string{"/f.yaml", "/c"}, added)
+	assert.Equal(t, []string{"/a", "/b", "/c"}, spec.CustomPackageDirs)
+	assert.Equal(t, []string{"/f.yaml"}, spec.CustomPackageFiles)
+	assert.Equal(t, []string{"https://github.com/org/repo//pkgs"}, spec.CustomPackageUrls)
+
+	assert.True(t, RemoveSource(&spec, "/a"))
+	assert.Equal(t, []string{"/b", "/c"}, spec.CustomPackageDirs)
+	assert.True(t, RemoveSource(&spec, "https://github.com/org/repo//pkgs"))
+	assert.Empty(t, spec.CustomPackageUrls)
+	assert.False(t, RemoveSource(&spec, "/missing"))
+}
+
+func customPackage(name, appName, source, priority string) *v1alpha1.CustomPackage {
+	return &v1alpha1.CustomPackage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "idpbuilder-localdev",
+			UID:       types.UID("uid-" + name),
+			Annotations: map[string]string{
+				v1alpha1.PackageSourcePathAnnotation: source,
+				v1alpha1.PackagePriorityAnnotation:   priority,
+			},
+		},
+		Spec: v1alpha1.CustomPackageSpec{
+			ArgoCD: v1alpha1.ArgoCDPackageSpec{Name: appName, Namespace: "argocd", Type: "Application"},
+		},
+	}
+}
+
+func TestFindAndRemove(t *testing.T) {
+	ctx := context.Background()
+	low := customPackage("app1-app1", "app1", "/low", "0")
+	high := customPackage("app1-app1-high", "app1", "/high", "1")
+	sibling := customPackage("app2-app2", "app2", "/high", "1")
+	app := &argov1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app1", Namespace: "argocd"}}
+	owned := &v1alpha1.GitRepository{ObjectMeta: metav1.ObjectMeta{
+		Name:      "app1-manifests",
+		Namespace: "idpbuilder-localdev",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: v1alpha1.GroupVersion.String(), Kind: "CustomPackage", Name: high.Name, UID: high.UID},
+		},
+	}}
+	other := &v1alpha1.GitRepository{ObjectMeta: metav1.ObjectMeta{
+		Name:      "app2-manifests",
+		Namespace: "idpbuilder-localdev",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: v1alpha1.GroupVersion.String(), Kind: "CustomPackage", Name: sibling.Name, UID: sibling.UID},
+		},
+	}}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).
+		WithObjects(low, high, sibling, app, owned, other).Build()
+
+	pkg, err := Find(ctx, kubeClient, "localdev", "app1")
+	require.NoError(t, err)
+	assert.Equal(t, high.Name, pkg.Name)
+
+	_, err = Find(ctx, kubeClient, "localdev", "missing")
+	assert.Error(t, err)
+
+	names, err := FromSameSource(ctx, kubeClient, pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app2"}, names)
+
+	require.NoError(t, Remove(ctx, kubeClient, pkg))
+
+	assert.True(t, k8serrors.IsNotFound(kubeClient.Get(ctx, client.ObjectKeyFromObject(high), &v1alpha1.CustomPackage{})))
+	assert.True(t, k8serrors.IsNotFound(kubeClient.Get(ctx, client.ObjectKeyFromObject(owned), &v1alpha1.GitRepository{})))
+	// the fake client keeps objects with finalizers until they are removed, the same as the API server.
+	got := &argov1alpha1.Application{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(app), got))
+	assert.Contains(t, got.Finalizers, argoCDResourcesFinalizer)
+	assert.NotNil(t, got.DeletionTimestamp)
+
+	assert.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(low), &v1alpha1.CustomPackage{}))
+	assert.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(other), &v1alpha1.GitRepository{}))
+}
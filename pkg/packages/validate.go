@@ -0,0 +1,399 @@
+package packages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	argocdapp "github.com/cnoe-io/argocd-api/api/argo/application"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/custompackage"
+	"github.com/cnoe-io/idpbuilder/pkg/controllers/localbuild"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-billy/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found in a package file. Line is 0 if the problem is not tied to a line.
+type Finding struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.File, f.Severity, f.Message)
+}
+
+// HasErrors returns true if any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type position struct {
+	file string
+	line int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+type repoUse struct {
+	dir string
+	pos position
+}
+
+// validator checks package files the way the Localbuild and CustomPackage controllers process them.
+type validator struct {
+	// remote is nil for local packages.
+	remote *util.KustomizeRemote
+	// wt is the worktree of remote packages.
+	wt billy.Filesystem
+
+	findings []Finding
+	apps     map[string]position
+	repos    map[string]repoUse
+}
+
+// Validate validates the package at location, which is a local file, a local directory, or a URL accepted by -p.
+func Validate(ctx context.Context, location string) ([]Finding, error) {
+	return ValidateWithAuth(ctx, location, v1alpha1.PackageAuthSpec{})
+}
+
+// ValidateWithAuth is Validate for URLs of repositories that require authentication.
+func ValidateWithAuth(ctx context.Context, location string, auth v1alpha1.PackageAuthSpec) ([]Finding, error) {
+	v := &validator{
+		apps:  map[string]position{},
+		repos: map[string]repoUse{},
+	}
+
+	if remote, err := util.NewKustomizeRemote(location); err == nil {
+		return v.validateRemote(ctx, location, remote, auth)
+	}
+
+	absPath, err := filepath.Abs(location)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of %s: %w", location, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading package %s: %w", location, err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		// same as the Localbuild controller, only files in the top level directory are processed.
+		entries, err := os.ReadDir(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading dir %s: %w", location, err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() && util.IsYamlFile(e.Name()) {
+				files = append(files, filepath.Join(absPath, e.Name()))
+			}
+		}
+	} else {
+		if !util.IsYamlFile(absPath) {
+			return nil, fmt.Errorf("file is not a yaml file: %s", location)
+		}
+		files = append(files, absPath)
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
+		display := f
+		if rel, err := filepath.Rel(".", f); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		v.validateFile(f, display, data)
+	}
+	return v.sorted(), nil
+}
+
+func (v *validator) validateRemote(ctx context.Context, location string, remote *util.KustomizeRemote, authSpec v1alpha1.PackageAuthSpec) ([]Finding, error) {
+	dir, err := os.MkdirTemp("", "idpbuilder-validate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	spec := v1alpha1.RemoteRepositorySpec{
+		Url:             remote.CloneUrl(),
+		Ref:             remote.Ref,
+		CloneSubmodules: remote.Submodules,
+		Path:            remote.Path(),
+	}
+	auth, err := util.RemoteRepoAuth(ctx, nil, authSpec, spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("getting credentials of repo, %s: %w", location, err)
+	}
+	wt, _, err := util.CloneRemoteRepoToDirWithAuth(ctx, spec, 1, false, dir, "", auth)
+	if err != nil {
+		return nil, fmt.Errorf("cloning repo, %s: %w", location, err)
+	}
+
+	files, err := util.GetWorktreeYamlFiles(remote.Path(), wt, false)
+	if err != nil {
+		return nil, fmt.Errorf("getting yaml files from repo, %s: %w", location, err)
+	}
+
+	v.remote = remote
+	v.wt = wt
+	for _, f := range files {
+		data, err := util.ReadWorktreeFile(wt, f)
+		if err != nil {
+			return nil, err
+		}
+		v.validateFile(f, strings.TrimPrefix(f, "/"), data)
+	}
+	return v.sorted(), nil
+}
+
+func (v *validator) add(file string, line int, severity Severity, format string, args ...any) {
+	v.findings = append(v.findings, Finding{File: file, Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) sorted() []Finding {
+	sort.SliceStable(v.findings, func(i, j int) bool {
+		if v.findings[i].File != v.findings[j].File {
+			return v.findings[i].File < v.findings[j].File
+		}
+		return v.findings[i].Line < v.findings[j].Line
+	})
+	return v.findings
+}
+
+// validateFile checks a package file. path is used to resolve cnoe:// references, display is used in findings.
+func (v *validator) validateFile(path, display string, data []byte) {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		v.add(display, 0, SeverityError, "parsing yaml: %v", err)
+		return
+	}
+	if len(docs) == 0 {
+		v.add(display, 0, SeverityWarning, "file is empty")
+		return
+	}
+
+	// the controllers decode each file as a single object, so only the first document is used.
+	for _, doc := range docs[1:] {
+		if isArgoCDDocument(doc) {
+			v.add(display, doc.Line, SeverityWarning, "only the first document of a file is used, %s %s is ignored",
+				stringValue(doc, "kind"), stringValue(doc, "metadata", "name"))
+		}
+	}
+
+	doc := docs[0]
+	o := &unstructured.Unstructured{}
+	_, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, o)
+	if err != nil {
+		v.add(display, doc.Line, SeverityError, "decoding kubernetes object: %v", err)
+		return
+	}
+	if !localbuild.IsSupportedArgoCDTypes(gvk) {
+		if gvk.Group == argocdapp.Group {
+			v.add(display, lineOf(doc, "kind"), SeverityError, "unsupported kind %s, must be %s or %s",
+				gvk.Kind, argocdapp.ApplicationKind, argocdapp.ApplicationSetKind)
+		} else {
+			v.add(display, doc.Line, SeverityWarning, "%s is not an Argo CD Application or ApplicationSet and is ignored", gvk.Kind)
+		}
+		return
+	}
+
+	// the CustomPackage controller converts the file to typed objects.
+	if _, err = k8s.ConvertYamlToObjects(k8s.GetScheme(), data); err != nil {
+		v.add(display, doc.Line, SeverityError, "decoding %s: %v", gvk.Kind, err)
+		return
+	}
+
+	appName := o.GetName()
+	namePos := position{file: display, line: lineOf(doc, "metadata", "name")}
+	switch {
+	case appName == "":
+		v.add(display, doc.Line, SeverityError, "metadata.name is required")
+		return
+	case appName == v1alpha1.ArgoCDPackageName || appName == v1alpha1.GiteaPackageName || appName == v1alpha1.IngressNginxPackageName ||
+		appName == v1alpha1.EnvoyGatewayPackageName || appName == v1alpha1.FluxPackageName:
+		v.add(display, namePos.line, SeverityError, "name %s conflicts with the core package of the same name", appName)
+	}
+	if prev, ok := v.apps[appName]; ok {
+		v.add(display, namePos.line, SeverityError, "duplicate application name %s, also defined at %s", appName, prev)
+	} else {
+		v.apps[appName] = namePos
+	}
+
+	spec := lookup(doc, "spec")
+	if gvk.Kind == argocdapp.ApplicationSetKind {
+		for _, g := range sequence(lookup(spec, "generators")) {
+			v.checkRepoURL(path, display, appName, lookup(g, "git", "repoURL"))
+			for _, nested := range sequence(lookup(g, "matrix", "generators")) {
+				v.checkRepoURL(path, display, appName, lookup(nested, "git", "repoURL"))
+			}
+		}
+		spec = lookup(spec, "template", "spec")
+	}
+
+	sources := sequence(lookup(spec, "sources"))
+	if s := lookup(spec, "source"); s != nil {
+		sources = append(sources, s)
+	}
+	for _, s := range sources {
+		v.checkRepoURL(path, display, appName, lookup(s, "repoURL"))
+		v.checkValuesObject(path, display, appName, lookup(s, "helm", "valuesObject"))
+	}
+}
+
+func (v *validator) checkValuesObject(path, display, appName string, n *kyaml.Node) {
+	if n == nil {
+		return
+	}
+	if n.Kind == kyaml.ScalarNode {
+		v.checkRepoURL(path, display, appName, n)
+		return
+	}
+	for i, c := range n.Content {
+		// skip keys of mappings
+		if n.Kind == kyaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		v.checkValuesObject(path, display, appName, c)
+	}
+}
+
+// checkRepoURL checks that a cnoe:// reference points to a directory and that the repository created for it has a
+// valid name that is not used for another directory.
+func (v *validator) checkRepoURL(path, display, appName string, n *kyaml.Node) {
+	if n == nil || !custompackage.IsCNOEScheme(n.Value) {
+		return
+	}
+
+	var dir, repoName string
+	if v.remote == nil {
+		absPath, err := custompackage.GetCNOEAbsPath(path, n.Value)
+		if err != nil {
+			v.add(display, n.Line, SeverityError, "%s does not refer to a directory: %v", n.Value, err)
+			return
+		}
+		dir = absPath
+		repoName = custompackage.LocalRepoName(appName, dir)
+	} else {
+		dir = filepath.Join(v.remote.Path(), strings.TrimPrefix(n.Value, v1alpha1.CNOEURIScheme))
+		info, err := v.wt.Stat(dir)
+		if err != nil {
+			v.add(display, n.Line, SeverityError, "%s does not refer to a directory: %v", n.Value, err)
+			return
+		}
+		if !info.IsDir() {
+			v.add(display, n.Line, SeverityError, "%s does not refer to a directory: path not a directory: %s", n.Value, dir)
+			return
+		}
+		repoName = custompackage.RemoteRepoName(appName, dir, v1alpha1.RemoteRepositorySpec{})
+	}
+
+	pos := position{file: display, line: n.Line}
+	if errs := validation.IsDNS1123Subdomain(repoName); len(errs) > 0 {
+		v.add(display, n.Line, SeverityError, "repository name %s generated for %s is not a valid kubernetes name: %s",
+			repoName, n.Value, strings.Join(errs, ", "))
+	}
+	if prev, ok := v.repos[repoName]; ok && prev.dir != dir {
+		v.add(display, n.Line, SeverityError, "repository name %s for %s collides with %s referenced at %s",
+			repoName, n.Value, prev.dir, prev.pos)
+		return
+	}
+	v.repos[repoName] = repoUse{dir: dir, pos: pos}
+}
+
+// parseDocuments returns the non-empty documents of a YAML stream.
+func parseDocuments(data []byte) ([]*kyaml.Node, error) {
+	dec := kyaml.NewDecoder(bytes.NewReader(data))
+	var docs []*kyaml.Node
+	for {
+		n := &kyaml.Node{}
+		err := dec.Decode(n)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n.Kind == kyaml.DocumentNode && len(n.Content) == 1 && n.Content[0].Kind == kyaml.MappingNode {
+			docs = append(docs, n.Content[0])
+		}
+	}
+}
+
+func isArgoCDDocument(n *kyaml.Node) bool {
+	group, _, _ := strings.Cut(stringValue(n, "apiVersion"), "/")
+	kind := stringValue(n, "kind")
+	return group == argocdapp.Group && (kind == argocdapp.ApplicationKind || kind == argocdapp.ApplicationSetKind)
+}
+
+// lookup returns the value at the given mapping keys or nil.
+func lookup(n *kyaml.Node, keys ...string) *kyaml.Node {
+	for _, k := range keys {
+		if n == nil || n.Kind != kyaml.MappingNode {
+			return nil
+		}
+		var next *kyaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+func sequence(n *kyaml.Node) []*kyaml.Node {
+	if n == nil || n.Kind != kyaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+func stringValue(n *kyaml.Node, keys ...string) string {
+	if v := lookup(n, keys...); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+// lineOf returns the line of the value at the given keys, or the line of n if it does not exist.
+func lineOf(n *kyaml.Node, keys ...string) int {
+	if v := lookup(n, keys...); v != nil {
+		return v.Line
+	}
+	return n.Line
+}
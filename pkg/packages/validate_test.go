@@ -0,0 +1,130 @@
+package packages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func messages(findings []Finding) []string {
+	out := make([]string, 0, len(findings))
+	for _, f := range findings {
+		out = append(out, f.String())
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app1/manifests/cm.yaml":       "",
+		"app1/other/manifests/cm.yaml": "",
+		"app1.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: app1
+spec:
+  sources:
+  - repoURL: cnoe://app1/manifests
+  - repoURL: cnoe://app1/other/manifests
+  - repoURL: cnoe://app1/missing
+    helm:
+      valuesObject:
+        repo:
+          url: cnoe://app1/values-missing
+`,
+		"app2.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: app1
+spec:
+  source:
+    repoURL: https://example.com/repo.git
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: ignored
+`,
+		"appset.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: appset
+spec:
+  generators:
+  - matrix:
+      generators:
+      - git:
+          repoURL: cnoe://appset/missing
+  template:
+    metadata:
+      name: appset
+    spec:
+      source:
+        repoURL: cnoe://app1/manifests
+`,
+		"project.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+metadata:
+  name: project
+`,
+		"gitea.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: gitea
+spec:
+  source:
+    repoURL: https://example.com/repo.git
+`,
+		"cm.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+`,
+	})
+
+	findings, err := Validate(context.Background(), dir)
+	require.NoError(t, err)
+	assert.True(t, HasErrors(findings))
+
+	expected := []string{
+		"app1.yaml:8: error: repository name app1-manifests for cnoe://app1/other/manifests collides with",
+		"app1.yaml:9: error: cnoe://app1/missing does not refer to a directory",
+		"app1.yaml:13: error: cnoe://app1/values-missing does not refer to a directory",
+		"app2.yaml:4: error: duplicate application name app1, also defined at",
+		"app2.yaml:9: warning: only the first document of a file is used, Application ignored is ignored",
+		"appset.yaml:10: error: cnoe://appset/missing does not refer to a directory",
+		"cm.yaml:1: warning: ConfigMap is not an Argo CD Application or ApplicationSet and is ignored",
+		"gitea.yaml:4: error: name gitea conflicts with the core package of the same name",
+		"project.yaml:2: error: unsupported kind AppProject, must be Application or ApplicationSet",
+	}
+	got := messages(findings)
+	require.Len(t, got, len(expected), strings.Join(got, "\n"))
+	for i := range expected {
+		assert.Contains(t, got[i], filepath.Join(dir, expected[i][:strings.Index(expected[i], ":")]))
+		assert.Contains(t, got[i], expected[i][strings.Index(expected[i], ":"):])
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	findings, err := Validate(context.Background(), "../controllers/custompackage/test/resources/customPackages/applicationSet")
+	require.NoError(t, err)
+	assert.False(t, HasErrors(findings), strings.Join(messages(findings), "\n"))
+
+	_, err = Validate(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
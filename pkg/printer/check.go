@@ -0,0 +1,47 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type CheckPrinter struct {
+	Checks    []types.Check
+	OutWriter io.Writer
+}
+
+func (cp CheckPrinter) PrintOutput(format string) error {
+	switch format {
+	case "json":
+		return PrintDataAsJson(cp.Checks, cp.OutWriter)
+	case "yaml":
+		return PrintDataAsYaml(cp.Checks, cp.OutWriter)
+	case "table":
+		return PrintDataAsTable(generateCheckTable(cp.Checks), cp.OutWriter)
+	default:
+		return fmt.Errorf("output format %s is not supported", format)
+	}
+}
+
+func generateCheckTable(input []types.Check) metav1.Table {
+	table := &metav1.Table{}
+	table.ColumnDefinitions = []metav1.TableColumnDefinition{
+		{Name: "Check", Type: "string"},
+		{Name: "Status", Type: "string"},
+		{Name: "Message", Type: "string"},
+	}
+	for _, check := range input {
+		row := metav1.TableRow{
+			Cells: []interface{}{
+				check.Name,
+				check.Status,
+				check.Message,
+			},
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return *table
+}
@@ -29,6 +29,7 @@ func generateClusterTable(input []types.Cluster) metav1.Table {
 	table := &metav1.Table{}
 	table.ColumnDefinitions = []metav1.TableColumnDefinition{
 		{Name: "Name", Type: "string"},
+		{Name: "State", Type: "string"},
 		{Name: "External-Port", Type: "string"},
 		{Name: "Kube-Api", Type: "string"},
 		{Name: "TLS", Type: "string"},
@@ -40,6 +41,7 @@ func generateClusterTable(input []types.Cluster) metav1.Table {
 		row := metav1.TableRow{
 			Cells: []interface{}{
 				cluster.Name,
+				cluster.State,
 				cluster.ExternalPort,
 				cluster.URLKubeApi,
 				cluster.TlsCheck,
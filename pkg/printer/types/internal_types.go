@@ -15,6 +15,7 @@ type Capacity struct {
 
 type Cluster struct {
 	Name         string
+	State        string
 	URLKubeApi   string
 	KubePort     int32
 	TlsCheck     bool
@@ -48,3 +49,17 @@ type Secret struct {
 	Token     string            `json:"token,omitempty"`
 	Data      map[string]string `json:"data,omitempty"`
 }
+
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type CorePackageUpgrade struct {
+	Name           string `json:"name"`
+	CurrentVersion string `json:"currentVersion"`
+	TargetVersion  string `json:"targetVersion"`
+	Changed        int    `json:"changed"`
+	Pruned         int    `json:"pruned"`
+}
@@ -0,0 +1,55 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cnoe-io/idpbuilder/pkg/printer/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type UpgradePlanPrinter struct {
+	Packages  []types.CorePackageUpgrade
+	OutWriter io.Writer
+}
+
+func (up UpgradePlanPrinter) PrintOutput(format string) error {
+	switch format {
+	case "json":
+		return PrintDataAsJson(up.Packages, up.OutWriter)
+	case "yaml":
+		return PrintDataAsYaml(up.Packages, up.OutWriter)
+	case "table":
+		return PrintDataAsTable(generateUpgradePlanTable(up.Packages), up.OutWriter)
+	default:
+		return fmt.Errorf("output format %s is not supported", format)
+	}
+}
+
+func generateUpgradePlanTable(input []types.CorePackageUpgrade) metav1.Table {
+	table := &metav1.Table{}
+	table.ColumnDefinitions = []metav1.TableColumnDefinition{
+		{Name: "Package", Type: "string"},
+		{Name: "Current", Type: "string"},
+		{Name: "Target", Type: "string"},
+		{Name: "Changed", Type: "integer"},
+		{Name: "Pruned", Type: "integer"},
+	}
+	for _, p := range input {
+		current := p.CurrentVersion
+		if current == "" {
+			current = "unknown"
+		}
+		row := metav1.TableRow{
+			Cells: []interface{}{
+				p.Name,
+				current,
+				p.TargetVersion,
+				p.Changed,
+				p.Pruned,
+			},
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return *table
+}
@@ -0,0 +1,130 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+)
+
+// go-git does not support git bundles. Bundles are written in the v2 format understood by git:
+// a signature line, one "<hash> <ref>" line per ref, an empty line, and a packfile with all objects reachable from the refs.
+// See https://git-scm.com/docs/gitformat-bundle
+const bundleSignature = "# v2 git bundle"
+
+// WriteBundle writes all branches and tags of repo as a git bundle to w.
+// The result can be used with git clone, e.g. git clone repo.bundle.
+func WriteBundle(repo *git.Repository, w io.Writer) error {
+	iter, err := repo.References()
+	if err != nil {
+		return fmt.Errorf("listing references: %w", err)
+	}
+
+	refs := map[plumbing.ReferenceName]plumbing.Hash{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		if ref.Name().IsBranch() || ref.Name().IsTag() {
+			refs[ref.Name()] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("listing references: %w", err)
+	}
+	if len(refs) == 0 {
+		return fmt.Errorf("repository has no branches or tags")
+	}
+
+	names := make([]string, 0, len(refs))
+	for n := range refs {
+		names = append(names, n.String())
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	if _, err = fmt.Fprintln(bw, bundleSignature); err != nil {
+		return err
+	}
+
+	hashes := make([]plumbing.Hash, 0, len(refs))
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		if _, err = fmt.Fprintf(bw, "%s %s\n", head.Hash(), plumbing.HEAD); err != nil {
+			return err
+		}
+	}
+	for _, n := range names {
+		h := refs[plumbing.ReferenceName(n)]
+		hashes = append(hashes, h)
+		if _, err = fmt.Fprintf(bw, "%s %s\n", h, n); err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprintln(bw); err != nil {
+		return err
+	}
+
+	objects, err := revlist.Objects(repo.Storer, hashes, nil)
+	if err != nil {
+		return fmt.Errorf("listing objects: %w", err)
+	}
+	if _, err = packfile.NewEncoder(bw, repo.Storer, false).Encode(objects, 10); err != nil {
+		return fmt.Errorf("writing packfile: %w", err)
+	}
+	return bw.Flush()
+}
+
+// ReadBundle reads a git bundle written by WriteBundle or git bundle create into repo and creates its references.
+// Bundles with prerequisites are not supported.
+func ReadBundle(repo *git.Repository, r io.Reader) error {
+	br := bufio.NewReader(r)
+	signature, err := br.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading bundle signature: %w", err)
+	}
+	if strings.TrimSuffix(signature, "\n") != bundleSignature {
+		return fmt.Errorf("unsupported bundle format %q", strings.TrimSpace(signature))
+	}
+
+	var refs []*plumbing.Reference
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("reading bundle references: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "-") {
+			return fmt.Errorf("bundles with prerequisites are not supported")
+		}
+
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok || !plumbing.IsHash(hash) {
+			return fmt.Errorf("invalid bundle reference %q", line)
+		}
+		refs = append(refs, plumbing.NewHashReference(plumbing.ReferenceName(name), plumbing.NewHash(hash)))
+	}
+
+	if err = packfile.UpdateObjectStorage(repo.Storer, br); err != nil {
+		return fmt.Errorf("reading packfile: %w", err)
+	}
+
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			continue
+		}
+		if err = repo.Storer.SetReference(ref); err != nil {
+			return fmt.Errorf("setting reference %s: %w", ref.Name(), err)
+		}
+	}
+	return nil
+}
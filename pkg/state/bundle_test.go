@@ -0,0 +1,113 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRepository(t *testing.T) *git.Repository {
+	t.Helper()
+	repo, err := git.InitWithOptions(memory.NewStorage(), memfs.New(), git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")})
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(file, content string) plumbing.Hash {
+		f, err := wt.Filesystem.Create(file)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		_, err = wt.Add(file)
+		require.NoError(t, err)
+		h, err := wt.Commit(file, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(0, 0)},
+		})
+		require.NoError(t, err)
+		return h
+	}
+
+	first := commit("a.yaml", "a")
+	_, err = repo.CreateTag("v1", first, nil)
+	require.NoError(t, err)
+	commit("b.yaml", "b")
+
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Hash: first, Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	commit("c.yaml", "c")
+	return repo
+}
+
+func references(t *testing.T, repo *git.Repository) map[plumbing.ReferenceName]plumbing.Hash {
+	t.Helper()
+	out := map[plumbing.ReferenceName]plumbing.Hash{}
+	iter, err := repo.References()
+	require.NoError(t, err)
+	require.NoError(t, iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() || ref.Name().IsTag() {
+			out[ref.Name()] = ref.Hash()
+		}
+		return nil
+	}))
+	return out
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := testRepository(t)
+
+	b := bytes.Buffer{}
+	require.NoError(t, WriteBundle(src, &b))
+	assert.True(t, bytes.HasPrefix(b.Bytes(), []byte(bundleSignature+"\n")))
+
+	dst, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+	require.NoError(t, ReadBundle(dst, &b))
+
+	want := references(t, src)
+	assert.Len(t, want, 3)
+	assert.Equal(t, want, references(t, dst))
+
+	// all objects must be present to check out the restored branch
+	feature, err := dst.Reference(plumbing.NewBranchReferenceName("feature"), true)
+	require.NoError(t, err)
+	c, err := dst.CommitObject(feature.Hash())
+	require.NoError(t, err)
+	files, err := c.Files()
+	require.NoError(t, err)
+	var names []string
+	require.NoError(t, files.ForEach(func(f *object.File) error {
+		names = append(names, f.Name)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"a.yaml", "c.yaml"}, names)
+}
+
+func TestReadBundleErrors(t *testing.T) {
+	cases := map[string]string{
+		"signature":     "# v3 git bundle\n\n",
+		"prerequisites": bundleSignature + "\n-0123456789012345678901234567890123456789 msg\n\n",
+		"reference":     bundleSignature + "\nnot-a-hash refs/heads/main\n\n",
+		"truncated":     bundleSignature + "\n",
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			repo, err := git.Init(memory.NewStorage(), nil)
+			require.NoError(t, err)
+			assert.Error(t, ReadBundle(repo, bytes.NewBufferString(in)))
+		})
+	}
+}
+
+func TestWriteBundleEmpty(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+	assert.Error(t, WriteBundle(repo, &bytes.Buffer{}))
+}
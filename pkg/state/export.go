@@ -0,0 +1,191 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/globals"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	remoteName = "origin"
+	// matches the default branch of repositories created by the GitRepository controller.
+	defaultBranchName = "main"
+)
+
+var refSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// Export returns the state of the Localbuild of the given name: its spec, the secrets shown by get secrets, and the
+// Gitea repositories created by the GitRepository controller. Other Localbuilds of the cluster are left out: only the
+// secrets of the core packages, which are shared, and of the project namespace of the Localbuild are exported, and
+// only the repositories in its project namespace.
+func Export(ctx context.Context, kubeClient client.Client, name string) (*Snapshot, error) {
+	localBuild := v1alpha1.Localbuild{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, &localBuild); err != nil {
+		return nil, fmt.Errorf("getting localbuild %s: %w", name, err)
+	}
+
+	s := &Snapshot{
+		Localbuild: v1alpha1.Localbuild{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "Localbuild",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: localBuild.Name},
+			Spec:       localBuild.Spec,
+		},
+	}
+	// the certificate is generated for each cluster
+	s.Localbuild.Spec.BuildCustomization.SelfSignedCert = ""
+
+	projectNamespace := globals.GetProjectNamespace(localBuild.Name)
+	for _, ns := range []string{globals.ArgoCDNamespace, util.GiteaNamespace, projectNamespace} {
+		secrets := corev1.SecretList{}
+		err := kubeClient.List(ctx, &secrets, client.InNamespace(ns),
+			client.MatchingLabels{v1alpha1.CLISecretLabelKey: v1alpha1.CLISecretLabelValue})
+		if err != nil {
+			return nil, fmt.Errorf("listing secrets in %s: %w", ns, err)
+		}
+		for i := range secrets.Items {
+			s.Secrets = append(s.Secrets, cleanSecret(secrets.Items[i]))
+		}
+	}
+
+	repos := v1alpha1.GitRepositoryList{}
+	if err := kubeClient.List(ctx, &repos, client.InNamespace(projectNamespace)); err != nil {
+		return nil, fmt.Errorf("listing git repositories: %w", err)
+	}
+	for i := range repos.Items {
+		repo := repos.Items[i]
+		if repo.Spec.Provider.Name != v1alpha1.GitProviderGitea || repo.Status.ExternalGitRepositoryUrl == "" {
+			continue
+		}
+		r, err := exportRepository(ctx, kubeClient, repo)
+		if err != nil {
+			return nil, fmt.Errorf("exporting repository of %s/%s: %w", repo.Namespace, repo.Name, err)
+		}
+		s.Repositories = append(s.Repositories, r)
+	}
+	return s, nil
+}
+
+func exportRepository(ctx context.Context, kubeClient client.Client, repo v1alpha1.GitRepository) (Repository, error) {
+	secret := corev1.Secret{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Namespace: repo.Spec.SecretRef.Namespace, Name: repo.Spec.SecretRef.Name}, &secret)
+	if err != nil {
+		return Repository{}, fmt.Errorf("getting git credentials: %w", err)
+	}
+	auth := &githttp.BasicAuth{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}
+
+	repoName, err := repositoryName(repo.Status.ExternalGitRepositoryUrl)
+	if err != nil {
+		return Repository{}, err
+	}
+
+	g, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return Repository{}, err
+	}
+	remote, err := g.CreateRemote(&config.RemoteConfig{Name: remoteName, URLs: []string{repo.Status.ExternalGitRepositoryUrl}})
+	if err != nil {
+		return Repository{}, err
+	}
+
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs:        refSpecs,
+		Auth:            auth,
+		InsecureSkipTLS: true,
+		Tags:            git.AllTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return Repository{}, fmt.Errorf("fetching %s: %w", repo.Status.ExternalGitRepositoryUrl, err)
+	}
+
+	defaultBranch := defaultBranchName
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth, InsecureSkipTLS: true})
+	if err == nil {
+		for _, ref := range refs {
+			if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+				defaultBranch = ref.Target().Short()
+			}
+		}
+	}
+	err = g.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(defaultBranch)))
+	if err != nil {
+		return Repository{}, err
+	}
+
+	b := bytes.Buffer{}
+	if err = WriteBundle(g, &b); err != nil {
+		return Repository{}, err
+	}
+
+	return Repository{
+		Namespace:     repo.Namespace,
+		Name:          repo.Name,
+		RepoName:      repoName,
+		DefaultBranch: defaultBranch,
+		Bundle:        b.Bytes(),
+	}, nil
+}
+
+// repositoryName returns the name of the repository from its clone url, e.g. https://gitea.cnoe.localtest.me:8443/giteaAdmin/my-repo.git
+func repositoryName(cloneUrl string) (string, error) {
+	u, err := url.Parse(cloneUrl)
+	if err != nil {
+		return "", fmt.Errorf("parsing repository url %s: %w", cloneUrl, err)
+	}
+	name := strings.TrimSuffix(path.Base(u.Path), ".git")
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("repository name not found in url %s", cloneUrl)
+	}
+	return name, nil
+}
+
+// cleanSecret removes fields that are specific to the cluster the secret was read from.
+func cleanSecret(in corev1.Secret) corev1.Secret {
+	annotations := map[string]string{}
+	for k, v := range in.Annotations {
+		if k != corev1.LastAppliedConfigAnnotation {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	return corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        in.Name,
+			Namespace:   in.Namespace,
+			Labels:      in.Labels,
+			Annotations: annotations,
+		},
+		Type: in.Type,
+		Data: in.Data,
+	}
+}
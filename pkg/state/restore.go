@@ -0,0 +1,110 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RestoreSecrets creates the secrets of the snapshot that do not exist in the cluster. It must run before the core
+// packages are installed so Gitea is set up with the exported admin password.
+func RestoreSecrets(ctx context.Context, kubeClient client.Client, s *Snapshot) error {
+	logger := log.FromContext(ctx)
+	for i := range s.Secrets {
+		secret := s.Secrets[i].DeepCopy()
+
+		switch {
+		case secret.Namespace == util.ArgocdNamespace && secret.Name == util.ArgocdInitialAdminSecretName:
+			// Argo CD generates its admin password on install and ignores an existing secret.
+			logger.V(1).Info("skipping argocd admin secret, a new password is generated", "name", secret.Name)
+			continue
+		case secret.Namespace == util.GiteaNamespace && secret.Name == util.GiteaAdminSecret:
+			// tokens are stored in the gitea database and are not exported. a new token is created after install.
+			delete(secret.Data, util.GiteaAdminTokenFieldName)
+		}
+
+		if err := k8s.EnsureNamespace(ctx, kubeClient, secret.Namespace); err != nil {
+			return err
+		}
+		err := kubeClient.Create(ctx, secret)
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreRepositories creates the repositories of the snapshot in Gitea and pushes their branches and tags.
+// It must run before the GitRepository controller creates them so the content committed in Gitea is kept.
+// Repositories are created as private repositories if private is set, like the GitRepository controller does.
+func RestoreRepositories(ctx context.Context, s *Snapshot, baseUrl, username, password string, private bool) error {
+	giteaClient, err := gitea.NewClient(baseUrl, gitea.SetHTTPClient(util.GetHttpClient()),
+		gitea.SetBasicAuth(username, password), gitea.SetContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("creating gitea client: %w", err)
+	}
+
+	for _, repo := range s.Repositories {
+		if err = restoreRepository(ctx, giteaClient, repo, username, password, private); err != nil {
+			return fmt.Errorf("restoring repository %s: %w", repo.RepoName, err)
+		}
+	}
+	return nil
+}
+
+func restoreRepository(ctx context.Context, giteaClient *gitea.Client, repo Repository, username, password string, private bool) error {
+	cloneUrl := ""
+	existing, resp, err := giteaClient.GetRepo(username, repo.RepoName)
+	switch {
+	case err == nil:
+		cloneUrl = existing.CloneURL
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		created, _, err := giteaClient.CreateRepo(gitea.CreateRepoOption{
+			Name:          repo.RepoName,
+			Description:   fmt.Sprintf("created by Git Repository controller for %s in %s namespace", repo.Name, repo.Namespace),
+			Private:       private,
+			DefaultBranch: repo.DefaultBranch,
+		})
+		if err != nil {
+			return fmt.Errorf("creating repository: %w", err)
+		}
+		cloneUrl = created.CloneURL
+	default:
+		return fmt.Errorf("getting repository: %w", err)
+	}
+
+	g, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return err
+	}
+	if err = ReadBundle(g, bytes.NewReader(repo.Bundle)); err != nil {
+		return err
+	}
+	remote, err := g.CreateRemote(&config.RemoteConfig{Name: remoteName, URLs: []string{cloneUrl}})
+	if err != nil {
+		return err
+	}
+
+	err = remote.PushContext(ctx, &git.PushOptions{
+		RefSpecs:        refSpecs,
+		Auth:            &githttp.BasicAuth{Username: username, Password: password},
+		InsecureSkipTLS: true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("pushing to %s: %w", cloneUrl, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Version is incremented when the layout of the state file changes in an incompatible way.
+	Version = 1
+
+	manifestFile     = "state.yaml"
+	localbuildFile   = "localbuild.yaml"
+	secretsFile      = "secrets.yaml"
+	repositoriesDir  = "repositories"
+	bundleFileSuffix = ".bundle"
+)
+
+// Snapshot is the state of an idpbuilder cluster that can be restored into a new cluster.
+type Snapshot struct {
+	Localbuild   v1alpha1.Localbuild
+	Secrets      []corev1.Secret
+	Repositories []Repository
+}
+
+// Repository is a git repository in Gitea created by the GitRepository controller.
+type Repository struct {
+	// Namespace and Name of the GitRepository object.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// RepoName is the name of the repository in Gitea.
+	RepoName string `json:"repoName"`
+	// DefaultBranch is the branch HEAD points to in Gitea.
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	// Bundle is the git bundle with all branches and tags of the repository.
+	Bundle []byte `json:"-"`
+}
+
+type manifest struct {
+	Version      int          `json:"version"`
+	Created      time.Time    `json:"created"`
+	Repositories []Repository `json:"repositories,omitempty"`
+}
+
+// Write writes the snapshot as a tar archive to w.
+func Write(w io.Writer, s *Snapshot) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+
+	add := func(name string, data []byte) error {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		})
+		if err != nil {
+			return fmt.Errorf("writing header for %s: %w", name, err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+		return nil
+	}
+
+	m, err := yaml.Marshal(manifest{Version: Version, Created: now.UTC(), Repositories: s.Repositories})
+	if err != nil {
+		return fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err = add(manifestFile, m); err != nil {
+		return err
+	}
+
+	lb, err := yaml.Marshal(s.Localbuild)
+	if err != nil {
+		return fmt.Errorf("marshaling localbuild: %w", err)
+	}
+	if err = add(localbuildFile, lb); err != nil {
+		return err
+	}
+
+	var secrets []byte
+	for i := range s.Secrets {
+		b, err := yaml.Marshal(s.Secrets[i])
+		if err != nil {
+			return fmt.Errorf("marshaling secret %s: %w", s.Secrets[i].Name, err)
+		}
+		secrets = append(secrets, []byte("---\n")...)
+		secrets = append(secrets, b...)
+	}
+	if err = add(secretsFile, secrets); err != nil {
+		return err
+	}
+
+	for _, r := range s.Repositories {
+		if err = add(bundlePath(r), r.Bundle); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// Read reads a snapshot written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading state file: %w", err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", h.Name, err)
+		}
+		files[path.Clean(h.Name)] = b
+	}
+
+	m := manifest{}
+	b, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%s not found, not an idpbuilder state file", manifestFile)
+	}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", manifestFile, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("unsupported state file version %d, expected %d", m.Version, Version)
+	}
+
+	s := &Snapshot{}
+	if err := yaml.Unmarshal(files[localbuildFile], &s.Localbuild); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", localbuildFile, err)
+	}
+
+	for _, doc := range bytes.Split(files[secretsFile], []byte("---\n")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		secret := corev1.Secret{}
+		if err := yaml.Unmarshal(doc, &secret); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", secretsFile, err)
+		}
+		s.Secrets = append(s.Secrets, secret)
+	}
+
+	for _, repo := range m.Repositories {
+		bundle, ok := files[bundlePath(repo)]
+		if !ok {
+			return nil, fmt.Errorf("bundle for repository %s not found", repo.RepoName)
+		}
+		repo.Bundle = bundle
+		s.Repositories = append(s.Repositories, repo)
+	}
+	return s, nil
+}
+
+func bundlePath(r Repository) string {
+	return path.Join(repositoriesDir, r.RepoName+bundleFileSuffix)
+}
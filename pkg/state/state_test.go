@@ -0,0 +1,227 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/cnoe-io/idpbuilder/pkg/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func cliSecret(namespace, name string, data map[string]string) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{v1alpha1.CLISecretLabelKey: v1alpha1.CLISecretLabelValue},
+		},
+		Data: map[string][]byte{},
+	}
+	for k, v := range data {
+		s.Data[k] = []byte(v)
+	}
+	return s
+}
+
+func TestWriteRead(t *testing.T) {
+	in := &Snapshot{
+		Localbuild: v1alpha1.Localbuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "localdev"},
+			Spec: v1alpha1.LocalbuildSpec{
+				BuildCustomization: v1alpha1.BuildCustomizationSpec{Host: "cnoe.localtest.me", Port: "8443"},
+			},
+		},
+		Secrets: []corev1.Secret{
+			*cliSecret(util.GiteaNamespace, util.GiteaAdminSecret, map[string]string{"username": "giteaAdmin", "password": "abc"}),
+			*cliSecret("default", "other", map[string]string{"key": "value"}),
+		},
+		Repositories: []Repository{
+			{Namespace: "idpbuilder-localdev", Name: "app", RepoName: "idpbuilder-localdev-app", DefaultBranch: "main", Bundle: []byte("bundle")},
+		},
+	}
+
+	b := bytes.Buffer{}
+	require.NoError(t, Write(&b, in))
+
+	out, err := Read(&b)
+	require.NoError(t, err)
+	assert.Equal(t, in.Localbuild.Name, out.Localbuild.Name)
+	assert.Equal(t, in.Localbuild.Spec, out.Localbuild.Spec)
+	require.Len(t, out.Secrets, 2)
+	assert.Equal(t, in.Secrets[0].Data, out.Secrets[0].Data)
+	assert.Equal(t, in.Secrets[1].Name, out.Secrets[1].Name)
+	assert.Equal(t, in.Repositories, out.Repositories)
+}
+
+func TestReadErrors(t *testing.T) {
+	archive := func(files map[string]string) *bytes.Buffer {
+		b := &bytes.Buffer{}
+		tw := tar.NewWriter(b)
+		for name, content := range files {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))}))
+			_, err := tw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		return b
+	}
+
+	m, err := yaml.Marshal(manifest{Version: Version + 1})
+	require.NoError(t, err)
+	_, err = Read(archive(map[string]string{manifestFile: string(m)}))
+	assert.ErrorContains(t, err, "unsupported state file version")
+
+	_, err = Read(archive(map[string]string{"other.yaml": ""}))
+	assert.ErrorContains(t, err, "not an idpbuilder state file")
+
+	m, err = yaml.Marshal(manifest{Version: Version, Repositories: []Repository{{RepoName: "missing"}}})
+	require.NoError(t, err)
+	_, err = Read(archive(map[string]string{manifestFile: string(m)}))
+	assert.ErrorContains(t, err, "bundle for repository missing not found")
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	secret := cliSecret(util.GiteaNamespace, util.GiteaAdminSecret, map[string]string{"username": "giteaAdmin", "password": "abc"})
+	secret.Annotations = map[string]string{corev1.LastAppliedConfigAnnotation: "{}", "keep": "true"}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(
+		&v1alpha1.Localbuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "localdev"},
+			Spec: v1alpha1.LocalbuildSpec{
+				BuildCustomization: v1alpha1.BuildCustomizationSpec{Host: "cnoe.localtest.me", SelfSignedCert: "cert"},
+			},
+		},
+		secret,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "default"}},
+		// repositories without a url in their status have not been created in gitea yet
+		&v1alpha1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "idpbuilder-localdev"},
+			Spec:       v1alpha1.GitRepositorySpec{Provider: v1alpha1.Provider{Name: v1alpha1.GitProviderGitea}},
+		},
+		// the secrets and repositories of other localbuilds are not exported. exporting the repository would fail,
+		// because its url cannot be reached.
+		cliSecret("idpbuilder-team-b", "team-b", map[string]string{"password": "team-b"}),
+		&v1alpha1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "idpbuilder-team-b"},
+			Spec:       v1alpha1.GitRepositorySpec{Provider: v1alpha1.Provider{Name: v1alpha1.GitProviderGitea}},
+			Status:     v1alpha1.GitRepositoryStatus{ExternalGitRepositoryUrl: "https://127.0.0.1:1/giteaAdmin/idpbuilder-team-b-app.git"},
+		},
+	).Build()
+
+	s, err := Export(ctx, kubeClient, "localdev")
+	require.NoError(t, err)
+	assert.Equal(t, "localdev", s.Localbuild.Name)
+	assert.Equal(t, "cnoe.localtest.me", s.Localbuild.Spec.BuildCustomization.Host)
+	assert.Empty(t, s.Localbuild.Spec.BuildCustomization.SelfSignedCert)
+	assert.Empty(t, s.Repositories)
+
+	require.Len(t, s.Secrets, 1)
+	assert.Equal(t, map[string]string{"keep": "true"}, s.Secrets[0].Annotations)
+	assert.Empty(t, s.Secrets[0].ResourceVersion)
+	assert.Equal(t, []byte("abc"), s.Secrets[0].Data["password"])
+
+	_, err = Export(ctx, kubeClient, "missing")
+	assert.Error(t, err)
+}
+
+func TestRepositoryName(t *testing.T) {
+	name, err := repositoryName("https://gitea.cnoe.localtest.me:8443/giteaAdmin/idpbuilder-localdev-app.git")
+	require.NoError(t, err)
+	assert.Equal(t, "idpbuilder-localdev-app", name)
+
+	_, err = repositoryName("https://gitea.cnoe.localtest.me:8443/")
+	assert.Error(t, err)
+}
+
+func TestRestoreSecrets(t *testing.T) {
+	ctx := context.Background()
+	existing := cliSecret("default", "existing", map[string]string{"key": "current"})
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(existing).Build()
+
+	s := &Snapshot{
+		Secrets: []corev1.Secret{
+			*cliSecret(util.GiteaNamespace, util.GiteaAdminSecret, map[string]string{"username": "giteaAdmin", "password": "abc", "token": "old"}),
+			*cliSecret(util.ArgocdNamespace, util.ArgocdInitialAdminSecretName, map[string]string{"password": "argo"}),
+			*cliSecret("default", "existing", map[string]string{"key": "exported"}),
+			*cliSecret("team", "creds", map[string]string{"key": "value"}),
+		},
+	}
+	require.NoError(t, RestoreSecrets(ctx, kubeClient, s))
+
+	gitea := corev1.Secret{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Namespace: util.GiteaNamespace, Name: util.GiteaAdminSecret}, &gitea))
+	assert.Equal(t, []byte("abc"), gitea.Data["password"])
+	assert.NotContains(t, gitea.Data, util.GiteaAdminTokenFieldName)
+	// the snapshot is not modified
+	assert.Contains(t, s.Secrets[0].Data, util.GiteaAdminTokenFieldName)
+
+	err := kubeClient.Get(ctx, client.ObjectKey{Namespace: util.ArgocdNamespace, Name: util.ArgocdInitialAdminSecretName}, &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	current := corev1.Secret{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(existing), &current))
+	assert.Equal(t, []byte("current"), current.Data["key"])
+
+	ns := corev1.Namespace{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Name: "team"}, &ns))
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Namespace: "team", Name: "creds"}, &corev1.Secret{}))
+}
+
+func TestRestoreRepositories(t *testing.T) {
+	bundle := bytes.Buffer{}
+	require.NoError(t, WriteBundle(testRepository(t), &bundle))
+	remote := t.TempDir()
+	_, err := git.PlainInit(remote, true)
+	require.NoError(t, err)
+
+	var created []gitea.CreateRepoOption
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"1.24.3"}`))
+	})
+	mux.HandleFunc("GET /api/v1/repos/giteaAdmin/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		opt := gitea.CreateRepoOption{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&opt))
+		created = append(created, opt)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(gitea.Repository{Name: opt.Name, CloneURL: remote})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s := &Snapshot{Repositories: []Repository{
+		{Namespace: "idpbuilder-localdev", Name: "app", RepoName: "idpbuilder-localdev-app", DefaultBranch: "main", Bundle: bundle.Bytes()},
+	}}
+	for _, private := range []bool{false, true} {
+		created = nil
+		require.NoError(t, RestoreRepositories(context.Background(), s, server.URL, "giteaAdmin", "password", private))
+		require.Len(t, created, 1)
+		assert.Equal(t, "idpbuilder-localdev-app", created[0].Name)
+		assert.Equal(t, private, created[0].Private)
+	}
+
+	pushed, err := git.PlainOpen(remote)
+	require.NoError(t, err)
+	assert.Contains(t, references(t, pushed), plumbing.NewBranchReferenceName("feature"))
+}
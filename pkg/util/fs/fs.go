@@ -16,6 +16,24 @@ type FS interface {
 	ReadFile(name string) ([]byte, error)
 }
 
+// dirFS reads files from a directory on the host.
+type dirFS struct {
+	fsys fs.FS
+}
+
+// DirFS returns an FS that reads the files in dir.
+func DirFS(dir string) FS {
+	return dirFS{fsys: os.DirFS(dir)}
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(d.fsys, name)
+}
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(d.fsys, name)
+}
+
 func ConvertFSToBytes(inFS FS, name string, templateData any) ([][]byte, error) {
 	d, err := inFS.ReadDir(name)
 	if err != nil {
@@ -0,0 +1,206 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// GitTokenEnv is the environment variable with the access token for https urls of remote packages.
+	GitTokenEnv = "IDPBUILDER_GIT_TOKEN"
+	// GitUsernameEnv is the environment variable with the user name used with GitTokenEnv.
+	GitUsernameEnv = "IDPBUILDER_GIT_USERNAME"
+	// SSHKeyPassphraseEnv is the environment variable with the passphrase of PackageAuthSpec.SSHKeyPath.
+	SSHKeyPassphraseEnv = "IDPBUILDER_SSH_KEY_PASSPHRASE"
+
+	// GitTokenUsername is sent with access tokens over basic auth. GitHub, GitLab, and Gitea accept any user name with
+	// an access token as the password.
+	GitTokenUsername = "oauth2"
+
+	// keys of the secret referenced by PackageAuthSpec.SecretRef. They match the keys of Argo CD repository secrets.
+	packageAuthUsernameKey      = "username"
+	packageAuthPasswordKey      = "password"
+	packageAuthSSHPrivateKeyKey = "sshPrivateKey"
+	packageAuthURLKey           = "url"
+)
+
+// GitCredentialFillFunc returns the user name and password git credential helpers hold for a url.
+type GitCredentialFillFunc func(ctx context.Context, protocol, host string) (string, string, error)
+
+// GitCredentialFill asks the git credential helpers configured on the host. It never prompts.
+var GitCredentialFill GitCredentialFillFunc = gitCredentialFill
+
+// RemoteRepoAuth returns the credentials used to clone a remote repository of a package, or nil when the repository is
+// cloned without authentication. kubeClient is only used when cfg references a secret.
+func RemoteRepoAuth(ctx context.Context, kubeClient client.Client, cfg v1alpha1.PackageAuthSpec, repoUrl string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(repoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url: %w", err)
+	}
+
+	var secretData map[string][]byte
+	if cfg.SecretRef.Name != "" && kubeClient != nil {
+		s, err := GetSecretByName(ctx, kubeClient, cfg.SecretRef.Namespace, cfg.SecretRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("getting package auth secret: %w", err)
+		}
+		secretData = s.Data
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		return sshAuth(cfg, ep, secretData)
+	case "http", "https":
+		return httpAuth(ctx, cfg, ep, secretData)
+	}
+	return nil, nil
+}
+
+func sshAuth(cfg v1alpha1.PackageAuthSpec, ep *transport.Endpoint, secretData map[string][]byte) (transport.AuthMethod, error) {
+	user := ep.User
+	if user == "" {
+		user = "git"
+	}
+
+	var auth transport.AuthMethod
+	var helper *ssh.HostKeyCallbackHelper
+	switch {
+	case cfg.SSHKeyPath != "":
+		keys, err := ssh.NewPublicKeysFromFile(user, cfg.SSHKeyPath, os.Getenv(SSHKeyPassphraseEnv))
+		if err != nil {
+			return nil, fmt.Errorf("reading ssh key %s: %w", cfg.SSHKeyPath, err)
+		}
+		auth, helper = keys, &keys.HostKeyCallbackHelper
+	case len(secretData[packageAuthSSHPrivateKeyKey]) != 0:
+		keys, err := ssh.NewPublicKeys(user, secretData[packageAuthSSHPrivateKeyKey], "")
+		if err != nil {
+			return nil, fmt.Errorf("parsing ssh key of package auth secret: %w", err)
+		}
+		auth, helper = keys, &keys.HostKeyCallbackHelper
+	default:
+		agent, err := ssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to ssh agent: %w", err)
+		}
+		auth, helper = agent, &agent.HostKeyCallbackHelper
+	}
+
+	// host keys are always checked. without a path, go-git reads the files used by ssh.
+	if cfg.KnownHostsPath != "" {
+		cb, err := ssh.NewKnownHostsCallback(cfg.KnownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading known hosts %s: %w", cfg.KnownHostsPath, err)
+		}
+		helper.HostKeyCallback = cb
+	}
+	return auth, nil
+}
+
+// httpAuth returns the credentials of an https url. The password of the secret and the access token in GitTokenEnv are
+// only sent to urls under the prefix of PackageAuthSpec.URL, or the url key of the secret, so a token of one server is
+// never sent to another.
+func httpAuth(ctx context.Context, cfg v1alpha1.PackageAuthSpec, ep *transport.Endpoint, secretData map[string][]byte) (transport.AuthMethod, error) {
+	if p := secretData[packageAuthPasswordKey]; len(p) != 0 {
+		prefix := cfg.URL
+		if u := secretData[packageAuthURLKey]; len(u) != 0 {
+			prefix = string(u)
+		}
+		if prefix == "" {
+			return nil, fmt.Errorf("package auth secret %s has no %s key and no package auth url is set",
+				cfg.SecretRef.Name, packageAuthURLKey)
+		}
+		if hasURLPrefix(ep, prefix) {
+			return basicAuth(string(secretData[packageAuthUsernameKey]), string(p)), nil
+		}
+	}
+
+	if token := os.Getenv(GitTokenEnv); token != "" && hasURLPrefix(ep, cfg.URL) {
+		return basicAuth(os.Getenv(GitUsernameEnv), token), nil
+	}
+
+	if cfg.CredentialHelper {
+		host := ep.Host
+		if ep.Port != 0 {
+			host = fmt.Sprintf("%s:%d", ep.Host, ep.Port)
+		}
+		user, pass, err := GitCredentialFill(ctx, ep.Protocol, host)
+		if err != nil {
+			return nil, fmt.Errorf("getting credentials from git credential helper: %w", err)
+		}
+		if pass != "" {
+			return basicAuth(user, pass), nil
+		}
+	}
+	return nil, nil
+}
+
+// hasURLPrefix reports whether ep is prefix or a repository under it. The scheme, host, and port must be equal, and
+// paths are compared by segment, so https://github.com/org does not match https://github.com/org-other/repo.
+func hasURLPrefix(ep *transport.Endpoint, prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	p, err := transport.NewEndpoint(prefix)
+	if err != nil {
+		return false
+	}
+	if p.Protocol != ep.Protocol || p.Host != ep.Host || p.Port != ep.Port {
+		return false
+	}
+	prefixPath := strings.TrimSuffix(strings.TrimSuffix(p.Path, "/"), ".git")
+	repoPath := strings.TrimSuffix(ep.Path, ".git")
+	return prefixPath == "" || repoPath == prefixPath || strings.HasPrefix(repoPath, prefixPath+"/")
+}
+
+func basicAuth(user, password string) *http.BasicAuth {
+	if user == "" {
+		user = GitTokenUsername
+	}
+	return &http.BasicAuth{Username: user, Password: password}
+}
+
+// gitCredentialFill runs git credential fill, see https://git-scm.com/docs/git-credential.
+func gitCredentialFill(ctx context.Context, protocol, host string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", protocol, host))
+	// helpers must not fall back to asking on the terminal the controllers run in.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", "", ctx.Err()
+		}
+		// when no helper has credentials for the host, git falls back to the terminal and fails because prompts are
+		// disabled.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && bytes.Contains(exitErr.Stderr, []byte("terminal prompts disabled")) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("running git credential fill: %w", err)
+	}
+
+	var user, pass string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		k, v, _ := strings.Cut(scanner.Text(), "=")
+		switch k {
+		case "username":
+			user = v
+		case "password":
+			pass = v
+		}
+	}
+	return user, pass, scanner.Err()
+}
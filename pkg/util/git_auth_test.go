@@ -0,0 +1,159 @@
+package util
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRemoteRepoAuthHTTP(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(GitTokenEnv, "")
+	repoUrl := "https://github.com/owner/repo"
+
+	auth, err := RemoteRepoAuth(ctx, nil, v1alpha1.PackageAuthSpec{}, repoUrl)
+	require.NoError(t, err)
+	assert.Nil(t, auth)
+
+	t.Setenv(GitTokenEnv, "env-token")
+	auth, err = RemoteRepoAuth(ctx, nil, v1alpha1.PackageAuthSpec{}, repoUrl)
+	require.NoError(t, err)
+	assert.Nil(t, auth, "the token is only sent to urls under the package auth url")
+
+	auth, err = RemoteRepoAuth(ctx, nil, v1alpha1.PackageAuthSpec{URL: "https://github.com/owner"}, repoUrl)
+	require.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: GitTokenUsername, Password: "env-token"}, auth)
+	assert.NotContains(t, auth.String(), "env-token")
+
+	// a referenced secret is preferred over the environment.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo-auth", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("secret-token")},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(secret).Build()
+	cfg := v1alpha1.PackageAuthSpec{SecretRef: v1alpha1.SecretReference{Name: "repo-auth", Namespace: "default"}, URL: "https://github.com"}
+	auth, err = RemoteRepoAuth(ctx, kubeClient, cfg, repoUrl)
+	require.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: "user", Password: "secret-token"}, auth)
+}
+
+func TestRemoteRepoAuthHTTPScope(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(GitTokenEnv, "env-token")
+	cfg := v1alpha1.PackageAuthSpec{URL: "https://github.com/owner"}
+
+	for repoUrl, sent := range map[string]bool{
+		"https://github.com/owner/repo.git":      true,
+		"https://github.com/owner-other/repo":    false,
+		"https://github.com:8443/owner/repo":     false,
+		"http://github.com/owner/repo":           false,
+		"https://some-other-host.com/owner/repo": false,
+	} {
+		auth, err := RemoteRepoAuth(ctx, nil, cfg, repoUrl)
+		require.NoError(t, err)
+		assert.Equal(t, sent, auth != nil, repoUrl)
+	}
+
+	// the url key of the secret scopes its credentials, and a secret without a url is rejected.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo-auth", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret-token"), "url": []byte("https://git.example.com")},
+	}
+	noURL := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "no-url", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret-token")},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(secret, noURL).Build()
+	t.Setenv(GitTokenEnv, "")
+
+	cfg = v1alpha1.PackageAuthSpec{SecretRef: v1alpha1.SecretReference{Name: "repo-auth", Namespace: "default"}, URL: "https://github.com"}
+	auth, err := RemoteRepoAuth(ctx, kubeClient, cfg, "https://github.com/owner/repo")
+	require.NoError(t, err)
+	assert.Nil(t, auth)
+	auth, err = RemoteRepoAuth(ctx, kubeClient, cfg, "https://git.example.com/owner/repo")
+	require.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: GitTokenUsername, Password: "secret-token"}, auth)
+
+	cfg = v1alpha1.PackageAuthSpec{SecretRef: v1alpha1.SecretReference{Name: "no-url", Namespace: "default"}}
+	_, err = RemoteRepoAuth(ctx, kubeClient, cfg, "https://git.example.com/owner/repo")
+	assert.ErrorContains(t, err, "no url key")
+}
+
+func TestRemoteRepoAuthCredentialHelper(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(GitTokenEnv, "")
+	fill := GitCredentialFill
+	defer func() { GitCredentialFill = fill }()
+
+	var gotProtocol, gotHost string
+	GitCredentialFill = func(ctx context.Context, protocol, host string) (string, string, error) {
+		gotProtocol, gotHost = protocol, host
+		return "helper-user", "helper-pass", nil
+	}
+
+	auth, err := RemoteRepoAuth(ctx, nil, v1alpha1.PackageAuthSpec{}, "https://git.example.com:8443/owner/repo")
+	require.NoError(t, err)
+	assert.Nil(t, auth, "credential helpers are only asked when enabled")
+
+	auth, err = RemoteRepoAuth(ctx, nil, v1alpha1.PackageAuthSpec{CredentialHelper: true}, "https://git.example.com:8443/owner/repo")
+	require.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: "helper-user", Password: "helper-pass"}, auth)
+	assert.Equal(t, "https", gotProtocol)
+	assert.Equal(t, "git.example.com:8443", gotHost)
+}
+
+func TestRemoteRepoAuthSSH(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	knownHosts := filepath.Join(dir, "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, nil, 0600))
+
+	cfg := v1alpha1.PackageAuthSpec{SSHKeyPath: keyPath, KnownHostsPath: knownHosts}
+	auth, err := RemoteRepoAuth(ctx, nil, cfg, "git@github.com:owner/repo")
+	require.NoError(t, err)
+	keys, ok := auth.(*ssh.PublicKeys)
+	require.True(t, ok)
+	assert.Equal(t, "git", keys.User)
+	assert.NotNil(t, keys.HostKeyCallback)
+
+	cfg.KnownHostsPath = filepath.Join(dir, "missing")
+	_, err = RemoteRepoAuth(ctx, nil, cfg, "ssh://deploy@git.example.com/owner/repo")
+	assert.ErrorContains(t, err, "reading known hosts")
+}
+
+func TestGitCredentialFill(t *testing.T) {
+	// no helper is configured, so git has no credentials for the host.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	user, pass, err := gitCredentialFill(context.Background(), "https", "git.example.com")
+	require.NoError(t, err)
+	assert.Empty(t, user)
+	assert.Empty(t, pass)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = gitCredentialFill(ctx, "https", "git.example.com")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
@@ -109,8 +110,14 @@ func ReadWorktreeFile(wt billy.Filesystem, path string) ([]byte, error) {
 }
 
 func CloneRemoteRepoToMemory(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool) (billy.Filesystem, *git.Repository, error) {
+	return CloneRemoteRepoToMemoryWithAuth(ctx, remote, depth, insecureSkipTLS, nil)
+}
+
+// CloneRemoteRepoToMemoryWithAuth is CloneRemoteRepoToMemory for repositories that require authentication.
+func CloneRemoteRepoToMemoryWithAuth(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, auth transport.AuthMethod) (billy.Filesystem, *git.Repository, error) {
 	cloneOptions := &git.CloneOptions{
 		URL:               remote.Url,
+		Auth:              auth,
 		Depth:             depth,
 		ShallowSubmodules: true,
 		SingleBranch:      true,
@@ -139,11 +146,17 @@ func CloneRemoteRepoToMemory(ctx context.Context, remote v1alpha1.RemoteReposito
 }
 
 func CloneRemoteRepoToDir(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, dir, fallbackUrl string) (billy.Filesystem, *git.Repository, error) {
+	return CloneRemoteRepoToDirWithAuth(ctx, remote, depth, insecureSkipTLS, dir, fallbackUrl, nil)
+}
+
+// CloneRemoteRepoToDirWithAuth is CloneRemoteRepoToDir for repositories that require authentication.
+func CloneRemoteRepoToDirWithAuth(ctx context.Context, remote v1alpha1.RemoteRepositorySpec, depth int, insecureSkipTLS bool, dir, fallbackUrl string, auth transport.AuthMethod) (billy.Filesystem, *git.Repository, error) {
 	repo, err := git.PlainOpen(dir)
 	if err != nil {
 		if errors.Is(err, git.ErrRepositoryNotExists) {
 			cloneOptions := &git.CloneOptions{
 				URL:               remote.Url,
+				Auth:              auth,
 				Depth:             depth,
 				ShallowSubmodules: true,
 				Tags:              git.AllTags,
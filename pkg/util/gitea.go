@@ -9,6 +9,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"os"
+	"path/filepath"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
 )
@@ -20,9 +22,67 @@ const (
 	GiteaAdminName           = "giteaAdmin"
 	GiteaAdminTokenName      = "admin"
 	GiteaAdminTokenFieldName = "token"
+	GiteaReadOnlyTokenName   = "argocd-read-only"
 	GiteaURLTempl            = "%s://%s%s:%s%s"
+
+	// GiteaDataNodeDir is where the Gitea data directory on the host is mounted in the kind node.
+	GiteaDataNodeDir = "/var/lib/idpbuilder/gitea"
+	// GiteaDataSubDir is the sub directory of the Gitea data directory that backs the Gitea volume.
+	// Files next to it, such as the admin password, are not visible to Gitea.
+	GiteaDataSubDir            = "data"
+	giteaAdminPasswordFileName = "admin-password"
+
+	// giteaAccessTokenScopeReadRepository grants read access to repositories. The scope was introduced after the
+	// scopes defined by the Gitea SDK.
+	giteaAccessTokenScopeReadRepository gitea.AccessTokenScope = "read:repository"
 )
 
+// PrepareGiteaDataDir creates the Gitea data directory on the host.
+// The volume directory must be writable by the rootless Gitea user whose uid differs from the host user. Ownership
+// cannot be used for this: kubelet does not apply fsGroup to hostPath volumes, and rootless or VM based container
+// runtimes map uids differently. The volume is therefore world writable, and dir is restricted to the host user so
+// other users of the host cannot reach it. Gitea reaches the volume through its own mount, not through dir.
+func PrepareGiteaDataDir(dir string) error {
+	dataDir := filepath.Join(dir, GiteaDataSubDir)
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return fmt.Errorf("creating gitea data directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("setting permissions of gitea data directory: %w", err)
+	}
+	if err := os.Chmod(dataDir, 0777); err != nil {
+		return fmt.Errorf("setting permissions of gitea data directory: %w", err)
+	}
+	return nil
+}
+
+// GiteaAdminPassword returns the Gitea admin password stored in the data directory, generating and storing it if it
+// does not exist. Reusing the password keeps the admin secret consistent with the persisted database.
+// A new password is generated if dataDir is empty.
+func GiteaAdminPassword(dataDir string) (string, error) {
+	if dataDir == "" {
+		return GeneratePassword()
+	}
+
+	p := filepath.Join(dataDir, giteaAdminPasswordFileName)
+	b, err := os.ReadFile(p)
+	if err == nil && len(strings.TrimSpace(string(b))) > 0 {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading gitea admin password: %w", err)
+	}
+
+	pass, err := GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(p, []byte(pass), 0600); err != nil {
+		return "", fmt.Errorf("storing gitea admin password: %w", err)
+	}
+	return pass, nil
+}
+
 func GiteaAdminSecretObject() corev1.Secret {
 	return corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -71,6 +131,16 @@ func PatchPasswordSecret(ctx context.Context, kubeClient client.Client, config v
 }
 
 func GetGiteaToken(ctx context.Context, baseUrl, username, password string) (string, error) {
+	return createGiteaToken(ctx, baseUrl, username, password, GiteaAdminTokenName, gitea.AccessTokenScopeAll)
+}
+
+// GetGiteaReadOnlyToken creates a token of the user that can only read repositories. It replaces an existing token
+// with the same name, because Gitea only returns the value of a token when it is created.
+func GetGiteaReadOnlyToken(ctx context.Context, baseUrl, username, password string) (string, error) {
+	return createGiteaToken(ctx, baseUrl, username, password, GiteaReadOnlyTokenName, giteaAccessTokenScopeReadRepository)
+}
+
+func createGiteaToken(ctx context.Context, baseUrl, username, password, name string, scope gitea.AccessTokenScope) (string, error) {
 	giteaClient, err := gitea.NewClient(baseUrl, gitea.SetHTTPClient(GetHttpClient()),
 		gitea.SetBasicAuth(username, password), gitea.SetContext(ctx),
 	)
@@ -83,7 +153,7 @@ func GetGiteaToken(ctx context.Context, baseUrl, username, password string) (str
 	}
 
 	for i := range tokens {
-		if tokens[i].Name == GiteaAdminTokenName {
+		if tokens[i].Name == name {
 			resp, err := giteaClient.DeleteAccessToken(tokens[i].ID)
 			if err != nil {
 				return "", fmt.Errorf("deleting gitea access tokens. status: %s error : %w", resp.Status, err)
@@ -93,9 +163,9 @@ func GetGiteaToken(ctx context.Context, baseUrl, username, password string) (str
 	}
 
 	token, resp, err := giteaClient.CreateAccessToken(gitea.CreateAccessTokenOption{
-		Name: GiteaAdminTokenName,
+		Name: name,
 		Scopes: []gitea.AccessTokenScope{
-			gitea.AccessTokenScopeAll,
+			scope,
 		},
 	})
 	if err != nil {
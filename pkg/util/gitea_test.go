@@ -1,10 +1,18 @@
 package util
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"code.gitea.io/sdk/gitea"
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGiteaBaseUrl(t *testing.T) {
@@ -21,3 +29,67 @@ func TestGiteaBaseUrl(t *testing.T) {
 	s = GiteaBaseUrl(c)
 	assert.Equal(t, "http://cnoe.localtest.me:8080/gitea", s)
 }
+
+func TestGiteaAdminPassword(t *testing.T) {
+	first, err := GiteaAdminPassword("")
+	assert.NoError(t, err)
+	second, err := GiteaAdminPassword("")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	dir := t.TempDir()
+	assert.NoError(t, PrepareGiteaDataDir(dir))
+	info, err := os.Stat(filepath.Join(dir, GiteaDataSubDir))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0777), info.Mode().Perm())
+	info, err = os.Stat(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	first, err = GiteaAdminPassword(dir)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first)
+	second, err = GiteaAdminPassword(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	info, err = os.Stat(filepath.Join(dir, giteaAdminPasswordFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestGetGiteaReadOnlyToken(t *testing.T) {
+	var deleted []string
+	var created gitea.CreateAccessTokenOption
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"1.24.3"}`))
+	})
+	mux.HandleFunc("GET /api/v1/users/giteaAdmin/tokens", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]gitea.AccessToken{{ID: 1, Name: GiteaAdminTokenName}, {ID: 2, Name: GiteaReadOnlyTokenName}})
+	})
+	mux.HandleFunc("DELETE /api/v1/users/giteaAdmin/tokens/{id}", func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/v1/users/giteaAdmin/tokens", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(gitea.AccessToken{ID: 3, Name: created.Name, Token: "read-only"})
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "giteaAdmin" || p != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	token, err := GetGiteaReadOnlyToken(context.Background(), server.URL, "giteaAdmin", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "read-only", token)
+	assert.Equal(t, []string{"2"}, deleted, "only the read-only token is replaced")
+	assert.Equal(t, GiteaReadOnlyTokenName, created.Name)
+	assert.Equal(t, []gitea.AccessTokenScope{"read:repository"}, created.Scopes)
+}
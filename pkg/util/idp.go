@@ -3,11 +3,15 @@ package util
 import (
 	"context"
 	"fmt"
+	"strings"
+
 	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func GetConfig(ctx context.Context) (v1alpha1.BuildCustomizationSpec, error) {
+// GetConfig returns the build customization of the Localbuild with the given name. See GetLocalbuild for how an empty
+// name is handled.
+func GetConfig(ctx context.Context, name string) (v1alpha1.BuildCustomizationSpec, error) {
 	b := v1alpha1.BuildCustomizationSpec{}
 
 	kubeConfig, err := GetKubeConfig()
@@ -20,16 +24,38 @@ func GetConfig(ctx context.Context) (v1alpha1.BuildCustomizationSpec, error) {
 		return b, fmt.Errorf("getting kube client: %w", err)
 	}
 
-	list, err := getLocalBuild(ctx, kubeClient)
+	localBuild, err := GetLocalbuild(ctx, kubeClient, name)
 	if err != nil {
 		return b, err
 	}
-
-	// TODO: We assume that only one LocalBuild exists !
-	return list.Items[0].Spec.BuildCustomization, nil
+	return localBuild.Spec.BuildCustomization, nil
 }
 
-func getLocalBuild(ctx context.Context, kubeClient client.Client) (v1alpha1.LocalbuildList, error) {
-	localBuildList := v1alpha1.LocalbuildList{}
-	return localBuildList, kubeClient.List(ctx, &localBuildList)
+// GetLocalbuild returns the Localbuild with the given name. If name is empty, the only Localbuild in the cluster is
+// returned. It is an error if the cluster has no Localbuild or more than one and name is empty.
+func GetLocalbuild(ctx context.Context, kubeClient client.Client, name string) (v1alpha1.Localbuild, error) {
+	localBuild := v1alpha1.Localbuild{}
+	if name != "" {
+		if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, &localBuild); err != nil {
+			return localBuild, fmt.Errorf("getting localbuild %s: %w", name, err)
+		}
+		return localBuild, nil
+	}
+
+	list := v1alpha1.LocalbuildList{}
+	if err := kubeClient.List(ctx, &list); err != nil {
+		return localBuild, fmt.Errorf("listing localbuilds: %w", err)
+	}
+	switch len(list.Items) {
+	case 0:
+		return localBuild, fmt.Errorf("no localbuild found in the cluster")
+	case 1:
+		return list.Items[0], nil
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for i := range list.Items {
+		names = append(names, list.Items[i].Name)
+	}
+	return localBuild, fmt.Errorf("found %d localbuilds (%s), select one with --name", len(names), strings.Join(names, ", "))
 }
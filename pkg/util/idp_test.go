@@ -0,0 +1,42 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/cnoe-io/idpbuilder/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetLocalbuild(t *testing.T) {
+	ctx := context.Background()
+	teamA := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	teamB := &v1alpha1.Localbuild{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}}
+
+	newClient := func(objs ...client.Object) client.Client {
+		return fake.NewClientBuilder().WithScheme(k8s.GetScheme()).WithObjects(objs...).Build()
+	}
+
+	_, err := GetLocalbuild(ctx, newClient(), "")
+	assert.ErrorContains(t, err, "no localbuild")
+
+	lb, err := GetLocalbuild(ctx, newClient(teamA), "")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", lb.Name)
+
+	_, err = GetLocalbuild(ctx, newClient(teamA, teamB), "")
+	assert.ErrorContains(t, err, "team-a, team-b")
+	assert.ErrorContains(t, err, "--name")
+
+	lb, err = GetLocalbuild(ctx, newClient(teamA, teamB), "team-b")
+	require.NoError(t, err)
+	assert.Equal(t, "team-b", lb.Name)
+
+	_, err = GetLocalbuild(ctx, newClient(teamA), "team-c")
+	assert.Error(t, err)
+}
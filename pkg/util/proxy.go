@@ -0,0 +1,140 @@
+package util
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+)
+
+// in-cluster destinations that must never go through a proxy. kind's default pod and service subnets are included
+// because kind only adds them to the node environment.
+var defaultNoProxy = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local", "10.96.0.0/12", "10.244.0.0/16"}
+
+var (
+	extraCACertsMu sync.RWMutex
+	extraCACerts   []byte
+)
+
+// ReadCACerts reads PEM encoded certificates from the given files and returns them as a single bundle.
+func ReadCACerts(paths []string) ([]byte, error) {
+	var bundle []byte
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificates from %s: %w", p, err)
+		}
+		if _, err = parseCertificates(b); err != nil {
+			return nil, fmt.Errorf("parsing CA certificates from %s: %w", p, err)
+		}
+		bundle = append(bundle, b...)
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			bundle = append(bundle, '\n')
+		}
+	}
+	return bundle, nil
+}
+
+func parseCertificates(in []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(in); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificates found")
+	}
+	return certs, nil
+}
+
+// SetExtraCACerts sets the certificate authorities trusted by HTTP and git clients in addition to the system pool.
+func SetExtraCACerts(bundle []byte) error {
+	if len(bundle) > 0 {
+		if _, err := parseCertificates(bundle); err != nil {
+			return err
+		}
+	}
+	extraCACertsMu.Lock()
+	defer extraCACertsMu.Unlock()
+	extraCACerts = bundle
+	return nil
+}
+
+// GetCertPool returns the system certificate pool with the extra certificate authorities added.
+// It returns nil when no extra certificate authorities are set, so callers use the system defaults.
+func GetCertPool() *x509.CertPool {
+	extraCACertsMu.RLock()
+	defer extraCACertsMu.RUnlock()
+	if len(extraCACerts) == 0 {
+		return nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AppendCertsFromPEM(extraCACerts)
+	return pool
+}
+
+// GetProxySpec returns the proxy configuration for the cluster. noProxy is extended with in-cluster destinations and
+// the host names used to reach the cluster from the local machine.
+func GetProxySpec(proxy string, noProxy []string, cfg v1alpha1.BuildCustomizationSpec) v1alpha1.ProxySpec {
+	if proxy == "" {
+		return v1alpha1.ProxySpec{}
+	}
+
+	entries := append([]string{}, noProxy...)
+	entries = append(entries, defaultNoProxy...)
+	for _, h := range []string{cfg.Host, cfg.IngressHost} {
+		if h != "" {
+			entries = append(entries, h, "."+h)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(entries))
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if _, ok := seen[e]; ok || e == "" {
+			continue
+		}
+		seen[e] = struct{}{}
+		out = append(out, e)
+	}
+
+	return v1alpha1.ProxySpec{
+		HTTPProxy:  proxy,
+		HTTPSProxy: proxy,
+		NoProxy:    strings.Join(out, ","),
+	}
+}
+
+// SetProxyEnv exports the proxy configuration to the environment of this process. kind passes these variables to
+// cluster nodes and http.ProxyFromEnvironment picks them up for idpbuilder's own clients.
+func SetProxyEnv(p v1alpha1.ProxySpec) error {
+	env := map[string]string{
+		"HTTP_PROXY":  p.HTTPProxy,
+		"HTTPS_PROXY": p.HTTPSProxy,
+		"NO_PROXY":    p.NoProxy,
+	}
+	for k, v := range env {
+		if v == "" {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("setting %s: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cnoe-io/idpbuilder/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCACert(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestReadCACerts(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.pem")
+	second := filepath.Join(dir, "second.pem")
+	invalid := filepath.Join(dir, "invalid.pem")
+	assert.NoError(t, os.WriteFile(first, testCACert(t), 0600))
+	assert.NoError(t, os.WriteFile(second, testCACert(t), 0600))
+	assert.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	bundle, err := ReadCACerts(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, bundle)
+
+	bundle, err = ReadCACerts([]string{first, second})
+	assert.NoError(t, err)
+	certs, err := parseCertificates(bundle)
+	assert.NoError(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = ReadCACerts([]string{first, invalid})
+	assert.Error(t, err)
+
+	_, err = ReadCACerts([]string{filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+}
+
+func TestGetCertPool(t *testing.T) {
+	defer SetExtraCACerts(nil)
+
+	assert.NoError(t, SetExtraCACerts(nil))
+	assert.Nil(t, GetCertPool())
+
+	assert.Error(t, SetExtraCACerts([]byte("not a certificate")))
+
+	assert.NoError(t, SetExtraCACerts(testCACert(t)))
+	assert.NotNil(t, GetCertPool())
+	assert.NotNil(t, GetHttpClient().Transport)
+}
+
+func TestGetProxySpec(t *testing.T) {
+	cfg := v1alpha1.BuildCustomizationSpec{Host: "cnoe.localtest.me", IngressHost: "cnoe.localtest.me"}
+
+	assert.Equal(t, v1alpha1.ProxySpec{}, GetProxySpec("", []string{"example.com"}, cfg))
+
+	p := GetProxySpec("http://proxy:3128", []string{"example.com", " localhost"}, cfg)
+	assert.Equal(t, "http://proxy:3128", p.HTTPProxy)
+	assert.Equal(t, "http://proxy:3128", p.HTTPSProxy)
+	assert.Equal(t, "example.com,localhost,127.0.0.1,.svc,.cluster.local,10.96.0.0/12,10.244.0.0/16,cnoe.localtest.me,.cnoe.localtest.me", p.NoProxy)
+}
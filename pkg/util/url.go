@@ -15,6 +15,8 @@ const (
 	QueryStringVersion    = "version"
 	QueryStringTimeout    = "timeout"
 	QueryStringSubmodules = "submodules"
+	// QueryStringReplicate is specific to idpbuilder. false makes Argo CD pull the remote repository directly.
+	QueryStringReplicate = "replicate"
 
 	RepoUrlDelimiter = "//"
 	SCPDelimiter     = ":"
@@ -22,6 +24,7 @@ const (
 
 	defaultTimeout        = time.Second * 27
 	defaultCloneSubmodule = true
+	defaultReplicate      = true
 
 	errMsgUrlUnsupported = "url must have // after the repository url. example: https://github.com/kubernetes-sigs/kustomize//examples"
 	errMsgUrlColon       = "first path segment in URL cannot contain colon"
@@ -42,8 +45,12 @@ type KustomizeRemote struct {
 	Ref        string
 	Submodules bool
 	Timeout    time.Duration
+	// Replicate is true if the repository is copied to the git server before Argo CD pulls it.
+	Replicate bool
 }
 
+// CloneUrl returns the url of the repository. The password is left out because the url is stored in resources,
+// credentials are looked up with RemoteRepoAuth.
 func (g *KustomizeRemote) CloneUrl() string {
 	sb := strings.Builder{}
 	if g.Scheme != "" {
@@ -51,9 +58,6 @@ func (g *KustomizeRemote) CloneUrl() string {
 	}
 	if g.User != "" {
 		sb.WriteString(g.User)
-		if g.Password != "" {
-			sb.WriteString(fmt.Sprintf(":%s", g.Password))
-		}
 		sb.Write([]byte(UserDelimiter))
 	}
 
@@ -114,9 +118,19 @@ func (g *KustomizeRemote) parseQuery() error {
 		}
 	}
 
+	replicate := defaultReplicate
+	replicateString := values.Get(QueryStringReplicate)
+	if replicateString != "" {
+		v, pErr := strconv.ParseBool(replicateString)
+		if pErr == nil {
+			replicate = v
+		}
+	}
+
 	g.Ref = version
 	g.Submodules = cloneSubmodules
 	g.Timeout = duration
+	g.Replicate = replicate
 
 	return nil
 }
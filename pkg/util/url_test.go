@@ -14,6 +14,7 @@ func TestURLParse(t *testing.T) {
 		path      string
 		ref       string
 		submodule bool
+		replicate bool
 		timeout   time.Duration
 		err       bool
 	}
@@ -31,6 +32,7 @@ func TestURLParse(t *testing.T) {
 				path:      "examples/multibases/dev",
 				ref:       "v3.3.1",
 				submodule: true,
+				replicate: true,
 				timeout:   120 * time.Second,
 			},
 		},
@@ -41,6 +43,7 @@ func TestURLParse(t *testing.T) {
 				path:      "examples",
 				ref:       "v3.3.1",
 				submodule: true,
+				replicate: true,
 				timeout:   120 * time.Second,
 			},
 		},
@@ -57,9 +60,21 @@ func TestURLParse(t *testing.T) {
 				path:      "examples/multibases/dev",
 				ref:       "v3.3.1",
 				submodule: false,
+				replicate: true,
 				timeout:   1 * time.Second,
 			},
 		},
+		{
+			input: "https://github.com/cnoe-io/stacks//ref-implementation?ref=v0.1.0&replicate=false",
+			expect: expect{
+				cloneUrl:  "https://github.com/cnoe-io/stacks",
+				path:      "ref-implementation",
+				ref:       "v0.1.0",
+				submodule: true,
+				replicate: false,
+				timeout:   defaultTimeout,
+			},
+		},
 	}
 
 	for i := range cases {
@@ -78,5 +93,6 @@ func TestURLParse(t *testing.T) {
 		assert.Equal(t, c.expect.timeout, r.Timeout)
 		assert.Equal(t, c.expect.ref, r.Ref)
 		assert.Equal(t, c.expect.submodule, r.Submodules)
+		assert.Equal(t, c.expect.replicate, r.Replicate)
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -141,9 +142,22 @@ func IsYamlFile(input string) bool {
 	return extension == ".yaml" || extension == ".yml"
 }
 
+// GetHttpClient returns a client for the endpoints served by the cluster, e.g. the Gitea and Argo CD APIs, which use
+// the self-signed certificate generated by idpbuilder.
 func GetHttpClient() *http.Client {
+	return newHttpClient(&tls.Config{InsecureSkipVerify: true})
+}
+
+// GetRemoteHttpClient returns a client for endpoints outside the cluster. Certificates are verified against the system
+// CA certificates and the extra CA certificates given to idpbuilder.
+func GetRemoteHttpClient() *http.Client {
+	return newHttpClient(&tls.Config{RootCAs: GetCertPool()})
+}
+
+func newHttpClient(tlsConfig *tls.Config) *http.Client {
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
 		DialContext: (&net.Dialer{
 			Timeout:   5 * time.Second,
 			KeepAlive: 30 * time.Second, // from http.DefaultTransport
@@ -169,16 +183,33 @@ func DetectKindNodeProvider() (cluster.ProviderOption, error) {
 	}
 }
 
+// KindNodeRuntimeBinary returns the container runtime command used by kind to manage node containers.
+// It mirrors the detection in DetectKindNodeProvider and returns an empty string if no runtime is found.
+func KindNodeRuntimeBinary() string {
+	switch p := os.Getenv("KIND_EXPERIMENTAL_PROVIDER"); p {
+	case "podman", "docker", "nerdctl", "finch", "nerdctl.lima":
+		return p
+	}
+	for _, n := range []string{"docker", "nerdctl", "podman"} {
+		if _, err := exec.LookPath(n); err == nil {
+			return n
+		}
+	}
+	return ""
+}
+
 func SetPackageLabels(obj client.Object) {
 	labels := obj.GetLabels()
 	if labels == nil {
 		labels = map[string]string{}
-		obj.SetLabels(labels)
 	}
+	// unstructured objects return a copy of their labels.
+	defer obj.SetLabels(labels)
 	labels[v1alpha1.PackageNameLabelKey] = obj.GetName()
 
 	switch n := obj.GetName(); n {
-	case v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName, v1alpha1.IngressNginxPackageName:
+	case v1alpha1.ArgoCDPackageName, v1alpha1.GiteaPackageName, v1alpha1.IngressNginxPackageName, v1alpha1.EnvoyGatewayPackageName,
+		v1alpha1.FluxPackageName:
 		labels[v1alpha1.PackageTypeLabelKey] = v1alpha1.PackageTypeLabelCore
 	default:
 		labels[v1alpha1.PackageTypeLabelKey] = v1alpha1.PackageTypeLabelCustom